@@ -0,0 +1,369 @@
+package atmail
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strings"
+	"sync"
+
+	"atmail/passwords"
+)
+
+// Admin is who manages users. Admins authenticate with their password or
+// with tokens, unless they are disabled.
+type Admin struct {
+	User     string
+	Roles    []string
+	Disabled bool
+}
+
+func ValidateAdmin(admin Admin) (string, bool) {
+	if admin.User == "" {
+		return "user cannot be blank!", false
+	}
+
+	// basic authentication separates the user from the password with a colon
+	if strings.Contains(admin.User, ":") {
+		return "user cannot contain a colon!", false
+	}
+
+	return "", true
+}
+
+func ValidatePassword(password string) (string, bool) {
+	if len(password) < 8 {
+		return "password must be at least 8 characters!", false
+	}
+
+	return "", true
+}
+
+// dummyHash is verified against when an admin does not exist so that the
+// response time does not reveal which admins exist.
+var dummyHash = sync.OnceValue(func() string {
+	hash, _ := passwords.Hash("")
+	return hash
+})
+
+// GetRoles returns the names of the roles of the admin with the given
+// credentials.
+func (s store) GetRoles(ctx context.Context, user string, password string) ([]string, error) {
+	var hash string
+	var disabled bool
+
+	// credentials are never read from replicas, so that disabling an admin,
+	// changing their password or removing their roles applies at once
+	if err := s.conn().QueryRowContext(ctx, "SELECT password, disabled FROM admins WHERE `user` = ?", user).Scan(&hash, &disabled); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, err
+		}
+
+		passwords.Verify(dummyHash(), password)
+
+		return nil, ErrAdminNone
+	}
+
+	ok, err := passwords.Verify(hash, password)
+	if err != nil {
+		return nil, err
+	}
+
+	// disabled admins are verified anyway so that they cannot be told apart
+	if !ok || disabled {
+		return nil, ErrAdminNone
+	}
+
+	// upgrade plaintext and outdated hashes now that we know the password
+	if passwords.NeedsRehash(hash) {
+		if err := s.rehash(ctx, user, hash, password); err != nil {
+			log.Printf("failed to rehash password of admin %s: %v", user, err)
+		}
+	}
+
+	return s.adminRoles(ctx, user)
+}
+
+func (s store) adminRoles(ctx context.Context, user string) ([]string, error) {
+	rows, err := s.conn().QueryContext(ctx, "SELECT r.name FROM admin_roles ar JOIN roles r ON r.id = ar.role_id WHERE ar.admin = ? ORDER BY r.name", user)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanStrings(rows)
+}
+
+// GetPermissions returns the names of the permissions granted by each role.
+func (s store) GetPermissions(ctx context.Context) (map[string][]string, error) {
+	rows, err := s.conn().QueryContext(ctx, "SELECT r.name, p.name FROM roles r JOIN role_permissions rp ON rp.role_id = r.id JOIN permissions p ON p.id = rp.permission_id")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	permissions := map[string][]string{}
+
+	for rows.Next() {
+		var role, permission string
+
+		if err := rows.Scan(&role, &permission); err != nil {
+			return nil, err
+		}
+
+		permissions[role] = append(permissions[role], permission)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+func scanStrings(rows *sql.Rows) ([]string, error) {
+	defer rows.Close()
+
+	ss := []string{}
+
+	for rows.Next() {
+		var s string
+
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+
+		ss = append(ss, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ss, nil
+}
+
+func (s store) rehash(ctx context.Context, user string, oldHash string, password string) error {
+	hash, err := passwords.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	// only replace the hash we verified against in case it changed meanwhile
+	if _, err := s.conn().ExecContext(ctx, "UPDATE admins SET password = ? WHERE `user` = ? AND password = ?", hash, user, oldHash); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateAdmin creates an admin with the given password, which is hashed
+// before it is stored.
+func (s store) CreateAdmin(ctx context.Context, admin Admin, password string) error {
+	hash, err := passwords.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	// the unique key tells admins created at the same time apart, which a
+	// check before the insert would not
+	if _, err := tx.ExecContext(ctx, "INSERT INTO admins (`user`, password, disabled) VALUES (?, ?, ?)", admin.User, hash, admin.Disabled); err != nil {
+		return s.adminExists(err)
+	}
+
+	if err := setAdminRoles(ctx, tx, admin.User, admin.Roles); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s store) ListAdmins(ctx context.Context) ([]Admin, error) {
+	rows, err := s.conn().QueryContext(ctx, "SELECT `user`, disabled FROM admins ORDER BY `user`")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	admins := []Admin{}
+
+	for rows.Next() {
+		admin := Admin{}
+
+		if err := rows.Scan(&admin.User, &admin.Disabled); err != nil {
+			return nil, err
+		}
+
+		admins = append(admins, admin)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range admins {
+		admins[i].Roles, err = s.adminRoles(ctx, admins[i].User)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return admins, nil
+}
+
+func (s store) GetAdmin(ctx context.Context, user string) (Admin, error) {
+	admin := Admin{}
+
+	if err := s.conn().QueryRowContext(ctx, "SELECT `user`, disabled FROM admins WHERE `user` = ?", user).Scan(&admin.User, &admin.Disabled); err != nil {
+		if err != sql.ErrNoRows {
+			return Admin{}, err
+		}
+
+		return Admin{}, ErrAdminNone
+	}
+
+	roles, err := s.adminRoles(ctx, user)
+	if err != nil {
+		return Admin{}, err
+	}
+
+	admin.Roles = roles
+
+	return admin, nil
+}
+
+// SetAdminRoles replaces the roles of an admin. It fails with ErrRoleNone if
+// any of the roles does not exist.
+func (s store) SetAdminRoles(ctx context.Context, user string, roles []string) error {
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err := adminExists(ctx, tx, user); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM admin_roles WHERE admin = ?", user); err != nil {
+		return err
+	}
+
+	if err := setAdminRoles(ctx, tx, user, roles); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s store) SetAdminPassword(ctx context.Context, user string, password string) error {
+	hash, err := passwords.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	return s.updateAdmin(ctx, "UPDATE admins SET password = ? WHERE `user` = ?", hash, user)
+}
+
+func (s store) SetAdminDisabled(ctx context.Context, user string, disabled bool) error {
+	return s.updateAdmin(ctx, "UPDATE admins SET disabled = ? WHERE `user` = ?", disabled, user)
+}
+
+// DeleteAdmin deletes an admin along with their roles and tokens.
+func (s store) DeleteAdmin(ctx context.Context, user string) error {
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM admins WHERE `user` = ?", user)
+	if err != nil {
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count != 1 {
+		return ErrAdminNone
+	}
+
+	for _, query := range []string{
+		"DELETE FROM admin_roles WHERE admin = ?",
+		"DELETE FROM token_roles WHERE token_id IN (SELECT id FROM admin_tokens WHERE admin = ?)",
+		"DELETE FROM admin_tokens WHERE admin = ?",
+	} {
+		if _, err := tx.ExecContext(ctx, query, user); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s store) updateAdmin(ctx context.Context, query string, value any, user string) error {
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	// rows affected is 0 when the value does not change, so check separately
+	if err := adminExists(ctx, tx, user); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, query, value, user); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func adminExists(ctx context.Context, tx querier, user string) error {
+	var exists bool
+
+	if err := tx.QueryRowContext(ctx, "SELECT count(*) != 0 FROM admins WHERE `user` = ?", user).Scan(&exists); err != nil {
+		return err
+	}
+
+	if !exists {
+		return ErrAdminNone
+	}
+
+	return nil
+}
+
+func setAdminRoles(ctx context.Context, tx querier, user string, roles []string) error {
+	for _, role := range roles {
+		result, err := tx.ExecContext(ctx, "INSERT INTO admin_roles (admin, role_id) SELECT ?, id FROM roles WHERE name = ?", user, role)
+		if err != nil {
+			return err
+		}
+
+		count, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if count != 1 {
+			return ErrRoleNone
+		}
+	}
+
+	return nil
+}
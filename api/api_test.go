@@ -1,22 +1,28 @@
 package api_test
 
-//go:generate go run github.com/ogen-go/ogen/cmd/ogen@latest -package api -target ../api --clean ../api.yaml
+// ogen is the version in go.mod, and ogen.yml reads the JSON patches as JSON.
+//
+//go:generate go run github.com/ogen-go/ogen/cmd/ogen --config ogen.yml -package api -target ../api --clean ../api.yaml
 
 import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"io"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"atmail"
 	"atmail/api"
 	"atmail/server"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-faster/jx"
+	"github.com/ogen-go/ogen/ogenerrors"
 )
 
 type basicAuth struct {
@@ -28,25 +34,83 @@ func (b basicAuth) BasicAuth(_ context.Context, _ api.OperationName) (api.BasicA
 	return api.BasicAuth{b.username, b.password}, nil
 }
 
-func TestApi(t *testing.T) {
-	// setup store
-	databaseUrl := os.Getenv("MYSQL_URL")
+func (b basicAuth) BearerAuth(_ context.Context, _ api.OperationName) (api.BearerAuth, error) {
+	return api.BearerAuth{}, ogenerrors.ErrSkipClientSecurity
+}
+
+type bearerAuth struct {
+	token string
+}
+
+func (b bearerAuth) BasicAuth(_ context.Context, _ api.OperationName) (api.BasicAuth, error) {
+	return api.BasicAuth{}, ogenerrors.ErrSkipClientSecurity
+}
+
+func (b bearerAuth) BearerAuth(_ context.Context, _ api.OperationName) (api.BearerAuth, error) {
+	return api.BearerAuth{Token: b.token}, nil
+}
 
-	if databaseUrl == "" {
-		log.Fatal("MYSQL_URL cannot be blank!")
+// openTestStore opens the database of DATABASE_URL, or the MySQL database of
+// MYSQL_URL, or else a new SQLite database. The database is migrated and has
+// dan, who is a bandit that may also narrow tokens to bingo.
+func openTestStore(t *testing.T) (*sql.DB, atmail.Store) {
+	ctx := context.Background()
+
+	url := "sqlite://" + filepath.Join(t.TempDir(), "atmail.db")
+
+	if databaseUrl := os.Getenv("MYSQL_URL"); databaseUrl != "" {
+		url = "mysql://" + databaseUrl
+	}
+
+	if databaseUrl := os.Getenv("DATABASE_URL"); databaseUrl != "" {
+		url = databaseUrl
 	}
 
-	db, err := sql.Open("mysql", databaseUrl)
+	db, err := atmail.Open(url)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if _, err := db.Exec("DELETE FROM users"); err != nil {
+	t.Cleanup(func() { db.Close() })
+
+	m, err := atmail.NewMigrator(db, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Up(ctx); err != nil {
 		t.Fatal(err)
 	}
 
 	store := atmail.NewStore(db)
 
+	// dan of setup.sql is only a bandit
+	err = store.CreateAdmin(ctx, atmail.Admin{User: "dan", Roles: []string{"bandit", "bingo"}}, "pass4567")
+	if err == atmail.ErrAdminExists {
+		err = store.SetAdminRoles(ctx, "dan", []string{"bandit", "bingo"})
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db, store
+}
+
+func TestApi(t *testing.T) {
+	// setup store
+	db, store := openTestStore(t)
+
+	if _, err := db.Exec("DELETE FROM users"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, query := range []string{"DELETE FROM admin_roles WHERE admin = 'erin'", "DELETE FROM admins WHERE user = 'erin'"} {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
 	// setup server
 	server := httptest.NewServer(server.New(store))
 	defer server.Close()
@@ -80,40 +144,134 @@ func TestApi(t *testing.T) {
 		t.Error(err)
 	}
 
-	gotUser, ok := getUserRes.(*api.User)
+	gotUser, ok := getUserRes.(*api.UserHeaders)
 	if !ok {
-		t.Error("response is not a user!")
-	} else if !reflect.DeepEqual(user, gotUser) {
+		t.Fatal("response is not a user!")
+	} else if !reflect.DeepEqual(user, &gotUser.Response) {
 		t.Error("user is not the same!")
 	}
 
+	etag := gotUser.Etag.Or("")
+
+	getUserRes, err = c.GetUser(ctx, api.GetUserParams{ID: user.ID, IfNoneMatch: api.NewOptString(etag)})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if _, ok := getUserRes.(*api.GetUserNotModified); !ok {
+		t.Error("response is not notModified!")
+	}
+
+	listUsersRes, err := c.ListUsers(ctx, api.ListUsersParams{Username: api.NewOptString("validuser")})
+	if err != nil {
+		t.Error(err)
+	}
+
+	listUsersOk, ok := listUsersRes.(*api.ListUsersOK)
+	if !ok {
+		t.Error("response is not listUsersOk")
+	} else if len(listUsersOk.Items) != 1 || !reflect.DeepEqual(user, &listUsersOk.Items[0]) {
+		t.Error("users are not the same!")
+	}
+
+	searchUsersRes, err := c.SearchUsers(ctx, api.SearchUsersParams{Q: "validuser"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	searchUsersOk, ok := searchUsersRes.(*api.SearchUsersOK)
+	if !ok {
+		t.Error("response is not searchUsersOk")
+	} else if len(searchUsersOk.Items) != 1 || !reflect.DeepEqual(user, &searchUsersOk.Items[0].User) {
+		t.Error("users are not the same!")
+	}
+
 	// Step 3: Update user
-	updateUserRes, err := c.UpdateUser(ctx, &api.UpdateUserReq{
-		Username: api.NewOptString("validusername2"),
-		Email:    api.NewOptString("valid2@email.com"),
-		Age:      api.NewOptInt64(321),
-	}, api.UpdateUserParams{ID: user.ID})
+	updateUserReq := &api.UpdateUserReq{
+		Username: "validusername2",
+		Email:    "valid2@email.com",
+		Age:      321,
+	}
+
+	updateUserRes, err := c.UpdateUser(ctx, updateUserReq, api.UpdateUserParams{ID: user.ID, IfMatch: api.NewOptString(`"0"`)})
 	if err != nil {
 		t.Error(err)
 	}
 
-	gotUser, ok = updateUserRes.(*api.User)
+	if _, ok := updateUserRes.(*api.PreconditionFailed); !ok {
+		t.Error("response is not preconditionFailed!")
+	}
+
+	updateUserRes, err = c.UpdateUser(ctx, updateUserReq, api.UpdateUserParams{ID: user.ID, IfMatch: api.NewOptString(etag)})
+	if err != nil {
+		t.Error(err)
+	}
+
+	gotUser, ok = updateUserRes.(*api.UserHeaders)
 	if !ok {
-		t.Error("response is not a user!")
+		t.Fatal("response is not a user!")
+	}
+
+	if gotUser.Etag.Or("") == etag {
+		t.Error("etag is unchanged")
 	}
 
-	if gotUser.Username != "validusername2" {
+	if gotUser.Response.Username != "validusername2" {
 		t.Error("username is wrong")
 	}
 
-	if gotUser.Email != "valid2@email.com" {
+	if gotUser.Response.Email != "valid2@email.com" {
 		t.Error("email is wrong")
 	}
 
-	if gotUser.Age != 321 {
+	if gotUser.Response.Age != 321 {
 		t.Error("age is wrong")
 	}
 
+	patchUserRes, err := c.PatchUser(ctx, &api.PatchUserReqApplicationMergePatchJSON{
+		Age: api.NewOptNilInt64(322),
+	}, api.PatchUserParams{ID: user.ID, IfMatch: gotUser.Etag})
+	if err != nil {
+		t.Error(err)
+	}
+
+	gotUser, ok = patchUserRes.(*api.UserHeaders)
+	if !ok {
+		t.Fatal("response is not a user!")
+	}
+
+	if gotUser.Response.Username != "validusername2" || gotUser.Response.Age != 322 {
+		t.Error("user is not merge patched")
+	}
+
+	patchUserRes, err = c.PatchUser(ctx, &api.PatchUserReqApplicationJSONPatchJSON{
+		{Op: api.PatchOperationOpTest, Path: "/age", Value: jx.Raw(`322`)},
+		{Op: api.PatchOperationOpReplace, Path: "/username", Value: jx.Raw(`"validusername3"`)},
+	}, api.PatchUserParams{ID: user.ID})
+	if err != nil {
+		t.Error(err)
+	}
+
+	gotUser, ok = patchUserRes.(*api.UserHeaders)
+	if !ok {
+		t.Fatal("response is not a user!")
+	}
+
+	if gotUser.Response.Username != "validusername3" || gotUser.Response.Age != 322 {
+		t.Error("user is not json patched")
+	}
+
+	patchUserRes, err = c.PatchUser(ctx, &api.PatchUserReqApplicationJSONPatchJSON{
+		{Op: api.PatchOperationOpTest, Path: "/age", Value: jx.Raw(`1`)},
+	}, api.PatchUserParams{ID: user.ID})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if _, ok := patchUserRes.(*api.Conflict); !ok {
+		t.Error("response is not conflict!")
+	}
+
 	// Step 4: Delete user
 	deleteUserRes, err := c.DeleteUser(ctx, api.DeleteUserParams{ID: user.ID})
 	if err != nil {
@@ -128,4 +286,315 @@ func TestApi(t *testing.T) {
 	if deleteUserOk.Message != fmt.Sprintf("successfully deleted user %d!", user.ID) {
 		t.Error("delete message is wrong!")
 	}
+
+	getUserRes, err = c.GetUser(ctx, api.GetUserParams{ID: user.ID})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if _, ok := getUserRes.(*api.BadRequest); !ok {
+		t.Error("response is not badRequest!")
+	}
+
+	restoreUserRes, err := c.RestoreUser(ctx, api.RestoreUserParams{ID: user.ID})
+	if err != nil {
+		t.Error(err)
+	}
+
+	gotUser, ok = restoreUserRes.(*api.UserHeaders)
+	if !ok {
+		t.Fatal("response is not a user!")
+	}
+
+	if gotUser.Response.Username != "validusername3" {
+		t.Error("user is not restored")
+	}
+
+	deleteUserRes, err = c.DeleteUser(ctx, api.DeleteUserParams{ID: user.ID, Hard: api.NewOptBool(true)})
+	if err != nil {
+		t.Error(err)
+	}
+
+	deleteUserOk, ok = deleteUserRes.(*api.DeleteUserOK)
+	if !ok {
+		t.Fatal("response is not deleteUserOk")
+	}
+
+	if deleteUserOk.Message != fmt.Sprintf("successfully purged user %d!", user.ID) {
+		t.Error("purge message is wrong!")
+	}
+
+	// Step 4: List audit events of user
+	listAuditEventsRes, err := c.ListAuditEvents(ctx, api.ListAuditEventsParams{Target: api.NewOptInt64(user.ID)})
+	if err != nil {
+		t.Error(err)
+	}
+
+	listAuditEventsOk, ok := listAuditEventsRes.(*api.ListAuditEventsOK)
+	if !ok {
+		t.Fatal("response is not listAuditEventsOk")
+	}
+
+	actions := []string{}
+
+	for _, event := range listAuditEventsOk.Items {
+		actions = append(actions, event.Action)
+
+		if event.Actor != "dan" {
+			t.Error("actor is wrong")
+		}
+	}
+
+	if want := []string{"user.purge", "user.restore", "user.delete", "user.update", "user.update", "user.update", "user.create"}; !reflect.DeepEqual(want, actions) {
+		t.Errorf("want %v; got %v", want, actions)
+	}
+
+	// Step 5: Create token limited to the bingo role
+	createTokenRes, err := c.CreateToken(ctx, &api.CreateTokenReq{
+		ExpiresIn: api.NewOptInt64(60),
+		Roles:     []string{"bingo"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, ok := createTokenRes.(*api.Token)
+	if !ok {
+		t.Fatal("response is not a token!")
+	}
+
+	// Step 6: Use token
+	tc, err := api.NewClient(server.URL, bearerAuth{token.Token})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	getUserRes, err = tc.GetUser(ctx, api.GetUserParams{ID: user.ID})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if _, ok := getUserRes.(*api.BadRequest); !ok {
+		t.Error("response is not badRequest!")
+	}
+
+	deleteUserRes, err = tc.DeleteUser(ctx, api.DeleteUserParams{ID: user.ID})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if _, ok := deleteUserRes.(*api.Forbidden); !ok {
+		t.Error("response is not forbidden!")
+	}
+
+	createTokenRes, err = tc.CreateToken(ctx, &api.CreateTokenReq{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if _, ok := createTokenRes.(*api.BadRequest); !ok {
+		t.Error("response is not badRequest!")
+	}
+
+	// Step 7: Revoke token
+	deleteTokenRes, err := tc.DeleteToken(ctx, api.DeleteTokenParams{ID: token.ID})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if _, ok := deleteTokenRes.(*api.DeleteTokenOK); !ok {
+		t.Error("response is not deleteTokenOk")
+	}
+
+	getUserRes, err = tc.GetUser(ctx, api.GetUserParams{ID: user.ID})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if _, ok := getUserRes.(*api.Unauthorized); !ok {
+		t.Error("response is not unauthorized!")
+	}
+
+	// Step 8: Create admin
+	createAdminRes, err := c.CreateAdmin(ctx, &api.CreateAdminReq{
+		User:     "erin",
+		Password: "pass8901",
+		Roles:    []string{"bingo"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := createAdminRes.(*api.Admin); !ok {
+		t.Fatal("response is not an admin!")
+	}
+
+	createAdminRes, err = c.CreateAdmin(ctx, &api.CreateAdminReq{
+		User:     "Erin",
+		Password: "pass8901",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := createAdminRes.(*api.Conflict); !ok {
+		t.Error("response is not conflict!")
+	}
+
+	ec, err := api.NewClient(server.URL, basicAuth{"erin", "pass8901"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	getUserRes, err = ec.GetUser(ctx, api.GetUserParams{ID: user.ID})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if _, ok := getUserRes.(*api.BadRequest); !ok {
+		t.Error("response is not badRequest!")
+	}
+
+	// Step 9: Disable admin
+	disableAdminRes, err := c.DisableAdmin(ctx, api.DisableAdminParams{User: "erin"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if _, ok := disableAdminRes.(*api.DisableAdminOK); !ok {
+		t.Error("response is not disableAdminOk")
+	}
+
+	getUserRes, err = ec.GetUser(ctx, api.GetUserParams{ID: user.ID})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if _, ok := getUserRes.(*api.Unauthorized); !ok {
+		t.Error("response is not unauthorized!")
+	}
+
+	// Step 10: Delete admin
+	deleteAdminRes, err := c.DeleteAdmin(ctx, api.DeleteAdminParams{User: "erin"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if _, ok := deleteAdminRes.(*api.DeleteAdminOK); !ok {
+		t.Error("response is not deleteAdminOk")
+	}
+
+	// Step 11: Import users
+	importUsersRes, err := c.ImportUsers(ctx, &api.ImportUsersReqApplicationXNdjson{
+		Data: strings.NewReader(`{ "username": "importeduser", "email": "imported@email.com", "age": 30 }
+{ "username": "invaliduser", "email": "invalid", "age": 30 }
+`),
+	}, api.ImportUsersParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	importReport, ok := importUsersRes.(*api.ImportReport)
+	if !ok {
+		t.Fatal("response is not an importReport!")
+	}
+
+	if importReport.Created != 1 || importReport.Failed != 1 {
+		t.Error("import report is wrong!")
+	}
+
+	// Step 12: Export users
+	exportUsersRes, err := c.ExportUsers(ctx, api.ExportUsersParams{Format: api.NewOptExportUsersFormat(api.ExportUsersFormatCsv)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exported, ok := exportUsersRes.(*api.ExportUsersOKTextCsv)
+	if !ok {
+		t.Fatal("response is not exportUsersOkTextCsv")
+	}
+
+	bs, err := io.ReadAll(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(bs), "importeduser,imported@email.com,30") {
+		t.Error("export is missing the imported user!")
+	}
+
+	// Step 13: Create webhook, delivered the users created after it
+	createWebhookRes, err := c.CreateWebhook(ctx, &api.CreateWebhookReq{
+		URL:    "https://billing.example.com/hooks",
+		Events: []api.CreateWebhookReqEventsItem{api.CreateWebhookReqEventsItemUserCreate},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	webhook, ok := createWebhookRes.(*api.Webhook)
+	if !ok {
+		t.Fatal("response is not a webhook!")
+	}
+
+	if !webhook.Secret.Set {
+		t.Error("webhook is missing its secret!")
+	}
+
+	if _, err := c.CreateUser(ctx, &api.CreateUserReq{Username: "webhookuser", Email: "webhook@email.com", Age: 30}); err != nil {
+		t.Fatal(err)
+	}
+
+	listDeliveriesRes, err := c.ListWebhookDeliveries(ctx, api.ListWebhookDeliveriesParams{ID: webhook.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries, ok := listDeliveriesRes.(*api.ListWebhookDeliveriesOK)
+	if !ok {
+		t.Fatal("response is not listWebhookDeliveriesOk!")
+	}
+
+	if len(deliveries.Items) != 1 || deliveries.Items[0].Event != "user.create" {
+		t.Fatalf("want 1 user.create delivery; got %v", deliveries.Items)
+	}
+
+	// Step 14: Redeliver delivery
+	redeliverRes, err := c.RedeliverWebhookDelivery(ctx, api.RedeliverWebhookDeliveryParams{ID: webhook.ID, Delivery: deliveries.Items[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if delivery, ok := redeliverRes.(*api.WebhookDelivery); !ok || delivery.Status != api.WebhookDeliveryStatusPending {
+		t.Error("delivery is not pending!")
+	}
+
+	// Step 15: Delete webhook
+	deleteWebhookRes, err := c.DeleteWebhook(ctx, api.DeleteWebhookParams{ID: webhook.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := deleteWebhookRes.(*api.DeleteWebhookOK); !ok {
+		t.Error("response is not deleteWebhookOk")
+	}
+
+	// Step 16: List events, the last of which created the user of the webhook
+	listEventsRes, err := c.ListEvents(ctx, api.ListEventsParams{Limit: api.NewOptInt(1000)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, ok := listEventsRes.(*api.ListEventsOKApplicationJSON)
+	if !ok {
+		t.Fatal("response is not listEventsOk!")
+	}
+
+	if len(events.Items) == 0 {
+		t.Fatal("events are missing!")
+	}
+
+	if last := events.Items[len(events.Items)-1]; last.Action != api.UserEventActionUserCreate || last.Seq != events.LastSeq {
+		t.Errorf("want last event user.create at %d; got %v", events.LastSeq, last)
+	}
 }
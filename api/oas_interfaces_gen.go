@@ -1,18 +1,130 @@
 // Code generated by ogen, DO NOT EDIT.
 package api
 
+type CreateAdminRes interface {
+	createAdminRes()
+}
+
+type CreateTokenRes interface {
+	createTokenRes()
+}
+
 type CreateUserRes interface {
 	createUserRes()
 }
 
+type CreateWebhookRes interface {
+	createWebhookRes()
+}
+
+type DeleteAdminRes interface {
+	deleteAdminRes()
+}
+
+type DeleteTokenRes interface {
+	deleteTokenRes()
+}
+
 type DeleteUserRes interface {
 	deleteUserRes()
 }
 
+type DeleteWebhookRes interface {
+	deleteWebhookRes()
+}
+
+type DisableAdminRes interface {
+	disableAdminRes()
+}
+
+type EnableAdminRes interface {
+	enableAdminRes()
+}
+
+type ExportUsersRes interface {
+	exportUsersRes()
+}
+
+type GetAdminRes interface {
+	getAdminRes()
+}
+
 type GetUserRes interface {
 	getUserRes()
 }
 
+type GetWebhookDeliveryRes interface {
+	getWebhookDeliveryRes()
+}
+
+type GetWebhookRes interface {
+	getWebhookRes()
+}
+
+type ImportUsersReq interface {
+	importUsersReq()
+}
+
+type ImportUsersRes interface {
+	importUsersRes()
+}
+
+type ListAdminsRes interface {
+	listAdminsRes()
+}
+
+type ListAuditEventsRes interface {
+	listAuditEventsRes()
+}
+
+type ListEventsRes interface {
+	listEventsRes()
+}
+
+type ListUsersRes interface {
+	listUsersRes()
+}
+
+type ListWebhookDeliveriesRes interface {
+	listWebhookDeliveriesRes()
+}
+
+type ListWebhooksRes interface {
+	listWebhooksRes()
+}
+
+type PatchUserReq interface {
+	patchUserReq()
+}
+
+type PatchUserRes interface {
+	patchUserRes()
+}
+
+type RedeliverWebhookDeliveryRes interface {
+	redeliverWebhookDeliveryRes()
+}
+
+type RestoreUserRes interface {
+	restoreUserRes()
+}
+
+type SearchUsersRes interface {
+	searchUsersRes()
+}
+
+type SetAdminPasswordRes interface {
+	setAdminPasswordRes()
+}
+
+type SetAdminRolesRes interface {
+	setAdminRolesRes()
+}
+
 type UpdateUserRes interface {
 	updateUserRes()
 }
+
+type UpdateWebhookRes interface {
+	updateWebhookRes()
+}
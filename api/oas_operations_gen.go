@@ -6,8 +6,34 @@ package api
 type OperationName = string
 
 const (
-	CreateUserOperation OperationName = "CreateUser"
-	DeleteUserOperation OperationName = "DeleteUser"
-	GetUserOperation    OperationName = "GetUser"
-	UpdateUserOperation OperationName = "UpdateUser"
+	CreateAdminOperation              OperationName = "CreateAdmin"
+	CreateTokenOperation              OperationName = "CreateToken"
+	CreateUserOperation               OperationName = "CreateUser"
+	CreateWebhookOperation            OperationName = "CreateWebhook"
+	DeleteAdminOperation              OperationName = "DeleteAdmin"
+	DeleteTokenOperation              OperationName = "DeleteToken"
+	DeleteUserOperation               OperationName = "DeleteUser"
+	DeleteWebhookOperation            OperationName = "DeleteWebhook"
+	DisableAdminOperation             OperationName = "DisableAdmin"
+	EnableAdminOperation              OperationName = "EnableAdmin"
+	ExportUsersOperation              OperationName = "ExportUsers"
+	GetAdminOperation                 OperationName = "GetAdmin"
+	GetUserOperation                  OperationName = "GetUser"
+	GetWebhookOperation               OperationName = "GetWebhook"
+	GetWebhookDeliveryOperation       OperationName = "GetWebhookDelivery"
+	ImportUsersOperation              OperationName = "ImportUsers"
+	ListAdminsOperation               OperationName = "ListAdmins"
+	ListAuditEventsOperation          OperationName = "ListAuditEvents"
+	ListEventsOperation               OperationName = "ListEvents"
+	ListUsersOperation                OperationName = "ListUsers"
+	ListWebhookDeliveriesOperation    OperationName = "ListWebhookDeliveries"
+	ListWebhooksOperation             OperationName = "ListWebhooks"
+	PatchUserOperation                OperationName = "PatchUser"
+	RedeliverWebhookDeliveryOperation OperationName = "RedeliverWebhookDelivery"
+	RestoreUserOperation              OperationName = "RestoreUser"
+	SearchUsersOperation              OperationName = "SearchUsers"
+	SetAdminPasswordOperation         OperationName = "SetAdminPassword"
+	SetAdminRolesOperation            OperationName = "SetAdminRoles"
+	UpdateUserOperation               OperationName = "UpdateUser"
+	UpdateWebhookOperation            OperationName = "UpdateWebhook"
 )
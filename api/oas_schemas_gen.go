@@ -2,283 +2,2996 @@
 
 package api
 
+import (
+	"io"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/go-faster/jx"
+)
+
+// Ref: #/components/schemas/admin
+type Admin struct {
+	User     string   `json:"user"`
+	Roles    []string `json:"roles"`
+	Disabled bool     `json:"disabled"`
+}
+
+// GetUser returns the value of User.
+func (s *Admin) GetUser() string {
+	return s.User
+}
+
+// GetRoles returns the value of Roles.
+func (s *Admin) GetRoles() []string {
+	return s.Roles
+}
+
+// GetDisabled returns the value of Disabled.
+func (s *Admin) GetDisabled() bool {
+	return s.Disabled
+}
+
+// SetUser sets the value of User.
+func (s *Admin) SetUser(val string) {
+	s.User = val
+}
+
+// SetRoles sets the value of Roles.
+func (s *Admin) SetRoles(val []string) {
+	s.Roles = val
+}
+
+// SetDisabled sets the value of Disabled.
+func (s *Admin) SetDisabled(val bool) {
+	s.Disabled = val
+}
+
+func (*Admin) createAdminRes()   {}
+func (*Admin) getAdminRes()      {}
+func (*Admin) setAdminRolesRes() {}
+
+// Ref: #/components/schemas/auditEvent
+type AuditEvent struct {
+	ID     int64     `json:"id"`
+	Time   time.Time `json:"time"`
+	Actor  string    `json:"actor"`
+	Action string    `json:"action"`
+	// Pattern of the route, e.g. PUT /users/{id}.
+	Route string `json:"route"`
+	// Id of the user, missing if there is none.
+	Target    OptInt64                `json:"target"`
+	Changes   []AuditEventChangesItem `json:"changes"`
+	ClientIP  string                  `json:"client_ip"`
+	RequestID string                  `json:"request_id"`
+	// SHA-256 of the previous event, all zeros for the first.
+	PrevHash string `json:"prev_hash"`
+	// SHA-256 of the event, chained to prev_hash.
+	Hash string `json:"hash"`
+}
+
+// GetID returns the value of ID.
+func (s *AuditEvent) GetID() int64 {
+	return s.ID
+}
+
+// GetTime returns the value of Time.
+func (s *AuditEvent) GetTime() time.Time {
+	return s.Time
+}
+
+// GetActor returns the value of Actor.
+func (s *AuditEvent) GetActor() string {
+	return s.Actor
+}
+
+// GetAction returns the value of Action.
+func (s *AuditEvent) GetAction() string {
+	return s.Action
+}
+
+// GetRoute returns the value of Route.
+func (s *AuditEvent) GetRoute() string {
+	return s.Route
+}
+
+// GetTarget returns the value of Target.
+func (s *AuditEvent) GetTarget() OptInt64 {
+	return s.Target
+}
+
+// GetChanges returns the value of Changes.
+func (s *AuditEvent) GetChanges() []AuditEventChangesItem {
+	return s.Changes
+}
+
+// GetClientIP returns the value of ClientIP.
+func (s *AuditEvent) GetClientIP() string {
+	return s.ClientIP
+}
+
+// GetRequestID returns the value of RequestID.
+func (s *AuditEvent) GetRequestID() string {
+	return s.RequestID
+}
+
+// GetPrevHash returns the value of PrevHash.
+func (s *AuditEvent) GetPrevHash() string {
+	return s.PrevHash
+}
+
+// GetHash returns the value of Hash.
+func (s *AuditEvent) GetHash() string {
+	return s.Hash
+}
+
+// SetID sets the value of ID.
+func (s *AuditEvent) SetID(val int64) {
+	s.ID = val
+}
+
+// SetTime sets the value of Time.
+func (s *AuditEvent) SetTime(val time.Time) {
+	s.Time = val
+}
+
+// SetActor sets the value of Actor.
+func (s *AuditEvent) SetActor(val string) {
+	s.Actor = val
+}
+
+// SetAction sets the value of Action.
+func (s *AuditEvent) SetAction(val string) {
+	s.Action = val
+}
+
+// SetRoute sets the value of Route.
+func (s *AuditEvent) SetRoute(val string) {
+	s.Route = val
+}
+
+// SetTarget sets the value of Target.
+func (s *AuditEvent) SetTarget(val OptInt64) {
+	s.Target = val
+}
+
+// SetChanges sets the value of Changes.
+func (s *AuditEvent) SetChanges(val []AuditEventChangesItem) {
+	s.Changes = val
+}
+
+// SetClientIP sets the value of ClientIP.
+func (s *AuditEvent) SetClientIP(val string) {
+	s.ClientIP = val
+}
+
+// SetRequestID sets the value of RequestID.
+func (s *AuditEvent) SetRequestID(val string) {
+	s.RequestID = val
+}
+
+// SetPrevHash sets the value of PrevHash.
+func (s *AuditEvent) SetPrevHash(val string) {
+	s.PrevHash = val
+}
+
+// SetHash sets the value of Hash.
+func (s *AuditEvent) SetHash(val string) {
+	s.Hash = val
+}
+
+type AuditEventChangesItem struct {
+	Field string `json:"field"`
+	// Value before the change, null if the field was created.
+	Before jx.Raw `json:"before"`
+	// Value after the change, null if the field was deleted.
+	After jx.Raw `json:"after"`
+}
+
+// GetField returns the value of Field.
+func (s *AuditEventChangesItem) GetField() string {
+	return s.Field
+}
+
+// GetBefore returns the value of Before.
+func (s *AuditEventChangesItem) GetBefore() jx.Raw {
+	return s.Before
+}
+
+// GetAfter returns the value of After.
+func (s *AuditEventChangesItem) GetAfter() jx.Raw {
+	return s.After
+}
+
+// SetField sets the value of Field.
+func (s *AuditEventChangesItem) SetField(val string) {
+	s.Field = val
+}
+
+// SetBefore sets the value of Before.
+func (s *AuditEventChangesItem) SetBefore(val jx.Raw) {
+	s.Before = val
+}
+
+// SetAfter sets the value of After.
+func (s *AuditEventChangesItem) SetAfter(val jx.Raw) {
+	s.After = val
+}
+
 // Ref: #/components/responses/badRequest
 type BadRequest struct{}
 
-func (*BadRequest) createUserRes() {}
-func (*BadRequest) deleteUserRes() {}
-func (*BadRequest) getUserRes()    {}
-func (*BadRequest) updateUserRes() {}
+func (*BadRequest) createAdminRes()              {}
+func (*BadRequest) createTokenRes()              {}
+func (*BadRequest) createUserRes()               {}
+func (*BadRequest) createWebhookRes()            {}
+func (*BadRequest) deleteAdminRes()              {}
+func (*BadRequest) deleteTokenRes()              {}
+func (*BadRequest) deleteUserRes()               {}
+func (*BadRequest) deleteWebhookRes()            {}
+func (*BadRequest) disableAdminRes()             {}
+func (*BadRequest) enableAdminRes()              {}
+func (*BadRequest) exportUsersRes()              {}
+func (*BadRequest) getAdminRes()                 {}
+func (*BadRequest) getUserRes()                  {}
+func (*BadRequest) getWebhookDeliveryRes()       {}
+func (*BadRequest) getWebhookRes()               {}
+func (*BadRequest) importUsersRes()              {}
+func (*BadRequest) listAdminsRes()               {}
+func (*BadRequest) listAuditEventsRes()          {}
+func (*BadRequest) listEventsRes()               {}
+func (*BadRequest) listUsersRes()                {}
+func (*BadRequest) listWebhookDeliveriesRes()    {}
+func (*BadRequest) listWebhooksRes()             {}
+func (*BadRequest) patchUserRes()                {}
+func (*BadRequest) redeliverWebhookDeliveryRes() {}
+func (*BadRequest) restoreUserRes()              {}
+func (*BadRequest) searchUsersRes()              {}
+func (*BadRequest) setAdminPasswordRes()         {}
+func (*BadRequest) setAdminRolesRes()            {}
+func (*BadRequest) updateUserRes()               {}
+func (*BadRequest) updateWebhookRes()            {}
+
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// GetUsername returns the value of Username.
+func (s *BasicAuth) GetUsername() string {
+	return s.Username
+}
+
+// GetPassword returns the value of Password.
+func (s *BasicAuth) GetPassword() string {
+	return s.Password
+}
+
+// SetUsername sets the value of Username.
+func (s *BasicAuth) SetUsername(val string) {
+	s.Username = val
+}
+
+// SetPassword sets the value of Password.
+func (s *BasicAuth) SetPassword(val string) {
+	s.Password = val
+}
+
+type BearerAuth struct {
+	Token string
+}
+
+// GetToken returns the value of Token.
+func (s *BearerAuth) GetToken() string {
+	return s.Token
+}
+
+// SetToken sets the value of Token.
+func (s *BearerAuth) SetToken(val string) {
+	s.Token = val
+}
+
+// Ref: #/components/responses/conflict
+type Conflict struct{}
+
+func (*Conflict) createAdminRes() {}
+func (*Conflict) createUserRes()  {}
+func (*Conflict) patchUserRes()   {}
+func (*Conflict) updateUserRes()  {}
+
+type CreateAdminReq struct {
+	User     string   `json:"user"`
+	Password string   `json:"password"`
+	Roles    []string `json:"roles"`
+}
+
+// GetUser returns the value of User.
+func (s *CreateAdminReq) GetUser() string {
+	return s.User
+}
+
+// GetPassword returns the value of Password.
+func (s *CreateAdminReq) GetPassword() string {
+	return s.Password
+}
+
+// GetRoles returns the value of Roles.
+func (s *CreateAdminReq) GetRoles() []string {
+	return s.Roles
+}
+
+// SetUser sets the value of User.
+func (s *CreateAdminReq) SetUser(val string) {
+	s.User = val
+}
+
+// SetPassword sets the value of Password.
+func (s *CreateAdminReq) SetPassword(val string) {
+	s.Password = val
+}
+
+// SetRoles sets the value of Roles.
+func (s *CreateAdminReq) SetRoles(val []string) {
+	s.Roles = val
+}
+
+type CreateTokenReq struct {
+	// Lifetime of the token in seconds.
+	ExpiresIn OptInt64 `json:"expires_in"`
+	// Subset of the admin's roles granted to the token.
+	Roles []string `json:"roles"`
+}
+
+// GetExpiresIn returns the value of ExpiresIn.
+func (s *CreateTokenReq) GetExpiresIn() OptInt64 {
+	return s.ExpiresIn
+}
+
+// GetRoles returns the value of Roles.
+func (s *CreateTokenReq) GetRoles() []string {
+	return s.Roles
+}
+
+// SetExpiresIn sets the value of ExpiresIn.
+func (s *CreateTokenReq) SetExpiresIn(val OptInt64) {
+	s.ExpiresIn = val
+}
+
+// SetRoles sets the value of Roles.
+func (s *CreateTokenReq) SetRoles(val []string) {
+	s.Roles = val
+}
+
+type CreateUserReq struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Age      int64  `json:"age"`
+}
+
+// GetUsername returns the value of Username.
+func (s *CreateUserReq) GetUsername() string {
+	return s.Username
+}
+
+// GetEmail returns the value of Email.
+func (s *CreateUserReq) GetEmail() string {
+	return s.Email
+}
+
+// GetAge returns the value of Age.
+func (s *CreateUserReq) GetAge() int64 {
+	return s.Age
+}
+
+// SetUsername sets the value of Username.
+func (s *CreateUserReq) SetUsername(val string) {
+	s.Username = val
+}
+
+// SetEmail sets the value of Email.
+func (s *CreateUserReq) SetEmail(val string) {
+	s.Email = val
+}
+
+// SetAge sets the value of Age.
+func (s *CreateUserReq) SetAge(val int64) {
+	s.Age = val
+}
+
+type CreateWebhookReq struct {
+	// Http or https url the events are posted to.
+	URL string `json:"url"`
+	// Key of the HMAC-SHA256 signatures of deliveries, at least 16 characters, random if missing.
+	Secret OptString `json:"secret"`
+	// Events delivered to the webhook, all of them if empty.
+	Events []CreateWebhookReqEventsItem `json:"events"`
+}
+
+// GetURL returns the value of URL.
+func (s *CreateWebhookReq) GetURL() string {
+	return s.URL
+}
+
+// GetSecret returns the value of Secret.
+func (s *CreateWebhookReq) GetSecret() OptString {
+	return s.Secret
+}
+
+// GetEvents returns the value of Events.
+func (s *CreateWebhookReq) GetEvents() []CreateWebhookReqEventsItem {
+	return s.Events
+}
+
+// SetURL sets the value of URL.
+func (s *CreateWebhookReq) SetURL(val string) {
+	s.URL = val
+}
+
+// SetSecret sets the value of Secret.
+func (s *CreateWebhookReq) SetSecret(val OptString) {
+	s.Secret = val
+}
+
+// SetEvents sets the value of Events.
+func (s *CreateWebhookReq) SetEvents(val []CreateWebhookReqEventsItem) {
+	s.Events = val
+}
+
+type CreateWebhookReqEventsItem string
+
+const (
+	CreateWebhookReqEventsItemUserCreate  CreateWebhookReqEventsItem = "user.create"
+	CreateWebhookReqEventsItemUserUpdate  CreateWebhookReqEventsItem = "user.update"
+	CreateWebhookReqEventsItemUserDelete  CreateWebhookReqEventsItem = "user.delete"
+	CreateWebhookReqEventsItemUserRestore CreateWebhookReqEventsItem = "user.restore"
+	CreateWebhookReqEventsItemUserPurge   CreateWebhookReqEventsItem = "user.purge"
+)
+
+// AllValues returns all CreateWebhookReqEventsItem values.
+func (CreateWebhookReqEventsItem) AllValues() []CreateWebhookReqEventsItem {
+	return []CreateWebhookReqEventsItem{
+		CreateWebhookReqEventsItemUserCreate,
+		CreateWebhookReqEventsItemUserUpdate,
+		CreateWebhookReqEventsItemUserDelete,
+		CreateWebhookReqEventsItemUserRestore,
+		CreateWebhookReqEventsItemUserPurge,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s CreateWebhookReqEventsItem) MarshalText() ([]byte, error) {
+	switch s {
+	case CreateWebhookReqEventsItemUserCreate:
+		return []byte(s), nil
+	case CreateWebhookReqEventsItemUserUpdate:
+		return []byte(s), nil
+	case CreateWebhookReqEventsItemUserDelete:
+		return []byte(s), nil
+	case CreateWebhookReqEventsItemUserRestore:
+		return []byte(s), nil
+	case CreateWebhookReqEventsItemUserPurge:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *CreateWebhookReqEventsItem) UnmarshalText(data []byte) error {
+	switch CreateWebhookReqEventsItem(data) {
+	case CreateWebhookReqEventsItemUserCreate:
+		*s = CreateWebhookReqEventsItemUserCreate
+		return nil
+	case CreateWebhookReqEventsItemUserUpdate:
+		*s = CreateWebhookReqEventsItemUserUpdate
+		return nil
+	case CreateWebhookReqEventsItemUserDelete:
+		*s = CreateWebhookReqEventsItemUserDelete
+		return nil
+	case CreateWebhookReqEventsItemUserRestore:
+		*s = CreateWebhookReqEventsItemUserRestore
+		return nil
+	case CreateWebhookReqEventsItemUserPurge:
+		*s = CreateWebhookReqEventsItemUserPurge
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+type DeleteAdminOK struct {
+	Message string `json:"message"`
+}
+
+// GetMessage returns the value of Message.
+func (s *DeleteAdminOK) GetMessage() string {
+	return s.Message
+}
+
+// SetMessage sets the value of Message.
+func (s *DeleteAdminOK) SetMessage(val string) {
+	s.Message = val
+}
+
+func (*DeleteAdminOK) deleteAdminRes() {}
+
+type DeleteTokenOK struct {
+	Message string `json:"message"`
+}
+
+// GetMessage returns the value of Message.
+func (s *DeleteTokenOK) GetMessage() string {
+	return s.Message
+}
+
+// SetMessage sets the value of Message.
+func (s *DeleteTokenOK) SetMessage(val string) {
+	s.Message = val
+}
+
+func (*DeleteTokenOK) deleteTokenRes() {}
+
+type DeleteUserOK struct {
+	Message string `json:"message"`
+}
+
+// GetMessage returns the value of Message.
+func (s *DeleteUserOK) GetMessage() string {
+	return s.Message
+}
+
+// SetMessage sets the value of Message.
+func (s *DeleteUserOK) SetMessage(val string) {
+	s.Message = val
+}
+
+func (*DeleteUserOK) deleteUserRes() {}
+
+type DeleteWebhookOK struct {
+	Message string `json:"message"`
+}
+
+// GetMessage returns the value of Message.
+func (s *DeleteWebhookOK) GetMessage() string {
+	return s.Message
+}
+
+// SetMessage sets the value of Message.
+func (s *DeleteWebhookOK) SetMessage(val string) {
+	s.Message = val
+}
+
+func (*DeleteWebhookOK) deleteWebhookRes() {}
+
+type DisableAdminOK struct {
+	Message string `json:"message"`
+}
+
+// GetMessage returns the value of Message.
+func (s *DisableAdminOK) GetMessage() string {
+	return s.Message
+}
+
+// SetMessage sets the value of Message.
+func (s *DisableAdminOK) SetMessage(val string) {
+	s.Message = val
+}
+
+func (*DisableAdminOK) disableAdminRes() {}
+
+type EnableAdminOK struct {
+	Message string `json:"message"`
+}
+
+// GetMessage returns the value of Message.
+func (s *EnableAdminOK) GetMessage() string {
+	return s.Message
+}
+
+// SetMessage sets the value of Message.
+func (s *EnableAdminOK) SetMessage(val string) {
+	s.Message = val
+}
+
+func (*EnableAdminOK) enableAdminRes() {}
+
+type ExportUsersFormat string
+
+const (
+	ExportUsersFormatNdjson ExportUsersFormat = "ndjson"
+	ExportUsersFormatCsv    ExportUsersFormat = "csv"
+)
+
+// AllValues returns all ExportUsersFormat values.
+func (ExportUsersFormat) AllValues() []ExportUsersFormat {
+	return []ExportUsersFormat{
+		ExportUsersFormatNdjson,
+		ExportUsersFormatCsv,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s ExportUsersFormat) MarshalText() ([]byte, error) {
+	switch s {
+	case ExportUsersFormatNdjson:
+		return []byte(s), nil
+	case ExportUsersFormatCsv:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *ExportUsersFormat) UnmarshalText(data []byte) error {
+	switch ExportUsersFormat(data) {
+	case ExportUsersFormatNdjson:
+		*s = ExportUsersFormatNdjson
+		return nil
+	case ExportUsersFormatCsv:
+		*s = ExportUsersFormatCsv
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+type ExportUsersOKApplicationXNdjson struct {
+	Data io.Reader
+}
+
+// Read reads data from the Data reader.
+//
+// Kept to satisfy the io.Reader interface.
+func (s ExportUsersOKApplicationXNdjson) Read(p []byte) (n int, err error) {
+	if s.Data == nil {
+		return 0, io.EOF
+	}
+	return s.Data.Read(p)
+}
+
+func (*ExportUsersOKApplicationXNdjson) exportUsersRes() {}
+
+type ExportUsersOKTextCsv struct {
+	Data io.Reader
+}
+
+// Read reads data from the Data reader.
+//
+// Kept to satisfy the io.Reader interface.
+func (s ExportUsersOKTextCsv) Read(p []byte) (n int, err error) {
+	if s.Data == nil {
+		return 0, io.EOF
+	}
+	return s.Data.Read(p)
+}
+
+func (*ExportUsersOKTextCsv) exportUsersRes() {}
+
+// Ref: #/components/responses/forbidden
+type Forbidden struct{}
+
+func (*Forbidden) createAdminRes()              {}
+func (*Forbidden) createTokenRes()              {}
+func (*Forbidden) createUserRes()               {}
+func (*Forbidden) createWebhookRes()            {}
+func (*Forbidden) deleteAdminRes()              {}
+func (*Forbidden) deleteTokenRes()              {}
+func (*Forbidden) deleteUserRes()               {}
+func (*Forbidden) deleteWebhookRes()            {}
+func (*Forbidden) disableAdminRes()             {}
+func (*Forbidden) enableAdminRes()              {}
+func (*Forbidden) exportUsersRes()              {}
+func (*Forbidden) getAdminRes()                 {}
+func (*Forbidden) getUserRes()                  {}
+func (*Forbidden) getWebhookDeliveryRes()       {}
+func (*Forbidden) getWebhookRes()               {}
+func (*Forbidden) importUsersRes()              {}
+func (*Forbidden) listAdminsRes()               {}
+func (*Forbidden) listAuditEventsRes()          {}
+func (*Forbidden) listEventsRes()               {}
+func (*Forbidden) listUsersRes()                {}
+func (*Forbidden) listWebhookDeliveriesRes()    {}
+func (*Forbidden) listWebhooksRes()             {}
+func (*Forbidden) patchUserRes()                {}
+func (*Forbidden) redeliverWebhookDeliveryRes() {}
+func (*Forbidden) restoreUserRes()              {}
+func (*Forbidden) searchUsersRes()              {}
+func (*Forbidden) setAdminPasswordRes()         {}
+func (*Forbidden) setAdminRolesRes()            {}
+func (*Forbidden) updateUserRes()               {}
+func (*Forbidden) updateWebhookRes()            {}
+
+// GetUserNotModified is response for GetUser operation.
+type GetUserNotModified struct{}
+
+func (*GetUserNotModified) getUserRes() {}
+
+// Merged schema.
+type GetWebhookDeliveryOK struct {
+	ID        int64                      `json:"id"`
+	WebhookID int64                      `json:"webhook_id"`
+	Event     string                     `json:"event"`
+	Status    GetWebhookDeliveryOKStatus `json:"status"`
+	Attempts  int                        `json:"attempts"`
+	// Only returned while the delivery is pending.
+	NextAttemptAt OptDateTime `json:"next_attempt_at"`
+	CreatedAt     time.Time   `json:"created_at"`
+	// JSON body delivered.
+	Payload GetWebhookDeliveryOKPayload `json:"payload"`
+	History []WebhookAttempt            `json:"history"`
+}
+
+// GetID returns the value of ID.
+func (s *GetWebhookDeliveryOK) GetID() int64 {
+	return s.ID
+}
+
+// GetWebhookID returns the value of WebhookID.
+func (s *GetWebhookDeliveryOK) GetWebhookID() int64 {
+	return s.WebhookID
+}
+
+// GetEvent returns the value of Event.
+func (s *GetWebhookDeliveryOK) GetEvent() string {
+	return s.Event
+}
+
+// GetStatus returns the value of Status.
+func (s *GetWebhookDeliveryOK) GetStatus() GetWebhookDeliveryOKStatus {
+	return s.Status
+}
+
+// GetAttempts returns the value of Attempts.
+func (s *GetWebhookDeliveryOK) GetAttempts() int {
+	return s.Attempts
+}
+
+// GetNextAttemptAt returns the value of NextAttemptAt.
+func (s *GetWebhookDeliveryOK) GetNextAttemptAt() OptDateTime {
+	return s.NextAttemptAt
+}
+
+// GetCreatedAt returns the value of CreatedAt.
+func (s *GetWebhookDeliveryOK) GetCreatedAt() time.Time {
+	return s.CreatedAt
+}
+
+// GetPayload returns the value of Payload.
+func (s *GetWebhookDeliveryOK) GetPayload() GetWebhookDeliveryOKPayload {
+	return s.Payload
+}
+
+// GetHistory returns the value of History.
+func (s *GetWebhookDeliveryOK) GetHistory() []WebhookAttempt {
+	return s.History
+}
+
+// SetID sets the value of ID.
+func (s *GetWebhookDeliveryOK) SetID(val int64) {
+	s.ID = val
+}
+
+// SetWebhookID sets the value of WebhookID.
+func (s *GetWebhookDeliveryOK) SetWebhookID(val int64) {
+	s.WebhookID = val
+}
+
+// SetEvent sets the value of Event.
+func (s *GetWebhookDeliveryOK) SetEvent(val string) {
+	s.Event = val
+}
+
+// SetStatus sets the value of Status.
+func (s *GetWebhookDeliveryOK) SetStatus(val GetWebhookDeliveryOKStatus) {
+	s.Status = val
+}
+
+// SetAttempts sets the value of Attempts.
+func (s *GetWebhookDeliveryOK) SetAttempts(val int) {
+	s.Attempts = val
+}
+
+// SetNextAttemptAt sets the value of NextAttemptAt.
+func (s *GetWebhookDeliveryOK) SetNextAttemptAt(val OptDateTime) {
+	s.NextAttemptAt = val
+}
+
+// SetCreatedAt sets the value of CreatedAt.
+func (s *GetWebhookDeliveryOK) SetCreatedAt(val time.Time) {
+	s.CreatedAt = val
+}
+
+// SetPayload sets the value of Payload.
+func (s *GetWebhookDeliveryOK) SetPayload(val GetWebhookDeliveryOKPayload) {
+	s.Payload = val
+}
+
+// SetHistory sets the value of History.
+func (s *GetWebhookDeliveryOK) SetHistory(val []WebhookAttempt) {
+	s.History = val
+}
+
+func (*GetWebhookDeliveryOK) getWebhookDeliveryRes() {}
+
+// JSON body delivered.
+type GetWebhookDeliveryOKPayload map[string]jx.Raw
+
+func (s *GetWebhookDeliveryOKPayload) init() GetWebhookDeliveryOKPayload {
+	m := *s
+	if m == nil {
+		m = map[string]jx.Raw{}
+		*s = m
+	}
+	return m
+}
+
+type GetWebhookDeliveryOKStatus string
+
+const (
+	GetWebhookDeliveryOKStatusPending   GetWebhookDeliveryOKStatus = "pending"
+	GetWebhookDeliveryOKStatusSucceeded GetWebhookDeliveryOKStatus = "succeeded"
+	GetWebhookDeliveryOKStatusFailed    GetWebhookDeliveryOKStatus = "failed"
+)
+
+// AllValues returns all GetWebhookDeliveryOKStatus values.
+func (GetWebhookDeliveryOKStatus) AllValues() []GetWebhookDeliveryOKStatus {
+	return []GetWebhookDeliveryOKStatus{
+		GetWebhookDeliveryOKStatusPending,
+		GetWebhookDeliveryOKStatusSucceeded,
+		GetWebhookDeliveryOKStatusFailed,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s GetWebhookDeliveryOKStatus) MarshalText() ([]byte, error) {
+	switch s {
+	case GetWebhookDeliveryOKStatusPending:
+		return []byte(s), nil
+	case GetWebhookDeliveryOKStatusSucceeded:
+		return []byte(s), nil
+	case GetWebhookDeliveryOKStatusFailed:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *GetWebhookDeliveryOKStatus) UnmarshalText(data []byte) error {
+	switch GetWebhookDeliveryOKStatus(data) {
+	case GetWebhookDeliveryOKStatusPending:
+		*s = GetWebhookDeliveryOKStatusPending
+		return nil
+	case GetWebhookDeliveryOKStatusSucceeded:
+		*s = GetWebhookDeliveryOKStatusSucceeded
+		return nil
+	case GetWebhookDeliveryOKStatusFailed:
+		*s = GetWebhookDeliveryOKStatusFailed
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+// Ref: #/components/responses/gone
+type Gone struct{}
+
+func (*Gone) listEventsRes() {}
+
+// Ref: #/components/schemas/importReport
+type ImportReport struct {
+	DryRun  bool                   `json:"dry_run"`
+	Created int                    `json:"created"`
+	Skipped int                    `json:"skipped"`
+	Failed  int                    `json:"failed"`
+	Rows    []ImportReportRowsItem `json:"rows"`
+	// Why the body could not be read to the end, after the rows before it were imported.
+	Error OptString `json:"error"`
+}
+
+// GetDryRun returns the value of DryRun.
+func (s *ImportReport) GetDryRun() bool {
+	return s.DryRun
+}
+
+// GetCreated returns the value of Created.
+func (s *ImportReport) GetCreated() int {
+	return s.Created
+}
+
+// GetSkipped returns the value of Skipped.
+func (s *ImportReport) GetSkipped() int {
+	return s.Skipped
+}
+
+// GetFailed returns the value of Failed.
+func (s *ImportReport) GetFailed() int {
+	return s.Failed
+}
+
+// GetRows returns the value of Rows.
+func (s *ImportReport) GetRows() []ImportReportRowsItem {
+	return s.Rows
+}
+
+// GetError returns the value of Error.
+func (s *ImportReport) GetError() OptString {
+	return s.Error
+}
+
+// SetDryRun sets the value of DryRun.
+func (s *ImportReport) SetDryRun(val bool) {
+	s.DryRun = val
+}
+
+// SetCreated sets the value of Created.
+func (s *ImportReport) SetCreated(val int) {
+	s.Created = val
+}
+
+// SetSkipped sets the value of Skipped.
+func (s *ImportReport) SetSkipped(val int) {
+	s.Skipped = val
+}
+
+// SetFailed sets the value of Failed.
+func (s *ImportReport) SetFailed(val int) {
+	s.Failed = val
+}
+
+// SetRows sets the value of Rows.
+func (s *ImportReport) SetRows(val []ImportReportRowsItem) {
+	s.Rows = val
+}
+
+// SetError sets the value of Error.
+func (s *ImportReport) SetError(val OptString) {
+	s.Error = val
+}
+
+func (*ImportReport) importUsersRes() {}
+
+type ImportReportRowsItem struct {
+	Row    int                        `json:"row"`
+	Status ImportReportRowsItemStatus `json:"status"`
+	ID     OptInt64                   `json:"id"`
+	Error  OptString                  `json:"error"`
+}
+
+// GetRow returns the value of Row.
+func (s *ImportReportRowsItem) GetRow() int {
+	return s.Row
+}
+
+// GetStatus returns the value of Status.
+func (s *ImportReportRowsItem) GetStatus() ImportReportRowsItemStatus {
+	return s.Status
+}
+
+// GetID returns the value of ID.
+func (s *ImportReportRowsItem) GetID() OptInt64 {
+	return s.ID
+}
+
+// GetError returns the value of Error.
+func (s *ImportReportRowsItem) GetError() OptString {
+	return s.Error
+}
+
+// SetRow sets the value of Row.
+func (s *ImportReportRowsItem) SetRow(val int) {
+	s.Row = val
+}
+
+// SetStatus sets the value of Status.
+func (s *ImportReportRowsItem) SetStatus(val ImportReportRowsItemStatus) {
+	s.Status = val
+}
+
+// SetID sets the value of ID.
+func (s *ImportReportRowsItem) SetID(val OptInt64) {
+	s.ID = val
+}
+
+// SetError sets the value of Error.
+func (s *ImportReportRowsItem) SetError(val OptString) {
+	s.Error = val
+}
+
+type ImportReportRowsItemStatus string
+
+const (
+	ImportReportRowsItemStatusCreated ImportReportRowsItemStatus = "created"
+	ImportReportRowsItemStatusSkipped ImportReportRowsItemStatus = "skipped"
+	ImportReportRowsItemStatusFailed  ImportReportRowsItemStatus = "failed"
+)
+
+// AllValues returns all ImportReportRowsItemStatus values.
+func (ImportReportRowsItemStatus) AllValues() []ImportReportRowsItemStatus {
+	return []ImportReportRowsItemStatus{
+		ImportReportRowsItemStatusCreated,
+		ImportReportRowsItemStatusSkipped,
+		ImportReportRowsItemStatusFailed,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s ImportReportRowsItemStatus) MarshalText() ([]byte, error) {
+	switch s {
+	case ImportReportRowsItemStatusCreated:
+		return []byte(s), nil
+	case ImportReportRowsItemStatusSkipped:
+		return []byte(s), nil
+	case ImportReportRowsItemStatusFailed:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *ImportReportRowsItemStatus) UnmarshalText(data []byte) error {
+	switch ImportReportRowsItemStatus(data) {
+	case ImportReportRowsItemStatusCreated:
+		*s = ImportReportRowsItemStatusCreated
+		return nil
+	case ImportReportRowsItemStatusSkipped:
+		*s = ImportReportRowsItemStatusSkipped
+		return nil
+	case ImportReportRowsItemStatusFailed:
+		*s = ImportReportRowsItemStatusFailed
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+type ImportUsersReqApplicationXNdjson struct {
+	Data io.Reader
+}
+
+// Read reads data from the Data reader.
+//
+// Kept to satisfy the io.Reader interface.
+func (s ImportUsersReqApplicationXNdjson) Read(p []byte) (n int, err error) {
+	if s.Data == nil {
+		return 0, io.EOF
+	}
+	return s.Data.Read(p)
+}
+
+func (*ImportUsersReqApplicationXNdjson) importUsersReq() {}
+
+type ImportUsersReqTextCsv struct {
+	Data io.Reader
+}
+
+// Read reads data from the Data reader.
+//
+// Kept to satisfy the io.Reader interface.
+func (s ImportUsersReqTextCsv) Read(p []byte) (n int, err error) {
+	if s.Data == nil {
+		return 0, io.EOF
+	}
+	return s.Data.Read(p)
+}
+
+func (*ImportUsersReqTextCsv) importUsersReq() {}
+
+// Ref: #/components/responses/internalServerError
+type InternalServerError struct{}
+
+func (*InternalServerError) createAdminRes()              {}
+func (*InternalServerError) createTokenRes()              {}
+func (*InternalServerError) createUserRes()               {}
+func (*InternalServerError) createWebhookRes()            {}
+func (*InternalServerError) deleteAdminRes()              {}
+func (*InternalServerError) deleteTokenRes()              {}
+func (*InternalServerError) deleteUserRes()               {}
+func (*InternalServerError) deleteWebhookRes()            {}
+func (*InternalServerError) disableAdminRes()             {}
+func (*InternalServerError) enableAdminRes()              {}
+func (*InternalServerError) exportUsersRes()              {}
+func (*InternalServerError) getAdminRes()                 {}
+func (*InternalServerError) getUserRes()                  {}
+func (*InternalServerError) getWebhookDeliveryRes()       {}
+func (*InternalServerError) getWebhookRes()               {}
+func (*InternalServerError) importUsersRes()              {}
+func (*InternalServerError) listAdminsRes()               {}
+func (*InternalServerError) listAuditEventsRes()          {}
+func (*InternalServerError) listEventsRes()               {}
+func (*InternalServerError) listUsersRes()                {}
+func (*InternalServerError) listWebhookDeliveriesRes()    {}
+func (*InternalServerError) listWebhooksRes()             {}
+func (*InternalServerError) patchUserRes()                {}
+func (*InternalServerError) redeliverWebhookDeliveryRes() {}
+func (*InternalServerError) restoreUserRes()              {}
+func (*InternalServerError) searchUsersRes()              {}
+func (*InternalServerError) setAdminPasswordRes()         {}
+func (*InternalServerError) setAdminRolesRes()            {}
+func (*InternalServerError) updateUserRes()               {}
+func (*InternalServerError) updateWebhookRes()            {}
+
+type ListAdminsOK struct {
+	Admins []Admin `json:"admins"`
+}
+
+// GetAdmins returns the value of Admins.
+func (s *ListAdminsOK) GetAdmins() []Admin {
+	return s.Admins
+}
+
+// SetAdmins sets the value of Admins.
+func (s *ListAdminsOK) SetAdmins(val []Admin) {
+	s.Admins = val
+}
+
+func (*ListAdminsOK) listAdminsRes() {}
+
+type ListAuditEventsAction string
+
+const (
+	ListAuditEventsActionUserCreate          ListAuditEventsAction = "user.create"
+	ListAuditEventsActionUserUpdate          ListAuditEventsAction = "user.update"
+	ListAuditEventsActionUserDelete          ListAuditEventsAction = "user.delete"
+	ListAuditEventsActionUserRestore         ListAuditEventsAction = "user.restore"
+	ListAuditEventsActionUserPurge           ListAuditEventsAction = "user.purge"
+	ListAuditEventsActionAuthorizationDenied ListAuditEventsAction = "authorization.denied"
+)
+
+// AllValues returns all ListAuditEventsAction values.
+func (ListAuditEventsAction) AllValues() []ListAuditEventsAction {
+	return []ListAuditEventsAction{
+		ListAuditEventsActionUserCreate,
+		ListAuditEventsActionUserUpdate,
+		ListAuditEventsActionUserDelete,
+		ListAuditEventsActionUserRestore,
+		ListAuditEventsActionUserPurge,
+		ListAuditEventsActionAuthorizationDenied,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s ListAuditEventsAction) MarshalText() ([]byte, error) {
+	switch s {
+	case ListAuditEventsActionUserCreate:
+		return []byte(s), nil
+	case ListAuditEventsActionUserUpdate:
+		return []byte(s), nil
+	case ListAuditEventsActionUserDelete:
+		return []byte(s), nil
+	case ListAuditEventsActionUserRestore:
+		return []byte(s), nil
+	case ListAuditEventsActionUserPurge:
+		return []byte(s), nil
+	case ListAuditEventsActionAuthorizationDenied:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *ListAuditEventsAction) UnmarshalText(data []byte) error {
+	switch ListAuditEventsAction(data) {
+	case ListAuditEventsActionUserCreate:
+		*s = ListAuditEventsActionUserCreate
+		return nil
+	case ListAuditEventsActionUserUpdate:
+		*s = ListAuditEventsActionUserUpdate
+		return nil
+	case ListAuditEventsActionUserDelete:
+		*s = ListAuditEventsActionUserDelete
+		return nil
+	case ListAuditEventsActionUserRestore:
+		*s = ListAuditEventsActionUserRestore
+		return nil
+	case ListAuditEventsActionUserPurge:
+		*s = ListAuditEventsActionUserPurge
+		return nil
+	case ListAuditEventsActionAuthorizationDenied:
+		*s = ListAuditEventsActionAuthorizationDenied
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+type ListAuditEventsOK struct {
+	Items []AuditEvent `json:"items"`
+	// Cursor of the next page, missing on the last page.
+	NextCursor OptString `json:"next_cursor"`
+}
+
+// GetItems returns the value of Items.
+func (s *ListAuditEventsOK) GetItems() []AuditEvent {
+	return s.Items
+}
+
+// GetNextCursor returns the value of NextCursor.
+func (s *ListAuditEventsOK) GetNextCursor() OptString {
+	return s.NextCursor
+}
+
+// SetItems sets the value of Items.
+func (s *ListAuditEventsOK) SetItems(val []AuditEvent) {
+	s.Items = val
+}
+
+// SetNextCursor sets the value of NextCursor.
+func (s *ListAuditEventsOK) SetNextCursor(val OptString) {
+	s.NextCursor = val
+}
+
+func (*ListAuditEventsOK) listAuditEventsRes() {}
+
+type ListEventsOKApplicationJSON struct {
+	Items []UserEvent `json:"items"`
+	// Seq to resume after.
+	LastSeq int64 `json:"last_seq"`
+}
+
+// GetItems returns the value of Items.
+func (s *ListEventsOKApplicationJSON) GetItems() []UserEvent {
+	return s.Items
+}
+
+// GetLastSeq returns the value of LastSeq.
+func (s *ListEventsOKApplicationJSON) GetLastSeq() int64 {
+	return s.LastSeq
+}
+
+// SetItems sets the value of Items.
+func (s *ListEventsOKApplicationJSON) SetItems(val []UserEvent) {
+	s.Items = val
+}
+
+// SetLastSeq sets the value of LastSeq.
+func (s *ListEventsOKApplicationJSON) SetLastSeq(val int64) {
+	s.LastSeq = val
+}
+
+func (*ListEventsOKApplicationJSON) listEventsRes() {}
+
+type ListEventsOKTextEventStream struct {
+	Data io.Reader
+}
+
+// Read reads data from the Data reader.
+//
+// Kept to satisfy the io.Reader interface.
+func (s ListEventsOKTextEventStream) Read(p []byte) (n int, err error) {
+	if s.Data == nil {
+		return 0, io.EOF
+	}
+	return s.Data.Read(p)
+}
+
+func (*ListEventsOKTextEventStream) listEventsRes() {}
+
+type ListUsersOK struct {
+	Items []User `json:"items"`
+	// Cursor of the next page, missing on the last page.
+	NextCursor OptString `json:"next_cursor"`
+}
+
+// GetItems returns the value of Items.
+func (s *ListUsersOK) GetItems() []User {
+	return s.Items
+}
+
+// GetNextCursor returns the value of NextCursor.
+func (s *ListUsersOK) GetNextCursor() OptString {
+	return s.NextCursor
+}
+
+// SetItems sets the value of Items.
+func (s *ListUsersOK) SetItems(val []User) {
+	s.Items = val
+}
+
+// SetNextCursor sets the value of NextCursor.
+func (s *ListUsersOK) SetNextCursor(val OptString) {
+	s.NextCursor = val
+}
+
+func (*ListUsersOK) listUsersRes() {}
+
+type ListUsersSort string
+
+const (
+	ListUsersSortID            ListUsersSort = "id"
+	ListUsersSortUsername      ListUsersSort = "username"
+	ListUsersSortAge           ListUsersSort = "age"
+	ListUsersSortMinusID       ListUsersSort = "-id"
+	ListUsersSortMinusUsername ListUsersSort = "-username"
+	ListUsersSortMinusAge      ListUsersSort = "-age"
+)
+
+// AllValues returns all ListUsersSort values.
+func (ListUsersSort) AllValues() []ListUsersSort {
+	return []ListUsersSort{
+		ListUsersSortID,
+		ListUsersSortUsername,
+		ListUsersSortAge,
+		ListUsersSortMinusID,
+		ListUsersSortMinusUsername,
+		ListUsersSortMinusAge,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s ListUsersSort) MarshalText() ([]byte, error) {
+	switch s {
+	case ListUsersSortID:
+		return []byte(s), nil
+	case ListUsersSortUsername:
+		return []byte(s), nil
+	case ListUsersSortAge:
+		return []byte(s), nil
+	case ListUsersSortMinusID:
+		return []byte(s), nil
+	case ListUsersSortMinusUsername:
+		return []byte(s), nil
+	case ListUsersSortMinusAge:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *ListUsersSort) UnmarshalText(data []byte) error {
+	switch ListUsersSort(data) {
+	case ListUsersSortID:
+		*s = ListUsersSortID
+		return nil
+	case ListUsersSortUsername:
+		*s = ListUsersSortUsername
+		return nil
+	case ListUsersSortAge:
+		*s = ListUsersSortAge
+		return nil
+	case ListUsersSortMinusID:
+		*s = ListUsersSortMinusID
+		return nil
+	case ListUsersSortMinusUsername:
+		*s = ListUsersSortMinusUsername
+		return nil
+	case ListUsersSortMinusAge:
+		*s = ListUsersSortMinusAge
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+type ListWebhookDeliveriesOK struct {
+	Items []WebhookDelivery `json:"items"`
+	// Cursor of the next page, missing on the last page.
+	NextCursor OptString `json:"next_cursor"`
+}
+
+// GetItems returns the value of Items.
+func (s *ListWebhookDeliveriesOK) GetItems() []WebhookDelivery {
+	return s.Items
+}
+
+// GetNextCursor returns the value of NextCursor.
+func (s *ListWebhookDeliveriesOK) GetNextCursor() OptString {
+	return s.NextCursor
+}
+
+// SetItems sets the value of Items.
+func (s *ListWebhookDeliveriesOK) SetItems(val []WebhookDelivery) {
+	s.Items = val
+}
+
+// SetNextCursor sets the value of NextCursor.
+func (s *ListWebhookDeliveriesOK) SetNextCursor(val OptString) {
+	s.NextCursor = val
+}
+
+func (*ListWebhookDeliveriesOK) listWebhookDeliveriesRes() {}
+
+type ListWebhookDeliveriesStatus string
+
+const (
+	ListWebhookDeliveriesStatusPending   ListWebhookDeliveriesStatus = "pending"
+	ListWebhookDeliveriesStatusSucceeded ListWebhookDeliveriesStatus = "succeeded"
+	ListWebhookDeliveriesStatusFailed    ListWebhookDeliveriesStatus = "failed"
+)
+
+// AllValues returns all ListWebhookDeliveriesStatus values.
+func (ListWebhookDeliveriesStatus) AllValues() []ListWebhookDeliveriesStatus {
+	return []ListWebhookDeliveriesStatus{
+		ListWebhookDeliveriesStatusPending,
+		ListWebhookDeliveriesStatusSucceeded,
+		ListWebhookDeliveriesStatusFailed,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s ListWebhookDeliveriesStatus) MarshalText() ([]byte, error) {
+	switch s {
+	case ListWebhookDeliveriesStatusPending:
+		return []byte(s), nil
+	case ListWebhookDeliveriesStatusSucceeded:
+		return []byte(s), nil
+	case ListWebhookDeliveriesStatusFailed:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *ListWebhookDeliveriesStatus) UnmarshalText(data []byte) error {
+	switch ListWebhookDeliveriesStatus(data) {
+	case ListWebhookDeliveriesStatusPending:
+		*s = ListWebhookDeliveriesStatusPending
+		return nil
+	case ListWebhookDeliveriesStatusSucceeded:
+		*s = ListWebhookDeliveriesStatusSucceeded
+		return nil
+	case ListWebhookDeliveriesStatusFailed:
+		*s = ListWebhookDeliveriesStatusFailed
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+type ListWebhooksOK struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+// GetWebhooks returns the value of Webhooks.
+func (s *ListWebhooksOK) GetWebhooks() []Webhook {
+	return s.Webhooks
+}
+
+// SetWebhooks sets the value of Webhooks.
+func (s *ListWebhooksOK) SetWebhooks(val []Webhook) {
+	s.Webhooks = val
+}
+
+func (*ListWebhooksOK) listWebhooksRes() {}
+
+// NewOptBool returns new OptBool with value set to v.
+func NewOptBool(v bool) OptBool {
+	return OptBool{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptBool is optional bool.
+type OptBool struct {
+	Value bool
+	Set   bool
+}
+
+// IsSet returns true if OptBool was set.
+func (o OptBool) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptBool) Reset() {
+	var v bool
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptBool) SetTo(v bool) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptBool) Get() (v bool, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptBool) Or(d bool) bool {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptDateTime returns new OptDateTime with value set to v.
+func NewOptDateTime(v time.Time) OptDateTime {
+	return OptDateTime{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptDateTime is optional time.Time.
+type OptDateTime struct {
+	Value time.Time
+	Set   bool
+}
+
+// IsSet returns true if OptDateTime was set.
+func (o OptDateTime) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptDateTime) Reset() {
+	var v time.Time
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptDateTime) SetTo(v time.Time) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptDateTime) Get() (v time.Time, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptDateTime) Or(d time.Time) time.Time {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptExportUsersFormat returns new OptExportUsersFormat with value set to v.
+func NewOptExportUsersFormat(v ExportUsersFormat) OptExportUsersFormat {
+	return OptExportUsersFormat{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptExportUsersFormat is optional ExportUsersFormat.
+type OptExportUsersFormat struct {
+	Value ExportUsersFormat
+	Set   bool
+}
+
+// IsSet returns true if OptExportUsersFormat was set.
+func (o OptExportUsersFormat) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptExportUsersFormat) Reset() {
+	var v ExportUsersFormat
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptExportUsersFormat) SetTo(v ExportUsersFormat) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptExportUsersFormat) Get() (v ExportUsersFormat, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptExportUsersFormat) Or(d ExportUsersFormat) ExportUsersFormat {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptInt returns new OptInt with value set to v.
+func NewOptInt(v int) OptInt {
+	return OptInt{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptInt is optional int.
+type OptInt struct {
+	Value int
+	Set   bool
+}
+
+// IsSet returns true if OptInt was set.
+func (o OptInt) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptInt) Reset() {
+	var v int
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptInt) SetTo(v int) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptInt) Get() (v int, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptInt) Or(d int) int {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptInt64 returns new OptInt64 with value set to v.
+func NewOptInt64(v int64) OptInt64 {
+	return OptInt64{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptInt64 is optional int64.
+type OptInt64 struct {
+	Value int64
+	Set   bool
+}
+
+// IsSet returns true if OptInt64 was set.
+func (o OptInt64) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptInt64) Reset() {
+	var v int64
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptInt64) SetTo(v int64) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptInt64) Get() (v int64, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptInt64) Or(d int64) int64 {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptListAuditEventsAction returns new OptListAuditEventsAction with value set to v.
+func NewOptListAuditEventsAction(v ListAuditEventsAction) OptListAuditEventsAction {
+	return OptListAuditEventsAction{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptListAuditEventsAction is optional ListAuditEventsAction.
+type OptListAuditEventsAction struct {
+	Value ListAuditEventsAction
+	Set   bool
+}
+
+// IsSet returns true if OptListAuditEventsAction was set.
+func (o OptListAuditEventsAction) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptListAuditEventsAction) Reset() {
+	var v ListAuditEventsAction
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptListAuditEventsAction) SetTo(v ListAuditEventsAction) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptListAuditEventsAction) Get() (v ListAuditEventsAction, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptListAuditEventsAction) Or(d ListAuditEventsAction) ListAuditEventsAction {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptListUsersSort returns new OptListUsersSort with value set to v.
+func NewOptListUsersSort(v ListUsersSort) OptListUsersSort {
+	return OptListUsersSort{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptListUsersSort is optional ListUsersSort.
+type OptListUsersSort struct {
+	Value ListUsersSort
+	Set   bool
+}
+
+// IsSet returns true if OptListUsersSort was set.
+func (o OptListUsersSort) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptListUsersSort) Reset() {
+	var v ListUsersSort
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptListUsersSort) SetTo(v ListUsersSort) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptListUsersSort) Get() (v ListUsersSort, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptListUsersSort) Or(d ListUsersSort) ListUsersSort {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptListWebhookDeliveriesStatus returns new OptListWebhookDeliveriesStatus with value set to v.
+func NewOptListWebhookDeliveriesStatus(v ListWebhookDeliveriesStatus) OptListWebhookDeliveriesStatus {
+	return OptListWebhookDeliveriesStatus{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptListWebhookDeliveriesStatus is optional ListWebhookDeliveriesStatus.
+type OptListWebhookDeliveriesStatus struct {
+	Value ListWebhookDeliveriesStatus
+	Set   bool
+}
+
+// IsSet returns true if OptListWebhookDeliveriesStatus was set.
+func (o OptListWebhookDeliveriesStatus) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptListWebhookDeliveriesStatus) Reset() {
+	var v ListWebhookDeliveriesStatus
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptListWebhookDeliveriesStatus) SetTo(v ListWebhookDeliveriesStatus) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptListWebhookDeliveriesStatus) Get() (v ListWebhookDeliveriesStatus, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptListWebhookDeliveriesStatus) Or(d ListWebhookDeliveriesStatus) ListWebhookDeliveriesStatus {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptNilInt64 returns new OptNilInt64 with value set to v.
+func NewOptNilInt64(v int64) OptNilInt64 {
+	return OptNilInt64{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptNilInt64 is optional nullable int64.
+type OptNilInt64 struct {
+	Value int64
+	Set   bool
+	Null  bool
+}
+
+// IsSet returns true if OptNilInt64 was set.
+func (o OptNilInt64) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptNilInt64) Reset() {
+	var v int64
+	o.Value = v
+	o.Set = false
+	o.Null = false
+}
+
+// SetTo sets value to v.
+func (o *OptNilInt64) SetTo(v int64) {
+	o.Set = true
+	o.Null = false
+	o.Value = v
+}
+
+// IsSet returns true if value is Null.
+func (o OptNilInt64) IsNull() bool { return o.Null }
+
+// SetNull sets value to null.
+func (o *OptNilInt64) SetToNull() {
+	o.Set = true
+	o.Null = true
+	var v int64
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptNilInt64) Get() (v int64, ok bool) {
+	if o.Null {
+		return v, false
+	}
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptNilInt64) Or(d int64) int64 {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptNilString returns new OptNilString with value set to v.
+func NewOptNilString(v string) OptNilString {
+	return OptNilString{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptNilString is optional nullable string.
+type OptNilString struct {
+	Value string
+	Set   bool
+	Null  bool
+}
+
+// IsSet returns true if OptNilString was set.
+func (o OptNilString) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptNilString) Reset() {
+	var v string
+	o.Value = v
+	o.Set = false
+	o.Null = false
+}
+
+// SetTo sets value to v.
+func (o *OptNilString) SetTo(v string) {
+	o.Set = true
+	o.Null = false
+	o.Value = v
+}
+
+// IsSet returns true if value is Null.
+func (o OptNilString) IsNull() bool { return o.Null }
+
+// SetNull sets value to null.
+func (o *OptNilString) SetToNull() {
+	o.Set = true
+	o.Null = true
+	var v string
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptNilString) Get() (v string, ok bool) {
+	if o.Null {
+		return v, false
+	}
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptNilString) Or(d string) string {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptString returns new OptString with value set to v.
+func NewOptString(v string) OptString {
+	return OptString{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptString is optional string.
+type OptString struct {
+	Value string
+	Set   bool
+}
+
+// IsSet returns true if OptString was set.
+func (o OptString) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptString) Reset() {
+	var v string
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptString) SetTo(v string) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptString) Get() (v string, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptString) Or(d string) string {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// Ref: #/components/schemas/patchOperation
+type PatchOperation struct {
+	Op PatchOperationOp `json:"op"`
+	// JSON Pointer (RFC 6901) to the field, e.g. /username.
+	Path string `json:"path"`
+	// JSON Pointer to the field moved or copied from.
+	From OptString `json:"from"`
+	// Value added, replaced with or tested for.
+	Value jx.Raw `json:"value"`
+}
+
+// GetOp returns the value of Op.
+func (s *PatchOperation) GetOp() PatchOperationOp {
+	return s.Op
+}
+
+// GetPath returns the value of Path.
+func (s *PatchOperation) GetPath() string {
+	return s.Path
+}
+
+// GetFrom returns the value of From.
+func (s *PatchOperation) GetFrom() OptString {
+	return s.From
+}
+
+// GetValue returns the value of Value.
+func (s *PatchOperation) GetValue() jx.Raw {
+	return s.Value
+}
+
+// SetOp sets the value of Op.
+func (s *PatchOperation) SetOp(val PatchOperationOp) {
+	s.Op = val
+}
+
+// SetPath sets the value of Path.
+func (s *PatchOperation) SetPath(val string) {
+	s.Path = val
+}
+
+// SetFrom sets the value of From.
+func (s *PatchOperation) SetFrom(val OptString) {
+	s.From = val
+}
+
+// SetValue sets the value of Value.
+func (s *PatchOperation) SetValue(val jx.Raw) {
+	s.Value = val
+}
+
+type PatchOperationOp string
+
+const (
+	PatchOperationOpAdd     PatchOperationOp = "add"
+	PatchOperationOpRemove  PatchOperationOp = "remove"
+	PatchOperationOpReplace PatchOperationOp = "replace"
+	PatchOperationOpMove    PatchOperationOp = "move"
+	PatchOperationOpCopy    PatchOperationOp = "copy"
+	PatchOperationOpTest    PatchOperationOp = "test"
+)
+
+// AllValues returns all PatchOperationOp values.
+func (PatchOperationOp) AllValues() []PatchOperationOp {
+	return []PatchOperationOp{
+		PatchOperationOpAdd,
+		PatchOperationOpRemove,
+		PatchOperationOpReplace,
+		PatchOperationOpMove,
+		PatchOperationOpCopy,
+		PatchOperationOpTest,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s PatchOperationOp) MarshalText() ([]byte, error) {
+	switch s {
+	case PatchOperationOpAdd:
+		return []byte(s), nil
+	case PatchOperationOpRemove:
+		return []byte(s), nil
+	case PatchOperationOpReplace:
+		return []byte(s), nil
+	case PatchOperationOpMove:
+		return []byte(s), nil
+	case PatchOperationOpCopy:
+		return []byte(s), nil
+	case PatchOperationOpTest:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *PatchOperationOp) UnmarshalText(data []byte) error {
+	switch PatchOperationOp(data) {
+	case PatchOperationOpAdd:
+		*s = PatchOperationOpAdd
+		return nil
+	case PatchOperationOpRemove:
+		*s = PatchOperationOpRemove
+		return nil
+	case PatchOperationOpReplace:
+		*s = PatchOperationOpReplace
+		return nil
+	case PatchOperationOpMove:
+		*s = PatchOperationOpMove
+		return nil
+	case PatchOperationOpCopy:
+		*s = PatchOperationOpCopy
+		return nil
+	case PatchOperationOpTest:
+		*s = PatchOperationOpTest
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+type PatchUserReqApplicationJSONPatchJSON []PatchOperation
+
+func (*PatchUserReqApplicationJSONPatchJSON) patchUserReq() {}
+
+type PatchUserReqApplicationMergePatchJSON struct {
+	Username OptNilString `json:"username"`
+	Email    OptNilString `json:"email"`
+	Age      OptNilInt64  `json:"age"`
+}
+
+// GetUsername returns the value of Username.
+func (s *PatchUserReqApplicationMergePatchJSON) GetUsername() OptNilString {
+	return s.Username
+}
+
+// GetEmail returns the value of Email.
+func (s *PatchUserReqApplicationMergePatchJSON) GetEmail() OptNilString {
+	return s.Email
+}
+
+// GetAge returns the value of Age.
+func (s *PatchUserReqApplicationMergePatchJSON) GetAge() OptNilInt64 {
+	return s.Age
+}
+
+// SetUsername sets the value of Username.
+func (s *PatchUserReqApplicationMergePatchJSON) SetUsername(val OptNilString) {
+	s.Username = val
+}
+
+// SetEmail sets the value of Email.
+func (s *PatchUserReqApplicationMergePatchJSON) SetEmail(val OptNilString) {
+	s.Email = val
+}
+
+// SetAge sets the value of Age.
+func (s *PatchUserReqApplicationMergePatchJSON) SetAge(val OptNilInt64) {
+	s.Age = val
+}
+
+func (*PatchUserReqApplicationMergePatchJSON) patchUserReq() {}
+
+// Ref: #/components/responses/preconditionFailed
+type PreconditionFailed struct{}
+
+func (*PreconditionFailed) patchUserRes()  {}
+func (*PreconditionFailed) updateUserRes() {}
+
+type SearchUsersOK struct {
+	Items []UserMatch `json:"items"`
+	// Cursor of the next page, missing on the last page.
+	NextCursor OptString `json:"next_cursor"`
+}
+
+// GetItems returns the value of Items.
+func (s *SearchUsersOK) GetItems() []UserMatch {
+	return s.Items
+}
+
+// GetNextCursor returns the value of NextCursor.
+func (s *SearchUsersOK) GetNextCursor() OptString {
+	return s.NextCursor
+}
+
+// SetItems sets the value of Items.
+func (s *SearchUsersOK) SetItems(val []UserMatch) {
+	s.Items = val
+}
+
+// SetNextCursor sets the value of NextCursor.
+func (s *SearchUsersOK) SetNextCursor(val OptString) {
+	s.NextCursor = val
+}
+
+func (*SearchUsersOK) searchUsersRes() {}
+
+type SetAdminPasswordOK struct {
+	Message string `json:"message"`
+}
+
+// GetMessage returns the value of Message.
+func (s *SetAdminPasswordOK) GetMessage() string {
+	return s.Message
+}
+
+// SetMessage sets the value of Message.
+func (s *SetAdminPasswordOK) SetMessage(val string) {
+	s.Message = val
+}
+
+func (*SetAdminPasswordOK) setAdminPasswordRes() {}
+
+type SetAdminPasswordReq struct {
+	Password string `json:"password"`
+}
+
+// GetPassword returns the value of Password.
+func (s *SetAdminPasswordReq) GetPassword() string {
+	return s.Password
+}
+
+// SetPassword sets the value of Password.
+func (s *SetAdminPasswordReq) SetPassword(val string) {
+	s.Password = val
+}
+
+type SetAdminRolesReq struct {
+	Roles []string `json:"roles"`
+}
+
+// GetRoles returns the value of Roles.
+func (s *SetAdminRolesReq) GetRoles() []string {
+	return s.Roles
+}
+
+// SetRoles sets the value of Roles.
+func (s *SetAdminRolesReq) SetRoles(val []string) {
+	s.Roles = val
+}
+
+// Ref: #/components/schemas/token
+type Token struct {
+	ID        int64     `json:"id"`
+	Token     string    `json:"token"`
+	Roles     []string  `json:"roles"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// GetID returns the value of ID.
+func (s *Token) GetID() int64 {
+	return s.ID
+}
+
+// GetToken returns the value of Token.
+func (s *Token) GetToken() string {
+	return s.Token
+}
+
+// GetRoles returns the value of Roles.
+func (s *Token) GetRoles() []string {
+	return s.Roles
+}
+
+// GetExpiresAt returns the value of ExpiresAt.
+func (s *Token) GetExpiresAt() time.Time {
+	return s.ExpiresAt
+}
+
+// SetID sets the value of ID.
+func (s *Token) SetID(val int64) {
+	s.ID = val
+}
+
+// SetToken sets the value of Token.
+func (s *Token) SetToken(val string) {
+	s.Token = val
+}
+
+// SetRoles sets the value of Roles.
+func (s *Token) SetRoles(val []string) {
+	s.Roles = val
+}
+
+// SetExpiresAt sets the value of ExpiresAt.
+func (s *Token) SetExpiresAt(val time.Time) {
+	s.ExpiresAt = val
+}
+
+func (*Token) createTokenRes() {}
+
+// Ref: #/components/responses/unauthorized
+type Unauthorized struct{}
+
+func (*Unauthorized) createAdminRes()              {}
+func (*Unauthorized) createTokenRes()              {}
+func (*Unauthorized) createUserRes()               {}
+func (*Unauthorized) createWebhookRes()            {}
+func (*Unauthorized) deleteAdminRes()              {}
+func (*Unauthorized) deleteTokenRes()              {}
+func (*Unauthorized) deleteUserRes()               {}
+func (*Unauthorized) deleteWebhookRes()            {}
+func (*Unauthorized) disableAdminRes()             {}
+func (*Unauthorized) enableAdminRes()              {}
+func (*Unauthorized) exportUsersRes()              {}
+func (*Unauthorized) getAdminRes()                 {}
+func (*Unauthorized) getUserRes()                  {}
+func (*Unauthorized) getWebhookDeliveryRes()       {}
+func (*Unauthorized) getWebhookRes()               {}
+func (*Unauthorized) importUsersRes()              {}
+func (*Unauthorized) listAdminsRes()               {}
+func (*Unauthorized) listAuditEventsRes()          {}
+func (*Unauthorized) listEventsRes()               {}
+func (*Unauthorized) listUsersRes()                {}
+func (*Unauthorized) listWebhookDeliveriesRes()    {}
+func (*Unauthorized) listWebhooksRes()             {}
+func (*Unauthorized) patchUserRes()                {}
+func (*Unauthorized) redeliverWebhookDeliveryRes() {}
+func (*Unauthorized) restoreUserRes()              {}
+func (*Unauthorized) searchUsersRes()              {}
+func (*Unauthorized) setAdminPasswordRes()         {}
+func (*Unauthorized) setAdminRolesRes()            {}
+func (*Unauthorized) updateUserRes()               {}
+func (*Unauthorized) updateWebhookRes()            {}
+
+// Ref: #/components/responses/unsupportedMediaType
+type UnsupportedMediaType struct{}
+
+func (*UnsupportedMediaType) importUsersRes() {}
+func (*UnsupportedMediaType) patchUserRes()   {}
+
+type UpdateUserReq struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Age      int64  `json:"age"`
+}
+
+// GetUsername returns the value of Username.
+func (s *UpdateUserReq) GetUsername() string {
+	return s.Username
+}
+
+// GetEmail returns the value of Email.
+func (s *UpdateUserReq) GetEmail() string {
+	return s.Email
+}
+
+// GetAge returns the value of Age.
+func (s *UpdateUserReq) GetAge() int64 {
+	return s.Age
+}
+
+// SetUsername sets the value of Username.
+func (s *UpdateUserReq) SetUsername(val string) {
+	s.Username = val
+}
+
+// SetEmail sets the value of Email.
+func (s *UpdateUserReq) SetEmail(val string) {
+	s.Email = val
+}
+
+// SetAge sets the value of Age.
+func (s *UpdateUserReq) SetAge(val int64) {
+	s.Age = val
+}
 
-type BasicAuth struct {
-	Username string
-	Password string
+type UpdateWebhookReq struct {
+	// Http or https url the events are posted to.
+	URL string `json:"url"`
+	// New secret, the current one is kept if missing.
+	Secret OptString `json:"secret"`
+	// Events delivered to the webhook, all of them if empty.
+	Events []UpdateWebhookReqEventsItem `json:"events"`
 }
 
-// GetUsername returns the value of Username.
-func (s *BasicAuth) GetUsername() string {
-	return s.Username
+// GetURL returns the value of URL.
+func (s *UpdateWebhookReq) GetURL() string {
+	return s.URL
 }
 
-// GetPassword returns the value of Password.
-func (s *BasicAuth) GetPassword() string {
-	return s.Password
+// GetSecret returns the value of Secret.
+func (s *UpdateWebhookReq) GetSecret() OptString {
+	return s.Secret
 }
 
-// SetUsername sets the value of Username.
-func (s *BasicAuth) SetUsername(val string) {
-	s.Username = val
+// GetEvents returns the value of Events.
+func (s *UpdateWebhookReq) GetEvents() []UpdateWebhookReqEventsItem {
+	return s.Events
 }
 
-// SetPassword sets the value of Password.
-func (s *BasicAuth) SetPassword(val string) {
-	s.Password = val
+// SetURL sets the value of URL.
+func (s *UpdateWebhookReq) SetURL(val string) {
+	s.URL = val
 }
 
-type CreateUserReq struct {
+// SetSecret sets the value of Secret.
+func (s *UpdateWebhookReq) SetSecret(val OptString) {
+	s.Secret = val
+}
+
+// SetEvents sets the value of Events.
+func (s *UpdateWebhookReq) SetEvents(val []UpdateWebhookReqEventsItem) {
+	s.Events = val
+}
+
+type UpdateWebhookReqEventsItem string
+
+const (
+	UpdateWebhookReqEventsItemUserCreate  UpdateWebhookReqEventsItem = "user.create"
+	UpdateWebhookReqEventsItemUserUpdate  UpdateWebhookReqEventsItem = "user.update"
+	UpdateWebhookReqEventsItemUserDelete  UpdateWebhookReqEventsItem = "user.delete"
+	UpdateWebhookReqEventsItemUserRestore UpdateWebhookReqEventsItem = "user.restore"
+	UpdateWebhookReqEventsItemUserPurge   UpdateWebhookReqEventsItem = "user.purge"
+)
+
+// AllValues returns all UpdateWebhookReqEventsItem values.
+func (UpdateWebhookReqEventsItem) AllValues() []UpdateWebhookReqEventsItem {
+	return []UpdateWebhookReqEventsItem{
+		UpdateWebhookReqEventsItemUserCreate,
+		UpdateWebhookReqEventsItemUserUpdate,
+		UpdateWebhookReqEventsItemUserDelete,
+		UpdateWebhookReqEventsItemUserRestore,
+		UpdateWebhookReqEventsItemUserPurge,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s UpdateWebhookReqEventsItem) MarshalText() ([]byte, error) {
+	switch s {
+	case UpdateWebhookReqEventsItemUserCreate:
+		return []byte(s), nil
+	case UpdateWebhookReqEventsItemUserUpdate:
+		return []byte(s), nil
+	case UpdateWebhookReqEventsItemUserDelete:
+		return []byte(s), nil
+	case UpdateWebhookReqEventsItemUserRestore:
+		return []byte(s), nil
+	case UpdateWebhookReqEventsItemUserPurge:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *UpdateWebhookReqEventsItem) UnmarshalText(data []byte) error {
+	switch UpdateWebhookReqEventsItem(data) {
+	case UpdateWebhookReqEventsItemUserCreate:
+		*s = UpdateWebhookReqEventsItemUserCreate
+		return nil
+	case UpdateWebhookReqEventsItemUserUpdate:
+		*s = UpdateWebhookReqEventsItemUserUpdate
+		return nil
+	case UpdateWebhookReqEventsItemUserDelete:
+		*s = UpdateWebhookReqEventsItemUserDelete
+		return nil
+	case UpdateWebhookReqEventsItemUserRestore:
+		*s = UpdateWebhookReqEventsItemUserRestore
+		return nil
+	case UpdateWebhookReqEventsItemUserPurge:
+		*s = UpdateWebhookReqEventsItemUserPurge
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+// Ref: #/components/schemas/user
+type User struct {
+	ID       int64  `json:"id"`
 	Username string `json:"username"`
 	Email    string `json:"email"`
 	Age      int64  `json:"age"`
 }
 
+// GetID returns the value of ID.
+func (s *User) GetID() int64 {
+	return s.ID
+}
+
 // GetUsername returns the value of Username.
-func (s *CreateUserReq) GetUsername() string {
+func (s *User) GetUsername() string {
 	return s.Username
 }
 
 // GetEmail returns the value of Email.
-func (s *CreateUserReq) GetEmail() string {
+func (s *User) GetEmail() string {
 	return s.Email
 }
 
 // GetAge returns the value of Age.
-func (s *CreateUserReq) GetAge() int64 {
+func (s *User) GetAge() int64 {
 	return s.Age
 }
 
+// SetID sets the value of ID.
+func (s *User) SetID(val int64) {
+	s.ID = val
+}
+
 // SetUsername sets the value of Username.
-func (s *CreateUserReq) SetUsername(val string) {
+func (s *User) SetUsername(val string) {
 	s.Username = val
 }
 
 // SetEmail sets the value of Email.
-func (s *CreateUserReq) SetEmail(val string) {
+func (s *User) SetEmail(val string) {
 	s.Email = val
 }
 
 // SetAge sets the value of Age.
-func (s *CreateUserReq) SetAge(val int64) {
+func (s *User) SetAge(val int64) {
 	s.Age = val
 }
 
-type DeleteUserOK struct {
-	Message string `json:"message"`
+func (*User) createUserRes() {}
+
+// Ref: #/components/schemas/userEvent
+type UserEvent struct {
+	Seq    int64           `json:"seq"`
+	Time   time.Time       `json:"time"`
+	Action UserEventAction `json:"action"`
+	UserID int64           `json:"user_id"`
+	// The user and the fields that changed.
+	Data UserEventData `json:"data"`
 }
 
-// GetMessage returns the value of Message.
-func (s *DeleteUserOK) GetMessage() string {
-	return s.Message
+// GetSeq returns the value of Seq.
+func (s *UserEvent) GetSeq() int64 {
+	return s.Seq
 }
 
-// SetMessage sets the value of Message.
-func (s *DeleteUserOK) SetMessage(val string) {
-	s.Message = val
+// GetTime returns the value of Time.
+func (s *UserEvent) GetTime() time.Time {
+	return s.Time
 }
 
-func (*DeleteUserOK) deleteUserRes() {}
+// GetAction returns the value of Action.
+func (s *UserEvent) GetAction() UserEventAction {
+	return s.Action
+}
 
-// Ref: #/components/responses/internalServerError
-type InternalServerError struct{}
+// GetUserID returns the value of UserID.
+func (s *UserEvent) GetUserID() int64 {
+	return s.UserID
+}
 
-func (*InternalServerError) createUserRes() {}
-func (*InternalServerError) deleteUserRes() {}
-func (*InternalServerError) getUserRes()    {}
-func (*InternalServerError) updateUserRes() {}
+// GetData returns the value of Data.
+func (s *UserEvent) GetData() UserEventData {
+	return s.Data
+}
 
-// NewOptInt64 returns new OptInt64 with value set to v.
-func NewOptInt64(v int64) OptInt64 {
-	return OptInt64{
-		Value: v,
-		Set:   true,
-	}
+// SetSeq sets the value of Seq.
+func (s *UserEvent) SetSeq(val int64) {
+	s.Seq = val
 }
 
-// OptInt64 is optional int64.
-type OptInt64 struct {
-	Value int64
-	Set   bool
+// SetTime sets the value of Time.
+func (s *UserEvent) SetTime(val time.Time) {
+	s.Time = val
 }
 
-// IsSet returns true if OptInt64 was set.
-func (o OptInt64) IsSet() bool { return o.Set }
+// SetAction sets the value of Action.
+func (s *UserEvent) SetAction(val UserEventAction) {
+	s.Action = val
+}
 
-// Reset unsets value.
-func (o *OptInt64) Reset() {
-	var v int64
-	o.Value = v
-	o.Set = false
+// SetUserID sets the value of UserID.
+func (s *UserEvent) SetUserID(val int64) {
+	s.UserID = val
 }
 
-// SetTo sets value to v.
-func (o *OptInt64) SetTo(v int64) {
-	o.Set = true
-	o.Value = v
+// SetData sets the value of Data.
+func (s *UserEvent) SetData(val UserEventData) {
+	s.Data = val
 }
 
-// Get returns value and boolean that denotes whether value was set.
-func (o OptInt64) Get() (v int64, ok bool) {
-	if !o.Set {
-		return v, false
+type UserEventAction string
+
+const (
+	UserEventActionUserCreate  UserEventAction = "user.create"
+	UserEventActionUserUpdate  UserEventAction = "user.update"
+	UserEventActionUserDelete  UserEventAction = "user.delete"
+	UserEventActionUserRestore UserEventAction = "user.restore"
+	UserEventActionUserPurge   UserEventAction = "user.purge"
+)
+
+// AllValues returns all UserEventAction values.
+func (UserEventAction) AllValues() []UserEventAction {
+	return []UserEventAction{
+		UserEventActionUserCreate,
+		UserEventActionUserUpdate,
+		UserEventActionUserDelete,
+		UserEventActionUserRestore,
+		UserEventActionUserPurge,
 	}
-	return o.Value, true
 }
 
-// Or returns value if set, or given parameter if does not.
-func (o OptInt64) Or(d int64) int64 {
-	if v, ok := o.Get(); ok {
-		return v
+// MarshalText implements encoding.TextMarshaler.
+func (s UserEventAction) MarshalText() ([]byte, error) {
+	switch s {
+	case UserEventActionUserCreate:
+		return []byte(s), nil
+	case UserEventActionUserUpdate:
+		return []byte(s), nil
+	case UserEventActionUserDelete:
+		return []byte(s), nil
+	case UserEventActionUserRestore:
+		return []byte(s), nil
+	case UserEventActionUserPurge:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
 	}
-	return d
 }
 
-// NewOptString returns new OptString with value set to v.
-func NewOptString(v string) OptString {
-	return OptString{
-		Value: v,
-		Set:   true,
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *UserEventAction) UnmarshalText(data []byte) error {
+	switch UserEventAction(data) {
+	case UserEventActionUserCreate:
+		*s = UserEventActionUserCreate
+		return nil
+	case UserEventActionUserUpdate:
+		*s = UserEventActionUserUpdate
+		return nil
+	case UserEventActionUserDelete:
+		*s = UserEventActionUserDelete
+		return nil
+	case UserEventActionUserRestore:
+		*s = UserEventActionUserRestore
+		return nil
+	case UserEventActionUserPurge:
+		*s = UserEventActionUserPurge
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
 	}
 }
 
-// OptString is optional string.
-type OptString struct {
-	Value string
-	Set   bool
+// The user and the fields that changed.
+type UserEventData map[string]jx.Raw
+
+func (s *UserEventData) init() UserEventData {
+	m := *s
+	if m == nil {
+		m = map[string]jx.Raw{}
+		*s = m
+	}
+	return m
 }
 
-// IsSet returns true if OptString was set.
-func (o OptString) IsSet() bool { return o.Set }
+// UserHeaders wraps User with response headers.
+type UserHeaders struct {
+	Etag     OptString
+	Response User
+}
 
-// Reset unsets value.
-func (o *OptString) Reset() {
-	var v string
-	o.Value = v
-	o.Set = false
+// GetEtag returns the value of Etag.
+func (s *UserHeaders) GetEtag() OptString {
+	return s.Etag
 }
 
-// SetTo sets value to v.
-func (o *OptString) SetTo(v string) {
-	o.Set = true
-	o.Value = v
+// GetResponse returns the value of Response.
+func (s *UserHeaders) GetResponse() User {
+	return s.Response
 }
 
-// Get returns value and boolean that denotes whether value was set.
-func (o OptString) Get() (v string, ok bool) {
-	if !o.Set {
-		return v, false
+// SetEtag sets the value of Etag.
+func (s *UserHeaders) SetEtag(val OptString) {
+	s.Etag = val
+}
+
+// SetResponse sets the value of Response.
+func (s *UserHeaders) SetResponse(val User) {
+	s.Response = val
+}
+
+func (*UserHeaders) getUserRes()     {}
+func (*UserHeaders) patchUserRes()   {}
+func (*UserHeaders) restoreUserRes() {}
+func (*UserHeaders) updateUserRes()  {}
+
+// Ref: #/components/schemas/userMatch
+type UserMatch struct {
+	User User `json:"user"`
+	// Fields of the user that matched.
+	Matched []UserMatchMatchedItem `json:"matched"`
+}
+
+// GetUser returns the value of User.
+func (s *UserMatch) GetUser() User {
+	return s.User
+}
+
+// GetMatched returns the value of Matched.
+func (s *UserMatch) GetMatched() []UserMatchMatchedItem {
+	return s.Matched
+}
+
+// SetUser sets the value of User.
+func (s *UserMatch) SetUser(val User) {
+	s.User = val
+}
+
+// SetMatched sets the value of Matched.
+func (s *UserMatch) SetMatched(val []UserMatchMatchedItem) {
+	s.Matched = val
+}
+
+type UserMatchMatchedItem string
+
+const (
+	UserMatchMatchedItemUsername UserMatchMatchedItem = "username"
+	UserMatchMatchedItemEmail    UserMatchMatchedItem = "email"
+)
+
+// AllValues returns all UserMatchMatchedItem values.
+func (UserMatchMatchedItem) AllValues() []UserMatchMatchedItem {
+	return []UserMatchMatchedItem{
+		UserMatchMatchedItemUsername,
+		UserMatchMatchedItemEmail,
 	}
-	return o.Value, true
 }
 
-// Or returns value if set, or given parameter if does not.
-func (o OptString) Or(d string) string {
-	if v, ok := o.Get(); ok {
-		return v
+// MarshalText implements encoding.TextMarshaler.
+func (s UserMatchMatchedItem) MarshalText() ([]byte, error) {
+	switch s {
+	case UserMatchMatchedItemUsername:
+		return []byte(s), nil
+	case UserMatchMatchedItemEmail:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
 	}
-	return d
 }
 
-// Ref: #/components/responses/unauthorized
-type Unauthorized struct{}
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *UserMatchMatchedItem) UnmarshalText(data []byte) error {
+	switch UserMatchMatchedItem(data) {
+	case UserMatchMatchedItemUsername:
+		*s = UserMatchMatchedItemUsername
+		return nil
+	case UserMatchMatchedItemEmail:
+		*s = UserMatchMatchedItemEmail
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
 
-func (*Unauthorized) createUserRes() {}
-func (*Unauthorized) deleteUserRes() {}
-func (*Unauthorized) getUserRes()    {}
-func (*Unauthorized) updateUserRes() {}
+// Ref: #/components/schemas/webhook
+type Webhook struct {
+	ID  int64  `json:"id"`
+	URL string `json:"url"`
+	// Events delivered to the webhook, all of them if empty.
+	Events []string `json:"events"`
+	// Only returned when the webhook is created.
+	Secret    OptString `json:"secret"`
+	CreatedAt time.Time `json:"created_at"`
+}
 
-type UpdateUserReq struct {
-	Username OptString `json:"username"`
-	Email    OptString `json:"email"`
-	Age      OptInt64  `json:"age"`
+// GetID returns the value of ID.
+func (s *Webhook) GetID() int64 {
+	return s.ID
 }
 
-// GetUsername returns the value of Username.
-func (s *UpdateUserReq) GetUsername() OptString {
-	return s.Username
+// GetURL returns the value of URL.
+func (s *Webhook) GetURL() string {
+	return s.URL
 }
 
-// GetEmail returns the value of Email.
-func (s *UpdateUserReq) GetEmail() OptString {
-	return s.Email
+// GetEvents returns the value of Events.
+func (s *Webhook) GetEvents() []string {
+	return s.Events
 }
 
-// GetAge returns the value of Age.
-func (s *UpdateUserReq) GetAge() OptInt64 {
-	return s.Age
+// GetSecret returns the value of Secret.
+func (s *Webhook) GetSecret() OptString {
+	return s.Secret
 }
 
-// SetUsername sets the value of Username.
-func (s *UpdateUserReq) SetUsername(val OptString) {
-	s.Username = val
+// GetCreatedAt returns the value of CreatedAt.
+func (s *Webhook) GetCreatedAt() time.Time {
+	return s.CreatedAt
 }
 
-// SetEmail sets the value of Email.
-func (s *UpdateUserReq) SetEmail(val OptString) {
-	s.Email = val
+// SetID sets the value of ID.
+func (s *Webhook) SetID(val int64) {
+	s.ID = val
 }
 
-// SetAge sets the value of Age.
-func (s *UpdateUserReq) SetAge(val OptInt64) {
-	s.Age = val
+// SetURL sets the value of URL.
+func (s *Webhook) SetURL(val string) {
+	s.URL = val
 }
 
-// Ref: #/components/schemas/user
-type User struct {
-	ID       int64  `json:"id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Age      int64  `json:"age"`
+// SetEvents sets the value of Events.
+func (s *Webhook) SetEvents(val []string) {
+	s.Events = val
+}
+
+// SetSecret sets the value of Secret.
+func (s *Webhook) SetSecret(val OptString) {
+	s.Secret = val
+}
+
+// SetCreatedAt sets the value of CreatedAt.
+func (s *Webhook) SetCreatedAt(val time.Time) {
+	s.CreatedAt = val
+}
+
+func (*Webhook) createWebhookRes() {}
+func (*Webhook) getWebhookRes()    {}
+func (*Webhook) updateWebhookRes() {}
+
+// Ref: #/components/schemas/webhookAttempt
+type WebhookAttempt struct {
+	Time time.Time `json:"time"`
+	// Status of the response, missing if there was none.
+	StatusCode OptInt `json:"status_code"`
+	// Why the attempt failed, missing if it succeeded.
+	Error      OptString `json:"error"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// GetTime returns the value of Time.
+func (s *WebhookAttempt) GetTime() time.Time {
+	return s.Time
+}
+
+// GetStatusCode returns the value of StatusCode.
+func (s *WebhookAttempt) GetStatusCode() OptInt {
+	return s.StatusCode
+}
+
+// GetError returns the value of Error.
+func (s *WebhookAttempt) GetError() OptString {
+	return s.Error
+}
+
+// GetDurationMs returns the value of DurationMs.
+func (s *WebhookAttempt) GetDurationMs() int64 {
+	return s.DurationMs
+}
+
+// SetTime sets the value of Time.
+func (s *WebhookAttempt) SetTime(val time.Time) {
+	s.Time = val
+}
+
+// SetStatusCode sets the value of StatusCode.
+func (s *WebhookAttempt) SetStatusCode(val OptInt) {
+	s.StatusCode = val
+}
+
+// SetError sets the value of Error.
+func (s *WebhookAttempt) SetError(val OptString) {
+	s.Error = val
+}
+
+// SetDurationMs sets the value of DurationMs.
+func (s *WebhookAttempt) SetDurationMs(val int64) {
+	s.DurationMs = val
+}
+
+// Ref: #/components/schemas/webhookDelivery
+type WebhookDelivery struct {
+	ID        int64                 `json:"id"`
+	WebhookID int64                 `json:"webhook_id"`
+	Event     string                `json:"event"`
+	Status    WebhookDeliveryStatus `json:"status"`
+	Attempts  int                   `json:"attempts"`
+	// Only returned while the delivery is pending.
+	NextAttemptAt OptDateTime `json:"next_attempt_at"`
+	CreatedAt     time.Time   `json:"created_at"`
 }
 
 // GetID returns the value of ID.
-func (s *User) GetID() int64 {
+func (s *WebhookDelivery) GetID() int64 {
 	return s.ID
 }
 
-// GetUsername returns the value of Username.
-func (s *User) GetUsername() string {
-	return s.Username
+// GetWebhookID returns the value of WebhookID.
+func (s *WebhookDelivery) GetWebhookID() int64 {
+	return s.WebhookID
 }
 
-// GetEmail returns the value of Email.
-func (s *User) GetEmail() string {
-	return s.Email
+// GetEvent returns the value of Event.
+func (s *WebhookDelivery) GetEvent() string {
+	return s.Event
 }
 
-// GetAge returns the value of Age.
-func (s *User) GetAge() int64 {
-	return s.Age
+// GetStatus returns the value of Status.
+func (s *WebhookDelivery) GetStatus() WebhookDeliveryStatus {
+	return s.Status
+}
+
+// GetAttempts returns the value of Attempts.
+func (s *WebhookDelivery) GetAttempts() int {
+	return s.Attempts
+}
+
+// GetNextAttemptAt returns the value of NextAttemptAt.
+func (s *WebhookDelivery) GetNextAttemptAt() OptDateTime {
+	return s.NextAttemptAt
+}
+
+// GetCreatedAt returns the value of CreatedAt.
+func (s *WebhookDelivery) GetCreatedAt() time.Time {
+	return s.CreatedAt
 }
 
 // SetID sets the value of ID.
-func (s *User) SetID(val int64) {
+func (s *WebhookDelivery) SetID(val int64) {
 	s.ID = val
 }
 
-// SetUsername sets the value of Username.
-func (s *User) SetUsername(val string) {
-	s.Username = val
+// SetWebhookID sets the value of WebhookID.
+func (s *WebhookDelivery) SetWebhookID(val int64) {
+	s.WebhookID = val
 }
 
-// SetEmail sets the value of Email.
-func (s *User) SetEmail(val string) {
-	s.Email = val
+// SetEvent sets the value of Event.
+func (s *WebhookDelivery) SetEvent(val string) {
+	s.Event = val
 }
 
-// SetAge sets the value of Age.
-func (s *User) SetAge(val int64) {
-	s.Age = val
+// SetStatus sets the value of Status.
+func (s *WebhookDelivery) SetStatus(val WebhookDeliveryStatus) {
+	s.Status = val
 }
 
-func (*User) createUserRes() {}
-func (*User) getUserRes()    {}
-func (*User) updateUserRes() {}
+// SetAttempts sets the value of Attempts.
+func (s *WebhookDelivery) SetAttempts(val int) {
+	s.Attempts = val
+}
+
+// SetNextAttemptAt sets the value of NextAttemptAt.
+func (s *WebhookDelivery) SetNextAttemptAt(val OptDateTime) {
+	s.NextAttemptAt = val
+}
+
+// SetCreatedAt sets the value of CreatedAt.
+func (s *WebhookDelivery) SetCreatedAt(val time.Time) {
+	s.CreatedAt = val
+}
+
+func (*WebhookDelivery) redeliverWebhookDeliveryRes() {}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusSucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "failed"
+)
+
+// AllValues returns all WebhookDeliveryStatus values.
+func (WebhookDeliveryStatus) AllValues() []WebhookDeliveryStatus {
+	return []WebhookDeliveryStatus{
+		WebhookDeliveryStatusPending,
+		WebhookDeliveryStatusSucceeded,
+		WebhookDeliveryStatusFailed,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s WebhookDeliveryStatus) MarshalText() ([]byte, error) {
+	switch s {
+	case WebhookDeliveryStatusPending:
+		return []byte(s), nil
+	case WebhookDeliveryStatusSucceeded:
+		return []byte(s), nil
+	case WebhookDeliveryStatusFailed:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *WebhookDeliveryStatus) UnmarshalText(data []byte) error {
+	switch WebhookDeliveryStatus(data) {
+	case WebhookDeliveryStatusPending:
+		*s = WebhookDeliveryStatusPending
+		return nil
+	case WebhookDeliveryStatusSucceeded:
+		*s = WebhookDeliveryStatusSucceeded
+		return nil
+	case WebhookDeliveryStatusFailed:
+		*s = WebhookDeliveryStatusFailed
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
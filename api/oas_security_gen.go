@@ -16,6 +16,8 @@ import (
 type SecurityHandler interface {
 	// HandleBasicAuth handles basicAuth security.
 	HandleBasicAuth(ctx context.Context, operationName OperationName, t BasicAuth) (context.Context, error)
+	// HandleBearerAuth handles bearerAuth security.
+	HandleBearerAuth(ctx context.Context, operationName OperationName, t BearerAuth) (context.Context, error)
 }
 
 func findAuthorization(h http.Header, prefix string) (string, bool) {
@@ -52,11 +54,28 @@ func (s *Server) securityBasicAuth(ctx context.Context, operationName OperationN
 	}
 	return rctx, true, err
 }
+func (s *Server) securityBearerAuth(ctx context.Context, operationName OperationName, req *http.Request) (context.Context, bool, error) {
+	var t BearerAuth
+	token, ok := findAuthorization(req.Header, "Bearer")
+	if !ok {
+		return ctx, false, nil
+	}
+	t.Token = token
+	rctx, err := s.sec.HandleBearerAuth(ctx, operationName, t)
+	if errors.Is(err, ogenerrors.ErrSkipServerSecurity) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return rctx, true, err
+}
 
 // SecuritySource is provider of security values (tokens, passwords, etc.).
 type SecuritySource interface {
 	// BasicAuth provides basicAuth security value.
 	BasicAuth(ctx context.Context, operationName OperationName) (BasicAuth, error)
+	// BearerAuth provides bearerAuth security value.
+	BearerAuth(ctx context.Context, operationName OperationName) (BearerAuth, error)
 }
 
 func (s *Client) securityBasicAuth(ctx context.Context, operationName OperationName, req *http.Request) error {
@@ -67,3 +86,11 @@ func (s *Client) securityBasicAuth(ctx context.Context, operationName OperationN
 	req.SetBasicAuth(t.Username, t.Password)
 	return nil
 }
+func (s *Client) securityBearerAuth(ctx context.Context, operationName OperationName, req *http.Request) error {
+	t, err := s.sec.BearerAuth(ctx, operationName)
+	if err != nil {
+		return errors.Wrap(err, "security source \"BearerAuth\"")
+	}
+	req.Header.Set("Authorization", "Bearer "+t.Token)
+	return nil
+}
@@ -8,30 +8,187 @@ import (
 
 // Handler handles operations described by OpenAPI v3 specification.
 type Handler interface {
+	// CreateAdmin implements createAdmin operation.
+	//
+	// Create an admin.
+	//
+	// POST /admins
+	CreateAdmin(ctx context.Context, req *CreateAdminReq) (CreateAdminRes, error)
+	// CreateToken implements createToken operation.
+	//
+	// Create an API token for the admin authenticated with their password.
+	//
+	// POST /tokens
+	CreateToken(ctx context.Context, req *CreateTokenReq) (CreateTokenRes, error)
 	// CreateUser implements createUser operation.
 	//
 	// Create a new user.
 	//
 	// POST /users
 	CreateUser(ctx context.Context, req *CreateUserReq) (CreateUserRes, error)
+	// CreateWebhook implements createWebhook operation.
+	//
+	// Create a webhook.
+	//
+	// POST /webhooks
+	CreateWebhook(ctx context.Context, req *CreateWebhookReq) (CreateWebhookRes, error)
+	// DeleteAdmin implements deleteAdmin operation.
+	//
+	// Delete an admin along with their tokens.
+	//
+	// DELETE /admins/{user}
+	DeleteAdmin(ctx context.Context, params DeleteAdminParams) (DeleteAdminRes, error)
+	// DeleteToken implements deleteToken operation.
+	//
+	// Revoke an API token of the authenticated admin.
+	//
+	// DELETE /tokens/{id}
+	DeleteToken(ctx context.Context, params DeleteTokenParams) (DeleteTokenRes, error)
 	// DeleteUser implements deleteUser operation.
 	//
-	// Delete a user.
+	// Soft deletes the user, which can be restored until it is purged.
 	//
 	// DELETE /users/{id}
 	DeleteUser(ctx context.Context, params DeleteUserParams) (DeleteUserRes, error)
+	// DeleteWebhook implements deleteWebhook operation.
+	//
+	// Delete a webhook along with its deliveries.
+	//
+	// DELETE /webhooks/{id}
+	DeleteWebhook(ctx context.Context, params DeleteWebhookParams) (DeleteWebhookRes, error)
+	// DisableAdmin implements disableAdmin operation.
+	//
+	// Disable an admin, rejecting their password and tokens.
+	//
+	// POST /admins/{user}/disable
+	DisableAdmin(ctx context.Context, params DisableAdminParams) (DisableAdminRes, error)
+	// EnableAdmin implements enableAdmin operation.
+	//
+	// Enable a disabled admin.
+	//
+	// POST /admins/{user}/enable
+	EnableAdmin(ctx context.Context, params EnableAdminParams) (EnableAdminRes, error)
+	// ExportUsers implements exportUsers operation.
+	//
+	// Export all users as NDJSON or CSV.
+	//
+	// GET /users:export
+	ExportUsers(ctx context.Context, params ExportUsersParams) (ExportUsersRes, error)
+	// GetAdmin implements getAdmin operation.
+	//
+	// Get an admin.
+	//
+	// GET /admins/{user}
+	GetAdmin(ctx context.Context, params GetAdminParams) (GetAdminRes, error)
 	// GetUser implements getUser operation.
 	//
 	// Get a user.
 	//
 	// GET /users/{id}
 	GetUser(ctx context.Context, params GetUserParams) (GetUserRes, error)
+	// GetWebhook implements getWebhook operation.
+	//
+	// Get a webhook.
+	//
+	// GET /webhooks/{id}
+	GetWebhook(ctx context.Context, params GetWebhookParams) (GetWebhookRes, error)
+	// GetWebhookDelivery implements getWebhookDelivery operation.
+	//
+	// Get a delivery with its payload and attempts.
+	//
+	// GET /webhooks/{id}/deliveries/{delivery}
+	GetWebhookDelivery(ctx context.Context, params GetWebhookDeliveryParams) (GetWebhookDeliveryRes, error)
+	// ImportUsers implements importUsers operation.
+	//
+	// Import users from NDJSON or CSV.
+	//
+	// POST /users:import
+	ImportUsers(ctx context.Context, req ImportUsersReq, params ImportUsersParams) (ImportUsersRes, error)
+	// ListAdmins implements listAdmins operation.
+	//
+	// List admins.
+	//
+	// GET /admins
+	ListAdmins(ctx context.Context) (ListAdminsRes, error)
+	// ListAuditEvents implements listAuditEvents operation.
+	//
+	// List audit events, newest first.
+	//
+	// GET /audit
+	ListAuditEvents(ctx context.Context, params ListAuditEventsParams) (ListAuditEventsRes, error)
+	// ListEvents implements listEvents operation.
+	//
+	// List the user events after a seq, or stream them as Server-Sent Events.
+	//
+	// GET /events
+	ListEvents(ctx context.Context, params ListEventsParams) (ListEventsRes, error)
+	// ListUsers implements listUsers operation.
+	//
+	// List users.
+	//
+	// GET /users
+	ListUsers(ctx context.Context, params ListUsersParams) (ListUsersRes, error)
+	// ListWebhookDeliveries implements listWebhookDeliveries operation.
+	//
+	// List the deliveries of a webhook, newest first.
+	//
+	// GET /webhooks/{id}/deliveries
+	ListWebhookDeliveries(ctx context.Context, params ListWebhookDeliveriesParams) (ListWebhookDeliveriesRes, error)
+	// ListWebhooks implements listWebhooks operation.
+	//
+	// List webhooks.
+	//
+	// GET /webhooks
+	ListWebhooks(ctx context.Context) (ListWebhooksRes, error)
+	// PatchUser implements patchUser operation.
+	//
+	// Updates the user with a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902), applied to the
+	// user as returned by GET.
+	//
+	// PATCH /users/{id}
+	PatchUser(ctx context.Context, req PatchUserReq, params PatchUserParams) (PatchUserRes, error)
+	// RedeliverWebhookDelivery implements redeliverWebhookDelivery operation.
+	//
+	// Deliver a delivery again right away, with all of its retries.
+	//
+	// POST /webhooks/{id}/deliveries/{delivery}/redeliver
+	RedeliverWebhookDelivery(ctx context.Context, params RedeliverWebhookDeliveryParams) (RedeliverWebhookDeliveryRes, error)
+	// RestoreUser implements restoreUser operation.
+	//
+	// Restore a deleted user.
+	//
+	// POST /users/{id}/restore
+	RestoreUser(ctx context.Context, params RestoreUserParams) (RestoreUserRes, error)
+	// SearchUsers implements searchUsers operation.
+	//
+	// Search users by partial username or email.
+	//
+	// GET /users/search
+	SearchUsers(ctx context.Context, params SearchUsersParams) (SearchUsersRes, error)
+	// SetAdminPassword implements setAdminPassword operation.
+	//
+	// Reset the password of an admin.
+	//
+	// PUT /admins/{user}/password
+	SetAdminPassword(ctx context.Context, req *SetAdminPasswordReq, params SetAdminPasswordParams) (SetAdminPasswordRes, error)
+	// SetAdminRoles implements setAdminRoles operation.
+	//
+	// Replace the roles of an admin.
+	//
+	// PUT /admins/{user}/roles
+	SetAdminRoles(ctx context.Context, req *SetAdminRolesReq, params SetAdminRolesParams) (SetAdminRolesRes, error)
 	// UpdateUser implements updateUser operation.
 	//
-	// Update a user.
+	// Replaces every field of the user, use PATCH to update some of them.
 	//
 	// PUT /users/{id}
 	UpdateUser(ctx context.Context, req *UpdateUserReq, params UpdateUserParams) (UpdateUserRes, error)
+	// UpdateWebhook implements updateWebhook operation.
+	//
+	// Replace the url and events of a webhook.
+	//
+	// PUT /webhooks/{id}
+	UpdateWebhook(ctx context.Context, req *UpdateWebhookReq, params UpdateWebhookParams) (UpdateWebhookRes, error)
 }
 
 // Server implements http server based on OpenAPI v3 specification and
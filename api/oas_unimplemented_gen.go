@@ -13,6 +13,24 @@ type UnimplementedHandler struct{}
 
 var _ Handler = UnimplementedHandler{}
 
+// CreateAdmin implements createAdmin operation.
+//
+// Create an admin.
+//
+// POST /admins
+func (UnimplementedHandler) CreateAdmin(ctx context.Context, req *CreateAdminReq) (r CreateAdminRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// CreateToken implements createToken operation.
+//
+// Create an API token for the admin authenticated with their password.
+//
+// POST /tokens
+func (UnimplementedHandler) CreateToken(ctx context.Context, req *CreateTokenReq) (r CreateTokenRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
 // CreateUser implements createUser operation.
 //
 // Create a new user.
@@ -22,15 +40,87 @@ func (UnimplementedHandler) CreateUser(ctx context.Context, req *CreateUserReq)
 	return r, ht.ErrNotImplemented
 }
 
+// CreateWebhook implements createWebhook operation.
+//
+// Create a webhook.
+//
+// POST /webhooks
+func (UnimplementedHandler) CreateWebhook(ctx context.Context, req *CreateWebhookReq) (r CreateWebhookRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// DeleteAdmin implements deleteAdmin operation.
+//
+// Delete an admin along with their tokens.
+//
+// DELETE /admins/{user}
+func (UnimplementedHandler) DeleteAdmin(ctx context.Context, params DeleteAdminParams) (r DeleteAdminRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// DeleteToken implements deleteToken operation.
+//
+// Revoke an API token of the authenticated admin.
+//
+// DELETE /tokens/{id}
+func (UnimplementedHandler) DeleteToken(ctx context.Context, params DeleteTokenParams) (r DeleteTokenRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
 // DeleteUser implements deleteUser operation.
 //
-// Delete a user.
+// Soft deletes the user, which can be restored until it is purged.
 //
 // DELETE /users/{id}
 func (UnimplementedHandler) DeleteUser(ctx context.Context, params DeleteUserParams) (r DeleteUserRes, _ error) {
 	return r, ht.ErrNotImplemented
 }
 
+// DeleteWebhook implements deleteWebhook operation.
+//
+// Delete a webhook along with its deliveries.
+//
+// DELETE /webhooks/{id}
+func (UnimplementedHandler) DeleteWebhook(ctx context.Context, params DeleteWebhookParams) (r DeleteWebhookRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// DisableAdmin implements disableAdmin operation.
+//
+// Disable an admin, rejecting their password and tokens.
+//
+// POST /admins/{user}/disable
+func (UnimplementedHandler) DisableAdmin(ctx context.Context, params DisableAdminParams) (r DisableAdminRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// EnableAdmin implements enableAdmin operation.
+//
+// Enable a disabled admin.
+//
+// POST /admins/{user}/enable
+func (UnimplementedHandler) EnableAdmin(ctx context.Context, params EnableAdminParams) (r EnableAdminRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// ExportUsers implements exportUsers operation.
+//
+// Export all users as NDJSON or CSV.
+//
+// GET /users:export
+func (UnimplementedHandler) ExportUsers(ctx context.Context, params ExportUsersParams) (r ExportUsersRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// GetAdmin implements getAdmin operation.
+//
+// Get an admin.
+//
+// GET /admins/{user}
+func (UnimplementedHandler) GetAdmin(ctx context.Context, params GetAdminParams) (r GetAdminRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
 // GetUser implements getUser operation.
 //
 // Get a user.
@@ -40,11 +130,156 @@ func (UnimplementedHandler) GetUser(ctx context.Context, params GetUserParams) (
 	return r, ht.ErrNotImplemented
 }
 
+// GetWebhook implements getWebhook operation.
+//
+// Get a webhook.
+//
+// GET /webhooks/{id}
+func (UnimplementedHandler) GetWebhook(ctx context.Context, params GetWebhookParams) (r GetWebhookRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// GetWebhookDelivery implements getWebhookDelivery operation.
+//
+// Get a delivery with its payload and attempts.
+//
+// GET /webhooks/{id}/deliveries/{delivery}
+func (UnimplementedHandler) GetWebhookDelivery(ctx context.Context, params GetWebhookDeliveryParams) (r GetWebhookDeliveryRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// ImportUsers implements importUsers operation.
+//
+// Import users from NDJSON or CSV.
+//
+// POST /users:import
+func (UnimplementedHandler) ImportUsers(ctx context.Context, req ImportUsersReq, params ImportUsersParams) (r ImportUsersRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// ListAdmins implements listAdmins operation.
+//
+// List admins.
+//
+// GET /admins
+func (UnimplementedHandler) ListAdmins(ctx context.Context) (r ListAdminsRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// ListAuditEvents implements listAuditEvents operation.
+//
+// List audit events, newest first.
+//
+// GET /audit
+func (UnimplementedHandler) ListAuditEvents(ctx context.Context, params ListAuditEventsParams) (r ListAuditEventsRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// ListEvents implements listEvents operation.
+//
+// List the user events after a seq, or stream them as Server-Sent Events.
+//
+// GET /events
+func (UnimplementedHandler) ListEvents(ctx context.Context, params ListEventsParams) (r ListEventsRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// ListUsers implements listUsers operation.
+//
+// List users.
+//
+// GET /users
+func (UnimplementedHandler) ListUsers(ctx context.Context, params ListUsersParams) (r ListUsersRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// ListWebhookDeliveries implements listWebhookDeliveries operation.
+//
+// List the deliveries of a webhook, newest first.
+//
+// GET /webhooks/{id}/deliveries
+func (UnimplementedHandler) ListWebhookDeliveries(ctx context.Context, params ListWebhookDeliveriesParams) (r ListWebhookDeliveriesRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// ListWebhooks implements listWebhooks operation.
+//
+// List webhooks.
+//
+// GET /webhooks
+func (UnimplementedHandler) ListWebhooks(ctx context.Context) (r ListWebhooksRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// PatchUser implements patchUser operation.
+//
+// Updates the user with a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902), applied to the
+// user as returned by GET.
+//
+// PATCH /users/{id}
+func (UnimplementedHandler) PatchUser(ctx context.Context, req PatchUserReq, params PatchUserParams) (r PatchUserRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// RedeliverWebhookDelivery implements redeliverWebhookDelivery operation.
+//
+// Deliver a delivery again right away, with all of its retries.
+//
+// POST /webhooks/{id}/deliveries/{delivery}/redeliver
+func (UnimplementedHandler) RedeliverWebhookDelivery(ctx context.Context, params RedeliverWebhookDeliveryParams) (r RedeliverWebhookDeliveryRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// RestoreUser implements restoreUser operation.
+//
+// Restore a deleted user.
+//
+// POST /users/{id}/restore
+func (UnimplementedHandler) RestoreUser(ctx context.Context, params RestoreUserParams) (r RestoreUserRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// SearchUsers implements searchUsers operation.
+//
+// Search users by partial username or email.
+//
+// GET /users/search
+func (UnimplementedHandler) SearchUsers(ctx context.Context, params SearchUsersParams) (r SearchUsersRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// SetAdminPassword implements setAdminPassword operation.
+//
+// Reset the password of an admin.
+//
+// PUT /admins/{user}/password
+func (UnimplementedHandler) SetAdminPassword(ctx context.Context, req *SetAdminPasswordReq, params SetAdminPasswordParams) (r SetAdminPasswordRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// SetAdminRoles implements setAdminRoles operation.
+//
+// Replace the roles of an admin.
+//
+// PUT /admins/{user}/roles
+func (UnimplementedHandler) SetAdminRoles(ctx context.Context, req *SetAdminRolesReq, params SetAdminRolesParams) (r SetAdminRolesRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
 // UpdateUser implements updateUser operation.
 //
-// Update a user.
+// Replaces every field of the user, use PATCH to update some of them.
 //
 // PUT /users/{id}
 func (UnimplementedHandler) UpdateUser(ctx context.Context, req *UpdateUserReq, params UpdateUserParams) (r UpdateUserRes, _ error) {
 	return r, ht.ErrNotImplemented
 }
+
+// UpdateWebhook implements updateWebhook operation.
+//
+// Replace the url and events of a webhook.
+//
+// PUT /webhooks/{id}
+func (UnimplementedHandler) UpdateWebhook(ctx context.Context, req *UpdateWebhookReq, params UpdateWebhookParams) (r UpdateWebhookRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
@@ -1,32 +1,118 @@
 package atmail
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/mail"
-
-	"atmail/server/roles"
+	"strings"
+	"time"
 )
 
 var ErrUserNone = errors.New("error user none")
 var ErrAdminNone = errors.New("error admin none")
+var ErrAdminExists = errors.New("error admin exists")
+var ErrRoleNone = errors.New("error role none")
+var ErrTokenNone = errors.New("error token none")
+
+// DefaultReservation is how long the username and email of a deleted user
+// stay reserved by default.
+const DefaultReservation = 7 * 24 * time.Hour
 
 type store struct {
 	db *sql.DB
+	// tx is set within WithTx
+	tx *sql.Tx
+	// reservation is how long deleted users keep their username and email
+	reservation time.Duration
+	dialect     dialect
+	// replicas are read from if set, see WithReplicas
+	replicas *Replicas
 }
 
-func NewStore(db *sql.DB) Store {
-	return store{db}
+// StoreOption configures the store returned by NewStore.
+type StoreOption func(*store)
+
+// WithReservation keeps the username and email of a deleted user reserved
+// for d, so that nobody else takes them while the user may still be
+// restored. After that they are free, and taking them purges the deleted
+// user.
+func WithReservation(d time.Duration) StoreOption {
+	return func(s *store) {
+		s.reservation = d
+	}
 }
 
-type Store interface {
-	CheckUser(string, string) (bool, error)
-	CreateUser(User) (int64, error)
-	GetUser(int64) (User, error)
-	UpdateUser(User) error
-	DeleteUser(int64) error
+// NewStore returns the store of db, which is a MySQL, SQLite or PostgreSQL
+// database, see Open.
+func NewStore(db *sql.DB, opts ...StoreOption) Store {
+	s := store{db: db, reservation: DefaultReservation, dialect: dialectOf(db)}
+
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	if s.dialect.search == nil {
+		return searchlessStore{s}
+	}
 
-	GetRole(string, string) (roles.Role, error)
+	return s
+}
+
+type Store interface {
+	WithTx(context.Context, func(Store) error) error
+
+	CheckUser(context.Context, string, string) (bool, error)
+	CreateUser(context.Context, User) (int64, error)
+	CreateUsers(context.Context, []User) ([]int64, error)
+	GetUser(context.Context, int64) (User, error)
+	ListUsers(context.Context, ListUsersOptions) ([]User, error)
+	UpdateUser(context.Context, User) error
+	DeleteUser(context.Context, int64) error
+	RestoreUser(context.Context, int64) (User, error)
+	PurgeUser(context.Context, int64) error
+	PurgeUsers(context.Context, time.Time) (int64, error)
+	ListReleasedUsers(context.Context, User) ([]int64, error)
+	ListDeletedUsers(context.Context, time.Time) ([]int64, error)
+
+	GetRoles(context.Context, string, string) ([]string, error)
+	GetPermissions(context.Context) (map[string][]string, error)
+
+	CreateAdmin(context.Context, Admin, string) error
+	ListAdmins(context.Context) ([]Admin, error)
+	GetAdmin(context.Context, string) (Admin, error)
+	SetAdminRoles(context.Context, string, []string) error
+	SetAdminPassword(context.Context, string, string) error
+	SetAdminDisabled(context.Context, string, bool) error
+	DeleteAdmin(context.Context, string) error
+
+	CreateToken(context.Context, Token) (int64, error)
+	GetToken(context.Context, string) (Token, error)
+	DeleteToken(context.Context, int64, string) error
+
+	CreateAuditEvent(context.Context, AuditEvent) (int64, error)
+	ListAuditEvents(context.Context, AuditOptions) ([]AuditEvent, error)
+	CreateAuditCheckpoint(context.Context, AuditCheckpoint) (int64, error)
+	ListAuditCheckpoints(context.Context) ([]AuditCheckpoint, error)
+
+	CreateWebhook(context.Context, Webhook) (int64, error)
+	GetWebhook(context.Context, int64) (Webhook, error)
+	ListWebhooks(context.Context) ([]Webhook, error)
+	UpdateWebhook(context.Context, Webhook) error
+	DeleteWebhook(context.Context, int64) error
+
+	CreateWebhookDelivery(context.Context, WebhookDelivery) (int64, error)
+	GetWebhookDelivery(context.Context, int64) (WebhookDelivery, error)
+	ListWebhookDeliveries(context.Context, WebhookDeliveryOptions) ([]WebhookDelivery, error)
+	UpdateWebhookDelivery(context.Context, WebhookDelivery) error
+	ClaimWebhookDelivery(context.Context, int64, time.Time, time.Time) error
+	CreateWebhookAttempt(context.Context, WebhookAttempt) (int64, error)
+	ListWebhookAttempts(context.Context, int64) ([]WebhookAttempt, error)
+
+	CreateUserEvent(context.Context, UserEvent) (int64, error)
+	ListUserEvents(context.Context, int64, int) ([]UserEvent, error)
+	CompactUserEvents(context.Context, time.Time) (int64, error)
 }
 
 type User struct {
@@ -34,6 +120,8 @@ type User struct {
 	Username string
 	Email    string
 	Age      uint
+	// Version starts at 1 and is incremented by every update.
+	Version int64
 }
 
 func ValidateUser(user User) (string, bool) {
@@ -52,10 +140,10 @@ func ValidateUser(user User) (string, bool) {
 	return "", true
 }
 
-func (s store) GetUser(id int64) (User, error) {
+func (s store) GetUser(ctx context.Context, id int64) (User, error) {
 	user := User{}
 
-	if err := s.db.QueryRow("SELECT id, username, email, age FROM users WHERE id = ?", id).Scan(&user.Id, &user.Username, &user.Email, &user.Age); err != nil {
+	if err := s.read(ctx).QueryRowContext(ctx, "SELECT id, username, email, age, version FROM users WHERE id = ? AND deleted_at IS NULL", id).Scan(&user.Id, &user.Username, &user.Email, &user.Age, &user.Version); err != nil {
 		if err != sql.ErrNoRows {
 			return User{}, err
 		}
@@ -66,40 +154,374 @@ func (s store) GetUser(id int64) (User, error) {
 	return user, nil
 }
 
-func (s store) CheckUser(username string, password string) (bool, error) {
+// Sort is what users are listed by. Users are always sorted by id last so
+// that the order is total and pages can be resumed from the last user.
+type Sort string
+
+const (
+	SortId       Sort = "id"
+	SortUsername Sort = "username"
+	SortAge      Sort = "age"
+)
+
+func (s Sort) Valid() bool {
+	return s == SortId || s == SortUsername || s == SortAge
+}
+
+type ListUsersOptions struct {
+	// Limit is the maximum number of users returned, all of them when 0.
+	Limit int
+	// After is the last user of the previous page, only users sorted after it
+	// are returned.
+	After *User
+
+	Sort Sort
+	Desc bool
+
+	// UsernamePrefix and EmailPrefix filter users whose username or email
+	// starts with them.
+	UsernamePrefix string
+	EmailPrefix    string
+	// MinAge and MaxAge filter users by age, inclusively. 0 means no bound.
+	MinAge uint
+	MaxAge uint
+}
+
+// Less reports whether a is listed before b.
+func (o ListUsersOptions) Less(a User, b User) bool {
+	c := 0
+
+	switch o.Sort {
+	case SortUsername:
+		c = strings.Compare(a.Username, b.Username)
+	case SortAge:
+		c = compare(a.Age, b.Age)
+	}
+
+	if c == 0 {
+		c = compare(a.Id, b.Id)
+	}
+
+	if o.Desc {
+		return c > 0
+	}
+
+	return c < 0
+}
+
+// Match reports whether user passes the filters. Prefixes match whatever
+// their case, as the columns of the SQL stores compare.
+func (o ListUsersOptions) Match(user User) bool {
+	if !hasPrefixFold(user.Username, o.UsernamePrefix) || !hasPrefixFold(user.Email, o.EmailPrefix) {
+		return false
+	}
+
+	if user.Age < o.MinAge || (o.MaxAge != 0 && user.Age > o.MaxAge) {
+		return false
+	}
+
+	return o.After == nil || o.Less(*o.After, user)
+}
+
+func hasPrefixFold(s string, prefix string) bool {
+	return strings.HasPrefix(strings.ToLower(s), strings.ToLower(prefix))
+}
+
+func compare[T int64 | uint](a T, b T) int {
+	if a < b {
+		return -1
+	}
+
+	if a > b {
+		return 1
+	}
+
+	return 0
+}
+
+func (s store) ListUsers(ctx context.Context, o ListUsersOptions) ([]User, error) {
+	sort := o.Sort
+
+	if sort == "" {
+		sort = SortId
+	}
+
+	if !sort.Valid() {
+		return nil, fmt.Errorf("invalid sort %q", sort)
+	}
+
+	where := []string{"deleted_at IS NULL"}
+	args := []any{}
+
+	if o.UsernamePrefix != "" {
+		where = append(where, "username LIKE ? ESCAPE '!'")
+		args = append(args, escapeLike(o.UsernamePrefix)+"%")
+	}
+
+	if o.EmailPrefix != "" {
+		where = append(where, "email LIKE ? ESCAPE '!'")
+		args = append(args, escapeLike(o.EmailPrefix)+"%")
+	}
+
+	if o.MinAge != 0 {
+		where = append(where, "age >= ?")
+		args = append(args, o.MinAge)
+	}
+
+	if o.MaxAge != 0 {
+		where = append(where, "age <= ?")
+		args = append(args, o.MaxAge)
+	}
+
+	op, order := ">", "ASC"
+
+	if o.Desc {
+		op, order = "<", "DESC"
+	}
+
+	// keyset pagination, sort is validated so it is safe to format
+	if o.After != nil {
+		if sort == SortId {
+			where = append(where, "id "+op+" ?")
+			args = append(args, o.After.Id)
+		} else {
+			where = append(where, fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", sort, op, sort, op))
+
+			value := any(o.After.Username)
+
+			if sort == SortAge {
+				value = o.After.Age
+			}
+
+			args = append(args, value, value, o.After.Id)
+		}
+	}
+
+	query := "SELECT id, username, email, age, version FROM users WHERE " + strings.Join(where, " AND ") + " ORDER BY "
+
+	if sort != SortId {
+		query += fmt.Sprintf("%s %s, ", sort, order)
+	}
+
+	query += "id " + order
+
+	if o.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, o.Limit)
+	}
+
+	rows, err := s.read(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	users := []User{}
+
+	for rows.Next() {
+		user := User{}
+
+		if err := rows.Scan(&user.Id, &user.Username, &user.Email, &user.Age, &user.Version); err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// escapeLike escapes the wildcards of LIKE in s with !, which is given as
+// the ESCAPE of every LIKE. SQLite has no default escape character, and a
+// backslash would have to be written differently in the strings of MySQL.
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+// CheckUser reports whether the username or email is taken, by a user or by
+// a deleted user whose reservation has not passed yet.
+func (s store) CheckUser(ctx context.Context, username string, email string) (bool, error) {
 	var exists bool
 
-	if err := s.db.QueryRow("SELECT count(*) != 0 FROM users WHERE username = ? OR email = ?", username, password).Scan(&exists); err != nil {
+	if err := s.read(ctx).QueryRowContext(ctx, "SELECT count(*) != 0 FROM users WHERE (username = ? OR email = ?) AND (deleted_at IS NULL OR deleted_at > ?)", username, email, s.reserved()).Scan(&exists); err != nil {
 		return false, err
 	}
 
 	return exists, nil
 }
 
-func (s store) CreateUser(user User) (int64, error) {
-	result, err := s.db.Exec("INSERT INTO users (username, email, age) VALUES (?, ?, ?)", user.Username, user.Email, user.Age)
+// reserved returns when users must have been deleted after to still reserve
+// their username and email.
+func (s store) reserved() time.Time {
+	return time.Now().UTC().Add(-s.reservation)
+}
+
+// release purges the deleted users whose reservation has passed and whose
+// username or email is taken by user, as they still hold the unique keys.
+func (s store) release(ctx context.Context, tx querier, user User) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM users WHERE (username = ? OR email = ?) AND deleted_at <= ? AND id != ?", user.Username, user.Email, s.reserved(), user.Id)
+	return err
+}
+
+func (s store) CreateUser(ctx context.Context, user User) (int64, error) {
+	tx, err := s.begin(ctx)
 	if err != nil {
 		return 0, err
 	}
 
-	id, err := result.LastInsertId()
+	defer tx.Rollback()
+
+	if err := s.release(ctx, tx, user); err != nil {
+		return 0, err
+	}
+
+	id, err := s.insert(ctx, tx, "INSERT INTO users (username, email, age) VALUES (?, ?, ?)", user.Username, user.Email, user.Age)
 	if err != nil {
+		return 0, s.userExists(err)
+	}
+
+	if err := tx.Commit(); err != nil {
 		return 0, err
 	}
 
 	return id, nil
 }
 
-func (s store) UpdateUser(user User) error {
-	if _, err := s.db.Exec("UPDATE users SET username = ?, email = ?, age = ? WHERE id = ?", user.Username, user.Email, user.Age, user.Id); err != nil {
+// CreateUsers creates users in one transaction, so either all of them are
+// created or none are. The ids are returned in the order of users.
+func (s store) CreateUsers(ctx context.Context, users []User) ([]int64, error) {
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	ids := []int64{}
+
+	for _, user := range users {
+		if err := s.release(ctx, tx, user); err != nil {
+			return nil, err
+		}
+
+		id, err := s.insert(ctx, tx, "INSERT INTO users (username, email, age) VALUES (?, ?, ?)", user.Username, user.Email, user.Age)
+		if err != nil {
+			return nil, s.userExists(err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// UpdateUser updates the user if its version is still user.Version, and
+// increments the version. Otherwise it fails with ErrVersionConflict.
+func (s store) UpdateUser(ctx context.Context, user User) error {
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err := s.release(ctx, tx, user); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, "UPDATE users SET username = ?, email = ?, age = ?, version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL", user.Username, user.Email, user.Age, user.Id, user.Version)
+	if err != nil {
+		return s.userExists(err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count != 1 {
+		tx.Rollback()
+
+		// the replicas may not have the current version yet
+		current, err := s.primary().GetUser(ctx, user.Id)
+		if err != nil {
+			return err
+		}
+
+		return ErrVersionConflict{user.Id, current.Version}
+	}
+
+	return tx.Commit()
+}
+
+// DeleteUser soft deletes the user, which can be restored until it is purged.
+func (s store) DeleteUser(ctx context.Context, id int64) error {
+	result, err := s.conn().ExecContext(ctx, "UPDATE users SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL", time.Now().UTC().Truncate(time.Second), id)
+	if err != nil {
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
 		return err
 	}
 
+	if count != 1 {
+		return ErrUserNone
+	}
+
 	return nil
 }
 
-func (s store) DeleteUser(id int64) error {
-	result, err := s.db.Exec("DELETE FROM users WHERE id = ?", id)
+// RestoreUser restores a deleted user and increments its version. It fails
+// with ErrUserNone if the user is not deleted.
+func (s store) RestoreUser(ctx context.Context, id int64) (User, error) {
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return User{}, err
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "UPDATE users SET deleted_at = NULL, version = version + 1 WHERE id = ? AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return User{}, err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return User{}, err
+	}
+
+	if count != 1 {
+		return User{}, ErrUserNone
+	}
+
+	user := User{}
+
+	if err := tx.QueryRowContext(ctx, "SELECT id, username, email, age, version FROM users WHERE id = ?", id).Scan(&user.Id, &user.Username, &user.Email, &user.Age, &user.Version); err != nil {
+		return User{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return User{}, err
+	}
+
+	return user, nil
+}
+
+// PurgeUser hard deletes the user, whether it is deleted or not.
+func (s store) PurgeUser(ctx context.Context, id int64) error {
+	result, err := s.conn().ExecContext(ctx, "DELETE FROM users WHERE id = ?", id)
 	if err != nil {
 		return err
 	}
@@ -116,16 +538,54 @@ func (s store) DeleteUser(id int64) error {
 	return nil
 }
 
-func (s store) GetRole(user string, password string) (roles.Role, error) {
-	var role roles.Role
+// PurgeUsers hard deletes the users deleted before t and returns how many
+// there were.
+func (s store) PurgeUsers(ctx context.Context, t time.Time) (int64, error) {
+	result, err := s.conn().ExecContext(ctx, "DELETE FROM users WHERE deleted_at < ?", t.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
 
-	if err := s.db.QueryRow("SELECT role FROM admins WHERE user = ? AND password = ?", user, password).Scan(&role); err != nil {
-		if err != sql.ErrNoRows {
-			return 0, err
+// ListReleasedUsers returns the ids of the deleted users that writing user
+// purges, whose reservation has passed and who hold its username or email.
+func (s store) ListReleasedUsers(ctx context.Context, user User) ([]int64, error) {
+	return s.ids(ctx, "SELECT id FROM users WHERE (username = ? OR email = ?) AND deleted_at <= ? AND id != ? ORDER BY id", user.Username, user.Email, s.reserved(), user.Id)
+}
+
+// ListDeletedUsers returns the ids of the users deleted before t, which
+// PurgeUsers purges.
+func (s store) ListDeletedUsers(ctx context.Context, t time.Time) ([]int64, error) {
+	return s.ids(ctx, "SELECT id FROM users WHERE deleted_at < ? ORDER BY id", t.UTC())
+}
+
+// ids returns the ids selected by query from the primary, as they are about
+// to be written.
+func (s store) ids(ctx context.Context, query string, args ...any) ([]int64, error) {
+	rows, err := s.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ids := []int64{}
+
+	for rows.Next() {
+		var id int64
+
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
 		}
 
-		return 0, ErrAdminNone
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return role, nil
+	return ids, nil
 }
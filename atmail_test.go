@@ -2,33 +2,66 @@ package atmail_test
 
 import (
 	"atmail"
+	"atmail/storetest"
+	"context"
 	"database/sql"
+	"errors"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
-
-	_ "github.com/go-sql-driver/mysql"
+	"time"
 )
 
-func TestAtmail(t *testing.T) {
-	// Step 1: Setup store
-	databaseUrl := os.Getenv("MYSQL_URL")
+// openTestDB opens the database of DATABASE_URL, or the MySQL database of
+// MYSQL_URL, or else a new SQLite database, with every migration applied.
+func openTestDB(t *testing.T) *sql.DB {
+	url := "sqlite://" + filepath.Join(t.TempDir(), "atmail.db")
+
+	if databaseUrl := os.Getenv("MYSQL_URL"); databaseUrl != "" {
+		url = "mysql://" + databaseUrl
+	}
 
-	if databaseUrl == "" {
-		t.Fatal("MYSQL_URL cannot be blank!")
+	if databaseUrl := os.Getenv("DATABASE_URL"); databaseUrl != "" {
+		url = databaseUrl
+	}
+
+	return openMigrated(t, url)
+}
+
+// openMigrated opens the database of url with every migration applied.
+func openMigrated(t *testing.T, url string) *sql.DB {
+	db, err := atmail.Open(url)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	db, err := sql.Open("mysql", databaseUrl)
+	t.Cleanup(func() { db.Close() })
+
+	m, err := atmail.NewMigrator(db, time.Minute)
 	if err != nil {
 		t.Fatal(err)
 	}
 
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func TestAtmail(t *testing.T) {
+	// Step 1: Setup store
+	db := openTestDB(t)
+
 	if _, err := db.Exec("DELETE FROM users"); err != nil {
 		t.Fatal(err)
 	}
 
 	s := atmail.NewStore(db)
 
+	ctx := context.Background()
+
 	// Step 2: Create user
 	user := atmail.User{
 		Username: "johndoe",
@@ -36,15 +69,15 @@ func TestAtmail(t *testing.T) {
 		Age:      55,
 	}
 
-	id, err := s.CreateUser(user)
+	id, err := s.CreateUser(ctx, user)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	user.Id = id
+	user.Id, user.Version = id, 1
 
 	// Step 2: Get user
-	gotUser, err := s.GetUser(id)
+	gotUser, err := s.GetUser(ctx, id)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -53,17 +86,136 @@ func TestAtmail(t *testing.T) {
 		t.Errorf("want %v; got %v", user, gotUser)
 	}
 
+	// Step 2: Create user with a taken email
+	_, err = s.CreateUser(ctx, atmail.User{Username: "john", Email: "john@doe.com", Age: 55})
+
+	var exists atmail.ErrUserExists
+
+	if !errors.As(err, &exists) || exists.Field != "email" {
+		t.Errorf("want %v; got %v", atmail.ErrUserExists{Field: "email"}, err)
+	}
+
+	// Step 2: Roll back transaction
+	rollback := errors.New("rollback")
+
+	if err := s.WithTx(ctx, func(s atmail.Store) error {
+		if _, err := s.CreateUser(ctx, atmail.User{Username: "jim", Email: "jim@doe.com", Age: 55}); err != nil {
+			return err
+		}
+
+		return rollback
+	}); err != rollback {
+		t.Errorf("want %v; got %v", rollback, err)
+	}
+
+	if exists, err := s.CheckUser(ctx, "jim", "jim@doe.com"); err != nil || exists {
+		t.Errorf("want %v; got %v", false, exists)
+	}
+
 	// Step 3: Update user
-	if err := s.UpdateUser(atmail.User{
-		Username: "jane",
-		Email:    "jane@doe.com",
-		Age:      321,
-	}); err != nil {
+	user.Username, user.Email, user.Age = "jane", "jane@doe.com", 321
+
+	if err := s.UpdateUser(ctx, user); err != nil {
 		t.Error(err)
 	}
 
+	user.Version++
+
+	// Step 3: Update user from a stale version
+	var conflict atmail.ErrVersionConflict
+
+	if err := s.UpdateUser(ctx, atmail.User{Id: id, Username: "jim", Email: "jim@doe.com", Age: 1, Version: 1}); !errors.As(err, &conflict) || conflict.Version != 2 {
+		t.Errorf("want %v; got %v", atmail.ErrVersionConflict{Id: id, Version: 2}, err)
+	}
+
 	// Step 4: Delete user
-	if err := s.DeleteUser(id); err != nil {
+	if err := s.DeleteUser(ctx, id); err != nil {
 		t.Error(err)
 	}
+
+	if _, err := s.GetUser(ctx, id); err != atmail.ErrUserNone {
+		t.Errorf("want %v; got %v", atmail.ErrUserNone, err)
+	}
+
+	if err := s.DeleteUser(ctx, id); err != atmail.ErrUserNone {
+		t.Errorf("want %v; got %v", atmail.ErrUserNone, err)
+	}
+
+	// Step 4: Username of deleted user is reserved
+	if exists, err := s.CheckUser(ctx, "jane", "jane@doe.com"); err != nil || !exists {
+		t.Errorf("want %v; got %v", true, exists)
+	}
+
+	// Step 5: Restore user
+	user.Version++
+
+	gotUser, err = s.RestoreUser(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(user, gotUser) {
+		t.Errorf("want %v; got %v", user, gotUser)
+	}
+
+	if _, err := s.RestoreUser(ctx, id); err != atmail.ErrUserNone {
+		t.Errorf("want %v; got %v", atmail.ErrUserNone, err)
+	}
+
+	// Step 6: Username of deleted user is free after the reservation
+	if err := s.DeleteUser(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	unreserved := atmail.NewStore(db, atmail.WithReservation(0))
+
+	if exists, err := unreserved.CheckUser(ctx, "jane", "jane@doe.com"); err != nil || exists {
+		t.Errorf("want %v; got %v", false, exists)
+	}
+
+	if ids, err := unreserved.ListReleasedUsers(ctx, atmail.User{Username: "jane", Email: "other@doe.com"}); err != nil || !reflect.DeepEqual([]int64{id}, ids) {
+		t.Errorf("want %v; got %v (%v)", []int64{id}, ids, err)
+	}
+
+	newId, err := unreserved.CreateUser(ctx, atmail.User{Username: "jane", Email: "jane@doe.com", Age: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// which purges the deleted user
+	if _, err := s.RestoreUser(ctx, id); err != atmail.ErrUserNone {
+		t.Errorf("want %v; got %v", atmail.ErrUserNone, err)
+	}
+
+	// Step 7: Purge deleted users
+	if err := s.DeleteUser(ctx, newId); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids, err := s.ListDeletedUsers(ctx, time.Now().Add(time.Minute)); err != nil || !reflect.DeepEqual([]int64{newId}, ids) {
+		t.Errorf("want %v; got %v (%v)", []int64{newId}, ids, err)
+	}
+
+	if n, err := s.PurgeUsers(ctx, time.Now().Add(time.Minute)); err != nil || n != 1 {
+		t.Errorf("want %v; got %v", 1, n)
+	}
+
+	if err := s.PurgeUser(ctx, newId); err != atmail.ErrUserNone {
+		t.Errorf("want %v; got %v", atmail.ErrUserNone, err)
+	}
+}
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func() atmail.Store {
+		db := openTestDB(t)
+
+		// the tables of a DATABASE_URL may have been written by other tests
+		for _, table := range []string{"token_roles", "admin_tokens", "admin_roles", "admins", "users"} {
+			if _, err := db.Exec("DELETE FROM " + table); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		return atmail.NewStore(db)
+	})
 }
@@ -0,0 +1,257 @@
+package atmail
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// GenesisHash is the previous hash of the first audit event.
+const GenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+const (
+	ActionUserCreate  = "user.create"
+	ActionUserUpdate  = "user.update"
+	ActionUserDelete  = "user.delete"
+	ActionUserRestore = "user.restore"
+	ActionUserPurge   = "user.purge"
+	// ActionDenied is recorded when an admin lacks the permission of a route.
+	ActionDenied = "authorization.denied"
+)
+
+// Change is the value of a field before and after an audited change. Before
+// is nil when the field was created and After when it was deleted.
+type Change struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+// AuditEvent records who did what to which user.
+type AuditEvent struct {
+	Id   int64
+	Time time.Time
+	// Actor is the id of the principal, e.g. the user of an admin.
+	Actor string
+	// Action is one of the Action constants.
+	Action string
+	// Route is the pattern of the request, e.g. "PUT /users/{id}".
+	Route string
+	// Target is the id of the user, 0 if there is none.
+	Target    int64
+	Changes   []Change
+	ClientIP  string
+	RequestId string
+	// PrevHash is the Hash of the previous event, chaining every event to
+	// the ones before it so that none can be edited or removed unnoticed.
+	PrevHash string
+	// Hash is the SHA-256 of the event, see ComputeHash.
+	Hash string
+}
+
+// ComputeHash returns the hex SHA-256 of the fields of the event, including
+// PrevHash but not Id or Hash.
+func (e AuditEvent) ComputeHash() string {
+	bs, _ := json.Marshal([]any{
+		e.PrevHash,
+		e.Time.UTC().Format(time.RFC3339Nano),
+		e.Actor,
+		e.Action,
+		e.Route,
+		e.Target,
+		e.Changes,
+		e.ClientIP,
+		e.RequestId,
+	})
+
+	sum := sha256.Sum256(bs)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditOptions filters the audit events returned by ListAuditEvents, newest
+// first. Zero fields do not filter.
+type AuditOptions struct {
+	Limit int
+	// Before only returns events older than the event with this id.
+	Before int64
+	// After only returns events newer than the event with this id.
+	After int64
+	// Asc returns the oldest events first, which is the order of the chain.
+	Asc    bool
+	Actor  string
+	Action string
+	Target int64
+	// Since and Until bound the time of the events, inclusive.
+	Since time.Time
+	Until time.Time
+}
+
+// Match reports whether event passes the filters of o.
+func (o AuditOptions) Match(event AuditEvent) bool {
+	return (o.Before == 0 || event.Id < o.Before) &&
+		(o.After == 0 || event.Id > o.After) &&
+		(o.Actor == "" || event.Actor == o.Actor) &&
+		(o.Action == "" || event.Action == o.Action) &&
+		(o.Target == 0 || event.Target == o.Target) &&
+		(o.Since.IsZero() || !event.Time.Before(o.Since)) &&
+		(o.Until.IsZero() || !event.Time.After(o.Until))
+}
+
+// DiffUsers returns the fields that differ between before and after, either
+// of which is nil when the user was created or deleted.
+func DiffUsers(before *User, after *User) []Change {
+	fields := func(user *User) []any {
+		if user == nil {
+			return []any{nil, nil, nil}
+		}
+
+		return []any{user.Username, user.Email, user.Age}
+	}
+
+	b, a := fields(before), fields(after)
+
+	changes := []Change{}
+
+	for i, name := range []string{"username", "email", "age"} {
+		if b[i] != a[i] {
+			changes = append(changes, Change{name, b[i], a[i]})
+		}
+	}
+
+	return changes
+}
+
+// CreateAuditEvent records event, at the current time unless it has one,
+// chained to the last event. Events are chained one at a time, as the head of
+// the chain is locked until the transaction commits.
+func (s store) CreateAuditEvent(ctx context.Context, event AuditEvent) (int64, error) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	// as precise as the database
+	event.Time = event.Time.UTC().Truncate(time.Microsecond)
+
+	if event.Changes == nil {
+		event.Changes = []Change{}
+	}
+
+	changes, err := json.Marshal(event.Changes)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, "SELECT hash FROM audit_head WHERE id = 1"+s.dialect.forUpdate).Scan(&event.PrevHash); err != nil {
+		return 0, err
+	}
+
+	event.Hash = event.ComputeHash()
+
+	id, err := s.insert(ctx, tx, "INSERT INTO audit_events (time, actor, action, route, target, changes, client_ip, request_id, prev_hash, hash) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", event.Time, event.Actor, event.Action, event.Route, event.Target, changes, event.ClientIP, event.RequestId, event.PrevHash, event.Hash)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE audit_head SET hash = ? WHERE id = 1", event.Hash); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (s store) ListAuditEvents(ctx context.Context, o AuditOptions) ([]AuditEvent, error) {
+	where := []string{"TRUE"}
+	args := []any{}
+
+	if o.Before != 0 {
+		where = append(where, "id < ?")
+		args = append(args, o.Before)
+	}
+
+	if o.After != 0 {
+		where = append(where, "id > ?")
+		args = append(args, o.After)
+	}
+
+	if o.Actor != "" {
+		where = append(where, "actor = ?")
+		args = append(args, o.Actor)
+	}
+
+	if o.Action != "" {
+		where = append(where, "action = ?")
+		args = append(args, o.Action)
+	}
+
+	if o.Target != 0 {
+		where = append(where, "target = ?")
+		args = append(args, o.Target)
+	}
+
+	if !o.Since.IsZero() {
+		where = append(where, "time >= ?")
+		args = append(args, o.Since.UTC())
+	}
+
+	if !o.Until.IsZero() {
+		where = append(where, "time <= ?")
+		args = append(args, o.Until.UTC())
+	}
+
+	query := "SELECT id, time, actor, action, route, target, changes, client_ip, request_id, prev_hash, hash FROM audit_events WHERE " + strings.Join(where, " AND ") + " ORDER BY id"
+
+	if !o.Asc {
+		query += " DESC"
+	}
+
+	if o.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, o.Limit)
+	}
+
+	rows, err := s.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	events := []AuditEvent{}
+
+	for rows.Next() {
+		event := AuditEvent{}
+
+		var changes []byte
+
+		if err := rows.Scan(&event.Id, &event.Time, &event.Actor, &event.Action, &event.Route, &event.Target, &changes, &event.ClientIP, &event.RequestId, &event.PrevHash, &event.Hash); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(changes, &event.Changes); err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
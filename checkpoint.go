@@ -0,0 +1,248 @@
+package atmail
+
+import (
+	"cmp"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+)
+
+// AuditCheckpoint is a signature of the hash of an audit event. As every hash
+// covers the ones before it, a checkpoint vouches for its event and all the
+// events before it, so that an exported segment of the chain can be verified
+// offline with just the public key.
+type AuditCheckpoint struct {
+	Id        int64
+	EventId   int64
+	Hash      string
+	Time      time.Time
+	Signature []byte
+}
+
+func checkpointMessage(eventId int64, hash string) []byte {
+	return []byte(fmt.Sprintf("atmail audit checkpoint %d %s", eventId, hash))
+}
+
+// SignAuditCheckpoint returns a checkpoint of event signed with key.
+func SignAuditCheckpoint(key ed25519.PrivateKey, event AuditEvent) AuditCheckpoint {
+	return AuditCheckpoint{
+		EventId:   event.Id,
+		Hash:      event.Hash,
+		Time:      time.Now().UTC().Truncate(time.Microsecond),
+		Signature: ed25519.Sign(key, checkpointMessage(event.Id, event.Hash)),
+	}
+}
+
+// Verify reports whether the checkpoint was signed by the private key of key.
+func (c AuditCheckpoint) Verify(key ed25519.PublicKey) bool {
+	return ed25519.Verify(key, checkpointMessage(c.EventId, c.Hash), c.Signature)
+}
+
+// ErrAuditBroken is the first link of the audit chain that does not hold.
+type ErrAuditBroken struct {
+	// Id is the id of the event where the chain breaks.
+	Id     int64
+	Reason string
+}
+
+func (e ErrAuditBroken) Error() string {
+	return fmt.Sprintf("error audit broken: %d: %s", e.Id, e.Reason)
+}
+
+// AuditVerifier walks audit events in the order of the chain, oldest first,
+// checking that each one follows from the one before it and matches its
+// checkpoints.
+type AuditVerifier struct {
+	key         ed25519.PublicKey
+	checkpoints map[int64]AuditCheckpoint
+	segment     bool
+	first       int64
+	prev        *AuditEvent
+	// Events is how many events were verified.
+	Events int
+	// Vouched is the id of the last event vouched for by a checkpoint, the
+	// events after it are only as trustworthy as the copy they were read from.
+	Vouched int64
+}
+
+// NewAuditVerifier returns a verifier of the chain from its first event, or
+// of a segment of it if segment is true. Checkpoints are verified with key
+// unless it is nil, and a segment can only be verified with a key.
+func NewAuditVerifier(key ed25519.PublicKey, checkpoints []AuditCheckpoint, segment bool) (*AuditVerifier, error) {
+	v := &AuditVerifier{key: key, checkpoints: map[int64]AuditCheckpoint{}, segment: segment}
+
+	if segment && key == nil {
+		return nil, errors.New("a segment cannot be verified without a key")
+	}
+
+	if !segment {
+		v.prev = &AuditEvent{Hash: GenesisHash}
+	}
+
+	for _, c := range checkpoints {
+		if key != nil && !c.Verify(key) {
+			return nil, ErrAuditBroken{Id: c.EventId, Reason: fmt.Sprintf("signature of checkpoint %d is invalid", c.Id)}
+		}
+
+		v.checkpoints[c.EventId] = c
+	}
+
+	return v, nil
+}
+
+// Add verifies the next event of the chain.
+func (v *AuditVerifier) Add(event AuditEvent) error {
+	if v.prev != nil && event.PrevHash != v.prev.Hash {
+		if v.prev.Id == 0 {
+			return ErrAuditBroken{Id: event.Id, Reason: "first event does not start the chain"}
+		}
+
+		return ErrAuditBroken{Id: event.Id, Reason: fmt.Sprintf("previous hash does not match event %d", v.prev.Id)}
+	}
+
+	if event.ComputeHash() != event.Hash {
+		return ErrAuditBroken{Id: event.Id, Reason: "hash does not match the event"}
+	}
+
+	if c, ok := v.checkpoints[event.Id]; ok {
+		if c.Hash != event.Hash {
+			return ErrAuditBroken{Id: event.Id, Reason: fmt.Sprintf("hash does not match checkpoint %d", c.Id)}
+		}
+
+		if v.key != nil {
+			v.Vouched = event.Id
+		}
+
+		delete(v.checkpoints, event.Id)
+	}
+
+	if v.Events == 0 {
+		v.first = event.Id
+	}
+
+	v.prev = &event
+	v.Events++
+
+	return nil
+}
+
+// Finish checks that no checkpoint vouches for an event that was not verified,
+// e.g. one removed from the end of the chain. A segment is only checked
+// against the checkpoints within it.
+func (v *AuditVerifier) Finish() error {
+	missing := []AuditCheckpoint{}
+
+	for _, c := range v.checkpoints {
+		if v.segment && (v.Events == 0 || c.EventId < v.first || c.EventId > v.prev.Id) {
+			continue
+		}
+
+		missing = append(missing, c)
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	c := slices.MinFunc(missing, func(a, b AuditCheckpoint) int {
+		return cmp.Compare(a.EventId, b.EventId)
+	})
+
+	return ErrAuditBroken{Id: c.EventId, Reason: fmt.Sprintf("event of checkpoint %d is missing", c.Id)}
+}
+
+// AuditCheckpointer signs a checkpoint of the last audit event every
+// interval, unless there was no event since the last checkpoint.
+type AuditCheckpointer struct {
+	store    Store
+	key      ed25519.PrivateKey
+	interval time.Duration
+}
+
+func NewAuditCheckpointer(store Store, key ed25519.PrivateKey, interval time.Duration) *AuditCheckpointer {
+	return &AuditCheckpointer{store: store, key: key, interval: interval}
+}
+
+// Run checkpoints every interval until ctx is done.
+func (c *AuditCheckpointer) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		// failures are retried on the next tick
+		if checkpoint, ok, err := c.Checkpoint(ctx); err != nil {
+			log.Printf("failed to checkpoint audit events: %v", err)
+		} else if ok {
+			log.Printf("checkpointed audit event %d", checkpoint.EventId)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Checkpoint signs a checkpoint of the last audit event once and reports
+// whether there was a new one.
+func (c *AuditCheckpointer) Checkpoint(ctx context.Context) (AuditCheckpoint, bool, error) {
+	events, err := c.store.ListAuditEvents(ctx, AuditOptions{Limit: 1})
+	if err != nil || len(events) == 0 {
+		return AuditCheckpoint{}, false, err
+	}
+
+	checkpoints, err := c.store.ListAuditCheckpoints(ctx)
+	if err != nil {
+		return AuditCheckpoint{}, false, err
+	}
+
+	if n := len(checkpoints); n > 0 && checkpoints[n-1].EventId >= events[0].Id {
+		return AuditCheckpoint{}, false, nil
+	}
+
+	checkpoint := SignAuditCheckpoint(c.key, events[0])
+
+	checkpoint.Id, err = c.store.CreateAuditCheckpoint(ctx, checkpoint)
+	if err != nil {
+		return AuditCheckpoint{}, false, err
+	}
+
+	return checkpoint, true, nil
+}
+
+func (s store) CreateAuditCheckpoint(ctx context.Context, c AuditCheckpoint) (int64, error) {
+	return s.insert(ctx, s.conn(), "INSERT INTO audit_checkpoints (event_id, hash, time, signature) VALUES (?, ?, ?, ?)", c.EventId, c.Hash, c.Time.UTC(), c.Signature)
+}
+
+// ListAuditCheckpoints lists every checkpoint, oldest first.
+func (s store) ListAuditCheckpoints(ctx context.Context) ([]AuditCheckpoint, error) {
+	rows, err := s.conn().QueryContext(ctx, "SELECT id, event_id, hash, time, signature FROM audit_checkpoints ORDER BY event_id")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	checkpoints := []AuditCheckpoint{}
+
+	for rows.Next() {
+		c := AuditCheckpoint{}
+
+		if err := rows.Scan(&c.Id, &c.EventId, &c.Hash, &c.Time, &c.Signature); err != nil {
+			return nil, err
+		}
+
+		checkpoints = append(checkpoints, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return checkpoints, nil
+}
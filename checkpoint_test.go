@@ -0,0 +1,170 @@
+package atmail_test
+
+import (
+	"atmail"
+	"crypto/ed25519"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testChain returns a chain of n audit events, as CreateAuditEvent would.
+func testChain(n int) []atmail.AuditEvent {
+	events := []atmail.AuditEvent{}
+	prev := atmail.GenesisHash
+
+	for i := range n {
+		event := atmail.AuditEvent{
+			Id:       int64(i + 1),
+			Time:     time.Date(2024, 1, 1, i, 0, 0, 0, time.UTC),
+			Actor:    "dan",
+			Action:   atmail.ActionUserUpdate,
+			Route:    "PUT /users/{id}",
+			Target:   1,
+			Changes:  []atmail.Change{{Field: "age", Before: float64(i), After: float64(i + 1)}},
+			PrevHash: prev,
+		}
+
+		event.Hash = event.ComputeHash()
+		prev = event.Hash
+
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func TestAuditVerifier(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chain := testChain(5)
+
+	checkpoint := func(id int64) atmail.AuditCheckpoint {
+		c := atmail.SignAuditCheckpoint(private, chain[id-1])
+		c.Id = id
+
+		return c
+	}
+
+	for name, tc := range map[string]struct {
+		key         ed25519.PublicKey
+		checkpoints []atmail.AuditCheckpoint
+		segment     bool
+		events      func() []atmail.AuditEvent
+		want        error
+		vouched     int64
+	}{
+		"chain": {
+			events: func() []atmail.AuditEvent { return chain },
+		},
+		"chain with checkpoints": {
+			key:         public,
+			checkpoints: []atmail.AuditCheckpoint{checkpoint(2), checkpoint(4)},
+			events:      func() []atmail.AuditEvent { return chain },
+			vouched:     4,
+		},
+		"edited event": {
+			events: func() []atmail.AuditEvent {
+				events := append([]atmail.AuditEvent{}, chain...)
+				events[2].Actor = "craig"
+
+				return events
+			},
+			want: atmail.ErrAuditBroken{Id: 3, Reason: "hash does not match the event"},
+		},
+		"removed event": {
+			events: func() []atmail.AuditEvent {
+				return append(append([]atmail.AuditEvent{}, chain[:2]...), chain[3:]...)
+			},
+			want: atmail.ErrAuditBroken{Id: 4, Reason: "previous hash does not match event 2"},
+		},
+		"removed first event": {
+			events: func() []atmail.AuditEvent { return chain[1:] },
+			want:   atmail.ErrAuditBroken{Id: 2, Reason: "first event does not start the chain"},
+		},
+		"rehashed events": {
+			checkpoints: []atmail.AuditCheckpoint{checkpoint(4)},
+			events: func() []atmail.AuditEvent {
+				events := append([]atmail.AuditEvent{}, chain...)
+				events[2].Actor = "craig"
+
+				for i := 2; i < len(events); i++ {
+					events[i].PrevHash = events[i-1].Hash
+					events[i].Hash = events[i].ComputeHash()
+				}
+
+				return events
+			},
+			want: atmail.ErrAuditBroken{Id: 4, Reason: "hash does not match checkpoint 4"},
+		},
+		"removed last events": {
+			checkpoints: []atmail.AuditCheckpoint{checkpoint(4)},
+			events:      func() []atmail.AuditEvent { return chain[:3] },
+			want:        atmail.ErrAuditBroken{Id: 4, Reason: "event of checkpoint 4 is missing"},
+		},
+		"invalid signature": {
+			key:         other,
+			checkpoints: []atmail.AuditCheckpoint{checkpoint(2)},
+			events:      func() []atmail.AuditEvent { return chain },
+			want:        atmail.ErrAuditBroken{Id: 2, Reason: "signature of checkpoint 2 is invalid"},
+		},
+		"segment": {
+			key:         public,
+			checkpoints: []atmail.AuditCheckpoint{checkpoint(2), checkpoint(4)},
+			segment:     true,
+			events:      func() []atmail.AuditEvent { return chain[2:] },
+			vouched:     4,
+		},
+		"edited segment": {
+			key:         public,
+			checkpoints: []atmail.AuditCheckpoint{checkpoint(4)},
+			segment:     true,
+			events: func() []atmail.AuditEvent {
+				events := append([]atmail.AuditEvent{}, chain[2:]...)
+				events[0].Actor = "craig"
+				events[0].Hash = events[0].ComputeHash()
+
+				return events
+			},
+			want: atmail.ErrAuditBroken{Id: 4, Reason: "previous hash does not match event 3"},
+		},
+		"segment missing an event": {
+			key:         public,
+			checkpoints: []atmail.AuditCheckpoint{checkpoint(2), checkpoint(4)},
+			segment:     true,
+			events:      func() []atmail.AuditEvent { return []atmail.AuditEvent{chain[2], chain[4]} },
+			want:        atmail.ErrAuditBroken{Id: 5, Reason: "previous hash does not match event 3"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			v, err := atmail.NewAuditVerifier(tc.key, tc.checkpoints, tc.segment)
+			if err == nil {
+				for _, event := range tc.events() {
+					if err = v.Add(event); err != nil {
+						break
+					}
+				}
+			}
+
+			if err == nil {
+				err = v.Finish()
+			}
+
+			if !reflect.DeepEqual(tc.want, err) {
+				t.Fatalf("want %v; got %v", tc.want, err)
+			}
+
+			if err == nil && v.Vouched != tc.vouched {
+				t.Errorf("want %v; got %v", tc.vouched, v.Vouched)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"atmail"
+)
+
+// auditPageSize is how many audit events are read from the database at once.
+const auditPageSize = 1000
+
+// segmentLine is a line of an exported segment of the audit chain, either an
+// event or a checkpoint.
+type segmentLine struct {
+	Event      *segmentEvent      `json:"event,omitempty"`
+	Checkpoint *segmentCheckpoint `json:"checkpoint,omitempty"`
+}
+
+type segmentEvent struct {
+	Id        int64           `json:"id"`
+	Time      time.Time       `json:"time"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"`
+	Route     string          `json:"route"`
+	Target    int64           `json:"target"`
+	Changes   []atmail.Change `json:"changes"`
+	ClientIP  string          `json:"client_ip"`
+	RequestId string          `json:"request_id"`
+	PrevHash  string          `json:"prev_hash"`
+	Hash      string          `json:"hash"`
+}
+
+type segmentCheckpoint struct {
+	Id        int64     `json:"id"`
+	EventId   int64     `json:"event_id"`
+	Hash      string    `json:"hash"`
+	Time      time.Time `json:"time"`
+	Signature []byte    `json:"signature"`
+}
+
+// exportAudit writes the audit events after -after to stdout, one json line
+// each, followed by the checkpoints within them.
+//
+//	atmail export-audit [-after id] [-limit n] > segment.jsonl
+func exportAudit(args []string) {
+	flags := flag.NewFlagSet("export-audit", flag.ExitOnError)
+	after := flags.Int64("after", 0, "export the events after the event with this id")
+	limit := flags.Int("limit", 0, "export at most this many events, 0 for all")
+	flags.Parse(args)
+
+	store := atmail.NewStore(openDB())
+
+	ctx := context.Background()
+
+	w := bufio.NewWriter(os.Stdout)
+	enc := json.NewEncoder(w)
+
+	first, last, n := int64(0), int64(0), 0
+
+	err := pageAuditEvents(ctx, store, *after, func(event atmail.AuditEvent) (bool, error) {
+		if first == 0 {
+			first = event.Id
+		}
+
+		last = event.Id
+		n++
+
+		e := segmentEvent(event)
+
+		return *limit == 0 || n < *limit, enc.Encode(segmentLine{Event: &e})
+	})
+	if err != nil {
+		log.Fatalf("failed to export audit events: %v", err)
+	}
+
+	checkpoints, err := store.ListAuditCheckpoints(ctx)
+	if err != nil {
+		log.Fatalf("failed to export audit checkpoints: %v", err)
+	}
+
+	for _, c := range checkpoints {
+		if n > 0 && c.EventId >= first && c.EventId <= last {
+			sc := segmentCheckpoint(c)
+
+			if err := enc.Encode(segmentLine{Checkpoint: &sc}); err != nil {
+				log.Fatalf("failed to export audit checkpoints: %v", err)
+			}
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		log.Fatalf("failed to export audit events: %v", err)
+	}
+}
+
+// verifyAudit walks the audit chain in the database, or an exported segment
+// of it given by -file, and reports the first broken link. Checkpoints are
+// verified with the public key of -key, which a segment requires.
+//
+//	atmail verify-audit [-key public.pem] [-file segment.jsonl]
+func verifyAudit(args []string) {
+	flags := flag.NewFlagSet("verify-audit", flag.ExitOnError)
+	keyFile := flags.String("key", "", "pem file of the ed25519 public key of the checkpoints")
+	file := flags.String("file", "", "exported segment to verify instead of the database")
+	flags.Parse(args)
+
+	var key ed25519.PublicKey
+
+	if *keyFile != "" {
+		var err error
+
+		key, err = readPublicKey(*keyFile)
+		if err != nil {
+			log.Fatalf("failed to read %s: %v", *keyFile, err)
+		}
+	}
+
+	var v *atmail.AuditVerifier
+	var err error
+
+	if *file != "" {
+		v, err = verifySegment(*file, key)
+	} else {
+		v, err = verifyDB(key)
+	}
+
+	var broken atmail.ErrAuditBroken
+
+	if errors.As(err, &broken) {
+		fmt.Printf("audit chain is broken at event %d: %s\n", broken.Id, broken.Reason)
+		os.Exit(1)
+	}
+
+	if err != nil {
+		log.Fatalf("failed to verify audit events: %v", err)
+	}
+
+	fmt.Printf("verified %d audit events\n", v.Events)
+
+	if key != nil {
+		if v.Vouched == 0 {
+			fmt.Println("no event is vouched for by a checkpoint")
+		} else {
+			fmt.Printf("events up to %d are vouched for by a checkpoint\n", v.Vouched)
+		}
+	}
+}
+
+func verifyDB(key ed25519.PublicKey) (*atmail.AuditVerifier, error) {
+	store := atmail.NewStore(openDB())
+
+	ctx := context.Background()
+
+	checkpoints, err := store.ListAuditCheckpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := atmail.NewAuditVerifier(key, checkpoints, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pageAuditEvents(ctx, store, 0, func(event atmail.AuditEvent) (bool, error) {
+		return true, v.Add(event)
+	}); err != nil {
+		return nil, err
+	}
+
+	return v, v.Finish()
+}
+
+func verifySegment(file string, key ed25519.PublicKey) (*atmail.AuditVerifier, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	events := []atmail.AuditEvent{}
+	checkpoints := []atmail.AuditCheckpoint{}
+
+	dec := json.NewDecoder(f)
+
+	for {
+		var line segmentLine
+
+		if err := dec.Decode(&line); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if line.Event != nil {
+			events = append(events, atmail.AuditEvent(*line.Event))
+		}
+
+		if line.Checkpoint != nil {
+			checkpoints = append(checkpoints, atmail.AuditCheckpoint(*line.Checkpoint))
+		}
+	}
+
+	v, err := atmail.NewAuditVerifier(key, checkpoints, true)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, event := range events {
+		if err := v.Add(event); err != nil {
+			return nil, err
+		}
+	}
+
+	return v, v.Finish()
+}
+
+// pageAuditEvents calls fn with the audit events after the event with id
+// after, oldest first, until it returns false.
+func pageAuditEvents(ctx context.Context, store atmail.Store, after int64, fn func(atmail.AuditEvent) (bool, error)) error {
+	for {
+		events, err := store.ListAuditEvents(ctx, atmail.AuditOptions{Limit: auditPageSize, After: after, Asc: true})
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			more, err := fn(event)
+			if err != nil || !more {
+				return err
+			}
+
+			after = event.Id
+		}
+
+		if len(events) < auditPageSize {
+			return nil
+		}
+	}
+}
+
+// readPrivateKey reads a pem encoded pkcs8 ed25519 private key, e.g. one made
+// with openssl genpkey -algorithm ed25519.
+func readPrivateKey(file string) (ed25519.PrivateKey, error) {
+	bs, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(bs)
+	if block == nil {
+		return nil, errors.New("no pem block")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	ed, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an ed25519 private key")
+	}
+
+	return ed, nil
+}
+
+// readPublicKey reads a pem encoded pkix ed25519 public key, e.g. one made
+// with openssl pkey -pubout.
+func readPublicKey(file string) (ed25519.PublicKey, error) {
+	bs, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(bs)
+	if block == nil {
+		return nil, errors.New("no pem block")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	ed, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("not an ed25519 public key")
+	}
+
+	return ed, nil
+}
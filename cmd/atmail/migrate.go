@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"atmail"
+)
+
+const migrateUsage = "usage: atmail migrate up|down|status|to <version>"
+
+// migrate applies or reverts the migrations of the schema, or lists them.
+//
+//	atmail migrate up|down|status|to <version>
+func migrate(args []string) {
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	m, err := atmail.NewMigrator(openDB(), atmail.DefaultMigrationLockTimeout)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	var done []atmail.Migration
+	verb := "applied"
+
+	switch args[0] {
+	case "status":
+		printMigrations(ctx, m)
+		return
+	case "up":
+		done, err = m.Up(ctx)
+	case "down":
+		done, err = m.Down(ctx)
+		verb = "reverted"
+	case "to":
+		if len(args) != 2 {
+			log.Fatal(migrateUsage)
+		}
+
+		version, perr := strconv.Atoi(args[1])
+		if perr != nil {
+			log.Fatalf("version %q is invalid!", args[1])
+		}
+
+		done, err = m.To(ctx, version)
+		verb = "migrated"
+	default:
+		log.Fatal(migrateUsage)
+	}
+
+	for _, migration := range done {
+		fmt.Printf("%s %d %s\n", verb, migration.Version, migration.Name)
+	}
+
+	if err != nil {
+		log.Fatalf("failed to migrate: %v", err)
+	}
+
+	if len(done) == 0 {
+		fmt.Println("nothing to migrate")
+	}
+}
+
+func printMigrations(ctx context.Context, m *atmail.Migrator) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT\tSTATUS")
+
+	for _, s := range statuses {
+		appliedAt, status := "", "pending"
+
+		if s.Applied {
+			appliedAt, status = s.AppliedAt.Format(time.RFC3339), "applied"
+		}
+
+		switch {
+		case s.Dirty:
+			status = "dirty"
+		case s.Changed:
+			status = "changed"
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, appliedAt, status)
+	}
+
+	w.Flush()
+}
+
+// autoMigrate applies the migrations on start when AUTO_MIGRATE is set.
+// Replicas starting together wait for whichever migrates first.
+func autoMigrate(db *sql.DB) {
+	v := os.Getenv("AUTO_MIGRATE")
+	if v == "" {
+		return
+	}
+
+	ok, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Fatalf("failed to parse AUTO_MIGRATE: %v", err)
+	}
+
+	if !ok {
+		return
+	}
+
+	m, err := atmail.NewMigrator(db, atmail.DefaultMigrationLockTimeout)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	done, err := m.Up(context.Background())
+
+	for _, migration := range done {
+		log.Printf("applied migration %d %s", migration.Version, migration.Name)
+	}
+
+	if err != nil {
+		log.Fatalf("failed to migrate: %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package atmail
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// DefaultEventRetention is how long user events are kept by default before
+// they are compacted.
+const DefaultEventRetention = 7 * 24 * time.Hour
+
+// UserEvent is a change of a user in the user_events feed. Events are
+// sequenced one at a time in the order they commit, so that the sequence has
+// no gaps and consumers can resume after the last one they saw.
+type UserEvent struct {
+	Seq  int64
+	Time time.Time
+	// Action is one of the ActionUser constants.
+	Action string
+	UserId int64
+	// Data is the json of the user and the fields that changed.
+	Data []byte
+}
+
+// CreateUserEvent appends event to the feed and returns its sequence.
+func (s store) CreateUserEvent(ctx context.Context, event UserEvent) (int64, error) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	// the head is locked until the transaction commits, so events commit in
+	// the order of their sequence
+	if err := tx.QueryRowContext(ctx, "SELECT seq FROM user_events_head WHERE id = 1"+s.dialect.forUpdate).Scan(&event.Seq); err != nil {
+		return 0, err
+	}
+
+	event.Seq++
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO user_events (seq, time, action, user_id, data) VALUES (?, ?, ?, ?, ?)", event.Seq, event.Time.UTC().Truncate(time.Microsecond), event.Action, event.UserId, event.Data); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE user_events_head SET seq = ? WHERE id = 1", event.Seq); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return event.Seq, nil
+}
+
+// ListUserEvents lists up to limit events after the sequence after, oldest
+// first.
+func (s store) ListUserEvents(ctx context.Context, after int64, limit int) ([]UserEvent, error) {
+	rows, err := s.conn().QueryContext(ctx, "SELECT seq, time, action, user_id, data FROM user_events WHERE seq > ? ORDER BY seq LIMIT ?", after, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	events := []UserEvent{}
+
+	for rows.Next() {
+		event := UserEvent{}
+
+		if err := rows.Scan(&event.Seq, &event.Time, &event.Action, &event.UserId, &event.Data); err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// CompactUserEvents deletes the events older than before, except for the
+// last one, so that consumers can still tell that they missed events.
+func (s store) CompactUserEvents(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.conn().ExecContext(ctx, "DELETE FROM user_events WHERE time < ? AND seq < (SELECT seq FROM user_events_head WHERE id = 1)", before.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// Compactor deletes the user events older than the retention, after which
+// consumers that had not read them yet can no longer resume.
+type Compactor struct {
+	store     Store
+	retention time.Duration
+	interval  time.Duration
+}
+
+func NewCompactor(store Store, retention time.Duration, interval time.Duration) *Compactor {
+	return &Compactor{store: store, retention: retention, interval: interval}
+}
+
+// Run compacts every interval until ctx is done.
+func (c *Compactor) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		// failures are retried on the next tick
+		if n, err := c.Compact(ctx); err != nil {
+			log.Printf("failed to compact user events: %v", err)
+		} else if n > 0 {
+			log.Printf("compacted %d user events", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Compact compacts the events past the retention once and returns how many
+// there were.
+func (c *Compactor) Compact(ctx context.Context) (int64, error) {
+	return c.store.CompactUserEvents(ctx, time.Now().Add(-c.retention))
+}
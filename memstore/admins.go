@@ -0,0 +1,347 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"atmail"
+	"atmail/passwords"
+)
+
+var errTokenHashExists = errors.New("error token hash exists")
+
+type admin struct {
+	user string
+	// password is a hash of passwords.Hash, or a plaintext password that is
+	// rehashed on the first login
+	password string
+	// roles are sorted by name
+	roles    []string
+	disabled bool
+}
+
+// adminKey is the key of the admin user, which is case insensitive.
+func adminKey(user string) string {
+	return strings.ToLower(user)
+}
+
+// dummyHash is verified against when an admin does not exist so that the
+// response time does not reveal which admins exist.
+var dummyHash = sync.OnceValue(func() string {
+	hash, _ := passwords.Hash("")
+	return hash
+})
+
+// GetRoles returns the names of the roles of the admin with the given
+// credentials.
+func (s *Store) GetRoles(ctx context.Context, user string, password string) ([]string, error) {
+	var a admin
+	var ok bool
+
+	err := s.view(ctx, func(st *state) error {
+		a, ok = st.admins[adminKey(user)]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		passwords.Verify(dummyHash(), password)
+
+		return nil, atmail.ErrAdminNone
+	}
+
+	verified, err := passwords.Verify(a.password, password)
+	if err != nil {
+		return nil, err
+	}
+
+	// disabled admins are verified anyway so that they cannot be told apart
+	if !verified || a.disabled {
+		return nil, atmail.ErrAdminNone
+	}
+
+	// upgrade plaintext and outdated hashes now that we know the password
+	if passwords.NeedsRehash(a.password) {
+		if err := s.rehash(ctx, user, a.password, password); err != nil {
+			log.Printf("failed to rehash password of admin %s: %v", user, err)
+		}
+	}
+
+	return s.adminRoles(ctx, user)
+}
+
+func (s *Store) adminRoles(ctx context.Context, user string) ([]string, error) {
+	roles := []string{}
+
+	err := s.view(ctx, func(st *state) error {
+		roles = append(roles, st.admins[adminKey(user)].roles...)
+		return nil
+	})
+
+	return roles, err
+}
+
+func (s *Store) rehash(ctx context.Context, user string, oldHash string, password string) error {
+	hash, err := passwords.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	return s.update(ctx, func(st *state) error {
+		// only replace the hash we verified against in case it changed meanwhile
+		if a, ok := st.admins[adminKey(user)]; ok && a.password == oldHash {
+			a.password = hash
+			st.admins[adminKey(user)] = a
+		}
+
+		return nil
+	})
+}
+
+// GetPermissions returns the names of the permissions granted by each role.
+func (s *Store) GetPermissions(ctx context.Context) (map[string][]string, error) {
+	permissions := map[string][]string{}
+
+	err := s.view(ctx, func(st *state) error {
+		for role, names := range st.permissions {
+			permissions[role] = slices.Clone(names)
+		}
+
+		return nil
+	})
+
+	return permissions, err
+}
+
+// roles returns the sorted roles, or ErrRoleNone if any of them does not
+// exist.
+func roles(st *state, names []string) ([]string, error) {
+	roles := []string{}
+
+	for _, name := range names {
+		if _, ok := st.permissions[name]; !ok {
+			return nil, atmail.ErrRoleNone
+		}
+
+		if !slices.Contains(roles, name) {
+			roles = append(roles, name)
+		}
+	}
+
+	slices.Sort(roles)
+
+	return roles, nil
+}
+
+// CreateAdmin creates an admin with the given password, which is hashed
+// before it is stored.
+func (s *Store) CreateAdmin(ctx context.Context, a atmail.Admin, password string) error {
+	hash, err := passwords.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	return s.update(ctx, func(st *state) error {
+		if _, ok := st.admins[adminKey(a.User)]; ok {
+			return atmail.ErrAdminExists
+		}
+
+		roles, err := roles(st, a.Roles)
+		if err != nil {
+			return err
+		}
+
+		st.admins[adminKey(a.User)] = admin{user: a.User, password: hash, roles: roles, disabled: a.Disabled}
+
+		return nil
+	})
+}
+
+func (s *Store) ListAdmins(ctx context.Context) ([]atmail.Admin, error) {
+	admins := []atmail.Admin{}
+
+	err := s.view(ctx, func(st *state) error {
+		for _, key := range slices.Sorted(maps.Keys(st.admins)) {
+			a := st.admins[key]
+			admins = append(admins, atmail.Admin{User: a.user, Roles: slices.Clone(a.roles), Disabled: a.disabled})
+		}
+
+		return nil
+	})
+
+	return admins, err
+}
+
+func (s *Store) GetAdmin(ctx context.Context, user string) (atmail.Admin, error) {
+	var found atmail.Admin
+
+	err := s.view(ctx, func(st *state) error {
+		a, ok := st.admins[adminKey(user)]
+		if !ok {
+			return atmail.ErrAdminNone
+		}
+
+		found = atmail.Admin{User: a.user, Roles: slices.Clone(a.roles), Disabled: a.disabled}
+
+		return nil
+	})
+
+	return found, err
+}
+
+// SetAdminRoles replaces the roles of an admin. It fails with ErrRoleNone if
+// any of the roles does not exist.
+func (s *Store) SetAdminRoles(ctx context.Context, user string, names []string) error {
+	return s.updateAdmin(ctx, user, func(st *state, a *admin) error {
+		roles, err := roles(st, names)
+		if err != nil {
+			return err
+		}
+
+		a.roles = roles
+
+		return nil
+	})
+}
+
+func (s *Store) SetAdminPassword(ctx context.Context, user string, password string) error {
+	hash, err := passwords.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	return s.updateAdmin(ctx, user, func(st *state, a *admin) error {
+		a.password = hash
+		return nil
+	})
+}
+
+func (s *Store) SetAdminDisabled(ctx context.Context, user string, disabled bool) error {
+	return s.updateAdmin(ctx, user, func(st *state, a *admin) error {
+		a.disabled = disabled
+		return nil
+	})
+}
+
+// updateAdmin calls fn with the admin user to change, which is only stored
+// if fn returns nil.
+func (s *Store) updateAdmin(ctx context.Context, user string, fn func(*state, *admin) error) error {
+	return s.update(ctx, func(st *state) error {
+		a, ok := st.admins[adminKey(user)]
+		if !ok {
+			return atmail.ErrAdminNone
+		}
+
+		if err := fn(st, &a); err != nil {
+			return err
+		}
+
+		st.admins[adminKey(user)] = a
+
+		return nil
+	})
+}
+
+// DeleteAdmin deletes an admin along with their roles and tokens.
+func (s *Store) DeleteAdmin(ctx context.Context, user string) error {
+	return s.update(ctx, func(st *state) error {
+		if _, ok := st.admins[adminKey(user)]; !ok {
+			return atmail.ErrAdminNone
+		}
+
+		delete(st.admins, adminKey(user))
+
+		for id, token := range st.tokens {
+			if adminKey(token.Admin) == adminKey(user) {
+				delete(st.tokens, id)
+			}
+		}
+
+		return nil
+	})
+}
+
+// CreateToken creates a token, whose roles that do not exist are dropped.
+func (s *Store) CreateToken(ctx context.Context, token atmail.Token) (int64, error) {
+	var id int64
+
+	err := s.update(ctx, func(st *state) error {
+		for _, other := range st.tokens {
+			if other.Hash == token.Hash {
+				return errTokenHashExists
+			}
+		}
+
+		token.Roles = slices.DeleteFunc(slices.Clone(token.Roles), func(role string) bool {
+			_, ok := st.permissions[role]
+			return !ok
+		})
+
+		token.Id = st.next("tokens")
+		token.ExpiresAt = token.ExpiresAt.UTC()
+		st.tokens[token.Id] = token
+
+		id = token.Id
+
+		return nil
+	})
+
+	return id, err
+}
+
+// GetToken returns the token with the given hash. The roles of the token are
+// narrowed to the current roles of its admin so that tokens never outlive a
+// demotion, and tokens of disabled admins are not returned at all.
+func (s *Store) GetToken(ctx context.Context, hash string) (atmail.Token, error) {
+	var found atmail.Token
+
+	err := s.view(ctx, func(st *state) error {
+		for _, token := range st.tokens {
+			if token.Hash != hash {
+				continue
+			}
+
+			a, ok := st.admins[adminKey(token.Admin)]
+			if !ok || a.disabled {
+				break
+			}
+
+			roles := []string{}
+
+			for _, role := range a.roles {
+				if len(token.Roles) == 0 || slices.Contains(token.Roles, role) {
+					roles = append(roles, role)
+				}
+			}
+
+			found = token
+			found.Roles = roles
+
+			return nil
+		}
+
+		return atmail.ErrTokenNone
+	})
+
+	return found, err
+}
+
+func (s *Store) DeleteToken(ctx context.Context, id int64, admin string) error {
+	return s.update(ctx, func(st *state) error {
+		token, ok := st.tokens[id]
+		if !ok || adminKey(token.Admin) != adminKey(admin) {
+			return atmail.ErrTokenNone
+		}
+
+		delete(st.tokens, id)
+
+		return nil
+	})
+}
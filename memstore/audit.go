@@ -0,0 +1,130 @@
+package memstore
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"atmail"
+)
+
+var errCheckpointExists = errors.New("error checkpoint exists")
+
+// auditEvent is an audit event with its changes as json, as they are stored
+// by the SQL stores, so that they are listed as the same types.
+type auditEvent struct {
+	atmail.AuditEvent
+	changes []byte
+}
+
+// CreateAuditEvent records event, at the current time unless it has one,
+// chained to the last event.
+func (s *Store) CreateAuditEvent(ctx context.Context, event atmail.AuditEvent) (int64, error) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	// as precise as the SQL stores
+	event.Time = event.Time.UTC().Truncate(time.Microsecond)
+
+	if event.Changes == nil {
+		event.Changes = []atmail.Change{}
+	}
+
+	changes, err := json.Marshal(event.Changes)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.update(ctx, func(st *state) error {
+		event.PrevHash = st.auditHead
+		event.Hash = event.ComputeHash()
+		event.Id = st.next("audit_events")
+		event.Changes = nil
+
+		st.auditEvents = append(st.auditEvents, auditEvent{event, changes})
+		st.auditHead = event.Hash
+
+		return nil
+	})
+
+	return event.Id, err
+}
+
+// ListAuditEvents lists the events that match o, newest first unless o.Asc.
+func (s *Store) ListAuditEvents(ctx context.Context, o atmail.AuditOptions) ([]atmail.AuditEvent, error) {
+	events := []atmail.AuditEvent{}
+
+	err := s.view(ctx, func(st *state) error {
+		for _, e := range st.auditEvents {
+			if !o.Match(e.AuditEvent) {
+				continue
+			}
+
+			event := e.AuditEvent
+
+			if err := json.Unmarshal(e.changes, &event.Changes); err != nil {
+				return err
+			}
+
+			events = append(events, event)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !o.Asc {
+		slices.Reverse(events)
+	}
+
+	if o.Limit > 0 && len(events) > o.Limit {
+		events = events[:o.Limit]
+	}
+
+	return events, nil
+}
+
+func (s *Store) CreateAuditCheckpoint(ctx context.Context, c atmail.AuditCheckpoint) (int64, error) {
+	err := s.update(ctx, func(st *state) error {
+		for _, other := range st.checkpoints {
+			if other.EventId == c.EventId {
+				return errCheckpointExists
+			}
+		}
+
+		c.Id = st.next("audit_checkpoints")
+		c.Time = c.Time.UTC()
+		c.Signature = slices.Clone(c.Signature)
+
+		st.checkpoints = append(st.checkpoints, c)
+
+		return nil
+	})
+
+	return c.Id, err
+}
+
+// ListAuditCheckpoints lists every checkpoint, oldest first.
+func (s *Store) ListAuditCheckpoints(ctx context.Context) ([]atmail.AuditCheckpoint, error) {
+	checkpoints := []atmail.AuditCheckpoint{}
+
+	err := s.view(ctx, func(st *state) error {
+		checkpoints = append(checkpoints, st.checkpoints...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(checkpoints, func(a atmail.AuditCheckpoint, b atmail.AuditCheckpoint) int {
+		return cmp.Compare(a.EventId, b.EventId)
+	})
+
+	return checkpoints, nil
+}
@@ -0,0 +1,74 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"atmail"
+)
+
+// CreateUserEvent appends event to the feed and returns its sequence.
+func (s *Store) CreateUserEvent(ctx context.Context, event atmail.UserEvent) (int64, error) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	event.Time = event.Time.UTC().Truncate(time.Microsecond)
+	event.Data = slices.Clone(event.Data)
+
+	err := s.update(ctx, func(st *state) error {
+		st.eventHead++
+
+		event.Seq = st.eventHead
+		st.userEvents = append(st.userEvents, event)
+
+		return nil
+	})
+
+	return event.Seq, err
+}
+
+// ListUserEvents lists up to limit events after the sequence after, oldest
+// first.
+func (s *Store) ListUserEvents(ctx context.Context, after int64, limit int) ([]atmail.UserEvent, error) {
+	events := []atmail.UserEvent{}
+
+	err := s.view(ctx, func(st *state) error {
+		for _, event := range st.userEvents {
+			if len(events) == limit {
+				break
+			}
+
+			if event.Seq > after {
+				events = append(events, event)
+			}
+		}
+
+		return nil
+	})
+
+	return events, err
+}
+
+// CompactUserEvents deletes the events older than before, except for the
+// last one, so that consumers can still tell that they missed events.
+func (s *Store) CompactUserEvents(ctx context.Context, before time.Time) (int64, error) {
+	var count int64
+
+	err := s.update(ctx, func(st *state) error {
+		st.userEvents = slices.DeleteFunc(st.userEvents, func(event atmail.UserEvent) bool {
+			compacted := event.Time.Before(before) && event.Seq < st.eventHead
+
+			if compacted {
+				count++
+			}
+
+			return compacted
+		})
+
+		return nil
+	})
+
+	return count, err
+}
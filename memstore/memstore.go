@@ -0,0 +1,521 @@
+// Package memstore is an in-memory atmail.Store, e.g. for the integration
+// tests of services that call atmail. It behaves like the MySQL store:
+// usernames and emails are unique regardless of case, ids are incremented and
+// never reused, deleted users keep their username and email reserved, and
+// transactions are rolled back if they fail.
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"atmail"
+)
+
+type user struct {
+	atmail.User
+	// deletedAt is zero unless the user is deleted
+	deletedAt time.Time
+}
+
+// state is everything in a store, copied by transactions.
+type state struct {
+	// ids are the last ids of each kind of row
+	ids         map[string]int64
+	users       map[int64]user
+	admins      map[string]admin
+	permissions map[string][]string
+	tokens      map[int64]atmail.Token
+	auditEvents []auditEvent
+	auditHead   string
+	checkpoints []atmail.AuditCheckpoint
+	webhooks    map[int64]atmail.Webhook
+	deliveries  map[int64]atmail.WebhookDelivery
+	attempts    []atmail.WebhookAttempt
+	userEvents  []atmail.UserEvent
+	eventHead   int64
+}
+
+func newState() *state {
+	return &state{
+		ids:         map[string]int64{},
+		users:       map[int64]user{},
+		admins:      map[string]admin{},
+		permissions: map[string][]string{},
+		tokens:      map[int64]atmail.Token{},
+		auditHead:   atmail.GenesisHash,
+		webhooks:    map[int64]atmail.Webhook{},
+		deliveries:  map[int64]atmail.WebhookDelivery{},
+	}
+}
+
+// clone returns a copy of st. Rows are copied along with their slices, so
+// that neither copy sees the changes of the other.
+func (st *state) clone() *state {
+	c := *st
+
+	c.ids = cloneMap(st.ids, func(id int64) int64 { return id })
+	c.users = cloneMap(st.users, func(u user) user { return u })
+	c.admins = cloneMap(st.admins, func(a admin) admin {
+		a.roles = slices.Clone(a.roles)
+		return a
+	})
+	c.permissions = cloneMap(st.permissions, slices.Clone[[]string])
+	c.tokens = cloneMap(st.tokens, func(t atmail.Token) atmail.Token {
+		t.Roles = slices.Clone(t.Roles)
+		return t
+	})
+	c.auditEvents = slices.Clone(st.auditEvents)
+	c.checkpoints = slices.Clone(st.checkpoints)
+	c.webhooks = cloneMap(st.webhooks, func(w atmail.Webhook) atmail.Webhook {
+		w.Events = slices.Clone(w.Events)
+		return w
+	})
+	c.deliveries = cloneMap(st.deliveries, func(d atmail.WebhookDelivery) atmail.WebhookDelivery { return d })
+	c.attempts = slices.Clone(st.attempts)
+	c.userEvents = slices.Clone(st.userEvents)
+
+	return &c
+}
+
+func cloneMap[K comparable, V any](m map[K]V, clone func(V) V) map[K]V {
+	c := make(map[K]V, len(m))
+
+	for k, v := range m {
+		c[k] = clone(v)
+	}
+
+	return c
+}
+
+// next returns the next id of kind, e.g. "users".
+func (st *state) next(kind string) int64 {
+	st.ids[kind]++
+	return st.ids[kind]
+}
+
+// db is the state shared by a store and its transactions.
+type db struct {
+	mu    sync.RWMutex
+	state *state
+}
+
+// Store is an in-memory atmail.Store that is safe for concurrent use.
+// Transactions run one at a time on a copy of the store, which replaces it
+// when they commit, so they are serializable.
+type Store struct {
+	db *db
+	// tx is the state changed within WithTx
+	tx          *state
+	reservation time.Duration
+}
+
+// Option configures the store returned by New.
+type Option func(*Store)
+
+// WithReservation keeps the username and email of a deleted user reserved
+// for d, see atmail.WithReservation.
+func WithReservation(d time.Duration) Option {
+	return func(s *Store) {
+		s.reservation = d
+	}
+}
+
+// New returns an empty store with the roles and permissions of the
+// migrations, see DefaultPermissions.
+func New(opts ...Option) *Store {
+	st := newState()
+
+	for role, permissions := range DefaultPermissions {
+		st.permissions[role] = slices.Clone(permissions)
+	}
+
+	s := &Store{db: &db{state: st}, reservation: atmail.DefaultReservation}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// DefaultPermissions are the permissions of each role, as in the migrations.
+var DefaultPermissions = map[string][]string{
+	"bingo":  {"users:read", "tokens:manage"},
+	"bluey":  {"users:read", "users:create", "tokens:manage"},
+	"chilli": {"users:read", "users:create", "users:write", "tokens:manage"},
+	"bandit": {"users:read", "users:create", "users:write", "users:delete", "tokens:manage", "admins:manage", "audit:read", "webhooks:manage"},
+}
+
+// view calls fn with the state to read, unless ctx is done.
+func (s *Store) view(ctx context.Context, fn func(*state) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if s.tx != nil {
+		return fn(s.tx)
+	}
+
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	return fn(s.db.state)
+}
+
+// update calls fn with the state to change, unless ctx is done. Methods check
+// everything that can fail before they change anything, so that a failed
+// update changes nothing.
+func (s *Store) update(ctx context.Context, fn func(*state) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if s.tx != nil {
+		return fn(s.tx)
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	return fn(s.db.state)
+}
+
+// WithTx calls fn with a store whose changes are only kept if fn returns nil.
+// Other calls wait until the transaction ends, so fn must only use the store
+// it is given.
+func (s *Store) WithTx(ctx context.Context, fn func(atmail.Store) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	tx := &Store{db: s.db, tx: s.db.state.clone(), reservation: s.reservation}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	s.db.state = tx.tx
+
+	return nil
+}
+
+// reserved returns when users must have been deleted after to still reserve
+// their username and email.
+func (s *Store) reserved() time.Time {
+	return time.Now().UTC().Add(-s.reservation)
+}
+
+func (s *Store) GetUser(ctx context.Context, id int64) (atmail.User, error) {
+	var found atmail.User
+
+	err := s.view(ctx, func(st *state) error {
+		u, ok := st.users[id]
+		if !ok || !u.deletedAt.IsZero() {
+			return atmail.ErrUserNone
+		}
+
+		found = u.User
+
+		return nil
+	})
+
+	return found, err
+}
+
+// ListUsers lists the users that match o in its order. Usernames and emails
+// are compared as they are, see atmail.ListUsersOptions.
+func (s *Store) ListUsers(ctx context.Context, o atmail.ListUsersOptions) ([]atmail.User, error) {
+	if o.Sort == "" {
+		o.Sort = atmail.SortId
+	}
+
+	if !o.Sort.Valid() {
+		return nil, fmt.Errorf("invalid sort %q", o.Sort)
+	}
+
+	users := []atmail.User{}
+
+	err := s.view(ctx, func(st *state) error {
+		for _, u := range st.users {
+			if u.deletedAt.IsZero() && o.Match(u.User) {
+				users = append(users, u.User)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(users, func(a atmail.User, b atmail.User) int {
+		if o.Less(a, b) {
+			return -1
+		}
+
+		return 1
+	})
+
+	if o.Limit > 0 && len(users) > o.Limit {
+		users = users[:o.Limit]
+	}
+
+	return users, nil
+}
+
+// CheckUser reports whether the username or email is taken, by a user or by
+// a deleted user whose reservation has not passed yet.
+func (s *Store) CheckUser(ctx context.Context, username string, email string) (bool, error) {
+	exists := false
+
+	err := s.view(ctx, func(st *state) error {
+		reserved := s.reserved()
+
+		for _, u := range st.users {
+			if (strings.EqualFold(u.Username, username) || strings.EqualFold(u.Email, email)) && (u.deletedAt.IsZero() || u.deletedAt.After(reserved)) {
+				exists = true
+			}
+		}
+
+		return nil
+	})
+
+	return exists, err
+}
+
+// conflict returns ErrUserExists if a and b have the same username or email.
+func conflict(a atmail.User, b atmail.User) error {
+	if strings.EqualFold(a.Username, b.Username) {
+		return atmail.ErrUserExists{Field: "username"}
+	}
+
+	if strings.EqualFold(a.Email, b.Email) {
+		return atmail.ErrUserExists{Field: "email"}
+	}
+
+	return nil
+}
+
+// released reports whether other is a deleted user whose reservation has
+// passed, which is purged when its username or email is taken.
+func (s *Store) released(other user) bool {
+	return !other.deletedAt.IsZero() && !other.deletedAt.After(s.reserved())
+}
+
+// taken returns ErrUserExists if another user has the username or email of
+// u, including deleted users whose reservation has not passed yet.
+func (s *Store) taken(st *state, u atmail.User) error {
+	for id, other := range st.users {
+		if id == u.Id || s.released(other) {
+			continue
+		}
+
+		if err := conflict(other.User, u); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// release purges the released users whose username or email is taken by u.
+func (s *Store) release(st *state, u atmail.User) {
+	for id, other := range st.users {
+		if id != u.Id && s.released(other) && conflict(other.User, u) != nil {
+			delete(st.users, id)
+		}
+	}
+}
+
+func (s *Store) CreateUser(ctx context.Context, u atmail.User) (int64, error) {
+	ids, err := s.CreateUsers(ctx, []atmail.User{u})
+	if err != nil {
+		return 0, err
+	}
+
+	return ids[0], nil
+}
+
+// CreateUsers creates users, so either all of them are created or none are.
+// The ids are returned in the order of users.
+func (s *Store) CreateUsers(ctx context.Context, users []atmail.User) ([]int64, error) {
+	ids := []int64{}
+
+	err := s.update(ctx, func(st *state) error {
+		for i, u := range users {
+			u.Id = 0
+
+			if err := s.taken(st, u); err != nil {
+				return err
+			}
+
+			for _, other := range users[:i] {
+				if err := conflict(other, u); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, u := range users {
+			s.release(st, u)
+
+			u.Id, u.Version = st.next("users"), 1
+			st.users[u.Id] = user{User: u}
+
+			ids = append(ids, u.Id)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// UpdateUser updates the user if its version is still u.Version, and
+// increments the version. Otherwise it fails with ErrVersionConflict.
+func (s *Store) UpdateUser(ctx context.Context, u atmail.User) error {
+	return s.update(ctx, func(st *state) error {
+		current, ok := st.users[u.Id]
+		if !ok || !current.deletedAt.IsZero() {
+			return atmail.ErrUserNone
+		}
+
+		if current.Version != u.Version {
+			return atmail.ErrVersionConflict{Id: u.Id, Version: current.Version}
+		}
+
+		if err := s.taken(st, u); err != nil {
+			return err
+		}
+
+		s.release(st, u)
+
+		u.Version++
+		st.users[u.Id] = user{User: u}
+
+		return nil
+	})
+}
+
+// DeleteUser soft deletes the user, which can be restored until it is purged.
+func (s *Store) DeleteUser(ctx context.Context, id int64) error {
+	return s.update(ctx, func(st *state) error {
+		u, ok := st.users[id]
+		if !ok || !u.deletedAt.IsZero() {
+			return atmail.ErrUserNone
+		}
+
+		u.deletedAt = time.Now().UTC().Truncate(time.Second)
+		st.users[id] = u
+
+		return nil
+	})
+}
+
+// RestoreUser restores a deleted user and increments its version. It fails
+// with ErrUserNone if the user is not deleted.
+func (s *Store) RestoreUser(ctx context.Context, id int64) (atmail.User, error) {
+	var restored atmail.User
+
+	err := s.update(ctx, func(st *state) error {
+		u, ok := st.users[id]
+		if !ok || u.deletedAt.IsZero() {
+			return atmail.ErrUserNone
+		}
+
+		u.deletedAt = time.Time{}
+		u.Version++
+		st.users[id] = u
+
+		restored = u.User
+
+		return nil
+	})
+
+	return restored, err
+}
+
+// PurgeUser hard deletes the user, whether it is deleted or not.
+func (s *Store) PurgeUser(ctx context.Context, id int64) error {
+	return s.update(ctx, func(st *state) error {
+		if _, ok := st.users[id]; !ok {
+			return atmail.ErrUserNone
+		}
+
+		delete(st.users, id)
+
+		return nil
+	})
+}
+
+// PurgeUsers hard deletes the users deleted before t and returns how many
+// there were.
+func (s *Store) PurgeUsers(ctx context.Context, t time.Time) (int64, error) {
+	var count int64
+
+	err := s.update(ctx, func(st *state) error {
+		for id, u := range st.users {
+			if !u.deletedAt.IsZero() && u.deletedAt.Before(t) {
+				delete(st.users, id)
+				count++
+			}
+		}
+
+		return nil
+	})
+
+	return count, err
+}
+
+// ListReleasedUsers returns the ids of the deleted users that writing u
+// purges, whose reservation has passed and who hold its username or email.
+func (s *Store) ListReleasedUsers(ctx context.Context, u atmail.User) ([]int64, error) {
+	return s.ids(ctx, func(id int64, other user) bool {
+		return id != u.Id && s.released(other) && conflict(other.User, u) != nil
+	})
+}
+
+// ListDeletedUsers returns the ids of the users deleted before t, which
+// PurgeUsers purges.
+func (s *Store) ListDeletedUsers(ctx context.Context, t time.Time) ([]int64, error) {
+	return s.ids(ctx, func(id int64, u user) bool {
+		return !u.deletedAt.IsZero() && u.deletedAt.Before(t)
+	})
+}
+
+// ids returns the sorted ids of the users that match.
+func (s *Store) ids(ctx context.Context, match func(int64, user) bool) ([]int64, error) {
+	ids := []int64{}
+
+	err := s.view(ctx, func(st *state) error {
+		for id, u := range st.users {
+			if match(id, u) {
+				ids = append(ids, id)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(ids)
+
+	return ids, nil
+}
@@ -0,0 +1,250 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"atmail"
+	"atmail/storetest"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+
+	id, err := s.CreateUser(ctx, atmail.User{Username: "bingo", Email: "bingo@heeler.com", Age: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id != 1 {
+		t.Errorf("want %v; got %v", 1, id)
+	}
+
+	for name, tc := range map[string]struct {
+		user atmail.User
+		want error
+	}{
+		"username": {
+			user: atmail.User{Username: "BINGO", Email: "other@heeler.com", Age: 6},
+			want: atmail.ErrUserExists{Field: "username"},
+		},
+		"email": {
+			user: atmail.User{Username: "other", Email: "Bingo@Heeler.com", Age: 6},
+			want: atmail.ErrUserExists{Field: "email"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := s.CreateUser(ctx, tc.user); err != tc.want {
+				t.Errorf("want %v; got %v", tc.want, err)
+			}
+		})
+	}
+
+	// ids are not reused after failures or purges
+	if err := s.PurgeUser(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	id, err = s.CreateUser(ctx, atmail.User{Username: "bluey", Email: "bluey@heeler.com", Age: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id != 2 {
+		t.Errorf("want %v; got %v", 2, id)
+	}
+
+	user := atmail.User{Id: id, Username: "bluey", Email: "bluey@heeler.com", Age: 8, Version: 1}
+
+	if err := s.UpdateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.UpdateUser(ctx, user); err != (atmail.ErrVersionConflict{Id: id, Version: 2}) {
+		t.Errorf("want %v; got %v", atmail.ErrVersionConflict{Id: id, Version: 2}, err)
+	}
+
+	if err := s.UpdateUser(ctx, atmail.User{Username: "nobody", Email: "nobody@heeler.com", Age: 1}); err != atmail.ErrUserNone {
+		t.Errorf("want %v; got %v", atmail.ErrUserNone, err)
+	}
+
+	// deleted users keep their username reserved until they are restored
+	if err := s.DeleteUser(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.GetUser(ctx, id); err != atmail.ErrUserNone {
+		t.Errorf("want %v; got %v", atmail.ErrUserNone, err)
+	}
+
+	if exists, _ := s.CheckUser(ctx, "Bluey", ""); !exists {
+		t.Error("want bluey reserved")
+	}
+
+	restored, err := s.RestoreUser(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user.Version = 3
+
+	if !reflect.DeepEqual(user, restored) {
+		t.Errorf("want %v; got %v", user, restored)
+	}
+}
+
+func TestStoreConformance(t *testing.T) {
+	storetest.Run(t, func() atmail.Store { return New() })
+}
+
+func TestStoreWithTx(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+
+	errRollback := errors.New("rollback")
+
+	err := s.WithTx(ctx, func(tx atmail.Store) error {
+		if _, err := tx.CreateUser(ctx, atmail.User{Username: "bingo", Email: "bingo@heeler.com", Age: 6}); err != nil {
+			return err
+		}
+
+		// the transaction sees its own changes
+		if exists, _ := tx.CheckUser(ctx, "bingo", ""); !exists {
+			t.Error("want bingo within the transaction")
+		}
+
+		return errRollback
+	})
+	if err != errRollback {
+		t.Errorf("want %v; got %v", errRollback, err)
+	}
+
+	if exists, _ := s.CheckUser(ctx, "bingo", ""); exists {
+		t.Error("want bingo rolled back")
+	}
+
+	// a failed batch creates none of its users
+	_, err = s.CreateUsers(ctx, []atmail.User{
+		{Username: "bluey", Email: "bluey@heeler.com", Age: 7},
+		{Username: "BLUEY", Email: "other@heeler.com", Age: 7},
+	})
+	if err != (atmail.ErrUserExists{Field: "username"}) {
+		t.Errorf("want %v; got %v", atmail.ErrUserExists{Field: "username"}, err)
+	}
+
+	if users, _ := s.ListUsers(ctx, atmail.ListUsersOptions{}); len(users) != 0 {
+		t.Errorf("want no users; got %v", users)
+	}
+}
+
+func TestStoreConcurrent(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	created := 0
+
+	for range 50 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			err := s.WithTx(ctx, func(tx atmail.Store) error {
+				if exists, err := tx.CheckUser(ctx, "bingo", "bingo@heeler.com"); err != nil || exists {
+					return atmail.ErrUserExists{Field: "username"}
+				}
+
+				_, err := tx.CreateUser(ctx, atmail.User{Username: "bingo", Email: "bingo@heeler.com", Age: 6})
+				return err
+			})
+			if err == nil {
+				mu.Lock()
+				created++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if created != 1 {
+		t.Errorf("want %v; got %v", 1, created)
+	}
+}
+
+func TestStoreSnapshot(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+
+	snapshot := s.Snapshot()
+
+	if _, err := s.CreateUser(ctx, atmail.User{Username: "bingo", Email: "bingo@heeler.com", Age: 6}); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Restore(snapshot)
+
+	if users, _ := s.ListUsers(ctx, atmail.ListUsersOptions{}); len(users) != 0 {
+		t.Errorf("want no users; got %v", users)
+	}
+}
+
+func TestStoreSeedJSON(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+
+	err := s.SeedJSON(strings.NewReader(`{
+		"users": [
+			{ "id": 10, "username": "bingo", "email": "bingo@heeler.com", "age": 6 },
+			{ "username": "bluey", "email": "bluey@heeler.com", "age": 7 }
+		],
+		"admins": [{ "user": "dan", "password": "pass4567", "roles": ["bandit", "bingo"] }]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := s.ListUsers(ctx, atmail.ListUsersOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []atmail.User{
+		{Id: 10, Username: "bingo", Email: "bingo@heeler.com", Age: 6, Version: 1},
+		{Id: 11, Username: "bluey", Email: "bluey@heeler.com", Age: 7, Version: 1},
+	}
+
+	if !reflect.DeepEqual(want, users) {
+		t.Errorf("want %v; got %v", want, users)
+	}
+
+	roles, err := s.GetRoles(ctx, "dan", "pass4567")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual([]string{"bandit", "bingo"}, roles) {
+		t.Errorf("want %v; got %v", []string{"bandit", "bingo"}, roles)
+	}
+
+	// seeds with an unknown role add nothing
+	err = s.Seed(Seed{
+		Users:  []SeedUser{{Username: "chilli", Email: "chilli@heeler.com", Age: 38}},
+		Admins: []SeedAdmin{{User: "erin", Password: "pass8901", Roles: []string{"muffin"}}},
+	})
+	if err != atmail.ErrRoleNone {
+		t.Errorf("want %v; got %v", atmail.ErrRoleNone, err)
+	}
+
+	if exists, _ := s.CheckUser(ctx, "chilli", ""); exists {
+		t.Error("want chilli rolled back")
+	}
+}
@@ -0,0 +1,137 @@
+package memstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"atmail"
+)
+
+// Snapshot is a copy of everything in a store at one point, see
+// Store.Snapshot.
+type Snapshot struct {
+	state *state
+}
+
+// Snapshot returns a copy of the store, which it can be restored to, e.g.
+// between tests sharing a seeded store.
+func (s *Store) Snapshot() Snapshot {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	return Snapshot{s.db.state.clone()}
+}
+
+// Restore replaces everything in the store with snapshot. Ids taken since
+// the snapshot are taken again.
+func (s *Store) Restore(snapshot Snapshot) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.state = snapshot.state.clone()
+}
+
+// Seed is what a store is seeded with, e.g. from json:
+//
+//	{
+//		"users": [{ "username": "johndoe", "email": "john@doe.com", "age": 30 }],
+//		"admins": [{ "user": "dan", "password": "pass4567", "roles": ["bandit"] }]
+//	}
+type Seed struct {
+	Users  []SeedUser  `json:"users"`
+	Admins []SeedAdmin `json:"admins"`
+	// Permissions replace the permissions of each role if set, see
+	// DefaultPermissions.
+	Permissions map[string][]string `json:"permissions"`
+}
+
+// SeedUser is a user to seed, whose id is the next one if it is 0.
+type SeedUser struct {
+	Id       int64  `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Age      uint   `json:"age"`
+	// DeletedAt seeds a deleted user if set.
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+// SeedAdmin is an admin to seed. Password is a hash of passwords.Hash, or a
+// plaintext password, which is rehashed on the first login like the
+// passwords of setup.sql.
+type SeedAdmin struct {
+	User     string   `json:"user"`
+	Password string   `json:"password"`
+	Roles    []string `json:"roles"`
+	Disabled bool     `json:"disabled"`
+}
+
+// Seed adds the users and admins of seed to the store. Nothing is added if
+// any of them fails, e.g. with ErrUserExists or ErrRoleNone.
+func (s *Store) Seed(seed Seed) error {
+	return s.WithTx(context.Background(), func(tx atmail.Store) error {
+		st := tx.(*Store).tx
+
+		if seed.Permissions != nil {
+			st.permissions = map[string][]string{}
+
+			for role, permissions := range seed.Permissions {
+				st.permissions[role] = slices.Clone(permissions)
+			}
+		}
+
+		for _, u := range seed.Users {
+			user := user{User: atmail.User{Id: u.Id, Username: u.Username, Email: u.Email, Age: u.Age, Version: 1}}
+
+			if _, ok := st.users[u.Id]; ok {
+				return fmt.Errorf("error user id exists: %d", u.Id)
+			}
+
+			if err := tx.(*Store).taken(st, user.User); err != nil {
+				return err
+			}
+
+			if u.Id == 0 {
+				user.Id = st.next("users")
+			}
+
+			// ids given are never taken again
+			st.ids["users"] = max(st.ids["users"], user.Id)
+
+			if u.DeletedAt != nil {
+				user.deletedAt = u.DeletedAt.UTC().Truncate(time.Second)
+			}
+
+			st.users[user.Id] = user
+		}
+
+		for _, a := range seed.Admins {
+			if _, ok := st.admins[adminKey(a.User)]; ok {
+				return atmail.ErrAdminExists
+			}
+
+			roles, err := roles(st, a.Roles)
+			if err != nil {
+				return err
+			}
+
+			st.admins[adminKey(a.User)] = admin{user: a.User, password: a.Password, roles: roles, disabled: a.Disabled}
+		}
+
+		return nil
+	})
+}
+
+// SeedJSON seeds the store with the json of a Seed read from r.
+func (s *Store) SeedJSON(r io.Reader) error {
+	seed := Seed{}
+
+	if err := json.NewDecoder(r).Decode(&seed); err != nil {
+		return err
+	}
+
+	return s.Seed(seed)
+}
@@ -0,0 +1,241 @@
+package memstore
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"atmail"
+)
+
+func (s *Store) CreateWebhook(ctx context.Context, w atmail.Webhook) (int64, error) {
+	if w.CreatedAt.IsZero() {
+		w.CreatedAt = time.Now()
+	}
+
+	w.CreatedAt = w.CreatedAt.UTC()
+
+	if len(w.Events) == 0 {
+		w.Events = nil
+	}
+
+	err := s.update(ctx, func(st *state) error {
+		w.Id = st.next("webhooks")
+		w.Events = slices.Clone(w.Events)
+		st.webhooks[w.Id] = w
+
+		return nil
+	})
+
+	return w.Id, err
+}
+
+func (s *Store) GetWebhook(ctx context.Context, id int64) (atmail.Webhook, error) {
+	var found atmail.Webhook
+
+	err := s.view(ctx, func(st *state) error {
+		w, ok := st.webhooks[id]
+		if !ok {
+			return atmail.ErrWebhookNone
+		}
+
+		found = w
+		found.Events = slices.Clone(w.Events)
+
+		return nil
+	})
+
+	return found, err
+}
+
+func (s *Store) ListWebhooks(ctx context.Context) ([]atmail.Webhook, error) {
+	webhooks := []atmail.Webhook{}
+
+	err := s.view(ctx, func(st *state) error {
+		for _, id := range slices.Sorted(maps.Keys(st.webhooks)) {
+			w := st.webhooks[id]
+			w.Events = slices.Clone(w.Events)
+
+			webhooks = append(webhooks, w)
+		}
+
+		return nil
+	})
+
+	return webhooks, err
+}
+
+// UpdateWebhook updates the url, secret and events of the webhook.
+func (s *Store) UpdateWebhook(ctx context.Context, w atmail.Webhook) error {
+	return s.update(ctx, func(st *state) error {
+		current, ok := st.webhooks[w.Id]
+		if !ok {
+			return atmail.ErrWebhookNone
+		}
+
+		current.Url, current.Secret, current.Events = w.Url, w.Secret, slices.Clone(w.Events)
+
+		if len(current.Events) == 0 {
+			current.Events = nil
+		}
+
+		st.webhooks[w.Id] = current
+
+		return nil
+	})
+}
+
+// DeleteWebhook deletes the webhook along with its deliveries.
+func (s *Store) DeleteWebhook(ctx context.Context, id int64) error {
+	return s.update(ctx, func(st *state) error {
+		if _, ok := st.webhooks[id]; !ok {
+			return atmail.ErrWebhookNone
+		}
+
+		delete(st.webhooks, id)
+
+		for deliveryId, d := range st.deliveries {
+			if d.WebhookId != id {
+				continue
+			}
+
+			delete(st.deliveries, deliveryId)
+
+			st.attempts = slices.DeleteFunc(st.attempts, func(a atmail.WebhookAttempt) bool {
+				return a.DeliveryId == deliveryId
+			})
+		}
+
+		return nil
+	})
+}
+
+func (s *Store) CreateWebhookDelivery(ctx context.Context, d atmail.WebhookDelivery) (int64, error) {
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now()
+	}
+
+	d.CreatedAt, d.NextAttemptAt = d.CreatedAt.UTC(), d.NextAttemptAt.UTC()
+
+	err := s.update(ctx, func(st *state) error {
+		d.Id = st.next("webhook_deliveries")
+		d.Payload = slices.Clone(d.Payload)
+		st.deliveries[d.Id] = d
+
+		return nil
+	})
+
+	return d.Id, err
+}
+
+func (s *Store) GetWebhookDelivery(ctx context.Context, id int64) (atmail.WebhookDelivery, error) {
+	var found atmail.WebhookDelivery
+
+	err := s.view(ctx, func(st *state) error {
+		d, ok := st.deliveries[id]
+		if !ok {
+			return atmail.ErrWebhookDeliveryNone
+		}
+
+		found = d
+
+		return nil
+	})
+
+	return found, err
+}
+
+// ListWebhookDeliveries lists the deliveries that match o, newest first
+// unless o.Asc.
+func (s *Store) ListWebhookDeliveries(ctx context.Context, o atmail.WebhookDeliveryOptions) ([]atmail.WebhookDelivery, error) {
+	deliveries := []atmail.WebhookDelivery{}
+
+	err := s.view(ctx, func(st *state) error {
+		for _, id := range slices.Sorted(maps.Keys(st.deliveries)) {
+			if d := st.deliveries[id]; o.Match(d) {
+				deliveries = append(deliveries, d)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !o.Asc {
+		slices.Reverse(deliveries)
+	}
+
+	if o.Limit > 0 && len(deliveries) > o.Limit {
+		deliveries = deliveries[:o.Limit]
+	}
+
+	return deliveries, nil
+}
+
+// UpdateWebhookDelivery updates the status, attempts and next attempt of the
+// delivery.
+func (s *Store) UpdateWebhookDelivery(ctx context.Context, d atmail.WebhookDelivery) error {
+	return s.update(ctx, func(st *state) error {
+		current, ok := st.deliveries[d.Id]
+		if !ok {
+			return atmail.ErrWebhookDeliveryNone
+		}
+
+		current.Status, current.Attempts, current.NextAttemptAt = d.Status, d.Attempts, d.NextAttemptAt.UTC()
+		st.deliveries[d.Id] = current
+
+		return nil
+	})
+}
+
+// ClaimWebhookDelivery leases the delivery until until, if it is still
+// pending and due at due. It fails with ErrWebhookDeliveryNone if it is not.
+func (s *Store) ClaimWebhookDelivery(ctx context.Context, id int64, due time.Time, until time.Time) error {
+	return s.update(ctx, func(st *state) error {
+		current, ok := st.deliveries[id]
+		if !ok || current.Status != atmail.DeliveryPending || current.NextAttemptAt.After(due) {
+			return atmail.ErrWebhookDeliveryNone
+		}
+
+		current.NextAttemptAt = until.UTC()
+		st.deliveries[id] = current
+
+		return nil
+	})
+}
+
+// CreateWebhookAttempt records an attempt, whose duration is kept in
+// milliseconds.
+func (s *Store) CreateWebhookAttempt(ctx context.Context, a atmail.WebhookAttempt) (int64, error) {
+	a.Time = a.Time.UTC()
+	a.Duration = a.Duration.Truncate(time.Millisecond)
+
+	err := s.update(ctx, func(st *state) error {
+		a.Id = st.next("webhook_attempts")
+		st.attempts = append(st.attempts, a)
+
+		return nil
+	})
+
+	return a.Id, err
+}
+
+// ListWebhookAttempts lists the attempts of a delivery, oldest first.
+func (s *Store) ListWebhookAttempts(ctx context.Context, deliveryId int64) ([]atmail.WebhookAttempt, error) {
+	attempts := []atmail.WebhookAttempt{}
+
+	err := s.view(ctx, func(st *state) error {
+		for _, a := range st.attempts {
+			if a.DeliveryId == deliveryId {
+				attempts = append(attempts, a)
+			}
+		}
+
+		return nil
+	})
+
+	return attempts, err
+}
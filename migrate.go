@@ -0,0 +1,494 @@
+package atmail
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+// migrationLock is the name of the advisory lock held while migrating, so
+// that replicas migrating on start do not race.
+const migrationLock = "atmail_migrations"
+
+// DefaultMigrationLockTimeout is how long a migrator waits for another to
+// finish by default.
+const DefaultMigrationLockTimeout = 10 * time.Minute
+
+var ErrMigrationLocked = errors.New("error migration locked")
+
+// ErrMigrationNone is returned when migrating to a version there is no
+// migration of.
+type ErrMigrationNone struct {
+	Version int
+}
+
+func (e ErrMigrationNone) Error() string {
+	return fmt.Sprintf("error migration none: %d", e.Version)
+}
+
+// ErrMigrationDirty is returned when a migration failed part way. MySQL
+// cannot roll back changes of the schema, so they must be fixed by hand
+// before dirty is cleared in schema_migrations. SQLite and PostgreSQL
+// migrations are never dirty, as they are rolled back.
+type ErrMigrationDirty struct {
+	Version int
+}
+
+func (e ErrMigrationDirty) Error() string {
+	return fmt.Sprintf("error migration dirty: %d", e.Version)
+}
+
+// ErrMigrationChecksum is returned when a migration was changed after it was
+// applied.
+type ErrMigrationChecksum struct {
+	Version int
+}
+
+func (e ErrMigrationChecksum) Error() string {
+	return fmt.Sprintf("error migration checksum: %d", e.Version)
+}
+
+// Migration is a numbered change of the schema, embedded from
+// migrations/<dialect>/<version>_<name>.up.sql and its .down.sql.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Checksum is the hex SHA-256 of the up migration, recorded when it is
+// applied.
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migrations returns the embedded migrations of MySQL in order of version.
+func Migrations() ([]Migration, error) {
+	return readMigrations(migrationFiles, "migrations/mysql")
+}
+
+// SQLiteMigrations returns the embedded migrations of SQLite in order of
+// version.
+func SQLiteMigrations() ([]Migration, error) {
+	return readMigrations(migrationFiles, "migrations/sqlite")
+}
+
+// PostgresMigrations returns the embedded migrations of PostgreSQL in order
+// of version.
+func PostgresMigrations() ([]Migration, error) {
+	return readMigrations(migrationFiles, "migrations/postgres")
+}
+
+// readMigrations reads the migrations of dir, whose versions must start at 1
+// and have no gaps.
+func readMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+
+	for _, entry := range entries {
+		m := migrationName.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migration %s is not named <version>_<name>.up.sql or .down.sql", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(m[1])
+
+		bs, err := fs.ReadFile(fsys, dir+"/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != m[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, migration.Name, m[2])
+		}
+
+		if m[3] == "up" {
+			migration.Up = string(bs)
+		} else {
+			migration.Down = string(bs)
+		}
+	}
+
+	migrations := []Migration{}
+
+	for version := 1; version <= len(byVersion); version++ {
+		migration, ok := byVersion[version]
+		if !ok {
+			return nil, fmt.Errorf("migration %d is missing", version)
+		}
+
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d is missing its up or down", version)
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	return migrations, nil
+}
+
+// statements splits a migration into its statements, which end with a ;
+// at the end of a line.
+func statements(migration string) []string {
+	statements := []string{}
+	lines := []string{}
+
+	for _, line := range strings.Split(migration, "\n") {
+		lines = append(lines, line)
+
+		if strings.HasSuffix(strings.TrimSpace(line), ";") {
+			statements = append(statements, strings.TrimSpace(strings.Join(lines, "\n")))
+			lines = lines[:0]
+		}
+	}
+
+	return statements
+}
+
+// MigrationStatus is a migration and whether it was applied.
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	Dirty     bool
+	// Changed is set if the migration was changed since it was applied.
+	Changed bool
+}
+
+// appliedMigration is a row of schema_migrations.
+type appliedMigration struct {
+	version   int
+	checksum  string
+	dirty     bool
+	appliedAt time.Time
+}
+
+// Migrator applies the embedded migrations of the dialect of a database and
+// records them in the schema_migrations table.
+type Migrator struct {
+	db          *sql.DB
+	dialect     dialect
+	migrations  []Migration
+	lockTimeout time.Duration
+}
+
+// NewMigrator returns the migrator of db, which is a MySQL, SQLite or
+// PostgreSQL database. lockTimeout is how long to wait for other migrators of
+// MySQL and PostgreSQL, SQLite waits for its busy timeout instead.
+func NewMigrator(db *sql.DB, lockTimeout time.Duration) (*Migrator, error) {
+	d := dialectOf(db)
+
+	migrations, err := readMigrations(migrationFiles, "migrations/"+d.name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, dialect: d, migrations: migrations, lockTimeout: lockTimeout}, nil
+}
+
+// Status returns every migration, applied or not.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	applied, err := m.applied(ctx, m.dialect.querier(conn))
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []MigrationStatus{}
+
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Migration: migration}
+
+		if a, ok := applied[migration.Version]; ok {
+			status.Applied, status.AppliedAt, status.Dirty = true, a.appliedAt, a.dirty
+			status.Changed = a.checksum != migration.Checksum()
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Up applies every migration not applied yet and returns them. Databases
+// already migrated past the embedded migrations, e.g. by a newer release,
+// are left as they are.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	return m.migrate(ctx, func(current int) int {
+		return max(current, len(m.migrations))
+	})
+}
+
+// Down reverts the last migration applied and returns it.
+func (m *Migrator) Down(ctx context.Context) ([]Migration, error) {
+	return m.migrate(ctx, func(current int) int {
+		return max(current-1, 0)
+	})
+}
+
+// To applies or reverts migrations until version is the last one applied,
+// and returns them in the order they were applied or reverted.
+func (m *Migrator) To(ctx context.Context, version int) ([]Migration, error) {
+	if version < 0 || version > len(m.migrations) {
+		return nil, ErrMigrationNone{version}
+	}
+
+	return m.migrate(ctx, func(current int) int {
+		return version
+	})
+}
+
+// migrate migrates from the current version to that returned by target,
+// locking out other migrators throughout. MySQL migrations hold an advisory
+// lock, SQLite and PostgreSQL migrations all run in one transaction, which is
+// rolled back if any of them fails. The transaction locks the whole SQLite
+// database, and holds an advisory lock of PostgreSQL.
+func (m *Migrator) migrate(ctx context.Context, target func(int) int) ([]Migration, error) {
+	// advisory locks belong to a connection, so everything runs on one
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	var q querier = conn
+	var tx *sql.Tx
+
+	if m.dialect.transactionalDDL {
+		tx, err = conn.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		defer tx.Rollback()
+
+		q = tx
+	}
+
+	q = m.dialect.querier(q)
+
+	switch m.dialect.name {
+	case mysqlDialect.name:
+		var locked sql.NullInt64
+
+		if err := q.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", migrationLock, int(m.lockTimeout.Seconds())).Scan(&locked); err != nil {
+			return nil, err
+		}
+
+		if locked.Int64 != 1 {
+			return nil, ErrMigrationLocked
+		}
+
+		defer conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", migrationLock)
+	case postgresDialect.name:
+		// the lock is released when the transaction ends
+		if _, err := q.ExecContext(ctx, "SELECT set_config('lock_timeout', ?, true)", fmt.Sprintf("%dms", m.lockTimeout.Milliseconds())); err != nil {
+			return nil, err
+		}
+
+		if _, err := q.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext(?))", migrationLock); err != nil {
+			var pgErr *pgconn.PgError
+
+			// lock_not_available
+			if errors.As(err, &pgErr) && pgErr.Code == "55P03" {
+				return nil, ErrMigrationLocked
+			}
+
+			return nil, err
+		}
+	}
+
+	applied, err := m.applied(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	current := 0
+
+	for version, a := range applied {
+		if a.dirty {
+			return nil, ErrMigrationDirty{version}
+		}
+
+		if version <= len(m.migrations) && a.checksum != m.migrations[version-1].Checksum() {
+			return nil, ErrMigrationChecksum{version}
+		}
+
+		current = max(current, version)
+	}
+
+	to := target(current)
+
+	if to == current {
+		return []Migration{}, nil
+	}
+
+	// migrations of a newer release cannot be reverted without their downs
+	if current > len(m.migrations) {
+		return nil, ErrMigrationNone{current}
+	}
+
+	done := []Migration{}
+
+	for _, step := range plan(current, to) {
+		migration := m.migrations[abs(step)-1]
+
+		if step > 0 {
+			err = m.up(ctx, q, migration)
+		} else {
+			err = m.down(ctx, q, migration)
+		}
+
+		if err != nil {
+			// the migrations done so far are rolled back too
+			if tx != nil {
+				done = []Migration{}
+			}
+
+			return done, fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+
+		done = append(done, migration)
+	}
+
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return []Migration{}, err
+		}
+	}
+
+	return done, nil
+}
+
+// plan returns the versions to migrate from current to target in order,
+// positive to apply and negative to revert.
+func plan(current int, target int) []int {
+	steps := []int{}
+
+	for v := current + 1; v <= target; v++ {
+		steps = append(steps, v)
+	}
+
+	for v := current; v > target; v-- {
+		steps = append(steps, -v)
+	}
+
+	return steps
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
+
+// up applies a migration, which is dirty until all of its statements ran.
+func (m *Migrator) up(ctx context.Context, q querier, migration Migration) error {
+	if _, err := q.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, checksum, dirty, applied_at) VALUES (?, ?, ?, ?, ?)", migration.Version, migration.Name, migration.Checksum(), true, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	for _, statement := range statements(migration.Up) {
+		if _, err := q.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	_, err := q.ExecContext(ctx, "UPDATE schema_migrations SET dirty = ? WHERE version = ?", false, migration.Version)
+
+	return err
+}
+
+// down reverts a migration, which is dirty until all of its statements ran.
+func (m *Migrator) down(ctx context.Context, q querier, migration Migration) error {
+	if _, err := q.ExecContext(ctx, "UPDATE schema_migrations SET dirty = ? WHERE version = ?", true, migration.Version); err != nil {
+		return err
+	}
+
+	for _, statement := range statements(migration.Down) {
+		if _, err := q.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	_, err := q.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", migration.Version)
+
+	return err
+}
+
+// applied returns the rows of schema_migrations by version, creating the
+// table if there is none yet.
+func (m *Migrator) applied(ctx context.Context, q querier) (map[int]appliedMigration, error) {
+	if _, err := q.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+  version int NOT NULL,
+  name varchar(255) NOT NULL,
+  checksum char(64) NOT NULL,
+  dirty boolean NOT NULL,
+  applied_at `+m.dialect.datetime+` NOT NULL,
+  PRIMARY KEY (version)
+)`); err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, "SELECT version, checksum, dirty, applied_at FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	applied := map[int]appliedMigration{}
+
+	for rows.Next() {
+		a := appliedMigration{}
+
+		if err := rows.Scan(&a.version, &a.checksum, &a.dirty, &a.appliedAt); err != nil {
+			return nil, err
+		}
+
+		applied[a.version] = a
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
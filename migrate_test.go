@@ -0,0 +1,54 @@
+package atmail_test
+
+import (
+	"atmail"
+	"strings"
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	tests := map[string]struct {
+		migrations func() ([]atmail.Migration, error)
+	}{
+		"mysql":    {atmail.Migrations},
+		"sqlite":   {atmail.SQLiteMigrations},
+		"postgres": {atmail.PostgresMigrations},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			migrations, err := test.migrations()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(migrations) == 0 {
+				t.Fatal("want migrations; got none")
+			}
+
+			for i, m := range migrations {
+				if m.Version != i+1 {
+					t.Errorf("want %v; got %v", i+1, m.Version)
+				}
+
+				// statements are split at semicolons that end a line
+				for _, sql := range []string{m.Up, m.Down} {
+					if !strings.HasSuffix(strings.TrimSpace(sql), ";") {
+						t.Errorf("migration %d: want statements ending with ;", m.Version)
+					}
+				}
+			}
+		})
+	}
+
+	// every dialect has the same versions, so any can be migrated to any
+	mysql, _ := atmail.Migrations()
+
+	for name, test := range tests {
+		migrations, _ := test.migrations()
+
+		if len(migrations) != len(mysql) {
+			t.Errorf("%s: want %v; got %v", name, len(mysql), len(migrations))
+		}
+	}
+}
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS token_roles;
+DROP TABLE IF EXISTS admin_tokens;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS admin_roles;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS roles;
+DROP TABLE IF EXISTS admins;
@@ -0,0 +1,132 @@
+-- passwords are argon2id (or bcrypt) hashes in PHC format, plaintext passwords
+-- are still accepted and rehashed on the admin's first successful login
+CREATE TABLE IF NOT EXISTS admins (
+  user varchar(255) NOT NULL,
+  password varchar(255) NOT NULL,
+  disabled tinyint(1) NOT NULL DEFAULT 0,
+  UNIQUE KEY user (user)
+);
+
+CREATE TABLE IF NOT EXISTS roles (
+  id int NOT NULL AUTO_INCREMENT,
+  name varchar(255) NOT NULL,
+  PRIMARY KEY (id),
+  UNIQUE KEY name (name)
+);
+
+INSERT IGNORE INTO roles VALUES (1,'bingo');
+INSERT IGNORE INTO roles VALUES (2,'bluey');
+INSERT IGNORE INTO roles VALUES (3,'chilli');
+INSERT IGNORE INTO roles VALUES (4,'bandit');
+
+CREATE TABLE IF NOT EXISTS permissions (
+  id int NOT NULL AUTO_INCREMENT,
+  name varchar(255) NOT NULL,
+  PRIMARY KEY (id),
+  UNIQUE KEY name (name)
+);
+
+INSERT IGNORE INTO permissions VALUES (1,'users:read');
+INSERT IGNORE INTO permissions VALUES (2,'users:create');
+INSERT IGNORE INTO permissions VALUES (3,'users:write');
+INSERT IGNORE INTO permissions VALUES (4,'users:delete');
+INSERT IGNORE INTO permissions VALUES (5,'tokens:manage');
+INSERT IGNORE INTO permissions VALUES (6,'admins:manage');
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+  role_id int NOT NULL,
+  permission_id int NOT NULL,
+  PRIMARY KEY (role_id, permission_id)
+);
+
+-- bingo
+INSERT IGNORE INTO role_permissions VALUES (1,1);
+INSERT IGNORE INTO role_permissions VALUES (1,5);
+-- bluey
+INSERT IGNORE INTO role_permissions VALUES (2,1);
+INSERT IGNORE INTO role_permissions VALUES (2,2);
+INSERT IGNORE INTO role_permissions VALUES (2,5);
+-- chilli
+INSERT IGNORE INTO role_permissions VALUES (3,1);
+INSERT IGNORE INTO role_permissions VALUES (3,2);
+INSERT IGNORE INTO role_permissions VALUES (3,3);
+INSERT IGNORE INTO role_permissions VALUES (3,5);
+-- bandit
+INSERT IGNORE INTO role_permissions VALUES (4,1);
+INSERT IGNORE INTO role_permissions VALUES (4,2);
+INSERT IGNORE INTO role_permissions VALUES (4,3);
+INSERT IGNORE INTO role_permissions VALUES (4,4);
+INSERT IGNORE INTO role_permissions VALUES (4,5);
+INSERT IGNORE INTO role_permissions VALUES (4,6);
+
+CREATE TABLE IF NOT EXISTS admin_roles (
+  admin varchar(255) NOT NULL,
+  role_id int NOT NULL,
+  PRIMARY KEY (admin, role_id)
+);
+
+-- deleted users keep their row, and their username and email, until purged
+CREATE TABLE IF NOT EXISTS users (
+  id int NOT NULL AUTO_INCREMENT,
+  username varchar(255) NOT NULL,
+  email varchar(255) NOT NULL,
+  age int NOT NULL,
+  version int NOT NULL DEFAULT 1,
+  deleted_at datetime DEFAULT NULL,
+  PRIMARY KEY (id),
+  UNIQUE KEY username (username),
+  UNIQUE KEY email (email),
+  KEY age (age, id),
+  KEY deleted_at (deleted_at),
+  FULLTEXT KEY search (username, email)
+);
+
+-- only the sha256 hash of a token is stored
+CREATE TABLE IF NOT EXISTS admin_tokens (
+  id int NOT NULL AUTO_INCREMENT,
+  admin varchar(255) NOT NULL,
+  hash char(64) NOT NULL,
+  expires_at datetime NOT NULL,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY hash (hash),
+  KEY admin (admin)
+);
+
+-- tokens without roles have all the roles of their admin
+CREATE TABLE IF NOT EXISTS token_roles (
+  token_id int NOT NULL,
+  role_id int NOT NULL,
+  PRIMARY KEY (token_id, role_id)
+);
+
+-- databases set up with the setup.sql of earlier releases already have users
+-- and admins, without the columns above, and with the roles of admins as a
+-- bitmask in admins.role. Each change only runs when it is missing, as MySQL
+-- has no ADD COLUMN IF NOT EXISTS.
+SET @adopt = IF((SELECT count(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'users' AND column_name = 'version') = 0, 'ALTER TABLE users ADD COLUMN version int NOT NULL DEFAULT 1, ADD COLUMN deleted_at datetime DEFAULT NULL, ADD KEY age (age, id), ADD KEY deleted_at (deleted_at)', 'DO 0');
+PREPARE adopt FROM @adopt;
+EXECUTE adopt;
+DEALLOCATE PREPARE adopt;
+
+SET @adopt = IF((SELECT count(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = 'users' AND index_name = 'search') = 0, 'ALTER TABLE users ADD FULLTEXT KEY search (username, email)', 'DO 0');
+PREPARE adopt FROM @adopt;
+EXECUTE adopt;
+DEALLOCATE PREPARE adopt;
+
+SET @adopt = IF((SELECT count(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'admins' AND column_name = 'disabled') = 0, 'ALTER TABLE admins ADD COLUMN disabled tinyint(1) NOT NULL DEFAULT 0', 'DO 0');
+PREPARE adopt FROM @adopt;
+EXECUTE adopt;
+DEALLOCATE PREPARE adopt;
+
+-- the bits of admins.role were bingo, bluey, chilli and bandit, in the order
+-- of the ids of their roles
+SET @adopt = IF((SELECT count(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'admins' AND column_name = 'role') = 1, 'INSERT IGNORE INTO admin_roles (admin, role_id) SELECT a.user, r.id FROM admins a JOIN roles r ON r.id <= 4 AND a.role & (1 << (r.id - 1)) != 0', 'DO 0');
+PREPARE adopt FROM @adopt;
+EXECUTE adopt;
+DEALLOCATE PREPARE adopt;
+
+SET @adopt = IF((SELECT count(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'admins' AND column_name = 'role') = 1, 'ALTER TABLE admins DROP COLUMN role', 'DO 0');
+PREPARE adopt FROM @adopt;
+EXECUTE adopt;
+DEALLOCATE PREPARE adopt;
@@ -0,0 +1,44 @@
+-- changes is a json array of the fields that changed, see atmail.Change
+CREATE TABLE IF NOT EXISTS audit_events (
+  id int NOT NULL AUTO_INCREMENT,
+  time datetime(6) NOT NULL,
+  actor varchar(255) NOT NULL,
+  action varchar(64) NOT NULL,
+  route varchar(255) NOT NULL,
+  target int NOT NULL DEFAULT 0,
+  changes text NOT NULL,
+  client_ip varchar(45) NOT NULL,
+  request_id varchar(64) NOT NULL,
+  prev_hash char(64) NOT NULL,
+  hash char(64) NOT NULL,
+  PRIMARY KEY (id),
+  KEY actor (actor, id),
+  KEY action (action, id),
+  KEY target (target, id),
+  KEY time (time)
+);
+
+-- the hash of the last audit event, locked while an event is chained to it
+CREATE TABLE IF NOT EXISTS audit_head (
+  id int NOT NULL,
+  hash char(64) NOT NULL,
+  PRIMARY KEY (id)
+);
+
+INSERT IGNORE INTO audit_head VALUES (1,'0000000000000000000000000000000000000000000000000000000000000000');
+
+-- signature is an ed25519 signature of the event id and hash, see
+-- atmail.AuditCheckpoint
+CREATE TABLE IF NOT EXISTS audit_checkpoints (
+  id int NOT NULL AUTO_INCREMENT,
+  event_id int NOT NULL,
+  hash char(64) NOT NULL,
+  time datetime(6) NOT NULL,
+  signature varbinary(64) NOT NULL,
+  PRIMARY KEY (id),
+  UNIQUE KEY event_id (event_id)
+);
+
+INSERT IGNORE INTO permissions VALUES (7,'audit:read');
+-- bandit
+INSERT IGNORE INTO role_permissions VALUES (4,7);
@@ -0,0 +1,40 @@
+-- events is a comma separated list of the actions delivered, all if empty
+CREATE TABLE IF NOT EXISTS webhooks (
+  id int NOT NULL AUTO_INCREMENT,
+  url varchar(2048) NOT NULL,
+  secret varchar(255) NOT NULL,
+  events varchar(255) NOT NULL,
+  created_at datetime NOT NULL,
+  PRIMARY KEY (id)
+);
+
+-- the outbox, added to in the same transaction as the change of the event
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  id int NOT NULL AUTO_INCREMENT,
+  webhook_id int NOT NULL,
+  event varchar(64) NOT NULL,
+  payload text NOT NULL,
+  status varchar(16) NOT NULL,
+  attempts int NOT NULL DEFAULT 0,
+  next_attempt_at datetime NOT NULL,
+  created_at datetime NOT NULL,
+  PRIMARY KEY (id),
+  KEY webhook_id (webhook_id, id),
+  KEY due (status, next_attempt_at)
+);
+
+-- status_code is 0 if there was no response, error says why
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+  id int NOT NULL AUTO_INCREMENT,
+  delivery_id int NOT NULL,
+  time datetime(6) NOT NULL,
+  status_code int NOT NULL,
+  error text NOT NULL,
+  duration_ms int NOT NULL,
+  PRIMARY KEY (id),
+  KEY delivery_id (delivery_id, id)
+);
+
+INSERT IGNORE INTO permissions VALUES (8,'webhooks:manage');
+-- bandit
+INSERT IGNORE INTO role_permissions VALUES (4,8);
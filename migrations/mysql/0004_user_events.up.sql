@@ -0,0 +1,20 @@
+-- seq is allocated from user_events_head, so that events commit in the order
+-- of their seq, data is the json of the user and its changes
+CREATE TABLE IF NOT EXISTS user_events (
+  seq bigint NOT NULL,
+  time datetime(6) NOT NULL,
+  action varchar(255) NOT NULL,
+  user_id int NOT NULL,
+  data json NOT NULL,
+  PRIMARY KEY (seq),
+  KEY time (time)
+);
+
+-- the seq of the last user event, locked while an event is appended
+CREATE TABLE IF NOT EXISTS user_events_head (
+  id int NOT NULL,
+  seq bigint NOT NULL,
+  PRIMARY KEY (id)
+);
+
+INSERT IGNORE INTO user_events_head VALUES (1,0);
@@ -0,0 +1,108 @@
+-- usernames, emails and admins are case insensitive, as with the collation of
+-- MySQL
+CREATE EXTENSION IF NOT EXISTS citext;
+
+-- passwords are argon2id (or bcrypt) hashes in PHC format, plaintext passwords
+-- are still accepted and rehashed on the admin's first successful login
+CREATE TABLE admins (
+  "user" citext NOT NULL,
+  password varchar(255) NOT NULL,
+  disabled boolean NOT NULL DEFAULT false,
+  UNIQUE ("user")
+);
+
+CREATE TABLE roles (
+  id integer GENERATED BY DEFAULT AS IDENTITY,
+  name varchar(255) NOT NULL,
+  PRIMARY KEY (id),
+  UNIQUE (name)
+);
+
+INSERT INTO roles VALUES (1,'bingo');
+INSERT INTO roles VALUES (2,'bluey');
+INSERT INTO roles VALUES (3,'chilli');
+INSERT INTO roles VALUES (4,'bandit');
+
+CREATE TABLE permissions (
+  id integer GENERATED BY DEFAULT AS IDENTITY,
+  name varchar(255) NOT NULL,
+  PRIMARY KEY (id),
+  UNIQUE (name)
+);
+
+INSERT INTO permissions VALUES (1,'users:read');
+INSERT INTO permissions VALUES (2,'users:create');
+INSERT INTO permissions VALUES (3,'users:write');
+INSERT INTO permissions VALUES (4,'users:delete');
+INSERT INTO permissions VALUES (5,'tokens:manage');
+INSERT INTO permissions VALUES (6,'admins:manage');
+
+CREATE TABLE role_permissions (
+  role_id integer NOT NULL,
+  permission_id integer NOT NULL,
+  PRIMARY KEY (role_id, permission_id)
+);
+
+-- bingo
+INSERT INTO role_permissions VALUES (1,1);
+INSERT INTO role_permissions VALUES (1,5);
+-- bluey
+INSERT INTO role_permissions VALUES (2,1);
+INSERT INTO role_permissions VALUES (2,2);
+INSERT INTO role_permissions VALUES (2,5);
+-- chilli
+INSERT INTO role_permissions VALUES (3,1);
+INSERT INTO role_permissions VALUES (3,2);
+INSERT INTO role_permissions VALUES (3,3);
+INSERT INTO role_permissions VALUES (3,5);
+-- bandit
+INSERT INTO role_permissions VALUES (4,1);
+INSERT INTO role_permissions VALUES (4,2);
+INSERT INTO role_permissions VALUES (4,3);
+INSERT INTO role_permissions VALUES (4,4);
+INSERT INTO role_permissions VALUES (4,5);
+INSERT INTO role_permissions VALUES (4,6);
+
+CREATE TABLE admin_roles (
+  admin citext NOT NULL,
+  role_id integer NOT NULL,
+  PRIMARY KEY (admin, role_id)
+);
+
+-- deleted users keep their row, and their username and email, until purged.
+-- Users are searched in memory, there is no FULLTEXT index. The unique keys
+-- are named users_<column>_key, which ErrUserExists is told from.
+CREATE TABLE users (
+  id integer GENERATED BY DEFAULT AS IDENTITY,
+  username citext NOT NULL,
+  email citext NOT NULL,
+  age integer NOT NULL,
+  version integer NOT NULL DEFAULT 1,
+  deleted_at timestamp DEFAULT NULL,
+  PRIMARY KEY (id),
+  UNIQUE (username),
+  UNIQUE (email)
+);
+
+CREATE INDEX users_age ON users (age, id);
+CREATE INDEX users_deleted_at ON users (deleted_at);
+
+-- only the sha256 hash of a token is stored
+CREATE TABLE admin_tokens (
+  id integer GENERATED BY DEFAULT AS IDENTITY,
+  admin citext NOT NULL,
+  hash char(64) NOT NULL,
+  expires_at timestamp NOT NULL,
+  created_at timestamp NOT NULL DEFAULT (now() AT TIME ZONE 'UTC'),
+  PRIMARY KEY (id),
+  UNIQUE (hash)
+);
+
+CREATE INDEX admin_tokens_admin ON admin_tokens (admin);
+
+-- tokens without roles have all the roles of their admin
+CREATE TABLE token_roles (
+  token_id integer NOT NULL,
+  role_id integer NOT NULL,
+  PRIMARY KEY (token_id, role_id)
+);
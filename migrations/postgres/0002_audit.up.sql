@@ -0,0 +1,45 @@
+-- changes is a json array of the fields that changed, see atmail.Change
+CREATE TABLE audit_events (
+  id integer GENERATED BY DEFAULT AS IDENTITY,
+  time timestamp NOT NULL,
+  actor varchar(255) NOT NULL,
+  action varchar(64) NOT NULL,
+  route varchar(255) NOT NULL,
+  target integer NOT NULL DEFAULT 0,
+  changes text NOT NULL,
+  client_ip varchar(45) NOT NULL,
+  request_id varchar(64) NOT NULL,
+  prev_hash char(64) NOT NULL,
+  hash char(64) NOT NULL,
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX audit_events_actor ON audit_events (actor, id);
+CREATE INDEX audit_events_action ON audit_events (action, id);
+CREATE INDEX audit_events_target ON audit_events (target, id);
+CREATE INDEX audit_events_time ON audit_events (time);
+
+-- the hash of the last audit event, locked while an event is chained to it
+CREATE TABLE audit_head (
+  id integer NOT NULL,
+  hash char(64) NOT NULL,
+  PRIMARY KEY (id)
+);
+
+INSERT INTO audit_head VALUES (1,'0000000000000000000000000000000000000000000000000000000000000000');
+
+-- signature is an ed25519 signature of the event id and hash, see
+-- atmail.AuditCheckpoint
+CREATE TABLE audit_checkpoints (
+  id integer GENERATED BY DEFAULT AS IDENTITY,
+  event_id integer NOT NULL,
+  hash char(64) NOT NULL,
+  time timestamp NOT NULL,
+  signature bytea NOT NULL,
+  PRIMARY KEY (id),
+  UNIQUE (event_id)
+);
+
+INSERT INTO permissions VALUES (7,'audit:read');
+-- bandit
+INSERT INTO role_permissions VALUES (4,7);
@@ -0,0 +1,42 @@
+-- events is a comma separated list of the actions delivered, all if empty
+CREATE TABLE webhooks (
+  id integer GENERATED BY DEFAULT AS IDENTITY,
+  url varchar(2048) NOT NULL,
+  secret varchar(255) NOT NULL,
+  events varchar(255) NOT NULL,
+  created_at timestamp NOT NULL,
+  PRIMARY KEY (id)
+);
+
+-- the outbox, added to in the same transaction as the change of the event
+CREATE TABLE webhook_deliveries (
+  id integer GENERATED BY DEFAULT AS IDENTITY,
+  webhook_id integer NOT NULL,
+  event varchar(64) NOT NULL,
+  payload text NOT NULL,
+  status varchar(16) NOT NULL,
+  attempts integer NOT NULL DEFAULT 0,
+  next_attempt_at timestamp NOT NULL,
+  created_at timestamp NOT NULL,
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id, id);
+CREATE INDEX webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+
+-- status_code is 0 if there was no response, error says why
+CREATE TABLE webhook_attempts (
+  id integer GENERATED BY DEFAULT AS IDENTITY,
+  delivery_id integer NOT NULL,
+  time timestamp NOT NULL,
+  status_code integer NOT NULL,
+  error text NOT NULL,
+  duration_ms integer NOT NULL,
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX webhook_attempts_delivery_id ON webhook_attempts (delivery_id, id);
+
+INSERT INTO permissions VALUES (8,'webhooks:manage');
+-- bandit
+INSERT INTO role_permissions VALUES (4,8);
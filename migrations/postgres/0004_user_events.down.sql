@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_events_head;
+DROP TABLE IF EXISTS user_events;
@@ -0,0 +1,21 @@
+-- seq is allocated from user_events_head, so that events commit in the order
+-- of their seq, data is the json of the user and its changes
+CREATE TABLE user_events (
+  seq bigint NOT NULL,
+  time timestamp NOT NULL,
+  action varchar(255) NOT NULL,
+  user_id integer NOT NULL,
+  data json NOT NULL,
+  PRIMARY KEY (seq)
+);
+
+CREATE INDEX user_events_time ON user_events (time);
+
+-- the seq of the last user event, locked while an event is appended
+CREATE TABLE user_events_head (
+  id integer NOT NULL,
+  seq bigint NOT NULL,
+  PRIMARY KEY (id)
+);
+
+INSERT INTO user_events_head VALUES (1,0);
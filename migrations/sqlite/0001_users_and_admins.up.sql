@@ -0,0 +1,99 @@
+-- passwords are argon2id (or bcrypt) hashes in PHC format, plaintext passwords
+-- are still accepted and rehashed on the admin's first successful login
+CREATE TABLE admins (
+  user TEXT NOT NULL COLLATE NOCASE,
+  password TEXT NOT NULL,
+  disabled INTEGER NOT NULL DEFAULT 0,
+  UNIQUE (user)
+);
+
+CREATE TABLE roles (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  UNIQUE (name)
+);
+
+INSERT INTO roles VALUES (1,'bingo');
+INSERT INTO roles VALUES (2,'bluey');
+INSERT INTO roles VALUES (3,'chilli');
+INSERT INTO roles VALUES (4,'bandit');
+
+CREATE TABLE permissions (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  UNIQUE (name)
+);
+
+INSERT INTO permissions VALUES (1,'users:read');
+INSERT INTO permissions VALUES (2,'users:create');
+INSERT INTO permissions VALUES (3,'users:write');
+INSERT INTO permissions VALUES (4,'users:delete');
+INSERT INTO permissions VALUES (5,'tokens:manage');
+INSERT INTO permissions VALUES (6,'admins:manage');
+
+CREATE TABLE role_permissions (
+  role_id INTEGER NOT NULL,
+  permission_id INTEGER NOT NULL,
+  PRIMARY KEY (role_id, permission_id)
+);
+
+-- bingo
+INSERT INTO role_permissions VALUES (1,1);
+INSERT INTO role_permissions VALUES (1,5);
+-- bluey
+INSERT INTO role_permissions VALUES (2,1);
+INSERT INTO role_permissions VALUES (2,2);
+INSERT INTO role_permissions VALUES (2,5);
+-- chilli
+INSERT INTO role_permissions VALUES (3,1);
+INSERT INTO role_permissions VALUES (3,2);
+INSERT INTO role_permissions VALUES (3,3);
+INSERT INTO role_permissions VALUES (3,5);
+-- bandit
+INSERT INTO role_permissions VALUES (4,1);
+INSERT INTO role_permissions VALUES (4,2);
+INSERT INTO role_permissions VALUES (4,3);
+INSERT INTO role_permissions VALUES (4,4);
+INSERT INTO role_permissions VALUES (4,5);
+INSERT INTO role_permissions VALUES (4,6);
+
+CREATE TABLE admin_roles (
+  admin TEXT NOT NULL COLLATE NOCASE,
+  role_id INTEGER NOT NULL,
+  PRIMARY KEY (admin, role_id)
+);
+
+-- deleted users keep their row, and their username and email, until purged.
+-- Users are searched in memory, there is no FULLTEXT index.
+CREATE TABLE users (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  username TEXT NOT NULL COLLATE NOCASE,
+  email TEXT NOT NULL COLLATE NOCASE,
+  age INTEGER NOT NULL,
+  version INTEGER NOT NULL DEFAULT 1,
+  deleted_at DATETIME DEFAULT NULL,
+  UNIQUE (username),
+  UNIQUE (email)
+);
+
+CREATE INDEX users_age ON users (age, id);
+CREATE INDEX users_deleted_at ON users (deleted_at);
+
+-- only the sha256 hash of a token is stored
+CREATE TABLE admin_tokens (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  admin TEXT NOT NULL COLLATE NOCASE,
+  hash TEXT NOT NULL,
+  expires_at DATETIME NOT NULL,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (hash)
+);
+
+CREATE INDEX admin_tokens_admin ON admin_tokens (admin);
+
+-- tokens without roles have all the roles of their admin
+CREATE TABLE token_roles (
+  token_id INTEGER NOT NULL,
+  role_id INTEGER NOT NULL,
+  PRIMARY KEY (token_id, role_id)
+);
@@ -0,0 +1,5 @@
+DELETE FROM role_permissions WHERE permission_id = 7;
+DELETE FROM permissions WHERE id = 7;
+DROP TABLE IF EXISTS audit_checkpoints;
+DROP TABLE IF EXISTS audit_head;
+DROP TABLE IF EXISTS audit_events;
@@ -0,0 +1,42 @@
+-- changes is a json array of the fields that changed, see atmail.Change
+CREATE TABLE audit_events (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  time DATETIME NOT NULL,
+  actor TEXT NOT NULL,
+  action TEXT NOT NULL,
+  route TEXT NOT NULL,
+  target INTEGER NOT NULL DEFAULT 0,
+  changes TEXT NOT NULL,
+  client_ip TEXT NOT NULL,
+  request_id TEXT NOT NULL,
+  prev_hash TEXT NOT NULL,
+  hash TEXT NOT NULL
+);
+
+CREATE INDEX audit_events_actor ON audit_events (actor, id);
+CREATE INDEX audit_events_action ON audit_events (action, id);
+CREATE INDEX audit_events_target ON audit_events (target, id);
+CREATE INDEX audit_events_time ON audit_events (time);
+
+-- the hash of the last audit event, updated in the transaction of each event
+CREATE TABLE audit_head (
+  id INTEGER PRIMARY KEY,
+  hash TEXT NOT NULL
+);
+
+INSERT INTO audit_head VALUES (1,'0000000000000000000000000000000000000000000000000000000000000000');
+
+-- signature is an ed25519 signature of the event id and hash, see
+-- atmail.AuditCheckpoint
+CREATE TABLE audit_checkpoints (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  event_id INTEGER NOT NULL,
+  hash TEXT NOT NULL,
+  time DATETIME NOT NULL,
+  signature BLOB NOT NULL,
+  UNIQUE (event_id)
+);
+
+INSERT INTO permissions VALUES (7,'audit:read');
+-- bandit
+INSERT INTO role_permissions VALUES (4,7);
@@ -0,0 +1,5 @@
+DELETE FROM role_permissions WHERE permission_id = 8;
+DELETE FROM permissions WHERE id = 8;
+DROP TABLE IF EXISTS webhook_attempts;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
@@ -0,0 +1,39 @@
+-- events is a comma separated list of the actions delivered, all if empty
+CREATE TABLE webhooks (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  url TEXT NOT NULL,
+  secret TEXT NOT NULL,
+  events TEXT NOT NULL,
+  created_at DATETIME NOT NULL
+);
+
+-- the outbox, added to in the same transaction as the change of the event
+CREATE TABLE webhook_deliveries (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  webhook_id INTEGER NOT NULL,
+  event TEXT NOT NULL,
+  payload TEXT NOT NULL,
+  status TEXT NOT NULL,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  next_attempt_at DATETIME NOT NULL,
+  created_at DATETIME NOT NULL
+);
+
+CREATE INDEX webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id, id);
+CREATE INDEX webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+
+-- status_code is 0 if there was no response, error says why
+CREATE TABLE webhook_attempts (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  delivery_id INTEGER NOT NULL,
+  time DATETIME NOT NULL,
+  status_code INTEGER NOT NULL,
+  error TEXT NOT NULL,
+  duration_ms INTEGER NOT NULL
+);
+
+CREATE INDEX webhook_attempts_delivery_id ON webhook_attempts (delivery_id, id);
+
+INSERT INTO permissions VALUES (8,'webhooks:manage');
+-- bandit
+INSERT INTO role_permissions VALUES (4,8);
@@ -0,0 +1,19 @@
+-- seq is allocated from user_events_head, so that events commit in the order
+-- of their seq, data is the json of the user and its changes
+CREATE TABLE user_events (
+  seq INTEGER PRIMARY KEY,
+  time DATETIME NOT NULL,
+  action TEXT NOT NULL,
+  user_id INTEGER NOT NULL,
+  data TEXT NOT NULL
+);
+
+CREATE INDEX user_events_time ON user_events (time);
+
+-- the seq of the last user event, updated in the transaction of each event
+CREATE TABLE user_events_head (
+  id INTEGER PRIMARY KEY,
+  seq INTEGER NOT NULL
+);
+
+INSERT INTO user_events_head VALUES (1,0);
@@ -0,0 +1,150 @@
+package passwords
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrHashInvalid = errors.New("error hash invalid")
+
+// Params are the argon2id parameters used when hashing a password.
+type Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Default are the parameters new hashes are created with. Hashes created with
+// anything else are reported by NeedsRehash.
+var Default = Params{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var encoding = base64.RawStdEncoding
+
+// Hash returns a PHC-formatted argon2id hash of password using Default, e.g.
+// $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>.
+func Hash(password string) (string, error) {
+	return HashWith(Default, password)
+}
+
+// HashWith is like Hash but with the given parameters.
+func HashWith(p Params, password string) (string, error) {
+	salt := make([]byte, p.SaltLength)
+
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		encoding.EncodeToString(salt), encoding.EncodeToString(key),
+	), nil
+}
+
+// Verify reports whether password matches encoded. Both argon2id PHC strings
+// and bcrypt hashes are accepted. Anything else is treated as a legacy
+// plaintext password.
+func Verify(encoded string, password string) (bool, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		p, salt, key, err := decodeArgon2id(encoded)
+		if err != nil {
+			return false, err
+		}
+
+		got := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+		return subtle.ConstantTimeCompare(key, got) == 1, nil
+	case isBcrypt(encoded):
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if err != nil {
+			if !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return false, ErrHashInvalid
+			}
+
+			return false, nil
+		}
+
+		return true, nil
+	default:
+		return subtle.ConstantTimeCompare([]byte(encoded), []byte(password)) == 1, nil
+	}
+}
+
+// NeedsRehash reports whether encoded should be replaced with a fresh Hash:
+// it is plaintext, bcrypt, or argon2id with parameters other than Default.
+func NeedsRehash(encoded string) bool {
+	if !strings.HasPrefix(encoded, "$argon2id$") {
+		return true
+	}
+
+	p, salt, _, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+
+	p.SaltLength = uint32(len(salt))
+
+	return p != Default
+}
+
+func isBcrypt(encoded string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(encoded, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func decodeArgon2id(encoded string) (Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(encoded, "$")
+
+	if len(parts) != 6 {
+		return Params{}, nil, nil, ErrHashInvalid
+	}
+
+	var version int
+
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Params{}, nil, nil, ErrHashInvalid
+	}
+
+	p := Params{}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return Params{}, nil, nil, ErrHashInvalid
+	}
+
+	salt, err := encoding.DecodeString(parts[4])
+	if err != nil {
+		return Params{}, nil, nil, ErrHashInvalid
+	}
+
+	key, err := encoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Params{}, nil, nil, ErrHashInvalid
+	}
+
+	p.KeyLength = uint32(len(key))
+
+	return p, salt, key, nil
+}
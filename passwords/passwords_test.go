@@ -0,0 +1,109 @@
+package passwords
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashVerify(t *testing.T) {
+	encoded, err := Hash("pass1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Errorf("unexpected hash format: %s", encoded)
+	}
+
+	if ok, err := Verify(encoded, "pass1234"); err != nil || !ok {
+		t.Errorf("want ok; got %v, %v", ok, err)
+	}
+
+	if ok, err := Verify(encoded, "pass4321"); err != nil || ok {
+		t.Errorf("want not ok; got %v, %v", ok, err)
+	}
+
+	if NeedsRehash(encoded) {
+		t.Error("fresh hash should not need rehash")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	bs, err := bcrypt.GenerateFromPassword([]byte("pass2345"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	weak, err := HashWith(Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 8, KeyLength: 16}, "pass3456")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tc := range map[string]struct {
+		encoded  string
+		password string
+		ok       bool
+		rehash   bool
+	}{
+		"bcrypt ok": {
+			encoded:  string(bs),
+			password: "pass2345",
+			ok:       true,
+			rehash:   true,
+		},
+		"bcrypt wrong password": {
+			encoded:  string(bs),
+			password: "wrong",
+			rehash:   true,
+		},
+		"old argon2id params": {
+			encoded:  weak,
+			password: "pass3456",
+			ok:       true,
+			rehash:   true,
+		},
+		"plaintext ok": {
+			encoded:  "pass4567",
+			password: "pass4567",
+			ok:       true,
+			rehash:   true,
+		},
+		"plaintext wrong password": {
+			encoded:  "pass4567",
+			password: "pass456",
+			rehash:   true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ok, err := Verify(tc.encoded, tc.password)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ok != tc.ok {
+				t.Errorf("want %v; got %v", tc.ok, ok)
+			}
+
+			if got := NeedsRehash(tc.encoded); got != tc.rehash {
+				t.Errorf("want %v; got %v", tc.rehash, got)
+			}
+		})
+	}
+}
+
+func TestVerifyInvalid(t *testing.T) {
+	for name, encoded := range map[string]string{
+		"missing key":  "$argon2id$v=19$m=19456,t=2,p=1$c2FsdA",
+		"bad version":  "$argon2id$v=16$m=19456,t=2,p=1$c2FsdA$a2V5",
+		"bad params":   "$argon2id$v=19$m=x,t=2,p=1$c2FsdA$a2V5",
+		"bad encoding": "$argon2id$v=19$m=19456,t=2,p=1$!!!$a2V5",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Verify(encoded, "anything"); err != ErrHashInvalid {
+				t.Errorf("want %v; got %v", ErrHashInvalid, err)
+			}
+		})
+	}
+}
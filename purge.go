@@ -0,0 +1,50 @@
+package atmail
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// DefaultRetention is how long deleted users are kept by default before they
+// are purged.
+const DefaultRetention = 30 * 24 * time.Hour
+
+// Purger hard deletes the users that were deleted longer than the retention
+// ago, after which they can no longer be restored.
+type Purger struct {
+	store     Store
+	retention time.Duration
+	interval  time.Duration
+}
+
+func NewPurger(store Store, retention time.Duration, interval time.Duration) *Purger {
+	return &Purger{store: store, retention: retention, interval: interval}
+}
+
+// Run purges every interval until ctx is done.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		// failures are retried on the next tick
+		if n, err := p.Purge(ctx); err != nil {
+			log.Printf("failed to purge deleted users: %v", err)
+		} else if n > 0 {
+			log.Printf("purged %d deleted users", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge purges the users past the retention once and returns how many there
+// were.
+func (p *Purger) Purge(ctx context.Context) (int64, error) {
+	return p.store.PurgeUsers(ctx, time.Now().Add(-p.retention))
+}
@@ -0,0 +1,248 @@
+package atmail
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Balance is how reads are spread over the replicas.
+type Balance int
+
+const (
+	// RoundRobin reads from each replica in turn.
+	RoundRobin Balance = iota
+	// LeastConnections reads from the replica with the fewest connections in
+	// use.
+	LeastConnections
+)
+
+// Replicas are read replicas of the database of a store, see WithReplicas.
+// Replicas are up until a query or a health check fails, and down until a
+// health check succeeds again.
+type Replicas struct {
+	replicas []*replica
+	balance  Balance
+	interval time.Duration
+	// next is the replica of the next round robin read
+	next atomic.Uint64
+}
+
+type replica struct {
+	db *sql.DB
+	// i is the position of the replica in the logs
+	i  int
+	up atomic.Bool
+}
+
+// NewReplicas returns the replicas of dbs, which are checked every interval
+// by Run.
+func NewReplicas(dbs []*sql.DB, balance Balance, interval time.Duration) *Replicas {
+	r := &Replicas{balance: balance, interval: interval}
+
+	for i, db := range dbs {
+		replica := &replica{db: db, i: i}
+		replica.up.Store(true)
+
+		r.replicas = append(r.replicas, replica)
+	}
+
+	return r
+}
+
+// Run checks the replicas every interval until ctx is done.
+func (r *Replicas) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.Check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check pings every replica once, and marks those that answer up and the
+// others down. Replicas are pinged concurrently, each within the interval, so
+// that one that hangs does not take the time of the others.
+func (r *Replicas) Check(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, replica := range r.replicas {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			replica.check(ctx, r.interval)
+		}()
+	}
+
+	wg.Wait()
+}
+
+// pick returns the replica to read from, or nil when all of them are down.
+func (r *Replicas) pick() *replica {
+	var picked *replica
+
+	switch r.balance {
+	case LeastConnections:
+		inUse := 0
+
+		for _, replica := range r.replicas {
+			if !replica.up.Load() {
+				continue
+			}
+
+			if n := replica.db.Stats().InUse; picked == nil || n < inUse {
+				picked, inUse = replica, n
+			}
+		}
+	default:
+		// skip the replicas that are down, each one at most once
+		for range r.replicas {
+			replica := r.replicas[(r.next.Add(1)-1)%uint64(len(r.replicas))]
+
+			if replica.up.Load() {
+				picked = replica
+				break
+			}
+		}
+	}
+
+	return picked
+}
+
+func (r *replica) check(ctx context.Context, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := r.db.PingContext(ctx); err != nil {
+		r.fail(err)
+		return
+	}
+
+	if !r.up.Swap(true) {
+		log.Printf("replica %d is up", r.i)
+	}
+}
+
+func (r *replica) fail(err error) {
+	if r.up.Swap(false) {
+		log.Printf("replica %d is down: %v", r.i, err)
+	}
+}
+
+// fallback runs queries on a replica, and on the primary instead when the
+// replica fails, which is marked down. Replicas are only read from.
+type fallback struct {
+	replica *replica
+	primary querier
+}
+
+func (q fallback) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return q.primary.ExecContext(ctx, query, args...)
+}
+
+func (q fallback) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	rows, err := q.replica.db.QueryContext(ctx, query, args...)
+
+	// a cancelled request is not the fault of the replica
+	if err != nil && ctx.Err() == nil {
+		q.replica.fail(err)
+		return q.primary.QueryContext(ctx, query, args...)
+	}
+
+	return rows, err
+}
+
+func (q fallback) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	row := q.replica.db.QueryRowContext(ctx, query, args...)
+
+	// Err is only the error of the query, not sql.ErrNoRows
+	if err := row.Err(); err != nil && ctx.Err() == nil {
+		q.replica.fail(err)
+		return q.primary.QueryRowContext(ctx, query, args...)
+	}
+
+	return row
+}
+
+// WithReplicas reads users from replicas instead of the primary, except
+// within transactions and after writes with a context of
+// WithReadYourWrites. Admins are always read from the primary, so that
+// changes of their credentials and roles apply at once.
+func WithReplicas(r *Replicas) StoreOption {
+	return func(s *store) {
+		s.replicas = r
+	}
+}
+
+type writesKey struct{}
+
+// WithReadYourWrites returns a context whose reads go to the primary once it
+// has been written with, e.g. for the rest of a request that updated a user,
+// as the replicas may not have the update yet.
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, writesKey{}, &atomic.Bool{})
+}
+
+func markWritten(ctx context.Context) {
+	if written, ok := ctx.Value(writesKey{}).(*atomic.Bool); ok {
+		written.Store(true)
+	}
+}
+
+func hasWritten(ctx context.Context) bool {
+	written, ok := ctx.Value(writesKey{}).(*atomic.Bool)
+	return ok && written.Load()
+}
+
+type primaryKey struct{}
+
+// WithPrimary returns a context whose reads all go to the primary, for reads
+// that a write is based on, e.g. the version of a user that is updated, as
+// the replicas may not have the writes of other requests yet.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// writer marks the context of every write through it.
+type writer struct {
+	querier
+}
+
+func (q writer) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	markWritten(ctx)
+	return q.querier.ExecContext(ctx, query, args...)
+}
+
+// read returns the querier of read-only queries, which is a replica unless
+// the store is in a transaction, ctx has been written with or is of
+// WithPrimary, or every replica is down.
+func (s store) read(ctx context.Context) querier {
+	if s.tx != nil || s.replicas == nil || hasWritten(ctx) || ctx.Value(primaryKey{}) != nil {
+		return s.conn()
+	}
+
+	replica := s.replicas.pick()
+	if replica == nil {
+		return s.conn()
+	}
+
+	return s.dialect.querier(fallback{replica, s.db})
+}
+
+// primary returns the store without its replicas, for reads that must see
+// the writes just made.
+func (s store) primary() store {
+	s.replicas = nil
+	return s
+}
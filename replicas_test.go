@@ -0,0 +1,212 @@
+package atmail_test
+
+import (
+	"atmail"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// openReplicas opens a primary and two replicas, which are separate SQLite
+// databases whose user 1 is bingo, bluey and chilli respectively, so that
+// reads tell which of them they were from.
+func openReplicas(t *testing.T) (*sql.DB, []*sql.DB) {
+	dbs := []*sql.DB{}
+
+	for _, username := range []string{"bingo", "bluey", "chilli"} {
+		db := openMigrated(t, "sqlite://"+filepath.Join(t.TempDir(), username+".db"))
+
+		if _, err := atmail.NewStore(db).CreateUser(context.Background(), atmail.User{Username: username, Email: username + "@heeler.com", Age: 6}); err != nil {
+			t.Fatal(err)
+		}
+
+		dbs = append(dbs, db)
+	}
+
+	return dbs[0], dbs[1:]
+}
+
+// readUsername returns the username of user 1 as read by s.
+func readUsername(t *testing.T, ctx context.Context, s atmail.Store) string {
+	t.Helper()
+
+	user, err := s.GetUser(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return user.Username
+}
+
+func TestReplicas(t *testing.T) {
+	primary, replicas := openReplicas(t)
+
+	s := atmail.NewStore(primary, atmail.WithReplicas(atmail.NewReplicas(replicas, atmail.RoundRobin, time.Minute)))
+
+	ctx := context.Background()
+
+	// Step 1: Reads go to each replica in turn
+	for _, want := range []string{"bluey", "chilli", "bluey"} {
+		if got := readUsername(t, ctx, s); got != want {
+			t.Errorf("want %v; got %v", want, got)
+		}
+	}
+
+	// Step 2: Transactions read from the primary
+	if err := s.WithTx(ctx, func(s atmail.Store) error {
+		if got := readUsername(t, ctx, s); got != "bingo" {
+			t.Errorf("want %v; got %v", "bingo", got)
+		}
+
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Step 3: Conflicts are reported with the version of the primary
+	stale := atmail.User{Id: 1, Username: "bingo", Email: "bingo@heeler.com", Age: 7, Version: 1}
+
+	if err := s.UpdateUser(ctx, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.UpdateUser(ctx, stale); err != (atmail.ErrVersionConflict{Id: 1, Version: 2}) {
+		t.Errorf("want %v; got %v", atmail.ErrVersionConflict{Id: 1, Version: 2}, err)
+	}
+
+	// Step 4: Reads after a write of the same context go to the primary
+	rywCtx := atmail.WithReadYourWrites(ctx)
+
+	if got := readUsername(t, rywCtx, s); got == "bingo" {
+		t.Errorf("want a replica; got %v", got)
+	}
+
+	if _, err := s.CreateUser(rywCtx, atmail.User{Username: "bandit", Email: "bandit@heeler.com", Age: 40}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readUsername(t, rywCtx, s); got != "bingo" {
+		t.Errorf("want %v; got %v", "bingo", got)
+	}
+
+	if got := readUsername(t, ctx, s); got == "bingo" {
+		t.Errorf("want a replica; got %v", got)
+	}
+
+	// Step 5: Reads that writes are based on go to the primary
+	if got := readUsername(t, atmail.WithPrimary(ctx), s); got != "bingo" {
+		t.Errorf("want %v; got %v", "bingo", got)
+	}
+
+	// Step 6: Admins are read from the primary, which only it has
+	if err := s.CreateAdmin(ctx, atmail.Admin{User: "dan", Roles: []string{"bandit"}}, "pass4567"); err != nil {
+		t.Fatal(err)
+	}
+
+	if roles, err := s.GetRoles(ctx, "dan", "pass4567"); err != nil || !reflect.DeepEqual([]string{"bandit"}, roles) {
+		t.Errorf("want %v; got %v (%v)", []string{"bandit"}, roles, err)
+	}
+}
+
+func TestReplicasLeastConnections(t *testing.T) {
+	primary, replicas := openReplicas(t)
+
+	s := atmail.NewStore(primary, atmail.WithReplicas(atmail.NewReplicas(replicas, atmail.LeastConnections, time.Minute)))
+
+	ctx := context.Background()
+
+	if got := readUsername(t, ctx, s); got != "bluey" {
+		t.Errorf("want %v; got %v", "bluey", got)
+	}
+
+	// a connection in use on the first replica sends reads to the second
+	conn, err := replicas[0].Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readUsername(t, ctx, s); got != "chilli" {
+		t.Errorf("want %v; got %v", "chilli", got)
+	}
+
+	conn.Close()
+
+	if got := readUsername(t, ctx, s); got != "bluey" {
+		t.Errorf("want %v; got %v", "bluey", got)
+	}
+}
+
+func TestReplicasFallback(t *testing.T) {
+	primary, replicas := openReplicas(t)
+
+	r := atmail.NewReplicas(replicas, atmail.RoundRobin, time.Minute)
+	s := atmail.NewStore(primary, atmail.WithReplicas(r))
+
+	ctx := context.Background()
+
+	// a failed replica is skipped, and its read goes to the primary
+	replicas[0].Close()
+
+	for _, want := range []string{"bingo", "chilli", "chilli"} {
+		if got := readUsername(t, ctx, s); got != want {
+			t.Errorf("want %v; got %v", want, got)
+		}
+	}
+
+	// until health checks find it up again, which they do not while closed
+	r.Check(ctx)
+
+	if got := readUsername(t, ctx, s); got != "chilli" {
+		t.Errorf("want %v; got %v", "chilli", got)
+	}
+
+	// and reads go to the primary when every replica is down
+	replicas[1].Close()
+	r.Check(ctx)
+
+	if got := readUsername(t, ctx, s); got != "bingo" {
+		t.Errorf("want %v; got %v", "bingo", got)
+	}
+}
+
+// hanging is a database that never answers until the context is done.
+type hanging struct{}
+
+func (hanging) Connect(ctx context.Context) (driver.Conn, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (hanging) Driver() driver.Driver {
+	return hanging{}
+}
+
+func (hanging) Open(string) (driver.Conn, error) {
+	return nil, errors.New("hanging database cannot be opened")
+}
+
+func TestReplicasCheckHanging(t *testing.T) {
+	primary, replicas := openReplicas(t)
+
+	// the first replica hangs instead of bluey
+	replicas[0] = sql.OpenDB(hanging{})
+
+	r := atmail.NewReplicas(replicas, atmail.RoundRobin, 100*time.Millisecond)
+	s := atmail.NewStore(primary, atmail.WithReplicas(r))
+
+	ctx := context.Background()
+
+	// which does not take the time of the other one, that stays up
+	r.Check(ctx)
+
+	for _, want := range []string{"chilli", "chilli"} {
+		if got := readUsername(t, ctx, s); got != want {
+			t.Errorf("want %v; got %v", want, got)
+		}
+	}
+}
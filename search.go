@@ -0,0 +1,160 @@
+package atmail
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+// UserMatch is a user found by a search along with the names of the fields
+// that matched, e.g. "username".
+type UserMatch struct {
+	User   User
+	Fields []string
+}
+
+// Searcher is implemented by stores that can search users themselves.
+type Searcher interface {
+	// SearchUsers returns at most limit users matching query, skipping the
+	// first offset, most relevant first.
+	SearchUsers(ctx context.Context, query string, limit int, offset int) ([]UserMatch, error)
+}
+
+// SearchTerms splits query into lower case words, dropping everything that is
+// not a letter or a digit.
+func SearchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// MatchedFields returns the names of the fields of user that contain any of
+// terms.
+func MatchedFields(user User, terms []string) []string {
+	fields := []string{}
+
+	for _, field := range []struct {
+		name  string
+		value string
+	}{
+		{"username", user.Username},
+		{"email", user.Email},
+	} {
+		value := strings.ToLower(field.value)
+
+		for _, term := range terms {
+			if strings.Contains(value, term) {
+				fields = append(fields, field.name)
+				break
+			}
+		}
+	}
+
+	return fields
+}
+
+// searchlessStore is a store of a database that cannot search users, such as
+// SQLite. It hides SearchUsers, so that users are searched in memory instead,
+// see server.NewStore.
+type searchlessStore struct {
+	Store
+}
+
+// WithTx keeps SearchUsers hidden within transactions.
+func (s searchlessStore) WithTx(ctx context.Context, fn func(Store) error) error {
+	return s.Store.WithTx(ctx, func(tx Store) error {
+		return fn(searchlessStore{tx})
+	})
+}
+
+// minFulltextTerm is the length of the shortest words that the FULLTEXT
+// index of MySQL has, shorter terms never match it.
+const minFulltextTerm = 4
+
+// SearchUsers searches the username and email of users, matching every term
+// as a prefix of the words in them, e.g. "john" finds
+// "jane.johnson@example.com". See the search of the dialect for how.
+func (s store) SearchUsers(ctx context.Context, query string, limit int, offset int) ([]UserMatch, error) {
+	terms := SearchTerms(query)
+
+	if len(terms) == 0 {
+		return []UserMatch{}, nil
+	}
+
+	where, order, args := s.dialect.search(terms)
+
+	rows, err := s.conn().QueryContext(ctx, "SELECT id, username, email, age, version FROM users WHERE ("+where+") AND deleted_at IS NULL ORDER BY "+order+" LIMIT ? OFFSET ?", append(args, limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	matches := []UserMatch{}
+
+	for rows.Next() {
+		user := User{}
+
+		if err := rows.Scan(&user.Id, &user.Username, &user.Email, &user.Age, &user.Version); err != nil {
+			return nil, err
+		}
+
+		matches = append(matches, UserMatch{user, MatchedFields(user, terms)})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return matches, nil
+}
+
+// searchFulltext searches the FULLTEXT index of MySQL on the username and
+// email of users. Terms too short for the index are matched as a prefix of the
+// whole username or email instead, e.g. "ja" finds "jane.johnson@example.com"
+// but "jo" does not.
+func searchFulltext(terms []string) (string, string, []any) {
+	against, where, args := []string{}, []string{}, []any{}
+
+	for _, term := range terms {
+		if len([]rune(term)) < minFulltextTerm {
+			where = append(where, "username LIKE ? ESCAPE '!'", "email LIKE ? ESCAPE '!'")
+			args = append(args, escapeLike(term)+"%", escapeLike(term)+"%")
+		} else {
+			against = append(against, term+"*")
+		}
+	}
+
+	order := "id"
+
+	if len(against) > 0 {
+		where = append(where, "MATCH (username, email) AGAINST (? IN BOOLEAN MODE)")
+		args = append(args, strings.Join(against, " "))
+
+		// users only found by short terms are the least relevant
+		order = "MATCH (username, email) AGAINST (? IN BOOLEAN MODE) DESC, id"
+		args = append(args, strings.Join(against, " "))
+	}
+
+	return strings.Join(where, " OR "), order, args
+}
+
+// searchWords searches the username and email of users with the regular
+// expressions of PostgreSQL, \m matching the start of a word. Users are ranked
+// by how many terms match their fields. Terms are only letters and digits, so
+// they need no quoting.
+func searchWords(terms []string) (string, string, []any) {
+	where, score, args := []string{}, []string{}, []any{}
+
+	for _, term := range terms {
+		where = append(where, "username ~* ?", "email ~* ?")
+		args = append(args, `\m`+term, `\m`+term)
+	}
+
+	for _, term := range terms {
+		score = append(score, "CASE WHEN username ~* ? THEN 1 ELSE 0 END", "CASE WHEN email ~* ? THEN 1 ELSE 0 END")
+		args = append(args, `\m`+term, `\m`+term)
+	}
+
+	return strings.Join(where, " OR "), "(" + strings.Join(score, " + ") + ") DESC, id", args
+}
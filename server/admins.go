@@ -0,0 +1,285 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"atmail"
+	"atmail/server/roles"
+)
+
+type adminOutload struct {
+	User     string   `json:"user"`
+	Roles    []string `json:"roles"`
+	Disabled bool     `json:"disabled"`
+}
+
+func toAdminOutload(admin atmail.Admin) adminOutload {
+	o := adminOutload{
+		User:     admin.User,
+		Roles:    admin.Roles,
+		Disabled: admin.Disabled,
+	}
+
+	if o.Roles == nil {
+		o.Roles = []string{}
+	}
+
+	return o
+}
+
+type getAdminOutload struct {
+	adminOutload
+}
+
+func (o getAdminOutload) code() int {
+	return http.StatusOK
+}
+
+type createAdminInload struct {
+	User     string   `json:"user"`
+	Password string   `json:"password"`
+	Roles    []string `json:"roles"`
+}
+
+type createAdminOutload struct {
+	adminOutload
+}
+
+func (o createAdminOutload) code() int {
+	return http.StatusCreated
+}
+
+func createAdmin(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	i := createAdminInload{}
+
+	if err := json.NewDecoder(r.Body).Decode(&i); err != nil {
+		return badRequest("invalid json!"), nil
+	}
+
+	admin := atmail.Admin{
+		User:  i.User,
+		Roles: i.Roles,
+	}
+
+	if s, ok := atmail.ValidateAdmin(admin); !ok {
+		return badRequest(s), nil
+	}
+
+	if s, ok := atmail.ValidatePassword(i.Password); !ok {
+		return badRequest(s), nil
+	}
+
+	if err := s.CreateAdmin(r.Context(), admin, i.Password); err != nil {
+		if errors.Is(err, atmail.ErrAdminExists) {
+			return conflict("admin already exists!"), nil
+		}
+
+		if errors.Is(err, atmail.ErrRoleNone) {
+			return badRequest("role does not exist!"), nil
+		}
+
+		return nil, err
+	}
+
+	return createAdminOutload{toAdminOutload(admin)}, nil
+}
+
+type listAdminsOutload struct {
+	Admins []adminOutload `json:"admins"`
+}
+
+func (o listAdminsOutload) code() int {
+	return http.StatusOK
+}
+
+func listAdmins(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	admins, err := s.ListAdmins(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	o := listAdminsOutload{Admins: []adminOutload{}}
+
+	for _, admin := range admins {
+		o.Admins = append(o.Admins, toAdminOutload(admin))
+	}
+
+	return o, nil
+}
+
+func getAdmin(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	admin, err := s.GetAdmin(r.Context(), r.PathValue("user"))
+	if err != nil {
+		if !errors.Is(err, atmail.ErrAdminNone) {
+			return nil, err
+		}
+
+		return badRequest("admin does not exist!"), nil
+	}
+
+	return getAdminOutload{toAdminOutload(admin)}, nil
+}
+
+type setAdminRolesInload struct {
+	Roles []string `json:"roles"`
+}
+
+func setAdminRoles(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	p, ok := PrincipalFromContext(r.Context())
+	if !ok {
+		return nil, errors.New("principal missing from context")
+	}
+
+	user := r.PathValue("user")
+
+	i := setAdminRolesInload{}
+
+	if err := json.NewDecoder(r.Body).Decode(&i); err != nil {
+		return badRequest("invalid json!"), nil
+	}
+
+	// otherwise nobody may be left to give the privilege back
+	if isSelf(p, user) {
+		ok, err := grants(r.Context(), s, i.Roles, roles.AdminsManage)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			return badRequest("cannot remove own admins:manage permission!"), nil
+		}
+	}
+
+	if err := s.SetAdminRoles(r.Context(), user, i.Roles); err != nil {
+		if errors.Is(err, atmail.ErrAdminNone) {
+			return badRequest("admin does not exist!"), nil
+		}
+
+		if errors.Is(err, atmail.ErrRoleNone) {
+			return badRequest("role does not exist!"), nil
+		}
+
+		return nil, err
+	}
+
+	admin, err := s.GetAdmin(r.Context(), user)
+	if err != nil {
+		return nil, err
+	}
+
+	return getAdminOutload{toAdminOutload(admin)}, nil
+}
+
+// grants reports whether any of names grants permission.
+func grants(ctx context.Context, s atmail.Store, names []string, permission roles.Permission) (bool, error) {
+	permissions, err := s.GetPermissions(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, name := range names {
+		if slices.Contains(permissions[name], string(permission)) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+type setAdminPasswordInload struct {
+	Password string `json:"password"`
+}
+
+func setAdminPassword(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	user := r.PathValue("user")
+
+	i := setAdminPasswordInload{}
+
+	if err := json.NewDecoder(r.Body).Decode(&i); err != nil {
+		return badRequest("invalid json!"), nil
+	}
+
+	if s, ok := atmail.ValidatePassword(i.Password); !ok {
+		return badRequest(s), nil
+	}
+
+	if err := s.SetAdminPassword(r.Context(), user, i.Password); err != nil {
+		if !errors.Is(err, atmail.ErrAdminNone) {
+			return nil, err
+		}
+
+		return badRequest("admin does not exist!"), nil
+	}
+
+	return messageOutload{fmt.Sprintf("successfully reset password of admin %s!", user)}, nil
+}
+
+func disableAdmin(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	return setAdminDisabled(s, r, true)
+}
+
+func enableAdmin(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	return setAdminDisabled(s, r, false)
+}
+
+func setAdminDisabled(s atmail.Store, r *http.Request, disabled bool) (outload, error) {
+	p, ok := PrincipalFromContext(r.Context())
+	if !ok {
+		return nil, errors.New("principal missing from context")
+	}
+
+	user := r.PathValue("user")
+
+	if disabled && isSelf(p, user) {
+		return badRequest("cannot disable own admin!"), nil
+	}
+
+	if err := s.SetAdminDisabled(r.Context(), user, disabled); err != nil {
+		if !errors.Is(err, atmail.ErrAdminNone) {
+			return nil, err
+		}
+
+		return badRequest("admin does not exist!"), nil
+	}
+
+	if disabled {
+		return messageOutload{fmt.Sprintf("successfully disabled admin %s!", user)}, nil
+	}
+
+	return messageOutload{fmt.Sprintf("successfully enabled admin %s!", user)}, nil
+}
+
+// isSelf returns whether user is the admin p, whose name may be of another
+// case, as admins are unique regardless of case.
+func isSelf(p Principal, user string) bool {
+	return p.IsAdmin() && strings.EqualFold(p.Id, user)
+}
+
+func deleteAdmin(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	p, ok := PrincipalFromContext(r.Context())
+	if !ok {
+		return nil, errors.New("principal missing from context")
+	}
+
+	user := r.PathValue("user")
+
+	if isSelf(p, user) {
+		return badRequest("cannot delete own admin!"), nil
+	}
+
+	if err := s.DeleteAdmin(r.Context(), user); err != nil {
+		if !errors.Is(err, atmail.ErrAdminNone) {
+			return nil, err
+		}
+
+		return badRequest("admin does not exist!"), nil
+	}
+
+	return messageOutload{fmt.Sprintf("successfully deleted admin %s!", user)}, nil
+}
@@ -0,0 +1,277 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"atmail/memstore"
+)
+
+func TestCreateAdmin(t *testing.T) {
+	for name, tc := range map[string]struct {
+		inload string
+		want   outload
+	}{
+		"ok": {
+			inload: `{ "user": "bar", "password": "password", "roles": ["bingo"] }`,
+			want:   createAdminOutload{adminOutload{User: "bar", Roles: []string{"bingo"}}},
+		},
+		"no roles": {
+			inload: `{ "user": "bar", "password": "password" }`,
+			want:   createAdminOutload{adminOutload{User: "bar", Roles: []string{}}},
+		},
+		"invalid json": {
+			inload: `this invalid json`,
+			want:   badRequest("invalid json!"),
+		},
+		"blank user": {
+			inload: `{ "password": "password" }`,
+			want:   badRequest("user cannot be blank!"),
+		},
+		"user contains colon": {
+			inload: `{ "user": "b:ar", "password": "password" }`,
+			want:   badRequest("user cannot contain a colon!"),
+		},
+		"short password": {
+			inload: `{ "user": "bar", "password": "pass" }`,
+			want:   badRequest("password must be at least 8 characters!"),
+		},
+		"admin already exists": {
+			inload: `{ "user": "foo", "password": "password" }`,
+			want:   conflict("admin already exists!"),
+		},
+		"admin of another case already exists": {
+			inload: `{ "user": "FOO", "password": "password" }`,
+			want:   conflict("admin already exists!"),
+		},
+		"role does not exist": {
+			inload: `{ "user": "bar", "password": "password", "roles": ["muffin"] }`,
+			want:   badRequest("role does not exist!"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/admins", bytes.NewBufferString(tc.inload))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			store := newTestStore(t, memstore.Seed{
+				Admins: []memstore.SeedAdmin{{User: "foo", Password: "password", Roles: []string{"bandit"}}},
+			})
+
+			rr := httptest.NewRecorder()
+
+			got, err := createAdmin(store, rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestGetAdmin(t *testing.T) {
+	store := newTestStore(t, memstore.Seed{
+		Admins: []memstore.SeedAdmin{{User: "foo", Password: "password", Roles: []string{"bandit"}, Disabled: true}},
+	})
+
+	for name, tc := range map[string]struct {
+		user string
+		want outload
+	}{
+		"ok": {
+			user: "foo",
+			want: getAdminOutload{adminOutload{User: "foo", Roles: []string{"bandit"}, Disabled: true}},
+		},
+		"admin does not exist": {
+			user: "bar",
+			want: badRequest("admin does not exist!"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/admins/"+tc.user, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.SetPathValue("user", tc.user)
+
+			rr := httptest.NewRecorder()
+
+			got, err := getAdmin(store, rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSetAdminRoles(t *testing.T) {
+	for name, tc := range map[string]struct {
+		principal string
+		inload    string
+		want      outload
+	}{
+		"ok": {
+			principal: "bar",
+			inload:    `{ "roles": ["bingo"] }`,
+			want:      getAdminOutload{adminOutload{User: "foo", Roles: []string{"bingo"}}},
+		},
+		"own roles keep admins:manage": {
+			principal: "foo",
+			inload:    `{ "roles": ["bingo", "bandit"] }`,
+			want:      getAdminOutload{adminOutload{User: "foo", Roles: []string{"bandit", "bingo"}}},
+		},
+		"own roles drop admins:manage": {
+			principal: "foo",
+			inload:    `{ "roles": ["chilli"] }`,
+			want:      badRequest("cannot remove own admins:manage permission!"),
+		},
+		"own roles of another case drop admins:manage": {
+			principal: "Foo",
+			inload:    `{ "roles": ["chilli"] }`,
+			want:      badRequest("cannot remove own admins:manage permission!"),
+		},
+		"role does not exist": {
+			principal: "bar",
+			inload:    `{ "roles": ["muffin"] }`,
+			want:      badRequest("role does not exist!"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			store := newTestStore(t, memstore.Seed{
+				Admins: []memstore.SeedAdmin{{User: "foo", Password: "password", Roles: []string{"bandit"}}},
+			})
+
+			req, err := http.NewRequest("PUT", "/admins/foo/roles", bytes.NewBufferString(tc.inload))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.SetPathValue("user", "foo")
+
+			req = withPrincipal(req, Principal{tc.principal, []string{"bandit"}, MethodBasic})
+
+			rr := httptest.NewRecorder()
+
+			got, err := setAdminRoles(store, rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDisableAdmin(t *testing.T) {
+	store := newTestStore(t, memstore.Seed{
+		Admins: []memstore.SeedAdmin{{User: "foo", Password: "password", Roles: []string{"bingo"}}},
+	})
+
+	for name, tc := range map[string]struct {
+		user string
+		want outload
+	}{
+		"ok": {
+			user: "foo",
+			want: messageOutload{"successfully disabled admin foo!"},
+		},
+		"own admin": {
+			user: "bar",
+			want: badRequest("cannot disable own admin!"),
+		},
+		"own admin of another case": {
+			user: "BAR",
+			want: badRequest("cannot disable own admin!"),
+		},
+		"admin does not exist": {
+			user: "baz",
+			want: badRequest("admin does not exist!"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/admins/"+tc.user+"/disable", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.SetPathValue("user", tc.user)
+
+			req = withPrincipal(req, Principal{"bar", []string{"bandit"}, MethodBasic})
+
+			rr := httptest.NewRecorder()
+
+			got, err := disableAdmin(store, rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDeleteAdmin(t *testing.T) {
+	store := newTestStore(t, memstore.Seed{
+		Admins: []memstore.SeedAdmin{{User: "foo", Password: "password", Roles: []string{"bingo"}}},
+	})
+
+	for name, tc := range map[string]struct {
+		user string
+		want outload
+	}{
+		"ok": {
+			user: "foo",
+			want: messageOutload{"successfully deleted admin foo!"},
+		},
+		"own admin": {
+			user: "bar",
+			want: badRequest("cannot delete own admin!"),
+		},
+		"own admin of another case": {
+			user: "BAR",
+			want: badRequest("cannot delete own admin!"),
+		},
+		"admin does not exist": {
+			user: "baz",
+			want: badRequest("admin does not exist!"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("DELETE", "/admins/"+tc.user, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.SetPathValue("user", tc.user)
+
+			req = withPrincipal(req, Principal{"bar", []string{"bandit"}, MethodBasic})
+
+			rr := httptest.NewRecorder()
+
+			got, err := deleteAdmin(store, rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
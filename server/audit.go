@@ -0,0 +1,128 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"atmail"
+)
+
+type changeOutload struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+type auditEventOutload struct {
+	Id        int64           `json:"id"`
+	Time      time.Time       `json:"time"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"`
+	Route     string          `json:"route"`
+	Target    int64           `json:"target,omitempty"`
+	Changes   []changeOutload `json:"changes"`
+	ClientIP  string          `json:"client_ip"`
+	RequestId string          `json:"request_id"`
+	PrevHash  string          `json:"prev_hash"`
+	Hash      string          `json:"hash"`
+}
+
+type listAuditEventsOutload struct {
+	Items      []auditEventOutload `json:"items"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+func (o listAuditEventsOutload) code() int {
+	return http.StatusOK
+}
+
+// listAuditEvents lists audit events newest first, filtered by actor, action,
+// target user and time range.
+func listAuditEvents(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	q := r.URL.Query()
+
+	o := atmail.AuditOptions{
+		Limit:  listUsersDefaultLimit,
+		Actor:  q.Get("actor"),
+		Action: q.Get("action"),
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > listUsersMaxLimit {
+			return badRequest("limit is invalid!"), nil
+		}
+
+		o.Limit = limit
+	}
+
+	if v := q.Get("target"); v != "" {
+		target, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || target < 1 {
+			return badRequest("target is invalid!"), nil
+		}
+
+		o.Target = target
+	}
+
+	for name, t := range map[string]*time.Time{"since": &o.Since, "until": &o.Until} {
+		if v := q.Get(name); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return badRequest(name + " is invalid!"), nil
+			}
+
+			*t = parsed
+		}
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		c, err := decodeCursor(v)
+		if err != nil || c.Id < 1 {
+			return badRequest("cursor is invalid!"), nil
+		}
+
+		o.Before = c.Id
+	}
+
+	// fetch one more event to know whether there is a next page
+	o.Limit++
+
+	events, err := s.ListAuditEvents(r.Context(), o)
+	if err != nil {
+		return nil, err
+	}
+
+	out := listAuditEventsOutload{Items: []auditEventOutload{}}
+
+	if len(events) == o.Limit {
+		events = events[:len(events)-1]
+
+		out.NextCursor = encodeCursor(cursor{Id: events[len(events)-1].Id})
+	}
+
+	for _, event := range events {
+		changes := []changeOutload{}
+
+		for _, change := range event.Changes {
+			changes = append(changes, changeOutload{change.Field, change.Before, change.After})
+		}
+
+		out.Items = append(out.Items, auditEventOutload{
+			Id:        event.Id,
+			Time:      event.Time,
+			Actor:     event.Actor,
+			Action:    event.Action,
+			Route:     event.Route,
+			Target:    event.Target,
+			Changes:   changes,
+			ClientIP:  event.ClientIP,
+			RequestId: event.RequestId,
+			PrevHash:  event.PrevHash,
+			Hash:      event.Hash,
+		})
+	}
+
+	return out, nil
+}
@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"context"
+
+	"atmail"
+	"atmail/server/change"
+)
+
+// Request is who made a request and from where, recorded with the changes it
+// makes.
+type Request struct {
+	Actor     string
+	Route     string
+	ClientIP  string
+	RequestId string
+}
+
+type requestKey struct{}
+
+func WithRequest(ctx context.Context, r Request) context.Context {
+	return context.WithValue(ctx, requestKey{}, r)
+}
+
+// RequestFromContext returns the request set by the handler. Changes made
+// outside of a request are recorded without one.
+func RequestFromContext(ctx context.Context) (Request, bool) {
+	r, ok := ctx.Value(requestKey{}).(Request)
+	return r, ok
+}
+
+// Event returns an audit event of the request in ctx.
+func Event(ctx context.Context, action string, target int64, changes []atmail.Change) atmail.AuditEvent {
+	r, _ := RequestFromContext(ctx)
+
+	return atmail.AuditEvent{
+		Actor:     r.Actor,
+		Action:    action,
+		Route:     r.Route,
+		Target:    target,
+		Changes:   changes,
+		ClientIP:  r.ClientIP,
+		RequestId: r.RequestId,
+	}
+}
+
+// Record records an audit event of the request in ctx for every change, in
+// the transaction that writes them so that there is no change without its
+// event.
+func Record(ctx context.Context, tx atmail.Store, changes []change.Change) error {
+	for _, c := range changes {
+		if _, err := tx.CreateAuditEvent(ctx, Event(ctx, c.Action, c.User.Id, c.Changes)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package audit
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"atmail"
+	"atmail/memstore"
+	"atmail/server/change"
+)
+
+func TestRecord(t *testing.T) {
+	store := memstore.New()
+
+	r := Request{Actor: "dan", Route: "PUT /users/{id}", ClientIP: "127.0.0.1", RequestId: "abc"}
+
+	ctx := WithRequest(context.Background(), r)
+
+	bingo := atmail.User{Id: 1, Username: "bingo", Email: "bingo@heeler.com", Age: 7}
+
+	if err := Record(ctx, store, []change.Change{
+		{Action: atmail.ActionUserUpdate, User: bingo, Changes: []atmail.Change{{Field: "age", Before: uint(6), After: uint(7)}}},
+		{Action: atmail.ActionUserDelete, User: bingo, Changes: atmail.DiffUsers(&bingo, nil)},
+		// only the id is known of users purged after they were deleted
+		{Action: atmail.ActionUserPurge, User: atmail.User{Id: 2}, Changes: []atmail.Change{}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := store.ListAuditEvents(ctx, atmail.AuditOptions{Asc: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// changes are listed as json types
+	want := []atmail.AuditEvent{
+		{
+			Actor: "dan", Action: atmail.ActionUserUpdate, Route: r.Route, Target: 1, ClientIP: r.ClientIP, RequestId: r.RequestId,
+			Changes: []atmail.Change{{Field: "age", Before: float64(6), After: float64(7)}},
+		},
+		{
+			Actor: "dan", Action: atmail.ActionUserDelete, Route: r.Route, Target: 1, ClientIP: r.ClientIP, RequestId: r.RequestId,
+			Changes: []atmail.Change{{Field: "username", Before: "bingo", After: nil}, {Field: "email", Before: "bingo@heeler.com", After: nil}, {Field: "age", Before: float64(7), After: nil}},
+		},
+		{
+			Actor: "dan", Action: atmail.ActionUserPurge, Route: r.Route, Target: 2, ClientIP: r.ClientIP, RequestId: r.RequestId,
+			Changes: []atmail.Change{},
+		},
+	}
+
+	got := []atmail.AuditEvent{}
+
+	for _, e := range events {
+		e.Id, e.Time, e.PrevHash, e.Hash = 0, time.Time{}, "", ""
+		got = append(got, e)
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v; got %v", want, got)
+	}
+}
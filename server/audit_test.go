@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"atmail"
+	"atmail/memstore"
+)
+
+// testAuditEvents are newest first, an hour apart.
+var testAuditEvents = []atmail.AuditEvent{
+	{Id: 5, Time: time.Date(2024, 1, 1, 4, 0, 0, 0, time.UTC), Actor: "dan", Action: atmail.ActionUserDelete, Target: 2},
+	{Id: 4, Time: time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC), Actor: "craig", Action: atmail.ActionDenied, Target: 2},
+	{Id: 3, Time: time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC), Actor: "craig", Action: atmail.ActionUserUpdate, Target: 1},
+	{Id: 2, Time: time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC), Actor: "dan", Action: atmail.ActionUserCreate, Target: 2},
+	{Id: 1, Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Actor: "dan", Action: atmail.ActionUserCreate, Target: 1},
+}
+
+// newAuditStore returns a store that recorded testAuditEvents.
+func newAuditStore(t *testing.T) *memstore.Store {
+	t.Helper()
+
+	store := memstore.New()
+
+	for _, event := range slices.Backward(testAuditEvents) {
+		if _, err := store.CreateAuditEvent(context.Background(), event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return store
+}
+
+func TestListAuditEventsOk(t *testing.T) {
+	store := newAuditStore(t)
+
+	for name, tc := range map[string]struct {
+		query string
+		want  []int64
+	}{
+		"defaults": {
+			query: "",
+			want:  []int64{5, 4, 3, 2, 1},
+		},
+		"actor": {
+			query: "actor=craig",
+			want:  []int64{4, 3},
+		},
+		"action": {
+			query: "action=user.create",
+			want:  []int64{2, 1},
+		},
+		"target": {
+			query: "target=2",
+			want:  []int64{5, 4, 2},
+		},
+		"time range": {
+			query: "since=2024-01-01T01:00:00Z&until=2024-01-01T03:00:00Z",
+			want:  []int64{4, 3, 2},
+		},
+		"combined": {
+			query: "actor=dan&target=2",
+			want:  []int64{5, 2},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got := []int64{}
+
+			// page through two events at a time
+			next := ""
+
+			for range len(testAuditEvents) {
+				req, err := http.NewRequest("GET", "/audit?limit=2&"+tc.query+"&cursor="+next, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				rr := httptest.NewRecorder()
+
+				res, err := listAuditEvents(store, rr, req)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				o, ok := res.(listAuditEventsOutload)
+				if !ok {
+					t.Fatalf("want listAuditEventsOutload; got %v", res)
+				}
+
+				for _, item := range o.Items {
+					got = append(got, item.Id)
+				}
+
+				if o.NextCursor == "" {
+					break
+				}
+
+				next = o.NextCursor
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestListAuditEventsNotOk(t *testing.T) {
+	store := newAuditStore(t)
+
+	for name, tc := range map[string]struct {
+		query string
+		want  outload
+	}{
+		"invalid limit": {
+			query: "limit=1000",
+			want:  badRequest("limit is invalid!"),
+		},
+		"invalid target": {
+			query: "target=bingo",
+			want:  badRequest("target is invalid!"),
+		},
+		"invalid since": {
+			query: "since=yesterday",
+			want:  badRequest("since is invalid!"),
+		},
+		"invalid until": {
+			query: "until=2024-01-01",
+			want:  badRequest("until is invalid!"),
+		},
+		"invalid cursor": {
+			query: "cursor=abc",
+			want:  badRequest("cursor is invalid!"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/audit?"+tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			got, err := listAuditEvents(store, rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"atmail"
+)
+
+// ErrNoCredentials is returned by an Authenticator when the request carries no
+// credentials it understands, so that the next one in a Chain is tried.
+var ErrNoCredentials = errors.New("error no credentials")
+
+// ErrInvalidCredentials is returned by an Authenticator when the request
+// carries credentials it understands but they are not valid.
+var ErrInvalidCredentials = errors.New("error invalid credentials")
+
+// ErrMalformedCredentials is returned by an Authenticator when the request
+// carries credentials it understands but cannot parse.
+var ErrMalformedCredentials = errors.New("error malformed credentials")
+
+const (
+	MethodBasic      = "basic"
+	MethodToken      = "token"
+	MethodJWT        = "jwt"
+	MethodClientCert = "client-cert"
+	MethodProxy      = "proxy"
+)
+
+// Principal is who is acting on a request.
+type Principal struct {
+	// Id identifies the principal, e.g. the admin's user or the JWT subject.
+	Id string
+	// Roles are the names of the roles of the principal.
+	Roles []string
+	// Method is how the principal was authenticated, e.g. MethodBasic.
+	Method string
+}
+
+// IsAdmin reports whether the principal is backed by a row in admins.
+func (p Principal) IsAdmin() bool {
+	return p.Method == MethodBasic || p.Method == MethodToken
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal authenticated by the handler.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+type Authenticator interface {
+	Authenticate(*http.Request) (Principal, error)
+}
+
+// Challenger is implemented by authenticators of an HTTP authentication
+// scheme, e.g. "Basic", so that clients can be challenged to use it.
+type Challenger interface {
+	Scheme() string
+}
+
+// Chain tries each Authenticator in order until one finds credentials.
+type Chain []Authenticator
+
+// Schemes returns the schemes of the authenticators in the chain.
+func (c Chain) Schemes() []string {
+	schemes := []string{}
+
+	for _, a := range c {
+		challenger, ok := a.(Challenger)
+		if !ok || slices.Contains(schemes, challenger.Scheme()) {
+			continue
+		}
+
+		schemes = append(schemes, challenger.Scheme())
+	}
+
+	return schemes
+}
+
+func (c Chain) Authenticate(r *http.Request) (Principal, error) {
+	for _, a := range c {
+		p, err := a.Authenticate(r)
+		if err != nil {
+			if errors.Is(err, ErrNoCredentials) {
+				continue
+			}
+
+			return Principal{}, err
+		}
+
+		return p, nil
+	}
+
+	return Principal{}, ErrNoCredentials
+}
+
+// BasicAuthenticator authenticates "Authorization: Basic" against the admins
+// of a store.
+type BasicAuthenticator struct {
+	store atmail.Store
+}
+
+func NewBasicAuthenticator(store atmail.Store) BasicAuthenticator {
+	return BasicAuthenticator{store}
+}
+
+func (a BasicAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Basic ")
+	if !ok {
+		return Principal{}, ErrNoCredentials
+	}
+
+	bs, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: invalid base64", ErrMalformedCredentials)
+	}
+
+	user, password, ok := strings.Cut(string(bs), ":")
+	if !ok {
+		return Principal{}, fmt.Errorf("%w: missing colon", ErrMalformedCredentials)
+	}
+
+	roles, err := a.store.GetRoles(r.Context(), user, password)
+	if err != nil {
+		if !errors.Is(err, atmail.ErrAdminNone) {
+			return Principal{}, err
+		}
+
+		return Principal{}, ErrInvalidCredentials
+	}
+
+	return Principal{user, roles, MethodBasic}, nil
+}
+
+func (a BasicAuthenticator) Scheme() string {
+	return "Basic"
+}
+
+// TokenAuthenticator authenticates "Authorization: Bearer" against the API
+// tokens of a store.
+type TokenAuthenticator struct {
+	store atmail.Store
+}
+
+func NewTokenAuthenticator(store atmail.Store) TokenAuthenticator {
+	return TokenAuthenticator{store}
+}
+
+func (a TokenAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return Principal{}, ErrNoCredentials
+	}
+
+	t, err := a.store.GetToken(r.Context(), hashToken(token))
+	if err != nil {
+		if !errors.Is(err, atmail.ErrTokenNone) {
+			return Principal{}, err
+		}
+
+		return Principal{}, ErrInvalidCredentials
+	}
+
+	if t.Expired(time.Now()) {
+		return Principal{}, fmt.Errorf("%w: token expired", ErrInvalidCredentials)
+	}
+
+	return Principal{t.Admin, t.Roles, MethodToken}, nil
+}
+
+func (a TokenAuthenticator) Scheme() string {
+	return "Bearer"
+}
+
+// ClientCertAuthenticator authenticates requests by their verified TLS client
+// certificate. The principal is the certificate's common name and its roles
+// are its organizational units. The server must be configured to verify
+// client certificates, e.g. with tls.VerifyClientCertIfGiven.
+type ClientCertAuthenticator struct {
+	// Roles maps organizational units to role names. By default organizational
+	// units are role names.
+	Roles map[string]string
+}
+
+func (a ClientCertAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return Principal{}, ErrNoCredentials
+	}
+
+	cert := r.TLS.VerifiedChains[0][0]
+
+	roles := mapRoles(a.Roles, cert.Subject.OrganizationalUnit)
+
+	if cert.Subject.CommonName == "" || len(roles) == 0 {
+		return Principal{}, fmt.Errorf("%w: certificate grants no roles", ErrInvalidCredentials)
+	}
+
+	return Principal{cert.Subject.CommonName, roles, MethodClientCert}, nil
+}
+
+// TrustedProxyAuthenticator trusts an authenticating reverse proxy to pass the
+// principal in headers. Headers are ignored unless the request comes directly
+// from one of Proxies.
+type TrustedProxyAuthenticator struct {
+	Proxies []netip.Prefix
+	// UserHeader defaults to "X-Forwarded-User".
+	UserHeader string
+	// RolesHeader holds comma separated role names and defaults to
+	// "X-Forwarded-Roles".
+	RolesHeader string
+	// Roles maps the names in RolesHeader to role names. By default they are
+	// role names.
+	Roles map[string]string
+}
+
+func (a TrustedProxyAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	if !a.trusted(r.RemoteAddr) {
+		return Principal{}, ErrNoCredentials
+	}
+
+	userHeader, rolesHeader := a.UserHeader, a.RolesHeader
+
+	if userHeader == "" {
+		userHeader = "X-Forwarded-User"
+	}
+
+	if rolesHeader == "" {
+		rolesHeader = "X-Forwarded-Roles"
+	}
+
+	user := r.Header.Get(userHeader)
+
+	if user == "" {
+		return Principal{}, ErrNoCredentials
+	}
+
+	names := []string{}
+
+	for _, name := range strings.Split(r.Header.Get(rolesHeader), ",") {
+		names = append(names, strings.TrimSpace(name))
+	}
+
+	roles := mapRoles(a.Roles, names)
+
+	if len(roles) == 0 {
+		return Principal{}, fmt.Errorf("%w: proxy granted no roles", ErrInvalidCredentials)
+	}
+
+	return Principal{user, roles, MethodProxy}, nil
+}
+
+func (a TrustedProxyAuthenticator) trusted(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+
+	for _, prefix := range a.Proxies {
+		if prefix.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mapRoles maps names to role names with m, or returns them as is when m is
+// nil. Names that are empty or missing from m are dropped.
+func mapRoles(m map[string]string, names []string) []string {
+	roles := []string{}
+
+	for _, name := range names {
+		if m != nil {
+			name = m[name]
+		}
+
+		if name != "" && !slices.Contains(roles, name) {
+			roles = append(roles, name)
+		}
+	}
+
+	return roles
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+type fakeAuthenticator struct {
+	p   Principal
+	err error
+}
+
+func (a fakeAuthenticator) Authenticate(*http.Request) (Principal, error) {
+	return a.p, a.err
+}
+
+func TestChain(t *testing.T) {
+	foo := Principal{"foo", []string{"bingo"}, MethodBasic}
+	bar := Principal{"bar", []string{"bluey"}, MethodToken}
+
+	for name, tc := range map[string]struct {
+		chain Chain
+		want  Principal
+		err   error
+	}{
+		"first with credentials wins": {
+			chain: Chain{fakeAuthenticator{err: ErrNoCredentials}, fakeAuthenticator{p: foo}, fakeAuthenticator{p: bar}},
+			want:  foo,
+		},
+		"invalid credentials stop the chain": {
+			chain: Chain{fakeAuthenticator{err: ErrInvalidCredentials}, fakeAuthenticator{p: bar}},
+			err:   ErrInvalidCredentials,
+		},
+		"no credentials": {
+			chain: Chain{fakeAuthenticator{err: ErrNoCredentials}},
+			err:   ErrNoCredentials,
+		},
+		"empty": {
+			chain: Chain{},
+			err:   ErrNoCredentials,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := tc.chain.Authenticate(httptest.NewRequest("GET", "/", nil))
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("want %v; got %v", tc.err, err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestClientCertAuthenticator(t *testing.T) {
+	withCert := func(subject pkix.Name) *http.Request {
+		r := httptest.NewRequest("GET", "/", nil)
+
+		r.TLS = &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{Subject: subject}}},
+		}
+
+		return r
+	}
+
+	for name, tc := range map[string]struct {
+		r    *http.Request
+		want Principal
+		err  error
+	}{
+		"no tls": {
+			r:   httptest.NewRequest("GET", "/", nil),
+			err: ErrNoCredentials,
+		},
+		"no client certificate": {
+			r: func() *http.Request {
+				r := httptest.NewRequest("GET", "/", nil)
+				r.TLS = &tls.ConnectionState{}
+				return r
+			}(),
+			err: ErrNoCredentials,
+		},
+		"roles from organizational units": {
+			r:    withCert(pkix.Name{CommonName: "billing", OrganizationalUnit: []string{"bluey", "chilli"}}),
+			want: Principal{"billing", []string{"bluey", "chilli"}, MethodClientCert},
+		},
+		"no roles": {
+			r:   withCert(pkix.Name{CommonName: "billing"}),
+			err: ErrInvalidCredentials,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := ClientCertAuthenticator{}.Authenticate(tc.r)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("want %v; got %v", tc.err, err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestTrustedProxyAuthenticator(t *testing.T) {
+	a := TrustedProxyAuthenticator{
+		Proxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+	}
+
+	request := func(remoteAddr string, user string, roles string) *http.Request {
+		r := httptest.NewRequest("GET", "/", nil)
+
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("X-Forwarded-User", user)
+		r.Header.Set("X-Forwarded-Roles", roles)
+
+		return r
+	}
+
+	for name, tc := range map[string]struct {
+		r    *http.Request
+		want Principal
+		err  error
+	}{
+		"trusted proxy": {
+			r:    request("10.1.2.3:4567", "jane", "bingo, bandit"),
+			want: Principal{"jane", []string{"bingo", "bandit"}, MethodProxy},
+		},
+		"untrusted remote address": {
+			r:   request("192.168.1.1:4567", "jane", "bandit"),
+			err: ErrNoCredentials,
+		},
+		"no user": {
+			r:   request("10.1.2.3:4567", "", "bandit"),
+			err: ErrNoCredentials,
+		},
+		"no roles": {
+			r:   request("10.1.2.3:4567", "jane", " , "),
+			err: ErrInvalidCredentials,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := a.Authenticate(tc.r)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("want %v; got %v", tc.err, err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,429 @@
+package server
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"atmail"
+)
+
+const (
+	importBatchSize = 500
+	exportPageSize  = 500
+	// maxNDJSONLine is the longest line of an NDJSON import
+	maxNDJSONLine = 1 << 20
+)
+
+const (
+	formatNDJSON = "ndjson"
+	formatCSV    = "csv"
+)
+
+var contentTypes = map[string]string{
+	formatNDJSON: "application/x-ndjson",
+	formatCSV:    "text/csv",
+}
+
+const (
+	rowCreated = "created"
+	rowSkipped = "skipped"
+	rowFailed  = "failed"
+)
+
+type importRow struct {
+	// Row is the line of the row, starting at 1, counting the CSV header.
+	Row    int    `json:"row"`
+	Status string `json:"status"`
+	Id     int64  `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type importUsersOutload struct {
+	DryRun  bool        `json:"dry_run"`
+	Created int         `json:"created"`
+	Skipped int         `json:"skipped"`
+	Failed  int         `json:"failed"`
+	Rows    []importRow `json:"rows"`
+	// Error is why the body could not be read to the end, after the rows
+	// before it were imported.
+	Error string `json:"error,omitempty"`
+}
+
+func (o importUsersOutload) code() int {
+	return http.StatusOK
+}
+
+// rowReader reads the users of an import one row at a time. A row that
+// cannot be parsed is returned as an errRow rather than an error, so that the
+// rest of the rows are still read.
+type rowReader interface {
+	Read() (int, atmail.User, error)
+}
+
+type errRow string
+
+func (e errRow) Error() string {
+	return string(e)
+}
+
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *ndjsonReader) Read() (int, atmail.User, error) {
+	for r.scanner.Scan() {
+		r.line++
+
+		if len(r.scanner.Bytes()) == 0 {
+			continue
+		}
+
+		i := createUserInload{}
+
+		if err := json.Unmarshal(r.scanner.Bytes(), &i); err != nil {
+			return r.line, atmail.User{}, errRow("invalid json!")
+		}
+
+		return r.line, atmail.User{Username: i.Username, Email: i.Email, Age: i.Age}, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return r.line, atmail.User{}, err
+	}
+
+	return r.line, atmail.User{}, io.EOF
+}
+
+type csvReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(r)
+
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+
+	for i, name := range header {
+		columns[name] = i
+	}
+
+	for _, name := range []string{"username", "email", "age"} {
+		if _, ok := columns[name]; !ok {
+			return nil, errRow("csv header must have username, email and age!")
+		}
+	}
+
+	return &csvReader{reader, columns}, nil
+}
+
+func (r *csvReader) Read() (int, atmail.User, error) {
+	record, err := r.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+
+		if errors.As(err, &parseErr) {
+			return parseErr.Line, atmail.User{}, errRow("invalid csv!")
+		}
+
+		return 0, atmail.User{}, err
+	}
+
+	line, _ := r.reader.FieldPos(0)
+
+	field := func(name string) string {
+		if i := r.columns[name]; i < len(record) {
+			return record[i]
+		}
+
+		return ""
+	}
+
+	age, err := strconv.ParseUint(field("age"), 10, 32)
+	if err != nil {
+		return line, atmail.User{}, errRow("age is invalid!")
+	}
+
+	return line, atmail.User{Username: field("username"), Email: field("email"), Age: uint(age)}, nil
+}
+
+// importUsers creates the users in the body, given as NDJSON or CSV by the
+// Content-Type. Users are created in batches, each batch in a transaction,
+// and users that already exist are skipped. With ?dry_run=true users are only
+// validated. If the body cannot be read to the end, e.g. a line is too long
+// or the connection broke, the rows read so far are still imported and
+// reported along with the error.
+func importUsers(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	dryRun, err := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	if err != nil && r.URL.Query().Get("dry_run") != "" {
+		return badRequest("dry_run is invalid!"), nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var reader rowReader
+
+	switch mediaType {
+	case "application/x-ndjson", "application/ndjson":
+		scanner := bufio.NewScanner(r.Body)
+
+		scanner.Buffer(nil, maxNDJSONLine)
+
+		reader = &ndjsonReader{scanner: scanner}
+	case "text/csv":
+		csvReader, err := newCSVReader(r.Body)
+		if err != nil {
+			var e errRow
+
+			if errors.As(err, &e) {
+				return badRequest(string(e)), nil
+			}
+
+			return badRequest("invalid csv!"), nil
+		}
+
+		reader = csvReader
+	default:
+		return errorOutload{http.StatusUnsupportedMediaType, "content type must be application/x-ndjson or text/csv!"}, nil
+	}
+
+	o := importUsersOutload{DryRun: dryRun, Rows: []importRow{}}
+
+	// rows of the batch, by index in o.Rows
+	batch := []int{}
+	users := []atmail.User{}
+
+	// usernames and emails of this import, which CheckUser cannot see yet
+	seen := map[string]bool{}
+
+	flush := func() {
+		if len(users) == 0 {
+			return
+		}
+
+		ids := make([]int64, len(users))
+		errs := make([]error, len(users))
+
+		if !dryRun {
+			created, err := s.CreateUsers(r.Context(), users)
+
+			switch _, exists := userExists(err); {
+			case exists:
+				// a user of the batch was created since it was checked, e.g.
+				// by a concurrent import, so the users are created one at a
+				// time to only skip that one
+				for j, user := range users {
+					ids[j], errs[j] = s.CreateUser(r.Context(), user)
+				}
+			case err != nil:
+				log.Printf("failed to import batch of %d users: %v", len(users), err)
+
+				for j := range errs {
+					errs[j] = err
+				}
+			default:
+				ids = created
+			}
+		}
+
+		for j, i := range batch {
+			if _, exists := userExists(errs[j]); exists {
+				o.Rows[i].Status, o.Rows[i].Error = rowSkipped, "username/email already exists!"
+				o.Skipped++
+
+				continue
+			}
+
+			if errs[j] != nil {
+				o.Rows[i].Status, o.Rows[i].Error = rowFailed, "failed to create user!"
+				o.Failed++
+
+				continue
+			}
+
+			o.Rows[i].Status, o.Rows[i].Id = rowCreated, ids[j]
+			o.Created++
+		}
+
+		batch, users = batch[:0], users[:0]
+	}
+
+	for {
+		line, user, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			var e errRow
+
+			if !errors.As(err, &e) {
+				log.Printf("failed to read import body: %v", err)
+
+				o.Error = "failed to read body!"
+
+				if errors.Is(err, bufio.ErrTooLong) {
+					o.Error = fmt.Sprintf("row %d is longer than %d bytes!", line+1, maxNDJSONLine)
+				}
+
+				break
+			}
+
+			o.Rows = append(o.Rows, importRow{Row: line, Status: rowFailed, Error: string(e)})
+			o.Failed++
+
+			continue
+		}
+
+		if message, ok := atmail.ValidateUser(user); !ok {
+			o.Rows = append(o.Rows, importRow{Row: line, Status: rowFailed, Error: message})
+			o.Failed++
+
+			continue
+		}
+
+		exists, err := s.CheckUser(r.Context(), user.Username, user.Email)
+		if err != nil {
+			return nil, err
+		}
+
+		// usernames and emails are unique regardless of case
+		username, email := "username:"+strings.ToLower(user.Username), "email:"+strings.ToLower(user.Email)
+
+		if exists || seen[username] || seen[email] {
+			o.Rows = append(o.Rows, importRow{Row: line, Status: rowSkipped, Error: "username/email already exists!"})
+			o.Skipped++
+
+			continue
+		}
+
+		seen[username], seen[email] = true, true
+
+		o.Rows = append(o.Rows, importRow{Row: line})
+
+		batch = append(batch, len(o.Rows)-1)
+		users = append(users, user)
+
+		if len(users) == importBatchSize {
+			flush()
+		}
+	}
+
+	flush()
+
+	return o, nil
+}
+
+// streamOutload is written by its stream function rather than as JSON.
+type streamOutload struct {
+	contentType string
+	stream      func(io.Writer) error
+}
+
+func (o streamOutload) code() int {
+	return http.StatusOK
+}
+
+// exportUsers streams every user as NDJSON or CSV, chosen by ?format= or
+// else the Accept header. Users are read a page at a time so that they are
+// never all in memory.
+func exportUsers(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	format := r.URL.Query().Get("format")
+
+	if format == "" {
+		format = formatNDJSON
+
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Accept")); mediaType == contentTypes[formatCSV] {
+			format = formatCSV
+		}
+	}
+
+	// encode writes what comes before the users and returns the function
+	// that writes each of them
+	var encode func(io.Writer) (func(atmail.User) error, error)
+
+	switch format {
+	case formatNDJSON:
+		encode = func(w io.Writer) (func(atmail.User) error, error) {
+			encoder := json.NewEncoder(w)
+
+			return func(user atmail.User) error {
+				return encoder.Encode(userOutload{user.Id, user.Username, user.Email, user.Age})
+			}, nil
+		}
+	case formatCSV:
+		encode = func(w io.Writer) (func(atmail.User) error, error) {
+			writer := csv.NewWriter(w)
+
+			// the header is flushed on its own, as there may be no users
+			if err := writer.Write([]string{"id", "username", "email", "age"}); err != nil {
+				return nil, err
+			}
+
+			writer.Flush()
+
+			if err := writer.Error(); err != nil {
+				return nil, err
+			}
+
+			return func(user atmail.User) error {
+				if err := writer.Write([]string{strconv.FormatInt(user.Id, 10), user.Username, user.Email, strconv.FormatUint(uint64(user.Age), 10)}); err != nil {
+					return err
+				}
+
+				writer.Flush()
+
+				return writer.Error()
+			}, nil
+		}
+	default:
+		return badRequest("format is invalid!"), nil
+	}
+
+	stream := func(w io.Writer) error {
+		write, err := encode(w)
+		if err != nil {
+			return err
+		}
+
+		o := atmail.ListUsersOptions{Limit: exportPageSize}
+
+		for {
+			users, err := s.ListUsers(r.Context(), o)
+			if err != nil {
+				return err
+			}
+
+			for _, user := range users {
+				if err := write(user); err != nil {
+					return err
+				}
+			}
+
+			if len(users) < exportPageSize {
+				return nil
+			}
+
+			o.After = &users[len(users)-1]
+		}
+	}
+
+	return streamOutload{contentTypes[format], stream}, nil
+}
@@ -0,0 +1,283 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"atmail"
+	"atmail/memstore"
+)
+
+func TestImportUsers(t *testing.T) {
+	for name, tc := range map[string]struct {
+		contentType string
+		query       string
+		body        string
+		// concurrentUsers are created by another import before each batch
+		concurrentUsers []atmail.User
+		want            outload
+	}{
+		"ndjson": {
+			contentType: "application/x-ndjson",
+			body: `{ "username": "bingo", "email": "bingo@heeler.com", "age": 6 }
+this invalid json
+
+{ "username": "bluey", "email": "bluey", "age": 7 }
+{ "username": "existinguser", "email": "other@email.com", "age": 7 }
+{ "username": "chilli", "email": "chilli@heeler.com", "age": 38 }
+{ "username": "bingo", "email": "bingo2@heeler.com", "age": 6 }
+`,
+			want: importUsersOutload{
+				Created: 2,
+				Skipped: 2,
+				Failed:  2,
+				Rows: []importRow{
+					{Row: 1, Status: rowCreated, Id: 2},
+					{Row: 2, Status: rowFailed, Error: "invalid json!"},
+					{Row: 4, Status: rowFailed, Error: "email is invalid!"},
+					{Row: 5, Status: rowSkipped, Error: "username/email already exists!"},
+					{Row: 6, Status: rowCreated, Id: 3},
+					{Row: 7, Status: rowSkipped, Error: "username/email already exists!"},
+				},
+			},
+		},
+		"duplicates of another case": {
+			contentType: "application/x-ndjson",
+			body: `{ "username": "Bingo", "email": "bingo@heeler.com", "age": 6 }
+{ "username": "bingo", "email": "bingo2@heeler.com", "age": 6 }
+{ "username": "bluey", "email": "BINGO@heeler.com", "age": 7 }
+`,
+			want: importUsersOutload{
+				Created: 1,
+				Skipped: 2,
+				Rows: []importRow{
+					{Row: 1, Status: rowCreated, Id: 2},
+					{Row: 2, Status: rowSkipped, Error: "username/email already exists!"},
+					{Row: 3, Status: rowSkipped, Error: "username/email already exists!"},
+				},
+			},
+		},
+		"created concurrently": {
+			contentType: "application/x-ndjson",
+			body: `{ "username": "bingo", "email": "bingo@heeler.com", "age": 6 }
+{ "username": "chilli", "email": "chilli@heeler.com", "age": 38 }
+`,
+			concurrentUsers: []atmail.User{{Username: "chilli", Email: "chilli@heeler.com", Age: 38}},
+			want: importUsersOutload{
+				Created: 1,
+				Skipped: 1,
+				Rows: []importRow{
+					{Row: 1, Status: rowCreated, Id: 3},
+					{Row: 2, Status: rowSkipped, Error: "username/email already exists!"},
+				},
+			},
+		},
+		"csv": {
+			contentType: "text/csv; charset=utf-8",
+			body:        "age,username,email\n6,bingo,bingo@heeler.com\nsix,bluey,bluey@heeler.com\n38,chilli,chilli@heeler.com\n",
+			want: importUsersOutload{
+				Created: 2,
+				Failed:  1,
+				Rows: []importRow{
+					{Row: 2, Status: rowCreated, Id: 2},
+					{Row: 3, Status: rowFailed, Error: "age is invalid!"},
+					{Row: 4, Status: rowCreated, Id: 3},
+				},
+			},
+		},
+		"dry run": {
+			contentType: "application/x-ndjson",
+			query:       "dry_run=true",
+			body:        `{ "username": "bingo", "email": "bingo@heeler.com", "age": 6 }`,
+			want: importUsersOutload{
+				DryRun:  true,
+				Created: 1,
+				Rows: []importRow{
+					{Row: 1, Status: rowCreated},
+				},
+			},
+		},
+		"csv header missing column": {
+			contentType: "text/csv",
+			body:        "username,email\nbingo,bingo@heeler.com\n",
+			want:        badRequest("csv header must have username, email and age!"),
+		},
+		"unsupported content type": {
+			contentType: "application/json",
+			body:        `[]`,
+			want:        errorOutload{http.StatusUnsupportedMediaType, "content type must be application/x-ndjson or text/csv!"},
+		},
+		"invalid dry run": {
+			contentType: "application/x-ndjson",
+			query:       "dry_run=maybe",
+			want:        badRequest("dry_run is invalid!"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/users:import?"+tc.query, strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Content-Type", tc.contentType)
+
+			store := newTestStore(t, memstore.Seed{
+				Users: []memstore.SeedUser{{Username: "existinguser", Email: "existing@email.com", Age: 30}},
+			})
+
+			rr := httptest.NewRecorder()
+
+			got, err := importUsers(faultStore{Store: store, concurrentUsers: tc.concurrentUsers}, rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestImportUsersReadError(t *testing.T) {
+	row := `{ "username": "bingo", "email": "bingo@heeler.com", "age": 6 }` + "\n"
+
+	for name, tc := range map[string]struct {
+		body io.Reader
+		want outload
+	}{
+		"line too long": {
+			body: strings.NewReader(row + strings.Repeat(" ", maxNDJSONLine) + "\n"),
+			want: importUsersOutload{
+				Created: 1,
+				Rows:    []importRow{{Row: 1, Status: rowCreated, Id: 1}},
+				Error:   "row 2 is longer than 1048576 bytes!",
+			},
+		},
+		"broken body": {
+			body: io.MultiReader(strings.NewReader(row), iotest.ErrReader(io.ErrUnexpectedEOF)),
+			want: importUsersOutload{
+				Created: 1,
+				Rows:    []importRow{{Row: 1, Status: rowCreated, Id: 1}},
+				Error:   "failed to read body!",
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/users:import", tc.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Content-Type", "application/x-ndjson")
+
+			store := memstore.New()
+
+			rr := httptest.NewRecorder()
+
+			got, err := importUsers(store, rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+
+			// the rows before the error are imported
+			if _, err := store.GetUser(context.Background(), 1); err != nil {
+				t.Errorf("want %v; got %v", nil, err)
+			}
+		})
+	}
+}
+
+func TestExportUsers(t *testing.T) {
+	store := newTestStore(t, memstore.Seed{Users: testUsers[:2]})
+
+	for name, tc := range map[string]struct {
+		query       string
+		accept      string
+		contentType string
+		want        string
+	}{
+		"ndjson": {
+			contentType: "application/x-ndjson",
+			want: `{"id":1,"username":"bingo","email":"bingo@heeler.com","age":6}
+{"id":2,"username":"bluey","email":"bluey@heeler.com","age":7}
+`,
+		},
+		"csv": {
+			query:       "format=csv",
+			contentType: "text/csv",
+			want:        "id,username,email,age\n1,bingo,bingo@heeler.com,6\n2,bluey,bluey@heeler.com,7\n",
+		},
+		"csv accepted": {
+			accept:      "text/csv",
+			contentType: "text/csv",
+			want:        "id,username,email,age\n1,bingo,bingo@heeler.com,6\n2,bluey,bluey@heeler.com,7\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/users:export?"+tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Accept", tc.accept)
+
+			rr := httptest.NewRecorder()
+
+			o, err := exportUsers(store, rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			write(rr, o)
+
+			if got := rr.Header().Get("Content-Type"); got != tc.contentType {
+				t.Errorf("want %v; got %v", tc.contentType, got)
+			}
+
+			if got := rr.Body.String(); got != tc.want {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestExportUsersEmpty(t *testing.T) {
+	store := newTestStore(t, memstore.Seed{})
+
+	// csv has its header even without users
+	for format, want := range map[string]string{
+		"ndjson": "",
+		"csv":    "id,username,email,age\n",
+	} {
+		t.Run(format, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/users:export?format="+format, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			o, err := exportUsers(store, rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			write(rr, o)
+
+			if got := rr.Body.String(); got != want {
+				t.Errorf("want %v; got %v", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package change
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"atmail"
+)
+
+// Change is a write of a user. User is the user after the write, or before it
+// when it was deleted or purged. Only the id is known of users purged after
+// they were deleted, whose fields were recorded then.
+type Change struct {
+	Action  string
+	User    atmail.User
+	Changes []atmail.Change
+}
+
+// Hook records changes in tx, the transaction that writes them, which is
+// rolled back if it fails.
+type Hook func(ctx context.Context, tx atmail.Store, changes []Change) error
+
+// Store calls its hooks with the users written through it, in the same
+// transaction as the write, so that there is no change without its records.
+// Users are read once for all of the hooks, including those hard deleted by
+// purges and by the writes that release their username or email.
+type Store struct {
+	atmail.Store
+	hooks []Hook
+}
+
+func NewStore(store atmail.Store, hooks ...Hook) *Store {
+	return &Store{store, hooks}
+}
+
+// SearchUsers searches with the store it wraps, which must be an
+// atmail.Searcher.
+func (s *Store) SearchUsers(ctx context.Context, query string, limit int, offset int) ([]atmail.UserMatch, error) {
+	searcher, ok := s.Store.(atmail.Searcher)
+	if !ok {
+		return nil, errors.New("store cannot search users")
+	}
+
+	return searcher.SearchUsers(ctx, query, limit, offset)
+}
+
+// WithTx calls the hooks with the writes of fn in the transaction.
+func (s *Store) WithTx(ctx context.Context, fn func(atmail.Store) error) error {
+	return s.Store.WithTx(ctx, func(tx atmail.Store) error {
+		return fn(&Store{tx, s.hooks})
+	})
+}
+
+// write runs fn in a transaction along with calling the hooks with the
+// changes it returns.
+func (s *Store) write(ctx context.Context, fn func(atmail.Store) ([]Change, error)) error {
+	return s.Store.WithTx(ctx, func(tx atmail.Store) error {
+		changes, err := fn(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, hook := range s.hooks {
+			if err := hook(ctx, tx, changes); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// release purges the deleted users that writing users would purge anyway, as
+// they hold their username or email, so that their purges are recorded.
+func release(ctx context.Context, tx atmail.Store, users ...atmail.User) ([]Change, error) {
+	changes := []Change{}
+
+	for _, user := range users {
+		ids, err := tx.ListReleasedUsers(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, id := range ids {
+			c, err := purge(ctx, tx, id)
+			if err != nil {
+				return nil, err
+			}
+
+			changes = append(changes, c)
+		}
+	}
+
+	return changes, nil
+}
+
+// purge purges the user, with its fields unless it was already deleted, in
+// which case they were recorded then.
+func purge(ctx context.Context, tx atmail.Store, id int64) (Change, error) {
+	user, changes := atmail.User{Id: id}, []atmail.Change{}
+
+	before, err := tx.GetUser(ctx, id)
+	if err == nil {
+		user, changes = before, atmail.DiffUsers(&before, nil)
+	} else if !errors.Is(err, atmail.ErrUserNone) {
+		return Change{}, err
+	}
+
+	if err := tx.PurgeUser(ctx, id); err != nil {
+		return Change{}, err
+	}
+
+	return Change{atmail.ActionUserPurge, user, changes}, nil
+}
+
+func (s *Store) CreateUser(ctx context.Context, user atmail.User) (int64, error) {
+	var id int64
+
+	err := s.write(ctx, func(tx atmail.Store) ([]Change, error) {
+		changes, err := release(ctx, tx, user)
+		if err != nil {
+			return nil, err
+		}
+
+		id, err = tx.CreateUser(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+
+		user.Id = id
+
+		return append(changes, Change{atmail.ActionUserCreate, user, atmail.DiffUsers(nil, &user)}), nil
+	})
+
+	return id, err
+}
+
+func (s *Store) CreateUsers(ctx context.Context, users []atmail.User) ([]int64, error) {
+	var ids []int64
+
+	err := s.write(ctx, func(tx atmail.Store) ([]Change, error) {
+		changes, err := release(ctx, tx, users...)
+		if err != nil {
+			return nil, err
+		}
+
+		ids, err = tx.CreateUsers(ctx, users)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, user := range users {
+			user.Id = ids[i]
+
+			changes = append(changes, Change{atmail.ActionUserCreate, user, atmail.DiffUsers(nil, &user)})
+		}
+
+		return changes, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (s *Store) UpdateUser(ctx context.Context, user atmail.User) error {
+	return s.write(ctx, func(tx atmail.Store) ([]Change, error) {
+		before, err := tx.GetUser(ctx, user.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		changes, err := release(ctx, tx, user)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := tx.UpdateUser(ctx, user); err != nil {
+			return nil, err
+		}
+
+		return append(changes, Change{atmail.ActionUserUpdate, user, atmail.DiffUsers(&before, &user)}), nil
+	})
+}
+
+func (s *Store) DeleteUser(ctx context.Context, id int64) error {
+	return s.write(ctx, func(tx atmail.Store) ([]Change, error) {
+		before, err := tx.GetUser(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := tx.DeleteUser(ctx, id); err != nil {
+			return nil, err
+		}
+
+		return []Change{{atmail.ActionUserDelete, before, atmail.DiffUsers(&before, nil)}}, nil
+	})
+}
+
+func (s *Store) RestoreUser(ctx context.Context, id int64) (atmail.User, error) {
+	var user atmail.User
+
+	err := s.write(ctx, func(tx atmail.Store) ([]Change, error) {
+		var err error
+
+		user, err = tx.RestoreUser(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Change{{atmail.ActionUserRestore, user, atmail.DiffUsers(nil, &user)}}, nil
+	})
+
+	return user, err
+}
+
+func (s *Store) PurgeUser(ctx context.Context, id int64) error {
+	return s.write(ctx, func(tx atmail.Store) ([]Change, error) {
+		c, err := purge(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Change{c}, nil
+	})
+}
+
+// PurgeUsers purges the users deleted before t one at a time, so that each of
+// them is recorded, and returns how many there were.
+func (s *Store) PurgeUsers(ctx context.Context, t time.Time) (int64, error) {
+	var count int64
+
+	err := s.write(ctx, func(tx atmail.Store) ([]Change, error) {
+		ids, err := tx.ListDeletedUsers(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+
+		changes := []Change{}
+
+		for _, id := range ids {
+			c, err := purge(ctx, tx, id)
+			if err != nil {
+				return nil, err
+			}
+
+			changes = append(changes, c)
+		}
+
+		count = int64(len(ids))
+
+		return changes, nil
+	})
+
+	return count, err
+}
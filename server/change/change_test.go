@@ -0,0 +1,206 @@
+package change
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"atmail"
+	"atmail/memstore"
+)
+
+// recorder is a hook that keeps the changes it is called with.
+type recorder struct {
+	changes []Change
+}
+
+func (r *recorder) hook(ctx context.Context, tx atmail.Store, changes []Change) error {
+	r.changes = append(r.changes, changes...)
+	return nil
+}
+
+// reads counts the users read, the rest is the wrapped store.
+type reads struct {
+	atmail.Store
+	count *int
+}
+
+func (s reads) WithTx(ctx context.Context, fn func(atmail.Store) error) error {
+	return s.Store.WithTx(ctx, func(tx atmail.Store) error {
+		return fn(reads{tx, s.count})
+	})
+}
+
+func (s reads) GetUser(ctx context.Context, id int64) (atmail.User, error) {
+	*s.count++
+	return s.Store.GetUser(ctx, id)
+}
+
+func TestStore(t *testing.T) {
+	count := 0
+
+	// every hook is called with the same changes
+	a, b := &recorder{}, &recorder{}
+
+	store := NewStore(reads{memstore.New(), &count}, a.hook, b.hook)
+
+	ctx := context.Background()
+
+	id, err := store.CreateUser(ctx, atmail.User{Username: "bluey", Email: "bluey@heeler.com", Age: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.UpdateUser(ctx, atmail.User{Id: id, Username: "bluey", Email: "bluey@heeler.com", Age: 8, Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// failed writes are not recorded
+	if err := store.UpdateUser(ctx, atmail.User{Id: id, Username: "bluey", Email: "bluey@heeler.com", Age: 9, Version: 1}); !errors.As(err, &atmail.ErrVersionConflict{}) {
+		t.Fatalf("want %v; got %v", atmail.ErrVersionConflict{Id: id, Version: 2}, err)
+	}
+
+	if err := store.DeleteUser(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.RestoreUser(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.PurgeUser(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	// the users are read once for both hooks, before they are updated,
+	// deleted and purged
+	if count != 4 {
+		t.Errorf("want %v; got %v", 4, count)
+	}
+
+	bluey := atmail.User{Id: id, Username: "bluey", Email: "bluey@heeler.com", Age: 8, Version: 2}
+
+	want := []Change{
+		{atmail.ActionUserCreate, atmail.User{Id: id, Username: "bluey", Email: "bluey@heeler.com", Age: 7}, atmail.DiffUsers(nil, &atmail.User{Username: "bluey", Email: "bluey@heeler.com", Age: 7})},
+		{atmail.ActionUserUpdate, atmail.User{Id: id, Username: "bluey", Email: "bluey@heeler.com", Age: 8, Version: 1}, []atmail.Change{{Field: "age", Before: uint(7), After: uint(8)}}},
+		{atmail.ActionUserDelete, bluey, atmail.DiffUsers(&bluey, nil)},
+		{atmail.ActionUserRestore, atmail.User{Id: id, Username: "bluey", Email: "bluey@heeler.com", Age: 8, Version: 3}, atmail.DiffUsers(nil, &bluey)},
+		{atmail.ActionUserPurge, atmail.User{Id: id, Username: "bluey", Email: "bluey@heeler.com", Age: 8, Version: 3}, atmail.DiffUsers(&bluey, nil)},
+	}
+
+	if !reflect.DeepEqual(want, a.changes) {
+		t.Errorf("want %v; got %v", want, a.changes)
+	}
+
+	if !reflect.DeepEqual(want, b.changes) {
+		t.Errorf("want %v; got %v", want, b.changes)
+	}
+}
+
+func TestStorePurges(t *testing.T) {
+	r := &recorder{}
+
+	inner := memstore.New(memstore.WithReservation(0))
+
+	deleted := time.Now().Add(-time.Hour)
+
+	if err := inner.Seed(memstore.Seed{Users: []memstore.SeedUser{
+		{Id: 1, Username: "bingo", Email: "bingo@heeler.com", Age: 6, DeletedAt: &deleted},
+		{Id: 2, Username: "bluey", Email: "bluey@heeler.com", Age: 7, DeletedAt: &deleted},
+		{Id: 3, Username: "chilli", Email: "chilli@heeler.com", Age: 38, DeletedAt: &deleted},
+		{Id: 4, Username: "bandit", Email: "bandit@heeler.com", Age: 40},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(inner, r.hook)
+
+	ctx := context.Background()
+
+	// users released by a write are purged with it
+	if _, err := store.CreateUser(ctx, atmail.User{Username: "Bingo", Email: "other@heeler.com", Age: 6}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.UpdateUser(ctx, atmail.User{Id: 4, Username: "bandit", Email: "bluey@heeler.com", Age: 40, Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// and the rest by the purger
+	if n, err := atmail.NewPurger(store, 0, time.Hour).Purge(ctx); err != nil || n != 1 {
+		t.Errorf("want %v; got %v (%v)", 1, n, err)
+	}
+
+	got := []string{}
+
+	for _, c := range r.changes {
+		// only the id is known of users purged after they were deleted
+		if c.Action == atmail.ActionUserPurge && (c.User != atmail.User{Id: c.User.Id} || len(c.Changes) != 0) {
+			t.Errorf("want %v; got %v", atmail.User{Id: c.User.Id}, c)
+		}
+
+		got = append(got, c.Action+" "+c.User.Username)
+	}
+
+	want := []string{
+		atmail.ActionUserPurge + " ",
+		atmail.ActionUserCreate + " Bingo",
+		atmail.ActionUserPurge + " ",
+		atmail.ActionUserUpdate + " bandit",
+		atmail.ActionUserPurge + " ",
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v; got %v", want, got)
+	}
+
+	ids := []int64{}
+
+	for _, c := range r.changes {
+		if c.Action == atmail.ActionUserPurge {
+			ids = append(ids, c.User.Id)
+		}
+	}
+
+	if !reflect.DeepEqual([]int64{1, 2, 3}, ids) {
+		t.Errorf("want %v; got %v", []int64{1, 2, 3}, ids)
+	}
+}
+
+func TestStoreRollback(t *testing.T) {
+	inner := memstore.New()
+
+	rollback := errors.New("rollback")
+
+	// a failed hook fails the write
+	store := NewStore(inner, func(ctx context.Context, tx atmail.Store, changes []Change) error {
+		if changes[0].User.Username == "bingo" {
+			return rollback
+		}
+
+		return nil
+	})
+
+	ctx := context.Background()
+
+	if _, err := store.CreateUser(ctx, atmail.User{Username: "bingo", Email: "bingo@heeler.com", Age: 6}); err != rollback {
+		t.Fatalf("want %v; got %v", rollback, err)
+	}
+
+	// and a failed transaction its writes
+	if err := store.WithTx(ctx, func(s atmail.Store) error {
+		if _, err := s.CreateUser(ctx, atmail.User{Username: "bluey", Email: "bluey@heeler.com", Age: 7}); err != nil {
+			return err
+		}
+
+		return rollback
+	}); err != rollback {
+		t.Fatalf("want %v; got %v", rollback, err)
+	}
+
+	if users, err := inner.ListUsers(ctx, atmail.ListUsersOptions{}); err != nil || len(users) != 0 {
+		t.Errorf("want %v; got %v (%v)", []atmail.User{}, users, err)
+	}
+}
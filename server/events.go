@@ -0,0 +1,202 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"atmail"
+)
+
+const (
+	listEventsDefaultLimit = 100
+	listEventsMaxLimit     = 1000
+	// listEventsMaxWait is the longest a long-poll waits for events.
+	listEventsMaxWait = 60 * time.Second
+)
+
+// eventsPollInterval is how often waiting requests and streams look for new
+// events.
+var eventsPollInterval = time.Second
+
+// eventsHeartbeat is how long a stream may be idle before a comment is sent,
+// so that proxies do not close it.
+var eventsHeartbeat = 15 * time.Second
+
+type eventOutload struct {
+	Seq    int64           `json:"seq"`
+	Time   time.Time       `json:"time"`
+	Action string          `json:"action"`
+	UserId int64           `json:"user_id"`
+	Data   json.RawMessage `json:"data"`
+}
+
+func toEventOutload(e atmail.UserEvent) eventOutload {
+	return eventOutload{e.Seq, e.Time, e.Action, e.UserId, e.Data}
+}
+
+type listEventsOutload struct {
+	Items []eventOutload `json:"items"`
+	// LastSeq is the seq to resume after, that of the last item or else the
+	// one asked for.
+	LastSeq int64 `json:"last_seq"`
+}
+
+func (o listEventsOutload) code() int {
+	return http.StatusOK
+}
+
+// listEvents lists the user events after the seq ?after= or, when resuming a
+// stream, the Last-Event-ID header. The events after 0 are those since the
+// oldest that was not compacted, otherwise the request fails with 410 Gone
+// if events after the seq were compacted. Clients that Accept
+// text/event-stream are sent events as they are appended until they
+// disconnect, others wait up to ?wait= seconds for an event when there is
+// none yet.
+func listEvents(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	q := r.URL.Query()
+
+	after, limit, wait := int64(0), listEventsDefaultLimit, time.Duration(0)
+
+	v := q.Get("after")
+	if v == "" {
+		v = r.Header.Get("Last-Event-ID")
+	}
+
+	if v != "" {
+		var err error
+
+		after, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || after < 0 {
+			return badRequest("after is invalid!"), nil
+		}
+	}
+
+	if v := q.Get("limit"); v != "" {
+		var err error
+
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > listEventsMaxLimit {
+			return badRequest("limit is invalid!"), nil
+		}
+	}
+
+	if v := q.Get("wait"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds < 0 || time.Duration(seconds)*time.Second > listEventsMaxWait {
+			return badRequest("wait is invalid!"), nil
+		}
+
+		wait = time.Duration(seconds) * time.Second
+	}
+
+	ctx := r.Context()
+
+	events, err := s.ListUserEvents(ctx, after, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// seqs have no gaps, so a gap after the seq is where events were compacted
+	if after > 0 && len(events) > 0 && events[0].Seq != after+1 {
+		return errorOutload{http.StatusGone, fmt.Sprintf("events after %d were compacted!", after)}, nil
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Accept")); mediaType == "text/event-stream" {
+		w.Header().Set("Cache-Control", "no-cache")
+
+		return streamOutload{"text/event-stream", streamEvents(s, w, r, after, limit, events)}, nil
+	}
+
+	for deadline := time.Now().Add(wait); len(events) == 0 && time.Now().Before(deadline); {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(eventsPollInterval):
+		}
+
+		events, err = s.ListUserEvents(ctx, after, limit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	o := listEventsOutload{Items: []eventOutload{}, LastSeq: after}
+
+	for _, e := range events {
+		o.Items = append(o.Items, toEventOutload(e))
+		o.LastSeq = e.Seq
+	}
+
+	return o, nil
+}
+
+// streamEvents streams events, starting with those already listed, as
+// Server-Sent Events whose ids are their seqs, until the client disconnects.
+func streamEvents(s atmail.Store, w http.ResponseWriter, r *http.Request, after int64, limit int, events []atmail.UserEvent) func(io.Writer) error {
+	return func(out io.Writer) error {
+		ctx := r.Context()
+		rc := http.NewResponseController(w)
+
+		// send the headers right away
+		if err := rc.Flush(); err != nil {
+			return err
+		}
+
+		idle := time.Now()
+
+		for {
+			for _, e := range events {
+				data, err := json.Marshal(toEventOutload(e))
+				if err != nil {
+					return err
+				}
+
+				if _, err := fmt.Fprintf(out, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Action, data); err != nil {
+					return err
+				}
+
+				after = e.Seq
+			}
+
+			if len(events) == 0 && time.Since(idle) >= eventsHeartbeat {
+				if _, err := io.WriteString(out, ": heartbeat\n\n"); err != nil {
+					return err
+				}
+			}
+
+			if len(events) > 0 || time.Since(idle) >= eventsHeartbeat {
+				if err := rc.Flush(); err != nil {
+					return err
+				}
+
+				idle = time.Now()
+			}
+
+			// a full page is followed right away by the next
+			if len(events) < limit {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(eventsPollInterval):
+				}
+			}
+
+			var err error
+
+			events, err = s.ListUserEvents(ctx, after, limit)
+			if err != nil {
+				// the client disconnecting is how streams end
+				if ctx.Err() != nil {
+					return nil
+				}
+
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"atmail"
+	"atmail/memstore"
+)
+
+// testUserEvents are sequenced from 3, the first two were compacted, see
+// newEventStore.
+var testUserEvents = []atmail.UserEvent{
+	{Seq: 3, Time: time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC), Action: atmail.ActionUserCreate, UserId: 1, Data: []byte(`{"user":{"id":1}}`)},
+	{Seq: 4, Time: time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC), Action: atmail.ActionUserUpdate, UserId: 1, Data: []byte(`{"user":{"id":1}}`)},
+	{Seq: 5, Time: time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC), Action: atmail.ActionUserDelete, UserId: 1, Data: []byte(`{"user":{"id":1}}`)},
+}
+
+// newEventStore returns a store whose feed has testUserEvents, after two
+// older events that were compacted.
+func newEventStore(t *testing.T) *memstore.Store {
+	t.Helper()
+
+	store := memstore.New()
+
+	compacted := []atmail.UserEvent{
+		{Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Action: atmail.ActionUserCreate, UserId: 1, Data: []byte(`{"user":{"id":1}}`)},
+		{Time: time.Date(2024, 1, 1, 0, 30, 0, 0, time.UTC), Action: atmail.ActionUserUpdate, UserId: 1, Data: []byte(`{"user":{"id":1}}`)},
+	}
+
+	for _, event := range append(compacted, testUserEvents...) {
+		if _, err := store.CreateUserEvent(context.Background(), event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := store.CompactUserEvents(context.Background(), testUserEvents[0].Time); err != nil {
+		t.Fatal(err)
+	}
+
+	return store
+}
+
+func TestListEvents(t *testing.T) {
+	for name, tc := range map[string]struct {
+		query       string
+		lastEventId string
+		want        outload
+	}{
+		"oldest": {
+			query: "limit=2",
+			want: listEventsOutload{
+				Items:   []eventOutload{toEventOutload(testUserEvents[0]), toEventOutload(testUserEvents[1])},
+				LastSeq: 4,
+			},
+		},
+		"after": {
+			query: "after=4",
+			want: listEventsOutload{
+				Items:   []eventOutload{toEventOutload(testUserEvents[2])},
+				LastSeq: 5,
+			},
+		},
+		"last event id": {
+			lastEventId: "3",
+			want: listEventsOutload{
+				Items:   []eventOutload{toEventOutload(testUserEvents[1]), toEventOutload(testUserEvents[2])},
+				LastSeq: 5,
+			},
+		},
+		"none yet": {
+			query: "after=5",
+			want:  listEventsOutload{Items: []eventOutload{}, LastSeq: 5},
+		},
+		"compacted": {
+			query: "after=1",
+			want:  errorOutload{http.StatusGone, "events after 1 were compacted!"},
+		},
+		"invalid after": {
+			query: "after=-1",
+			want:  badRequest("after is invalid!"),
+		},
+		"invalid limit": {
+			query: "limit=1001",
+			want:  badRequest("limit is invalid!"),
+		},
+		"invalid wait": {
+			query: "wait=61",
+			want:  badRequest("wait is invalid!"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/events?"+tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.lastEventId != "" {
+				req.Header.Set("Last-Event-ID", tc.lastEventId)
+			}
+
+			rr := httptest.NewRecorder()
+
+			got, err := listEvents(newEventStore(t), rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	// the stream ends when the client disconnects
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", "/events?after=3", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Accept", "text/event-stream")
+
+	rr := httptest.NewRecorder()
+
+	got, err := listEvents(newEventStore(t), rr, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o, ok := got.(streamOutload)
+	if !ok {
+		t.Fatalf("want streamOutload; got %v", got)
+	}
+
+	if err := o.stream(rr); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"id: 4",
+		"event: user.update",
+		`data: {"seq":4,"time":"2024-01-01T02:00:00Z","action":"user.update","user_id":1,"data":{"user":{"id":1}}}`,
+		"",
+		"id: 5",
+		"event: user.delete",
+		`data: {"seq":5,"time":"2024-01-01T03:00:00Z","action":"user.delete","user_id":1,"data":{"user":{"id":1}}}`,
+		"",
+		"",
+	}, "\n")
+
+	if got := rr.Body.String(); got != want {
+		t.Errorf("want %v; got %v", want, got)
+	}
+
+	if !rr.Flushed {
+		t.Error("want flushed; got not flushed")
+	}
+}
@@ -0,0 +1,59 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"atmail"
+	"atmail/server/change"
+)
+
+// User is the user of an event. Only the id is known of users purged after
+// they were deleted.
+type User struct {
+	Id       int64  `json:"id"`
+	Username string `json:"username,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Age      uint   `json:"age,omitempty"`
+}
+
+// Data is the json data of an event.
+type Data struct {
+	User    User            `json:"user"`
+	Changes []atmail.Change `json:"changes"`
+}
+
+// Append appends an event to the feed for every change, in the transaction
+// that writes them.
+func Append(ctx context.Context, tx atmail.Store, changes []change.Change) error {
+	for _, c := range changes {
+		e, err := event(c.Action, c.User, c.Changes)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.CreateUserEvent(ctx, e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func event(action string, user atmail.User, changes []atmail.Change) (atmail.UserEvent, error) {
+	bs, err := json.Marshal(Data{
+		User:    User{user.Id, user.Username, user.Email, user.Age},
+		Changes: changes,
+	})
+	if err != nil {
+		return atmail.UserEvent{}, err
+	}
+
+	return atmail.UserEvent{
+		Time:   time.Now().UTC(),
+		Action: action,
+		UserId: user.Id,
+		Data:   bs,
+	}, nil
+}
@@ -0,0 +1,64 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"atmail"
+	"atmail/memstore"
+	"atmail/server/change"
+)
+
+func TestAppend(t *testing.T) {
+	store := memstore.New()
+
+	ctx := context.Background()
+
+	bluey := atmail.User{Id: 1, Username: "bluey", Email: "bluey@heeler.com", Age: 7}
+
+	if err := Append(ctx, store, []change.Change{
+		{Action: atmail.ActionUserCreate, User: bluey, Changes: atmail.DiffUsers(nil, &bluey)},
+		{Action: atmail.ActionUserDelete, User: bluey, Changes: atmail.DiffUsers(&bluey, nil)},
+		// only the id is known of users purged after they were deleted
+		{Action: atmail.ActionUserPurge, User: atmail.User{Id: 2}, Changes: []atmail.Change{}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := store.ListUserEvents(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type event struct {
+		Seq     int64
+		Action  string
+		UserId  int64
+		User    User
+		Changes int
+	}
+
+	want := []event{
+		{1, atmail.ActionUserCreate, 1, User{1, "bluey", "bluey@heeler.com", 7}, 3},
+		{2, atmail.ActionUserDelete, 1, User{1, "bluey", "bluey@heeler.com", 7}, 3},
+		{3, atmail.ActionUserPurge, 2, User{Id: 2}, 0},
+	}
+
+	got := []event{}
+
+	for _, e := range events {
+		d := Data{}
+
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			t.Fatal(err)
+		}
+
+		got = append(got, event{e.Seq, e.Action, e.UserId, d.User, len(d.Changes)})
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v; got %v", want, got)
+	}
+}
@@ -1,69 +1,115 @@
 package server
 
 import (
-	"encoding/base64"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"atmail"
+	"atmail/server/audit"
 	"atmail/server/roles"
 )
 
 type handlerFunc func(atmail.Store, http.ResponseWriter, *http.Request) (outload, error)
 
 type handler struct {
-	store atmail.Store
-	roles roles.Role
-	fn    handlerFunc
+	store      atmail.Store
+	permission roles.Permission
+	fn         handlerFunc
+	auth       Authenticator
+	resolver   *roles.Resolver
+	challenges []string
+	// timeout is the deadline of the context of the request, none when 0
+	timeout time.Duration
 }
 
 func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// set headers
 	w.Header().Add("Content-Type", "application/json")
 
-	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Basic ")
-	if !ok {
-		fail(w, "unauthorized!", http.StatusUnauthorized)
-		return
+	// clients and proxies may send their own request id to correlate logs
+	requestId := r.Header.Get("X-Request-Id")
+
+	if requestId == "" || len(requestId) > 64 {
+		requestId = newRequestId()
 	}
 
-	bs, err := base64.StdEncoding.DecodeString(token)
+	w.Header().Set("X-Request-Id", requestId)
+
+	// reads after a write of the request see the write, even with replicas
+	r = r.WithContext(atmail.WithReadYourWrites(r.Context()))
+
+	// the context is also cancelled when the client disconnects
+	if h.timeout > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+		defer cancel()
+
+		r = r.WithContext(ctx)
+	}
+
+	p, err := h.auth.Authenticate(r)
 	if err != nil {
-		fail(w, "internal server error!", http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, ErrMalformedCredentials):
+			fail(w, "malformed authorization header!", http.StatusBadRequest)
+		case errors.Is(err, ErrNoCredentials), errors.Is(err, ErrInvalidCredentials):
+			for _, challenge := range h.challenges {
+				w.Header().Add("WWW-Authenticate", challenge)
+			}
+
+			fail(w, "unauthorized!", http.StatusUnauthorized)
+		default:
+			failInternal(w, err)
+		}
+
 		return
 	}
 
-	ss := strings.Split(string(bs), ":")
-
-	if len(ss) != 2 {
-		fail(w, "unauthorized!", http.StatusUnauthorized)
+	ok, err := h.resolver.IsAuthorized(r.Context(), p.Roles, h.permission)
+	if err != nil {
+		failInternal(w, err)
 		return
 	}
 
-	user, password := ss[0], ss[1]
+	r = r.WithContext(audit.WithRequest(WithPrincipal(r.Context(), p), audit.Request{
+		Actor:     p.Id,
+		Route:     r.Pattern,
+		ClientIP:  clientIP(r),
+		RequestId: requestId,
+	}))
 
-	role, err := h.store.GetRole(user, password)
-	if err != nil {
-		if !errors.Is(err, atmail.ErrAdminNone) {
-			fail(w, "internal server error!", http.StatusInternalServerError)
-			return
+	if !ok {
+		// the request is answered either way, so failing to record it is logged
+		target := int64(0)
+
+		// only the {id} of user routes is a user
+		if _, path, _ := strings.Cut(r.Pattern, " "); strings.HasPrefix(path, "/users/") {
+			target, _ = strconv.ParseInt(r.PathValue("id"), 10, 64)
 		}
 
-		fail(w, "unauthorized!", http.StatusUnauthorized)
-		return
-	}
+		if _, err := h.store.CreateAuditEvent(r.Context(), audit.Event(r.Context(), atmail.ActionDenied, target, nil)); err != nil {
+			log.Printf("failed to record denied request: %v", err)
+		}
 
-	if !roles.IsAuthorized(h.roles, role) {
-		fail(w, "unauthorized!", http.StatusUnauthorized)
+		fail(w, fmt.Sprintf("forbidden! requires permission %s", h.permission), http.StatusForbidden)
 		return
 	}
 
 	// get outload
 	outload, err := h.fn(h.store, w, r)
 	if err != nil {
-		fail(w, "internal server error!", http.StatusInternalServerError)
+		failInternal(w, err)
 		return
 	}
 
@@ -72,8 +118,24 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func write(w http.ResponseWriter, o outload) {
+	if o, ok := o.(streamOutload); ok {
+		w.Header().Set("Content-Type", o.contentType)
+		w.WriteHeader(o.code())
+
+		// the status is already sent, so all that is left is to stop
+		if err := o.stream(w); err != nil {
+			log.Printf("failed to stream response: %v", err)
+		}
+
+		return
+	}
+
 	w.WriteHeader(o.code())
 
+	if o.code() == http.StatusNotModified {
+		return
+	}
+
 	encoder := json.NewEncoder(w)
 
 	encoder.SetIndent("", "\t")
@@ -87,3 +149,57 @@ func write(w http.ResponseWriter, o outload) {
 func fail(w http.ResponseWriter, message string, code int) {
 	write(w, errorOutload{code, message})
 }
+
+// failInternal fails with the status of an unexpected error. Running out of
+// time and failing to reach the database are told apart from other errors so
+// that clients know they may retry.
+func failInternal(w http.ResponseWriter, err error) {
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		fail(w, "request timed out!", http.StatusGatewayTimeout)
+	case errors.Is(err, context.Canceled), errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone), errors.As(err, &netErr):
+		fail(w, "service unavailable!", http.StatusServiceUnavailable)
+	default:
+		fail(w, "internal server error!", http.StatusInternalServerError)
+	}
+}
+
+func newRequestId() string {
+	bs := make([]byte, 16)
+
+	rand.Read(bs)
+
+	return hex.EncodeToString(bs)
+}
+
+// clientIP returns the address the request came from.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// challenges returns the WWW-Authenticate challenges for the schemes of a.
+func challenges(a Authenticator, realm string) []string {
+	schemes := []string{}
+
+	switch a := a.(type) {
+	case Chain:
+		schemes = a.Schemes()
+	case Challenger:
+		schemes = append(schemes, a.Scheme())
+	}
+
+	challenges := []string{}
+
+	for _, scheme := range schemes {
+		challenges = append(challenges, fmt.Sprintf("%s realm=%q", scheme, realm))
+	}
+
+	return challenges
+}
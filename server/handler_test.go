@@ -1,11 +1,21 @@
 package server
 
 import (
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"atmail"
+	"atmail/memstore"
 	"atmail/server/roles"
 )
 
@@ -16,13 +26,15 @@ func (o fakeOutload) code() int {
 }
 
 func TestHandlerServeHTTPOk(t *testing.T) {
+	store := newTestStore(t, memstore.Seed{
+		Admins: []memstore.SeedAdmin{{User: "foo", Password: "bar", Roles: []string{"chilli"}}},
+	})
+
 	h := handler{
-		store: fakeStore{
-			existingAdminUser:     "foo",
-			existingAdminPassword: "bar",
-			existingAdminRole:     roles.Chilli,
-		},
-		roles: roles.Chilli | roles.Bandit,
+		store:      store,
+		auth:       Chain{NewBasicAuthenticator(store), NewTokenAuthenticator(store)},
+		permission: roles.UsersWrite,
+		resolver:   testResolver,
 		fn: func(atmail.Store, http.ResponseWriter, *http.Request) (outload, error) {
 			return fakeOutload{}, nil
 		},
@@ -42,35 +54,215 @@ func TestHandlerServeHTTPOk(t *testing.T) {
 }
 
 func TestHandlerServeHTTPUnauthorized(t *testing.T) {
+	store := newTestStore(t, memstore.Seed{
+		Admins: []memstore.SeedAdmin{{User: "foo", Password: "bar", Roles: []string{"chilli"}}},
+	})
+
+	auth := Chain{NewBasicAuthenticator(store), NewTokenAuthenticator(store)}
+
 	h := handler{
-		store: fakeStore{
-			existingAdminUser:     "foo",
-			existingAdminPassword: "bar",
-			existingAdminRole:     roles.Chilli,
-		},
-		roles: roles.Bingo | roles.Bandit,
+		store:      store,
+		auth:       auth,
+		challenges: challenges(auth, "atmail"),
+		permission: roles.UsersDelete,
+		resolver:   testResolver,
 		fn: func(atmail.Store, http.ResponseWriter, *http.Request) (outload, error) {
 			return nil, nil
 		},
 	}
 
 	for name, tc := range map[string]struct {
-		username string
-		password string
+		authorization string
+		want          int
 	}{
 		"admin does not exist": {
-			username: "baz",
-			password: "qux",
+			authorization: "Basic " + base64.StdEncoding.EncodeToString([]byte("baz:qux")),
+			want:          http.StatusUnauthorized,
+		},
+		"wrong password": {
+			authorization: "Basic " + base64.StdEncoding.EncodeToString([]byte("foo:qux")),
+			want:          http.StatusUnauthorized,
+		},
+		"missing header": {
+			want: http.StatusUnauthorized,
+		},
+		"unsupported scheme": {
+			authorization: "Digest username=foo",
+			want:          http.StatusUnauthorized,
+		},
+		"malformed base64": {
+			authorization: "Basic !!!",
+			want:          http.StatusBadRequest,
+		},
+		"missing colon": {
+			authorization: "Basic " + base64.StdEncoding.EncodeToString([]byte("foobar")),
+			want:          http.StatusBadRequest,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := http.Request{Header: http.Header{}}
+
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+
+			rr := httptest.NewRecorder()
+
+			h.ServeHTTP(rr, &req)
+
+			if got := rr.Code; got != tc.want {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+
+			var want []string
+
+			if tc.want == http.StatusUnauthorized {
+				want = []string{`Basic realm="atmail"`, `Bearer realm="atmail"`}
+			}
+
+			if got := rr.Header().Values("WWW-Authenticate"); !reflect.DeepEqual(want, got) {
+				t.Errorf("want %v; got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestHandlerServeHTTPForbidden(t *testing.T) {
+	store := newTestStore(t, memstore.Seed{
+		Admins: []memstore.SeedAdmin{{User: "foo", Password: "bar", Roles: []string{"chilli"}}},
+	})
+
+	h := handler{
+		store:      store,
+		auth:       Chain{NewBasicAuthenticator(store), NewTokenAuthenticator(store)},
+		permission: roles.UsersDelete,
+		resolver:   testResolver,
+		fn: func(atmail.Store, http.ResponseWriter, *http.Request) (outload, error) {
+			return nil, nil
+		},
+	}
+
+	req := http.Request{Header: http.Header{}, RemoteAddr: "10.0.0.1:1234", Pattern: "DELETE /users/{id}"}
+
+	req.SetBasicAuth("foo", "bar")
+	req.SetPathValue("id", "7")
+
+	req.Header.Set("X-Request-Id", "abc")
+
+	rr := httptest.NewRecorder()
+
+	h.ServeHTTP(rr, &req)
+
+	if got := rr.Code; got != http.StatusForbidden {
+		t.Errorf("want %v; got %v", http.StatusForbidden, got)
+	}
+
+	if want, got := "forbidden! requires permission users:delete", rr.Body.String(); !strings.Contains(got, want) {
+		t.Errorf("want %v; got %v", want, got)
+	}
+
+	want := []atmail.AuditEvent{{
+		Actor:     "foo",
+		Action:    atmail.ActionDenied,
+		Route:     "DELETE /users/{id}",
+		Target:    7,
+		ClientIP:  "10.0.0.1",
+		RequestId: "abc",
+	}}
+
+	got, err := store.ListAuditEvents(context.Background(), atmail.AuditOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only what the handler recorded, not what the store added
+	for i := range got {
+		got[i].Id, got[i].Time, got[i].Changes, got[i].PrevHash, got[i].Hash = 0, time.Time{}, nil, "", ""
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v; got %v", want, got)
+	}
+
+	if want, got := "abc", rr.Header().Get("X-Request-Id"); want != got {
+		t.Errorf("want %v; got %v", want, got)
+	}
+}
+
+func TestHandlerServeHTTPBearerOk(t *testing.T) {
+	store := newTestStore(t, memstore.Seed{
+		Admins: []memstore.SeedAdmin{{User: "foo", Password: "bar", Roles: []string{"chilli"}}},
+	})
+
+	if _, err := store.CreateToken(context.Background(), atmail.Token{
+		Admin:     "foo",
+		Hash:      hashToken("atm_sometoken"),
+		Roles:     []string{"chilli"},
+		ExpiresAt: time.Now().Add(time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler{
+		store:      store,
+		auth:       Chain{NewBasicAuthenticator(store), NewTokenAuthenticator(store)},
+		permission: roles.UsersWrite,
+		resolver:   testResolver,
+		fn: func(_ atmail.Store, _ http.ResponseWriter, r *http.Request) (outload, error) {
+			if p, ok := PrincipalFromContext(r.Context()); !ok || p.Id != "foo" || p.Method != MethodToken {
+				t.Errorf("want principal foo; got %v", p)
+			}
+
+			return fakeOutload{}, nil
 		},
-		"role not allowed": {
-			username: "foo",
-			password: "bar",
+	}
+
+	req := http.Request{Header: http.Header{}}
+
+	req.Header.Set("Authorization", "Bearer atm_sometoken")
+
+	rr := httptest.NewRecorder()
+
+	h.ServeHTTP(rr, &req)
+
+	if got := rr.Code; got != http.StatusOK {
+		t.Errorf("want %v; got %v", http.StatusOK, got)
+	}
+}
+
+func TestHandlerServeHTTPBearerUnauthorized(t *testing.T) {
+	for name, tc := range map[string]struct {
+		token atmail.Token
+	}{
+		"token does not exist": {
+			token: atmail.Token{Admin: "foo", Hash: hashToken("atm_othertoken"), Roles: []string{"chilli"}, ExpiresAt: time.Now().Add(time.Hour)},
+		},
+		"token expired": {
+			token: atmail.Token{Admin: "foo", Hash: hashToken("atm_sometoken"), Roles: []string{"chilli"}, ExpiresAt: time.Now().Add(-time.Hour)},
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
+			store := newTestStore(t, memstore.Seed{
+				Admins: []memstore.SeedAdmin{{User: "foo", Password: "bar", Roles: []string{"chilli"}}},
+			})
+
+			if _, err := store.CreateToken(context.Background(), tc.token); err != nil {
+				t.Fatal(err)
+			}
+
+			h := handler{
+				store:      store,
+				auth:       Chain{NewBasicAuthenticator(store), NewTokenAuthenticator(store)},
+				permission: roles.UsersWrite,
+				resolver:   testResolver,
+				fn: func(atmail.Store, http.ResponseWriter, *http.Request) (outload, error) {
+					return fakeOutload{}, nil
+				},
+			}
+
 			req := http.Request{Header: http.Header{}}
 
-			req.SetBasicAuth(tc.username, tc.password)
+			req.Header.Set("Authorization", "Bearer atm_sometoken")
 
 			rr := httptest.NewRecorder()
 
@@ -79,7 +271,76 @@ func TestHandlerServeHTTPUnauthorized(t *testing.T) {
 			if got := rr.Code; got != http.StatusUnauthorized {
 				t.Errorf("want %v; got %v", http.StatusUnauthorized, got)
 			}
+		})
+	}
+}
 
+func TestHandlerServeHTTPTimeout(t *testing.T) {
+	store := newTestStore(t, memstore.Seed{
+		Admins: []memstore.SeedAdmin{{User: "foo", Password: "bar", Roles: []string{"chilli"}}},
+	})
+
+	h := handler{
+		store:      store,
+		auth:       Chain{NewBasicAuthenticator(store), NewTokenAuthenticator(store)},
+		permission: roles.UsersRead,
+		resolver:   testResolver,
+		timeout:    time.Millisecond,
+		fn: func(_ atmail.Store, _ http.ResponseWriter, r *http.Request) (outload, error) {
+			// a query that outlives the deadline
+			<-r.Context().Done()
+
+			return nil, r.Context().Err()
+		},
+	}
+
+	req := http.Request{Header: http.Header{}}
+
+	req.SetBasicAuth("foo", "bar")
+
+	rr := httptest.NewRecorder()
+
+	h.ServeHTTP(rr, &req)
+
+	if got := rr.Code; got != http.StatusGatewayTimeout {
+		t.Errorf("want %v; got %v", http.StatusGatewayTimeout, got)
+	}
+}
+
+func TestFailInternal(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err  error
+		want int
+	}{
+		"deadline exceeded": {
+			err:  fmt.Errorf("query: %w", context.DeadlineExceeded),
+			want: http.StatusGatewayTimeout,
+		},
+		"canceled": {
+			err:  context.Canceled,
+			want: http.StatusServiceUnavailable,
+		},
+		"bad connection": {
+			err:  driver.ErrBadConn,
+			want: http.StatusServiceUnavailable,
+		},
+		"connection refused": {
+			err:  &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+			want: http.StatusServiceUnavailable,
+		},
+		"other": {
+			err:  errors.New("other"),
+			want: http.StatusInternalServerError,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+
+			failInternal(rr, tc.err)
+
+			if got := rr.Code; got != tc.want {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
 		})
 	}
 }
@@ -1,13 +1,19 @@
 package server
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"atmail"
+	"atmail/server/patch"
 )
 
 type createUserInload struct {
@@ -44,19 +50,35 @@ func createUser(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload
 		return badRequest(s), nil
 	}
 
-	exists, err := s.CheckUser(user.Username, user.Email)
-	if err != nil {
+	var id int64
+	var exists bool
+
+	// the unique keys of users catch whoever creates the same user between the
+	// check and the insert, which surfaces as atmail.ErrUserExists
+	if err := s.WithTx(r.Context(), func(s atmail.Store) error {
+		var err error
+
+		exists, err = s.CheckUser(r.Context(), user.Username, user.Email)
+		if err != nil || exists {
+			return err
+		}
+
+		id, err = s.CreateUser(r.Context(), user)
+		return err
+	}); err != nil {
+		if o, ok := userExists(err); ok {
+			return o, nil
+		}
+
 		return nil, err
 	}
 
 	if exists {
-		return badRequest("username/email already exists!"), nil
+		return conflict("username/email already exists!"), nil
 	}
 
-	id, err := s.CreateUser(user)
-	if err != nil {
-		return nil, err
-	}
+	// users start at version 1
+	w.Header().Set("ETag", etag(1))
 
 	o := createUserOutload{
 		Id:       id,
@@ -86,7 +108,7 @@ func getUser(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, e
 		return badRequest("user does not exist!"), nil
 	}
 
-	user, err := s.GetUser(id)
+	user, err := s.GetUser(r.Context(), id)
 	if err != nil {
 		if !errors.Is(err, atmail.ErrUserNone) {
 			return nil, err
@@ -95,6 +117,12 @@ func getUser(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, e
 		return badRequest("user does not exist!"), nil
 	}
 
+	w.Header().Set("ETag", etag(user.Version))
+
+	if header := r.Header.Get("If-None-Match"); header != "" && matchETag(header, user.Version, true) {
+		return notModifiedOutload{}, nil
+	}
+
 	o := getUserOutload{
 		Id:       user.Id,
 		Username: user.Username,
@@ -116,6 +144,63 @@ func badRequest(message string) errorOutload {
 	}
 }
 
+func conflict(message string) errorOutload {
+	return errorOutload{
+		Code:  http.StatusConflict,
+		Error: message,
+	}
+}
+
+// userExists returns the conflict to respond with when err is
+// atmail.ErrUserExists.
+func userExists(err error) (errorOutload, bool) {
+	var e atmail.ErrUserExists
+
+	if !errors.As(err, &e) {
+		return errorOutload{}, false
+	}
+
+	return conflict(fmt.Sprintf("%s already exists!", e.Field)), true
+}
+
+func preconditionFailed() errorOutload {
+	return errorOutload{
+		Code:  http.StatusPreconditionFailed,
+		Error: "user has been modified!",
+	}
+}
+
+// notModifiedOutload is written without a body.
+type notModifiedOutload struct{}
+
+func (o notModifiedOutload) code() int {
+	return http.StatusNotModified
+}
+
+// etag returns the entity tag of a version of a user.
+func etag(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// matchETag reports whether header, a list of entity tags as in If-Match and
+// If-None-Match, matches version. Weak tags only match if weak is set, as
+// If-Match requires strong comparison.
+func matchETag(header string, version int64, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+
+		if tag == "*" || tag == etag(version) {
+			return true
+		}
+	}
+
+	return false
+}
+
 type errorOutload struct {
 	Code  int    `json:"-"`
 	Error string `json:"error"`
@@ -142,14 +227,101 @@ func (o updateUserOutload) code() int {
 	return http.StatusOK
 }
 
+// updateUser replaces a user, so every field is required. Partial updates
+// are made with PATCH.
 func updateUser(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	i := updateUserInload{}
+
+	if err := json.NewDecoder(r.Body).Decode(&i); err != nil {
+		return badRequest("invalid json!"), nil
+	}
+
+	if i.Username == nil || i.Email == nil || i.Age == nil {
+		return badRequest("username, email and age are required!"), nil
+	}
+
+	return modifyUser(s, w, r, func(user atmail.User) (atmail.User, outload) {
+		user.Username, user.Email, user.Age = *i.Username, *i.Email, *i.Age
+
+		return user, nil
+	})
+}
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// patchUser updates a user with a JSON Merge Patch or a JSON Patch, chosen by
+// the Content-Type. Patches apply to the user as it is returned by GET.
+func patchUser(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var apply func([]byte, []byte) ([]byte, error)
+
+	switch mediaType {
+	case mergePatchType:
+		apply = patch.Merge
+	case jsonPatchType:
+		apply = patch.Apply
+	default:
+		return errorOutload{http.StatusUnsupportedMediaType, "content type must be " + mergePatchType + " or " + jsonPatchType + "!"}, nil
+	}
+
+	p, err := io.ReadAll(r.Body)
+	if err != nil {
+		return badRequest("failed to read body!"), nil
+	}
+
+	return modifyUser(s, w, r, func(user atmail.User) (atmail.User, outload) {
+		doc, _ := json.Marshal(userOutload{user.Id, user.Username, user.Email, user.Age})
+
+		doc, err := apply(doc, p)
+		if err != nil {
+			switch {
+			case errors.Is(err, patch.ErrTestFailed):
+				return atmail.User{}, conflict("patch test failed!")
+			case errors.Is(err, patch.ErrPathNone):
+				return atmail.User{}, badRequest("patch path does not exist!")
+			default:
+				return atmail.User{}, badRequest("patch is invalid!")
+			}
+		}
+
+		// fields that were removed are left blank, and then fail validation
+		patched := userOutload{}
+
+		decoder := json.NewDecoder(bytes.NewReader(doc))
+
+		decoder.DisallowUnknownFields()
+
+		if err := decoder.Decode(&patched); err != nil {
+			return atmail.User{}, badRequest("patched user is invalid!")
+		}
+
+		if patched.Id != user.Id {
+			return atmail.User{}, badRequest("id cannot be changed!")
+		}
+
+		user.Username, user.Email, user.Age = patched.Username, patched.Email, patched.Age
+
+		return user, nil
+	})
+}
+
+// modifyUser saves the user of {id} as changed by fn, which returns an
+// outload instead to fail. The user is only saved if it matches If-Match and
+// has not been updated since it was read.
+func modifyUser(s atmail.Store, w http.ResponseWriter, r *http.Request, fn func(atmail.User) (atmail.User, outload)) (outload, error) {
 	// if {id} is invalid, just return that the user does not exist
 	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil {
 		return badRequest("user does not exist!"), nil
 	}
 
-	user, err := s.GetUser(id)
+	// the version that If-Match and the update are checked against must be
+	// the current one, not that of a replica lagging behind
+	user, err := s.GetUser(atmail.WithPrimary(r.Context()), id)
 	if err != nil {
 		if err != atmail.ErrUserNone {
 			return nil, err
@@ -158,40 +330,46 @@ func updateUser(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload
 		return badRequest("user does not exist!"), nil
 	}
 
-	i := updateUserInload{}
+	ifMatch := r.Header.Get("If-Match")
 
-	if err := json.NewDecoder(r.Body).Decode(&i); err != nil {
-		return nil, err
+	if ifMatch != "" && !matchETag(ifMatch, user.Version, false) {
+		return preconditionFailed(), nil
 	}
 
-	if i.Username != nil {
-		user.Username = *i.Username
-	}
-
-	if i.Email != nil {
-		user.Email = *i.Email
-	}
-
-	if i.Age != nil {
-		user.Age = uint(*i.Age)
+	user, o := fn(user)
+	if o != nil {
+		return o, nil
 	}
 
 	if s, ok := atmail.ValidateUser(user); !ok {
 		return badRequest(s), nil
 	}
 
-	if err := s.UpdateUser(user); err != nil {
+	// the user may have been updated since it was read above
+	if err := s.UpdateUser(r.Context(), user); err != nil {
+		if o, ok := userExists(err); ok {
+			return o, nil
+		}
+
+		if errors.As(err, &atmail.ErrVersionConflict{}) {
+			if ifMatch != "" {
+				return preconditionFailed(), nil
+			}
+
+			return conflict("user was updated concurrently!"), nil
+		}
+
 		return nil, err
 	}
 
-	o := updateUserOutload{
+	w.Header().Set("ETag", etag(user.Version+1))
+
+	return updateUserOutload{
 		Id:       user.Id,
 		Username: user.Username,
 		Email:    user.Email,
-		Age:      uint(user.Age),
-	}
-
-	return o, nil
+		Age:      user.Age,
+	}, nil
 }
 
 type messageOutload struct {
@@ -202,13 +380,26 @@ func (o messageOutload) code() int {
 	return http.StatusOK
 }
 
+// deleteUser soft deletes a user, so that it can be restored until it is
+// purged. With ?hard=true the user is purged at once.
 func deleteUser(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
 	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := s.DeleteUser(id); err != nil {
+	hard, err := strconv.ParseBool(r.URL.Query().Get("hard"))
+	if err != nil && r.URL.Query().Get("hard") != "" {
+		return badRequest("hard is invalid!"), nil
+	}
+
+	if hard {
+		err = s.PurgeUser(r.Context(), id)
+	} else {
+		err = s.DeleteUser(r.Context(), id)
+	}
+
+	if err != nil {
 		if !errors.Is(err, atmail.ErrUserNone) {
 			return nil, err
 		}
@@ -216,5 +407,259 @@ func deleteUser(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload
 		return badRequest("user does not exist!"), nil
 	}
 
+	if hard {
+		return messageOutload{fmt.Sprintf("successfully purged user %d!", id)}, nil
+	}
+
 	return messageOutload{fmt.Sprintf("successfully deleted user %d!", id)}, nil
 }
+
+// restoreUser restores a deleted user.
+func restoreUser(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	// if {id} is invalid, just return that the user does not exist
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return badRequest("deleted user does not exist!"), nil
+	}
+
+	user, err := s.RestoreUser(r.Context(), id)
+	if err != nil {
+		if !errors.Is(err, atmail.ErrUserNone) {
+			return nil, err
+		}
+
+		return badRequest("deleted user does not exist!"), nil
+	}
+
+	w.Header().Set("ETag", etag(user.Version))
+
+	return getUserOutload{
+		Id:       user.Id,
+		Username: user.Username,
+		Email:    user.Email,
+		Age:      user.Age,
+	}, nil
+}
+
+const (
+	listUsersDefaultLimit = 20
+	listUsersMaxLimit     = 100
+)
+
+type userOutload struct {
+	Id       int64  `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Age      uint   `json:"age"`
+}
+
+type listUsersOutload struct {
+	Items      []userOutload `json:"items"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+func (o listUsersOutload) code() int {
+	return http.StatusOK
+}
+
+// cursor is the last user of a page along with how the users were sorted,
+// encoded for clients as an opaque string.
+type cursor struct {
+	Sort     atmail.Sort `json:"s,omitempty"`
+	Desc     bool        `json:"d,omitempty"`
+	Id       int64       `json:"i"`
+	Username string      `json:"u,omitempty"`
+	Age      uint        `json:"a,omitempty"`
+	// Offset is where searches resume, as they cannot be resumed by user.
+	Offset int `json:"o,omitempty"`
+}
+
+func encodeCursor(c cursor) string {
+	bs, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(bs)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	bs, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor{}, err
+	}
+
+	c := cursor{}
+
+	if err := json.Unmarshal(bs, &c); err != nil {
+		return cursor{}, err
+	}
+
+	return c, nil
+}
+
+func listUsers(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	q := r.URL.Query()
+
+	o := atmail.ListUsersOptions{
+		Limit:          listUsersDefaultLimit,
+		Sort:           atmail.SortId,
+		UsernamePrefix: q.Get("username"),
+		EmailPrefix:    q.Get("email"),
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > listUsersMaxLimit {
+			return badRequest("limit is invalid!"), nil
+		}
+
+		o.Limit = limit
+	}
+
+	// a leading "-" sorts descending, e.g. "-age"
+	if v := q.Get("sort"); v != "" {
+		v, o.Desc = strings.CutPrefix(v, "-")
+		o.Sort = atmail.Sort(v)
+
+		if !o.Sort.Valid() {
+			return badRequest("sort is invalid!"), nil
+		}
+	}
+
+	for name, age := range map[string]*uint{"min_age": &o.MinAge, "max_age": &o.MaxAge} {
+		if v := q.Get(name); v != "" {
+			n, err := strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				return badRequest(name + " is invalid!"), nil
+			}
+
+			*age = uint(n)
+		}
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		c, err := decodeCursor(v)
+
+		// cursors cannot be reused with another sort
+		if err != nil || c.Sort != o.Sort || c.Desc != o.Desc {
+			return badRequest("cursor is invalid!"), nil
+		}
+
+		o.After = &atmail.User{Id: c.Id, Username: c.Username, Age: c.Age}
+	}
+
+	// fetch one more user to know whether there is a next page
+	o.Limit++
+
+	users, err := s.ListUsers(r.Context(), o)
+	if err != nil {
+		return nil, err
+	}
+
+	out := listUsersOutload{Items: []userOutload{}}
+
+	if len(users) == o.Limit {
+		users = users[:len(users)-1]
+
+		last := users[len(users)-1]
+
+		c := cursor{Sort: o.Sort, Desc: o.Desc, Id: last.Id}
+
+		switch o.Sort {
+		case atmail.SortUsername:
+			c.Username = last.Username
+		case atmail.SortAge:
+			c.Age = last.Age
+		}
+
+		out.NextCursor = encodeCursor(c)
+	}
+
+	for _, user := range users {
+		out.Items = append(out.Items, userOutload{
+			Id:       user.Id,
+			Username: user.Username,
+			Email:    user.Email,
+			Age:      user.Age,
+		})
+	}
+
+	return out, nil
+}
+
+type userMatchOutload struct {
+	User    userOutload `json:"user"`
+	Matched []string    `json:"matched"`
+}
+
+type searchUsersOutload struct {
+	Items      []userMatchOutload `json:"items"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+func (o searchUsersOutload) code() int {
+	return http.StatusOK
+}
+
+func searchUsers(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	searcher, ok := s.(atmail.Searcher)
+	if !ok {
+		return nil, errors.New("store cannot search users")
+	}
+
+	q := r.URL.Query()
+
+	query := q.Get("q")
+
+	if len(atmail.SearchTerms(query)) == 0 {
+		return badRequest("q is invalid!"), nil
+	}
+
+	limit := listUsersDefaultLimit
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > listUsersMaxLimit {
+			return badRequest("limit is invalid!"), nil
+		}
+
+		limit = n
+	}
+
+	// results are ranked by relevance, so pages are resumed by offset
+	offset := 0
+
+	if v := q.Get("cursor"); v != "" {
+		c, err := decodeCursor(v)
+		if err != nil || c.Offset < 1 {
+			return badRequest("cursor is invalid!"), nil
+		}
+
+		offset = c.Offset
+	}
+
+	// fetch one more match to know whether there is a next page
+	matches, err := searcher.SearchUsers(r.Context(), query, limit+1, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	o := searchUsersOutload{Items: []userMatchOutload{}}
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+
+		o.NextCursor = encodeCursor(cursor{Offset: offset + limit})
+	}
+
+	for _, match := range matches {
+		o.Items = append(o.Items, userMatchOutload{
+			User: userOutload{
+				Id:       match.User.Id,
+				Username: match.User.Username,
+				Email:    match.User.Email,
+				Age:      match.User.Age,
+			},
+			Matched: match.Fields,
+		})
+	}
+
+	return o, nil
+}
@@ -7,10 +7,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
-	"strconv"
 	"testing"
+	"time"
 
 	"atmail"
+	"atmail/memstore"
+	"atmail/server/search"
 )
 
 func TestCreateUserOk(t *testing.T) {
@@ -31,16 +33,14 @@ func TestCreateUserOk(t *testing.T) {
 
 	rr := httptest.NewRecorder()
 
-	s := fakeStore{newUserId: 1234}
-
 	want := createUserOutload{
-		Id:       1234,
+		Id:       1,
 		Username: "johndoe",
 		Email:    "john@doe.com",
 		Age:      42,
 	}
 
-	got, err := createUser(s, rr, req)
+	got, err := createUser(memstore.New(), rr, req)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -51,12 +51,9 @@ func TestCreateUserOk(t *testing.T) {
 }
 
 func TestCreateUserNotOk(t *testing.T) {
-	store := fakeStore{
-		existingUser: atmail.User{
-			Username: "existinguser",
-			Email:    "existing@email.com",
-		},
-	}
+	store := newTestStore(t, memstore.Seed{
+		Users: []memstore.SeedUser{{Username: "existinguser", Email: "existing@email.com", Age: 30}},
+	})
 
 	for name, tc := range map[string]struct {
 		inload string
@@ -93,7 +90,7 @@ func TestCreateUserNotOk(t *testing.T) {
 		"username or email already exists": {
 			inload: `{ "username": "existinguser", "email": "existing@email.com", "age": 1 }`,
 			want: errorOutload{
-				Code:  http.StatusBadRequest,
+				Code:  http.StatusConflict,
 				Error: "username/email already exists!",
 			},
 		},
@@ -118,22 +115,65 @@ func TestCreateUserNotOk(t *testing.T) {
 	}
 }
 
-func TestGetUserOk(t *testing.T) {
-	s := fakeStore{
-		existingUser: atmail.User{
-			Id:       4321,
-			Username: "janedoe",
-			Email:    "jane@doe.com",
-			Age:      24,
+func TestCreateUserConflict(t *testing.T) {
+	for name, tc := range map[string]struct {
+		store      faultStore
+		want       outload
+		committed  int
+		rolledBack int
+	}{
+		"ok": {
+			store:     faultStore{Store: memstore.New()},
+			want:      createUserOutload{Id: 1, Username: "johndoe", Email: "john@doe.com", Age: 42},
+			committed: 1,
 		},
+		"created concurrently": {
+			store:      faultStore{Store: memstore.New(), createUserErr: atmail.ErrUserExists{Field: "email"}},
+			want:       conflict("email already exists!"),
+			rolledBack: 1,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tc.store.txs = &txCounts{}
+
+			req, err := http.NewRequest("POST", "/users", bytes.NewBufferString(`{ "username": "johndoe", "email": "john@doe.com", "age": 42 }`))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			got, err := createUser(tc.store, rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+
+			if got := tc.store.txs.committed; got != tc.committed {
+				t.Errorf("want %v; got %v", tc.committed, got)
+			}
+
+			if got := tc.store.txs.rolledBack; got != tc.rolledBack {
+				t.Errorf("want %v; got %v", tc.rolledBack, got)
+			}
+		})
 	}
+}
+
+func TestGetUserOk(t *testing.T) {
+	s := newTestStore(t, memstore.Seed{
+		Users: []memstore.SeedUser{{Id: 4321, Username: "janedoe", Email: "jane@doe.com", Age: 24}},
+	})
 
-	req, err := http.NewRequest("GET", fmt.Sprintf("/users/%d", s.existingUser.Id), nil)
+	req, err := http.NewRequest("GET", "/users/4321", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	req.SetPathValue("id", strconv.FormatInt(s.existingUser.Id, 10))
+	req.SetPathValue("id", "4321")
 
 	rr := httptest.NewRecorder()
 
@@ -157,7 +197,7 @@ func TestGetUserOk(t *testing.T) {
 func TestGetUserNotOk(t *testing.T) {
 	for name, tc := range map[string]struct {
 		want  outload
-		store fakeStore
+		store *memstore.Store
 		id    string
 	}{
 		"user does not exist": {
@@ -165,7 +205,7 @@ func TestGetUserNotOk(t *testing.T) {
 				Code:  http.StatusBadRequest,
 				Error: "user does not exist!",
 			},
-			store: fakeStore{},
+			store: memstore.New(),
 			id:    "999",
 		},
 		"invalid id format": {
@@ -173,7 +213,7 @@ func TestGetUserNotOk(t *testing.T) {
 				Code:  http.StatusBadRequest,
 				Error: "user does not exist!",
 			},
-			store: fakeStore{},
+			store: memstore.New(),
 			id:    "some-invalid-id",
 		},
 	} {
@@ -200,47 +240,15 @@ func TestGetUserNotOk(t *testing.T) {
 }
 
 func TestUpdateUserOk(t *testing.T) {
-	store := fakeStore{
-		existingUser: atmail.User{
-			Id:       1234,
-			Username: "some-username",
-			Email:    "valid@email.com",
-			Age:      64,
-		},
-	}
+	store := newTestStore(t, memstore.Seed{
+		Users: []memstore.SeedUser{{Id: 1234, Username: "some-username", Email: "valid@email.com", Age: 64}},
+	})
 
 	for name, tc := range map[string]struct {
 		inload string
 		id     string
 		want   outload
 	}{
-		"username only provided": {
-			inload: `{ "username": "something-else" }`,
-			want: updateUserOutload{
-				Id:       1234,
-				Username: "something-else",
-				Email:    "valid@email.com",
-				Age:      64,
-			},
-		},
-		"email only provided": {
-			inload: `{ "email": "foo@bar.com" }`,
-			want: updateUserOutload{
-				Id:       1234,
-				Username: "some-username",
-				Email:    "foo@bar.com",
-				Age:      64,
-			},
-		},
-		"age only provided ": {
-			inload: `{ "age": 123 }`,
-			want: updateUserOutload{
-				Id:       1234,
-				Username: "some-username",
-				Email:    "valid@email.com",
-				Age:      123,
-			},
-		},
 		"all provided": {
 			inload: `{ "username": "bandit", "email": "new@email.com", "age": 321 }`,
 			want: updateUserOutload{
@@ -274,14 +282,9 @@ func TestUpdateUserOk(t *testing.T) {
 }
 
 func TestUpdateUserNotOk(t *testing.T) {
-	store := fakeStore{
-		existingUser: atmail.User{
-			Id:       1234,
-			Username: "some-username",
-			Email:    "valid@email.com",
-			Age:      64,
-		},
-	}
+	store := newTestStore(t, memstore.Seed{
+		Users: []memstore.SeedUser{{Id: 1234, Username: "some-username", Email: "valid@email.com", Age: 64}},
+	})
 
 	for name, tc := range map[string]struct {
 		inload string
@@ -289,28 +292,42 @@ func TestUpdateUserNotOk(t *testing.T) {
 		want   outload
 	}{
 		"empty body": {
-			inload: `{ "username": "" }`,
+			inload: `{}`,
 			want: errorOutload{
 				Code:  http.StatusBadRequest,
-				Error: "username cannot be blank!",
+				Error: "username, email and age are required!",
+			},
+		},
+		"missing age": {
+			inload: `{ "username": "bandit", "email": "new@email.com" }`,
+			want: errorOutload{
+				Code:  http.StatusBadRequest,
+				Error: "username, email and age are required!",
+			},
+		},
+		"invalid json": {
+			inload: `{ "username": `,
+			want: errorOutload{
+				Code:  http.StatusBadRequest,
+				Error: "invalid json!",
 			},
 		},
 		"blank username": {
-			inload: `{ "username": "" }`,
+			inload: `{ "username": "", "email": "valid@email.com", "age": 64 }`,
 			want: errorOutload{
 				Code:  http.StatusBadRequest,
 				Error: "username cannot be blank!",
 			},
 		},
 		"invalid email": {
-			inload: `{ "email": "invalid-email"}`,
+			inload: `{ "username": "some-username", "email": "invalid-email", "age": 64 }`,
 			want: errorOutload{
 				Code:  http.StatusBadRequest,
 				Error: "email is invalid!",
 			},
 		},
 		"invalid age": {
-			inload: `{ "age": 0 }`,
+			inload: `{ "username": "some-username", "email": "valid@email.com", "age": 0 }`,
 			want: errorOutload{
 				Code:  http.StatusBadRequest,
 				Error: "age is invalid!",
@@ -339,26 +356,270 @@ func TestUpdateUserNotOk(t *testing.T) {
 	}
 }
 
-func TestDeleteUserOk(t *testing.T) {
-	store := fakeStore{}
+func TestPatchUser(t *testing.T) {
+	for name, tc := range map[string]struct {
+		contentType string
+		inload      string
+		want        outload
+	}{
+		"merge patch": {
+			contentType: "application/merge-patch+json",
+			inload:      `{ "username": "something-else" }`,
+			want:        updateUserOutload{Id: 1234, Username: "something-else", Email: "valid@email.com", Age: 64},
+		},
+		"merge patch with charset": {
+			contentType: "application/merge-patch+json; charset=utf-8",
+			inload:      `{ "email": "foo@bar.com", "age": 123 }`,
+			want:        updateUserOutload{Id: 1234, Username: "some-username", Email: "foo@bar.com", Age: 123},
+		},
+		"merge patch clears field": {
+			contentType: "application/merge-patch+json",
+			inload:      `{ "username": null }`,
+			want:        badRequest("username cannot be blank!"),
+		},
+		"merge patch unknown field": {
+			contentType: "application/merge-patch+json",
+			inload:      `{ "name": "bandit" }`,
+			want:        badRequest("patched user is invalid!"),
+		},
+		"merge patch wrong type": {
+			contentType: "application/merge-patch+json",
+			inload:      `{ "age": "old" }`,
+			want:        badRequest("patched user is invalid!"),
+		},
+		"json patch": {
+			contentType: "application/json-patch+json",
+			inload:      `[{ "op": "test", "path": "/username", "value": "some-username" }, { "op": "replace", "path": "/username", "value": "bandit" }, { "op": "replace", "path": "/age", "value": 40 }]`,
+			want:        updateUserOutload{Id: 1234, Username: "bandit", Email: "valid@email.com", Age: 40},
+		},
+		"json patch test failed": {
+			contentType: "application/json-patch+json",
+			inload:      `[{ "op": "test", "path": "/username", "value": "bandit" }, { "op": "replace", "path": "/username", "value": "chilli" }]`,
+			want:        conflict("patch test failed!"),
+		},
+		"json patch missing path": {
+			contentType: "application/json-patch+json",
+			inload:      `[{ "op": "replace", "path": "/name", "value": "bandit" }]`,
+			want:        badRequest("patch path does not exist!"),
+		},
+		"json patch removes field": {
+			contentType: "application/json-patch+json",
+			inload:      `[{ "op": "remove", "path": "/age" }]`,
+			want:        badRequest("age is invalid!"),
+		},
+		"json patch invalid": {
+			contentType: "application/json-patch+json",
+			inload:      `{ "username": "bandit" }`,
+			want:        badRequest("patch is invalid!"),
+		},
+		"json patch changes id": {
+			contentType: "application/json-patch+json",
+			inload:      `[{ "op": "replace", "path": "/id", "value": 1 }]`,
+			want:        badRequest("id cannot be changed!"),
+		},
+		"unsupported content type": {
+			contentType: "application/json",
+			inload:      `{ "username": "bandit" }`,
+			want:        errorOutload{http.StatusUnsupportedMediaType, "content type must be application/merge-patch+json or application/json-patch+json!"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			store := newTestStore(t, memstore.Seed{
+				Users: []memstore.SeedUser{{Id: 1234, Username: "some-username", Email: "valid@email.com", Age: 64}},
+			})
+
+			req, err := http.NewRequest("PATCH", "/users/1234", bytes.NewBufferString(tc.inload))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.SetPathValue("id", "1234")
+
+			req.Header.Set("Content-Type", tc.contentType)
+
+			rr := httptest.NewRecorder()
+
+			got, err := patchUser(store, rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestGetUserETag(t *testing.T) {
+	store := newTestStore(t, memstore.Seed{
+		Users: []memstore.SeedUser{{Id: 1, Username: "johndoe", Email: "john@doe.com", Age: 42}},
+	})
+
+	bumpVersion(t, store, 1, 2)
 
 	for name, tc := range map[string]struct {
-		id   string
-		want outload
+		ifNoneMatch string
+		want        int
+	}{
+		"no header": {
+			want: http.StatusOK,
+		},
+		"match": {
+			ifNoneMatch: `"3"`,
+			want:        http.StatusNotModified,
+		},
+		"weak match": {
+			ifNoneMatch: `"2", W/"3"`,
+			want:        http.StatusNotModified,
+		},
+		"any": {
+			ifNoneMatch: `*`,
+			want:        http.StatusNotModified,
+		},
+		"no match": {
+			ifNoneMatch: `"2"`,
+			want:        http.StatusOK,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/users/1", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.SetPathValue("id", "1")
+
+			if tc.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tc.ifNoneMatch)
+			}
+
+			rr := httptest.NewRecorder()
+
+			got, err := getUser(store, rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.code() != tc.want {
+				t.Errorf("want %v; got %v", tc.want, got.code())
+			}
+
+			if want, got := `"3"`, rr.Header().Get("ETag"); want != got {
+				t.Errorf("want %v; got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestUpdateUserPrecondition(t *testing.T) {
+	for name, tc := range map[string]struct {
+		ifMatch           string
+		concurrentUpdates int
+		want              outload
+		etag              string
+	}{
+		"match": {
+			ifMatch: `"3"`,
+			want:    updateUserOutload{Id: 1, Username: "janedoe", Email: "john@doe.com", Age: 42},
+			etag:    `"4"`,
+		},
+		"no header": {
+			want: updateUserOutload{Id: 1, Username: "janedoe", Email: "john@doe.com", Age: 42},
+			etag: `"4"`,
+		},
+		"no match": {
+			ifMatch: `"2"`,
+			want:    preconditionFailed(),
+		},
+		"weak tags do not match": {
+			ifMatch: `W/"3"`,
+			want:    preconditionFailed(),
+		},
+		"updated concurrently": {
+			ifMatch:           `"3"`,
+			concurrentUpdates: 1,
+			want:              preconditionFailed(),
+		},
+		"updated concurrently without header": {
+			concurrentUpdates: 1,
+			want:              conflict("user was updated concurrently!"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			store := newTestStore(t, memstore.Seed{
+				Users: []memstore.SeedUser{{Id: 1, Username: "johndoe", Email: "john@doe.com", Age: 42}},
+			})
+
+			bumpVersion(t, store, 1, 2)
+
+			req, err := http.NewRequest("PUT", "/users/1", bytes.NewBufferString(`{ "username": "janedoe", "email": "john@doe.com", "age": 42 }`))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.SetPathValue("id", "1")
+
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+
+			rr := httptest.NewRecorder()
+
+			got, err := updateUser(faultStore{Store: store, concurrentUpdates: tc.concurrentUpdates}, rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+
+			if got := rr.Header().Get("ETag"); got != tc.etag {
+				t.Errorf("want %v; got %v", tc.etag, got)
+			}
+		})
+	}
+}
+
+func TestDeleteUserOk(t *testing.T) {
+	for name, tc := range map[string]struct {
+		id    string
+		query string
+		want  outload
 	}{
 		"ok": {
 			id:   "12345",
 			want: messageOutload{"successfully deleted user 12345!"},
 		},
+		"hard": {
+			id:    "12345",
+			query: "hard=true",
+			want:  messageOutload{"successfully purged user 12345!"},
+		},
+		"not hard": {
+			id:    "12345",
+			query: "hard=false",
+			want:  messageOutload{"successfully deleted user 12345!"},
+		},
+		"invalid hard": {
+			id:    "12345",
+			query: "hard=yes",
+			want:  badRequest("hard is invalid!"),
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
-			req, err := http.NewRequest("DELETE", fmt.Sprintf("/users/%s", tc.id), nil)
+			req, err := http.NewRequest("DELETE", fmt.Sprintf("/users/%s?%s", tc.id, tc.query), nil)
 			if err != nil {
 				t.Fatal(err)
 			}
 
 			req.SetPathValue("id", tc.id)
 
+			store := newTestStore(t, memstore.Seed{
+				Users: []memstore.SeedUser{{Id: 12345, Username: "johndoe", Email: "john@doe.com", Age: 42}},
+			})
+
 			rr := httptest.NewRecorder()
 
 			got, err := deleteUser(store, rr, req)
@@ -372,3 +633,282 @@ func TestDeleteUserOk(t *testing.T) {
 		})
 	}
 }
+
+func TestRestoreUser(t *testing.T) {
+	deletedAt := time.Now()
+
+	store := newTestStore(t, memstore.Seed{
+		Users: []memstore.SeedUser{
+			{Id: 1, Username: "johndoe", Email: "john@doe.com", Age: 42, DeletedAt: &deletedAt},
+			{Id: 2, Username: "janedoe", Email: "jane@doe.com", Age: 24},
+		},
+	})
+
+	for name, tc := range map[string]struct {
+		id   string
+		want outload
+		etag string
+	}{
+		"ok": {
+			id:   "1",
+			want: getUserOutload{Id: 1, Username: "johndoe", Email: "john@doe.com", Age: 42},
+			etag: `"2"`,
+		},
+		"not deleted": {
+			id:   "2",
+			want: badRequest("deleted user does not exist!"),
+		},
+		"invalid id": {
+			id:   "john",
+			want: badRequest("deleted user does not exist!"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", fmt.Sprintf("/users/%s/restore", tc.id), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.SetPathValue("id", tc.id)
+
+			rr := httptest.NewRecorder()
+
+			got, err := restoreUser(store, rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+
+			if got := rr.Header().Get("ETag"); got != tc.etag {
+				t.Errorf("want %v; got %v", tc.etag, got)
+			}
+		})
+	}
+}
+
+var testUsers = []memstore.SeedUser{
+	{Id: 1, Username: "bingo", Email: "bingo@heeler.com", Age: 6},
+	{Id: 2, Username: "bluey", Email: "bluey@heeler.com", Age: 7},
+	{Id: 3, Username: "chilli", Email: "chilli@heeler.com", Age: 38},
+	{Id: 4, Username: "bandit", Email: "bandit@heeler.com", Age: 40},
+	{Id: 5, Username: "muffin", Email: "muffin@cattle.com", Age: 3},
+	{Id: 6, Username: "socks", Email: "socks@cattle.com", Age: 7},
+}
+
+func TestListUsersOk(t *testing.T) {
+	store := newTestStore(t, memstore.Seed{Users: testUsers})
+
+	for name, tc := range map[string]struct {
+		query string
+		want  []int64
+	}{
+		"defaults": {
+			query: "",
+			want:  []int64{1, 2, 3, 4, 5, 6},
+		},
+		"sort by username": {
+			query: "sort=username",
+			want:  []int64{4, 1, 2, 3, 5, 6},
+		},
+		"sort by age descending": {
+			query: "sort=-age",
+			want:  []int64{4, 3, 6, 2, 1, 5},
+		},
+		"username prefix": {
+			query: "username=b",
+			want:  []int64{1, 2, 4},
+		},
+		"email prefix": {
+			query: "email=muffin@",
+			want:  []int64{5},
+		},
+		"age range": {
+			query: "min_age=6&max_age=7",
+			want:  []int64{1, 2, 6},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got := []int64{}
+
+			// page through two users at a time
+			next := ""
+
+			for range len(testUsers) {
+				req, err := http.NewRequest("GET", "/users?limit=2&"+tc.query+"&cursor="+next, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				rr := httptest.NewRecorder()
+
+				res, err := listUsers(store, rr, req)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				o, ok := res.(listUsersOutload)
+				if !ok {
+					t.Fatalf("want listUsersOutload; got %v", res)
+				}
+
+				for _, item := range o.Items {
+					got = append(got, item.Id)
+				}
+
+				if o.NextCursor == "" {
+					break
+				}
+
+				next = o.NextCursor
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestListUsersNotOk(t *testing.T) {
+	store := newTestStore(t, memstore.Seed{Users: testUsers})
+
+	for name, tc := range map[string]struct {
+		query string
+		want  outload
+	}{
+		"limit too large": {
+			query: "limit=101",
+			want:  badRequest("limit is invalid!"),
+		},
+		"limit not a number": {
+			query: "limit=ten",
+			want:  badRequest("limit is invalid!"),
+		},
+		"unknown sort": {
+			query: "sort=email",
+			want:  badRequest("sort is invalid!"),
+		},
+		"negative age": {
+			query: "min_age=-1",
+			want:  badRequest("min_age is invalid!"),
+		},
+		"malformed cursor": {
+			query: "cursor=!!!",
+			want:  badRequest("cursor is invalid!"),
+		},
+		"cursor of another sort": {
+			query: "sort=age&cursor=" + encodeCursor(cursor{Sort: atmail.SortUsername, Id: 1, Username: "bingo"}),
+			want:  badRequest("cursor is invalid!"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/users?"+tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			got, err := listUsers(store, rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSearchUsersOk(t *testing.T) {
+	store := search.NewStore(newTestStore(t, memstore.Seed{Users: testUsers}))
+
+	got := []userMatchOutload{}
+
+	// page through one match at a time
+	next := ""
+
+	for range len(testUsers) {
+		req, err := http.NewRequest("GET", "/users/search?q=cattle&limit=1&cursor="+next, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		res, err := searchUsers(store, rr, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		o, ok := res.(searchUsersOutload)
+		if !ok {
+			t.Fatalf("want searchUsersOutload; got %v", res)
+		}
+
+		got = append(got, o.Items...)
+
+		if o.NextCursor == "" {
+			break
+		}
+
+		next = o.NextCursor
+	}
+
+	want := []userMatchOutload{
+		{userOutload{6, "socks", "socks@cattle.com", 7}, []string{"email"}},
+		{userOutload{5, "muffin", "muffin@cattle.com", 3}, []string{"email"}},
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v; got %v", want, got)
+	}
+}
+
+func TestSearchUsersNotOk(t *testing.T) {
+	store := search.NewStore(newTestStore(t, memstore.Seed{Users: testUsers}))
+
+	for name, tc := range map[string]struct {
+		query string
+		want  outload
+	}{
+		"missing q": {
+			query: "",
+			want:  badRequest("q is invalid!"),
+		},
+		"q without terms": {
+			query: "q=@.",
+			want:  badRequest("q is invalid!"),
+		},
+		"limit too large": {
+			query: "q=bingo&limit=101",
+			want:  badRequest("limit is invalid!"),
+		},
+		"malformed cursor": {
+			query: "q=bingo&cursor=!!!",
+			want:  badRequest("cursor is invalid!"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/users/search?"+tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			got, err := searchUsers(store, rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
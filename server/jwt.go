@@ -0,0 +1,561 @@
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errKeyNone = errors.New("error key none")
+
+// JWTConfig configures how a JWTAuthenticator validates claims and maps them to
+// roles.
+type JWTConfig struct {
+	// Issuer, if set, must equal the "iss" claim.
+	Issuer string
+	// Audience, if set, must be contained in the "aud" claim.
+	Audience string
+	// RoleClaim is the claim holding the roles, nested claims are separated by
+	// dots, e.g. "realm_access.roles". Defaults to "roles".
+	RoleClaim string
+	// Roles maps the values of RoleClaim to role names. By default the values
+	// are role names.
+	Roles map[string]string
+	// Leeway is the clock skew tolerated when checking "exp" and "nbf".
+	Leeway time.Duration
+}
+
+// JWTAuthenticator verifies RS256, ES256 and EdDSA signed JWTs against a JWKS.
+type JWTAuthenticator struct {
+	keys   *JWKS
+	config JWTConfig
+	now    func() time.Time
+}
+
+func NewJWTAuthenticator(keys *JWKS, config JWTConfig) *JWTAuthenticator {
+	if config.RoleClaim == "" {
+		config.RoleClaim = "roles"
+	}
+
+	return &JWTAuthenticator{keys, config, time.Now}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Issuer    string          `json:"iss"`
+	Subject   string          `json:"sub"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+}
+
+// Authenticate verifies "Authorization: Bearer" tokens that are JWTs. Other
+// bearer tokens are left to the next Authenticator.
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || strings.Count(token, ".") != 2 {
+		return Principal{}, ErrNoCredentials
+	}
+
+	return a.authenticate(r.Context(), token)
+}
+
+func (a *JWTAuthenticator) Scheme() string {
+	return "Bearer"
+}
+
+func (a *JWTAuthenticator) authenticate(ctx context.Context, token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+
+	if len(parts) != 3 {
+		return Principal{}, fmt.Errorf("%w: malformed jwt", ErrInvalidCredentials)
+	}
+
+	header := jwtHeader{}
+
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Principal{}, fmt.Errorf("%w: malformed jwt header", ErrInvalidCredentials)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: malformed jwt signature", ErrInvalidCredentials)
+	}
+
+	keys, err := a.keys.find(ctx, header.Kid)
+	if err != nil {
+		if !errors.Is(err, errKeyNone) {
+			return Principal{}, err
+		}
+
+		return Principal{}, fmt.Errorf("%w: unknown key %q", ErrInvalidCredentials, header.Kid)
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+
+	if !verifyAny(keys, header.Alg, signed, signature) {
+		return Principal{}, fmt.Errorf("%w: invalid jwt signature", ErrInvalidCredentials)
+	}
+
+	// the payload is only trusted once the signature checks out
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: malformed jwt payload", ErrInvalidCredentials)
+	}
+
+	claims := jwtClaims{}
+
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return Principal{}, fmt.Errorf("%w: malformed jwt claims", ErrInvalidCredentials)
+	}
+
+	if err := a.validate(claims); err != nil {
+		return Principal{}, err
+	}
+
+	raw := map[string]any{}
+
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return Principal{}, fmt.Errorf("%w: malformed jwt claims", ErrInvalidCredentials)
+	}
+
+	roles := a.roles(raw)
+
+	if len(roles) == 0 {
+		return Principal{}, fmt.Errorf("%w: jwt grants no roles", ErrInvalidCredentials)
+	}
+
+	return Principal{claims.Subject, roles, MethodJWT}, nil
+}
+
+func (a *JWTAuthenticator) validate(claims jwtClaims) error {
+	now := a.now()
+
+	if a.config.Issuer != "" && claims.Issuer != a.config.Issuer {
+		return fmt.Errorf("%w: jwt issuer mismatch", ErrInvalidCredentials)
+	}
+
+	if a.config.Audience != "" && !containsAudience(claims.Audience, a.config.Audience) {
+		return fmt.Errorf("%w: jwt audience mismatch", ErrInvalidCredentials)
+	}
+
+	if claims.ExpiresAt == nil || !now.Before(time.Unix(*claims.ExpiresAt, 0).Add(a.config.Leeway)) {
+		return fmt.Errorf("%w: jwt expired", ErrInvalidCredentials)
+	}
+
+	if claims.NotBefore != nil && now.Add(a.config.Leeway).Before(time.Unix(*claims.NotBefore, 0)) {
+		return fmt.Errorf("%w: jwt not yet valid", ErrInvalidCredentials)
+	}
+
+	if claims.Subject == "" {
+		return fmt.Errorf("%w: jwt subject missing", ErrInvalidCredentials)
+	}
+
+	return nil
+}
+
+// roles maps the role claim to role names. The claim may be a role name or a
+// list of role names.
+func (a *JWTAuthenticator) roles(claims map[string]any) []string {
+	var v any = claims
+
+	for _, key := range strings.Split(a.config.RoleClaim, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+
+		v = m[key]
+	}
+
+	names := []string{}
+
+	switch v := v.(type) {
+	case string:
+		names = append(names, v)
+	case []any:
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				names = append(names, s)
+			}
+		}
+	}
+
+	return mapRoles(a.config.Roles, names)
+}
+
+func containsAudience(raw json.RawMessage, audience string) bool {
+	var one string
+
+	if err := json.Unmarshal(raw, &one); err == nil {
+		return one == audience
+	}
+
+	var many []string
+
+	if err := json.Unmarshal(raw, &many); err == nil {
+		for _, aud := range many {
+			if aud == audience {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func decodeSegment(segment string, v any) error {
+	bs, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(bs, v)
+}
+
+func verifyAny(keys []crypto.PublicKey, alg string, signed []byte, signature []byte) bool {
+	for _, key := range keys {
+		if verify(key, alg, signed, signature) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// verify checks the signature, making sure alg matches the type of key so that
+// a token cannot pick a weaker algorithm than the key was published for.
+func verify(key crypto.PublicKey, alg string, signed []byte, signature []byte) bool {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if alg != "RS256" {
+			return false
+		}
+
+		sum := sha256.Sum256(signed)
+
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], signature) == nil
+	case *ecdsa.PublicKey:
+		if alg != "ES256" || key.Curve != elliptic.P256() || len(signature) != 64 {
+			return false
+		}
+
+		sum := sha256.Sum256(signed)
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+
+		return ecdsa.Verify(key, sum[:], r, s)
+	case ed25519.PublicKey:
+		if alg != "EdDSA" {
+			return false
+		}
+
+		return ed25519.Verify(key, signed, signature)
+	default:
+		return false
+	}
+}
+
+// JWKS is a JSON Web Key Set loaded from a file or URL. Keys are cached and
+// reloaded once TTL has passed, or sooner when a token names an unknown key
+// so that rotated keys are picked up.
+type JWKS struct {
+	load func(context.Context) ([]byte, error)
+
+	// TTL is how long keys are cached for.
+	TTL time.Duration
+	// MinRefresh limits how often unknown keys trigger a reload.
+	MinRefresh time.Duration
+
+	mu     sync.Mutex
+	keys   map[string][]crypto.PublicKey
+	loaded time.Time
+	// tried is when the keys were last reloaded, whether it failed or not,
+	// and err how it failed
+	tried time.Time
+	err   error
+	// reloading is closed once the reload in progress is done, nil when
+	// there is none
+	reloading chan struct{}
+}
+
+// NewJWKSFile returns a JWKS read from the file at path.
+func NewJWKSFile(path string) *JWKS {
+	return newJWKS(func(context.Context) ([]byte, error) {
+		return os.ReadFile(path)
+	})
+}
+
+// NewJWKSURL returns a JWKS fetched from url.
+func NewJWKSURL(url string, client *http.Client) *JWKS {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return newJWKS(func(ctx context.Context) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetching jwks from %s: unexpected status %s", url, res.Status)
+		}
+
+		return io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	})
+}
+
+func newJWKS(load func(context.Context) ([]byte, error)) *JWKS {
+	return &JWKS{
+		load:       load,
+		TTL:        time.Hour,
+		MinRefresh: time.Minute,
+	}
+}
+
+func (k *JWKS) find(ctx context.Context, kid string) ([]crypto.PublicKey, error) {
+	k.mu.Lock()
+	stale := k.keys == nil || time.Since(k.loaded) > k.TTL
+	k.mu.Unlock()
+
+	if stale {
+		if err := k.refresh(ctx); err != nil {
+			k.mu.Lock()
+			none := k.keys == nil
+			k.mu.Unlock()
+
+			// keep serving stale keys rather than failing every request
+			if none {
+				return nil, err
+			}
+
+			log.Printf("failed to refresh jwks: %v", err)
+		}
+	}
+
+	keys := k.lookup(kid)
+
+	if len(keys) == 0 {
+		if err := k.refresh(ctx); err != nil {
+			return nil, err
+		}
+
+		keys = k.lookup(kid)
+	}
+
+	if len(keys) == 0 {
+		return nil, errKeyNone
+	}
+
+	return keys, nil
+}
+
+// lookup returns the cached keys of kid.
+func (k *JWKS) lookup(kid string) []crypto.PublicKey {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.keys[kid]
+}
+
+// refresh reloads the keys, at most once every MinRefresh whether reloads
+// fail or not, so that an identity provider that is down is not fetched from
+// on every request. The keys are loaded outside of mu, one reload at a time
+// that concurrent callers wait for, and which does not end with the request
+// that started it.
+func (k *JWKS) refresh(ctx context.Context) error {
+	k.mu.Lock()
+
+	if reloading := k.reloading; reloading != nil {
+		k.mu.Unlock()
+
+		select {
+		case <-reloading:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		return k.err
+	}
+
+	if !k.tried.IsZero() && time.Since(k.tried) < k.MinRefresh {
+		defer k.mu.Unlock()
+		return k.err
+	}
+
+	reloading := make(chan struct{})
+	k.reloading = reloading
+
+	k.mu.Unlock()
+
+	keys, err := k.reload(context.WithoutCancel(ctx))
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.tried, k.err, k.reloading = time.Now(), err, nil
+
+	if err == nil {
+		k.keys, k.loaded = keys, k.tried
+	}
+
+	close(reloading)
+
+	return err
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// reload loads and parses the keys.
+func (k *JWKS) reload(ctx context.Context) (map[string][]crypto.PublicKey, error) {
+	bs, err := k.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+
+	if err := json.Unmarshal(bs, &set); err != nil {
+		return nil, fmt.Errorf("parsing jwks: %w", err)
+	}
+
+	// keys are indexed by kid, and all of them under "" for tokens without one
+	keys := map[string][]crypto.PublicKey{}
+
+	for _, j := range set.Keys {
+		if j.Use != "" && j.Use != "sig" {
+			continue
+		}
+
+		key, err := j.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("parsing jwk %q: %w", j.Kid, err)
+		}
+
+		if key == nil {
+			continue
+		}
+
+		if j.Kid != "" {
+			keys[j.Kid] = append(keys[j.Kid], key)
+		}
+
+		keys[""] = append(keys[""], key)
+	}
+
+	return keys, nil
+}
+
+// publicKey returns the key or nil if its type is not supported.
+func (j jwk) publicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeBigInt(j.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(j.E)
+		if err != nil {
+			return nil, err
+		}
+
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("rsa exponent too large")
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if j.Crv != "P-256" {
+			return nil, nil
+		}
+
+		x, err := decodeBigInt(j.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(j.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("point not on curve")
+		}
+
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, nil
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid ed25519 key size")
+		}
+
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, nil
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	bs, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bs) == 0 {
+		return nil, errors.New("empty integer")
+	}
+
+	return new(big.Int).SetBytes(bs), nil
+}
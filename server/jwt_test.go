@@ -0,0 +1,416 @@
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"atmail/memstore"
+)
+
+type testKey struct {
+	kid     string
+	alg     string
+	private crypto.Signer
+}
+
+func newTestKeys(t *testing.T) []testKey {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return []testKey{
+		{"rsa", "RS256", rsaKey},
+		{"ec", "ES256", ecKey},
+		{"ed", "EdDSA", edKey},
+	}
+}
+
+func (k testKey) jwk() map[string]string {
+	b64 := base64.RawURLEncoding.EncodeToString
+
+	switch pub := k.private.Public().(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "kid": k.kid, "n": b64(pub.N.Bytes()), "e": b64(big.NewInt(int64(pub.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		return map[string]string{"kty": "EC", "kid": k.kid, "crv": "P-256", "x": b64(pub.X.FillBytes(make([]byte, 32))), "y": b64(pub.Y.FillBytes(make([]byte, 32)))}
+	case ed25519.PublicKey:
+		return map[string]string{"kty": "OKP", "kid": k.kid, "crv": "Ed25519", "x": b64(pub)}
+	}
+
+	return nil
+}
+
+func (k testKey) sign(t *testing.T, claims map[string]any) string {
+	b64 := base64.RawURLEncoding.EncodeToString
+
+	header, err := json.Marshal(map[string]string{"alg": k.alg, "kid": k.kid, "typ": "JWT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := b64(header) + "." + b64(payload)
+
+	var signature []byte
+
+	switch key := k.private.(type) {
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(key, []byte(signed))
+	case *ecdsa.PrivateKey:
+		sum := sha256.Sum256([]byte(signed))
+
+		r, s, err := ecdsa.Sign(rand.Reader, key, sum[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	case *rsa.PrivateKey:
+		sum := sha256.Sum256([]byte(signed))
+
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return signed + "." + b64(signature)
+}
+
+func jwksJSON(t *testing.T, keys ...testKey) []byte {
+	set := struct {
+		Keys []map[string]string `json:"keys"`
+	}{}
+
+	for _, k := range keys {
+		set.Keys = append(set.Keys, k.jwk())
+	}
+
+	bs, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bs
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"iss":   "https://idp.example.com",
+		"aud":   []string{"atmail", "other"},
+		"sub":   "jane",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nbf":   time.Now().Add(-time.Minute).Unix(),
+		"roles": []string{"chilli", "unknown"},
+	}
+}
+
+var testJWTConfig = JWTConfig{
+	Issuer:   "https://idp.example.com",
+	Audience: "atmail",
+}
+
+func TestJWTAuthenticatorOk(t *testing.T) {
+	keys := newTestKeys(t)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+
+	if err := os.WriteFile(path, jwksJSON(t, keys...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	a := NewJWTAuthenticator(NewJWKSFile(path), testJWTConfig)
+
+	for _, k := range keys {
+		t.Run(k.alg, func(t *testing.T) {
+			got, err := a.authenticate(context.Background(), k.sign(t, validClaims()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want := (Principal{"jane", []string{"chilli", "unknown"}, MethodJWT}); !reflect.DeepEqual(want, got) {
+				t.Errorf("want %v; got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticatorRoleClaim(t *testing.T) {
+	keys := newTestKeys(t)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+
+	if err := os.WriteFile(path, jwksJSON(t, keys...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tc := range map[string]struct {
+		config JWTConfig
+		claims map[string]any
+		want   []string
+	}{
+		"single name": {
+			claims: map[string]any{"roles": "bandit"},
+			want:   []string{"bandit"},
+		},
+		"nested claim with mapping": {
+			config: JWTConfig{
+				RoleClaim: "realm_access.roles",
+				Roles:     map[string]string{"support": "bingo", "ops": "bandit"},
+			},
+			claims: map[string]any{"realm_access": map[string]any{"roles": []string{"support", "ops", "other"}}},
+			want:   []string{"bingo", "bandit"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			a := NewJWTAuthenticator(NewJWKSFile(path), tc.config)
+
+			claims := map[string]any{"sub": "jane", "exp": time.Now().Add(time.Hour).Unix()}
+
+			for k, v := range tc.claims {
+				claims[k] = v
+			}
+
+			got, err := a.authenticate(context.Background(), keys[0].sign(t, claims))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got.Roles) {
+				t.Errorf("want %v; got %v", tc.want, got.Roles)
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticatorUnauthorized(t *testing.T) {
+	keys := newTestKeys(t)
+	other := newTestKeys(t)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+
+	if err := os.WriteFile(path, jwksJSON(t, keys...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	a := NewJWTAuthenticator(NewJWKSFile(path), testJWTConfig)
+
+	with := func(key string, value any) map[string]any {
+		claims := validClaims()
+
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+
+		return claims
+	}
+
+	wrongAlg := keys[0]
+	wrongAlg.alg = "HS256"
+
+	for name, token := range map[string]string{
+		"malformed":          "not.a.jwt",
+		"unknown key":        testKey{"missing", "RS256", other[0].private}.sign(t, validClaims()),
+		"wrong signature":    testKey{"rsa", "RS256", other[0].private}.sign(t, validClaims()),
+		"alg mismatch":       testKey{"rsa", "EdDSA", other[2].private}.sign(t, validClaims()),
+		"unsupported alg":    wrongAlg.sign(t, validClaims()),
+		"wrong issuer":       keys[1].sign(t, with("iss", "https://evil.example.com")),
+		"wrong audience":     keys[1].sign(t, with("aud", "other")),
+		"expired":            keys[1].sign(t, with("exp", time.Now().Add(-time.Hour).Unix())),
+		"missing exp":        keys[1].sign(t, with("exp", nil)),
+		"not yet valid":      keys[1].sign(t, with("nbf", time.Now().Add(time.Hour).Unix())),
+		"missing subject":    keys[1].sign(t, with("sub", nil)),
+		"no roles":           keys[1].sign(t, with("roles", []string{})),
+		"missing role claim": keys[1].sign(t, with("roles", nil)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := a.authenticate(context.Background(), token); !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("want %v; got %v", ErrInvalidCredentials, err)
+			}
+		})
+	}
+}
+
+func TestJWKSURLRotation(t *testing.T) {
+	keys := newTestKeys(t)
+	rotated := newTestKeys(t)
+
+	var mu sync.Mutex
+	var fetches int
+
+	current := jwksJSON(t, keys[0])
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		fetches++
+
+		w.Write(current)
+	}))
+	defer srv.Close()
+
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return fetches
+	}
+
+	jwks := NewJWKSURL(srv.URL, srv.Client())
+
+	jwks.MinRefresh = 0
+
+	a := NewJWTAuthenticator(jwks, testJWTConfig)
+
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := a.authenticate(ctx, keys[0].sign(t, validClaims())); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := count(); got != 1 {
+		t.Errorf("want keys cached after %d fetch; got %d fetches", 1, got)
+	}
+
+	// rotate to a new key with a new kid
+	newKey := rotated[1]
+	newKey.kid = "ec-2"
+
+	mu.Lock()
+	current = jwksJSON(t, newKey)
+	mu.Unlock()
+
+	if _, err := a.authenticate(ctx, newKey.sign(t, validClaims())); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := count(); got != 2 {
+		t.Errorf("want unknown kid to trigger a fetch; got %d fetches", got)
+	}
+
+	if _, err := a.authenticate(ctx, keys[0].sign(t, validClaims())); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("want retired key rejected; got %v", err)
+	}
+}
+
+func TestJWKSReloadFailing(t *testing.T) {
+	keys := newTestKeys(t)
+
+	var mu sync.Mutex
+	var loads int
+
+	// the identity provider goes down after the first load
+	jwks := newJWKS(func(context.Context) ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		loads++
+
+		if loads > 1 {
+			return nil, errors.New("identity provider is down")
+		}
+
+		return jwksJSON(t, keys[0]), nil
+	})
+
+	jwks.TTL = 0
+	jwks.MinRefresh = 50 * time.Millisecond
+
+	ctx := context.Background()
+
+	if _, err := jwks.find(ctx, keys[0].kid); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(jwks.MinRefresh)
+
+	// the failed reload is not retried within MinRefresh, and the stale
+	// keys are served meanwhile
+	var wg sync.WaitGroup
+
+	for range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if _, err := jwks.find(ctx, keys[0].kid); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if _, err := jwks.find(ctx, "unknown"); err == nil {
+		t.Errorf("want error; got nil")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if loads != 2 {
+		t.Errorf("want %v; got %v", 2, loads)
+	}
+}
+
+func TestHandlerServeHTTPJWT(t *testing.T) {
+	keys := newTestKeys(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(jwksJSON(t, keys...))
+	}))
+	defer srv.Close()
+
+	mux := New(newTestStore(t, memstore.Seed{Users: []memstore.SeedUser{{Id: 1, Username: "bingo", Email: "bingo@heeler.com", Age: 6}}}), WithAuthenticators(NewJWTAuthenticator(NewJWKSURL(srv.URL, srv.Client()), testJWTConfig)))
+
+	req := httptest.NewRequest("GET", "/users/1", nil)
+
+	req.Header.Set("Authorization", "Bearer "+keys[2].sign(t, validClaims()))
+
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	if got := rr.Code; got != http.StatusOK {
+		t.Errorf("want %v; got %v: %s", http.StatusOK, got, rr.Body)
+	}
+}
@@ -0,0 +1,372 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalid = errors.New("error patch invalid")
+var ErrPathNone = errors.New("error path none")
+var ErrTestFailed = errors.New("error test failed")
+
+// Merge applies a JSON Merge Patch (RFC 7396) to doc. Members of patch that
+// are null remove the member of doc, others replace it, recursively for
+// objects.
+func Merge(doc []byte, patch []byte) ([]byte, error) {
+	var d, p any
+
+	if err := json.Unmarshal(doc, &d); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, ErrInvalid
+	}
+
+	return json.Marshal(merge(d, p))
+}
+
+func merge(target any, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+
+		t[k] = merge(t[k], v)
+	}
+
+	return t
+}
+
+type operation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Apply applies a JSON Patch (RFC 6902) to doc. The operations are applied in
+// order and either all of them are or, on the first that fails, none.
+func Apply(doc []byte, patch []byte) ([]byte, error) {
+	var d any
+
+	if err := json.Unmarshal(doc, &d); err != nil {
+		return nil, err
+	}
+
+	ops := []operation{}
+
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, ErrInvalid
+	}
+
+	for _, op := range ops {
+		var err error
+
+		d, err = apply(d, op)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(d)
+}
+
+func apply(doc any, op operation) (any, error) {
+	if op.Path == nil {
+		return nil, ErrInvalid
+	}
+
+	path, err := pointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+
+	switch op.Op {
+	case "add", "replace", "test":
+		// a missing value is not the same as null
+		if op.Value == nil {
+			return nil, ErrInvalid
+		}
+
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, ErrInvalid
+		}
+	case "move", "copy":
+		if op.From == nil {
+			return nil, ErrInvalid
+		}
+
+		from, err := pointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+
+		// an object cannot be moved into itself
+		if op.Op == "move" && len(from) < len(path) && reflect.DeepEqual(from, path[:len(from)]) {
+			return nil, ErrInvalid
+		}
+
+		value, err = get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Op == "move" {
+			doc, err = remove(doc, from)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			value = clone(value)
+		}
+	}
+
+	switch op.Op {
+	case "add", "move", "copy":
+		return add(doc, path, value)
+	case "remove":
+		return remove(doc, path)
+	case "replace":
+		if _, err := get(doc, path); err != nil {
+			return nil, err
+		}
+
+		doc, err := remove(doc, path)
+		if err != nil {
+			return nil, err
+		}
+
+		return add(doc, path, value)
+	case "test":
+		current, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !reflect.DeepEqual(current, value) {
+			return nil, ErrTestFailed
+		}
+
+		return doc, nil
+	default:
+		return nil, ErrInvalid
+	}
+}
+
+// pointer splits a JSON Pointer (RFC 6901) into its reference tokens, e.g.
+// "/a~1b/0" into "a/b" and "0". The empty pointer is the whole document.
+func pointer(s string) ([]string, error) {
+	if s == "" {
+		return []string{}, nil
+	}
+
+	if !strings.HasPrefix(s, "/") {
+		return nil, ErrInvalid
+	}
+
+	tokens := strings.Split(s[1:], "/")
+
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+
+	return tokens, nil
+}
+
+// index parses the array index token, which may be at most max.
+func index(token string, max int) (int, error) {
+	// leading zeros are not allowed
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, ErrPathNone
+	}
+
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > max {
+		return 0, ErrPathNone
+	}
+
+	return i, nil
+}
+
+func get(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch d := doc.(type) {
+		case map[string]any:
+			v, ok := d[token]
+			if !ok {
+				return nil, ErrPathNone
+			}
+
+			doc = v
+		case []any:
+			i, err := index(token, len(d)-1)
+			if err != nil {
+				return nil, err
+			}
+
+			doc = d[i]
+		default:
+			return nil, ErrPathNone
+		}
+	}
+
+	return doc, nil
+}
+
+// add returns doc with value added at path. Values are inserted into arrays
+// rather than replacing the element at the index, "-" appends.
+func add(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	token := path[0]
+
+	switch d := doc.(type) {
+	case map[string]any:
+		if len(path) == 1 {
+			d[token] = value
+			return d, nil
+		}
+
+		child, ok := d[token]
+		if !ok {
+			return nil, ErrPathNone
+		}
+
+		v, err := add(child, path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+
+		d[token] = v
+
+		return d, nil
+	case []any:
+		if len(path) == 1 {
+			if token == "-" {
+				return append(d, value), nil
+			}
+
+			i, err := index(token, len(d))
+			if err != nil {
+				return nil, err
+			}
+
+			d = append(d, nil)
+			copy(d[i+1:], d[i:])
+			d[i] = value
+
+			return d, nil
+		}
+
+		i, err := index(token, len(d)-1)
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := add(d[i], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+
+		d[i] = v
+
+		return d, nil
+	default:
+		return nil, ErrPathNone
+	}
+}
+
+// remove returns doc without the value at path, which must exist.
+func remove(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, nil
+	}
+
+	token := path[0]
+
+	switch d := doc.(type) {
+	case map[string]any:
+		child, ok := d[token]
+		if !ok {
+			return nil, ErrPathNone
+		}
+
+		if len(path) == 1 {
+			delete(d, token)
+			return d, nil
+		}
+
+		v, err := remove(child, path[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		d[token] = v
+
+		return d, nil
+	case []any:
+		i, err := index(token, len(d)-1)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(path) == 1 {
+			return append(d[:i], d[i+1:]...), nil
+		}
+
+		v, err := remove(d[i], path[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		d[i] = v
+
+		return d, nil
+	default:
+		return nil, ErrPathNone
+	}
+}
+
+// clone deep copies a decoded JSON value, so that a copy does not share
+// objects and arrays with the original.
+func clone(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		m := map[string]any{}
+
+		for k, e := range v {
+			m[k] = clone(e)
+		}
+
+		return m
+	case []any:
+		s := []any{}
+
+		for _, e := range v {
+			s = append(s, clone(e))
+		}
+
+		return s
+	default:
+		return v
+	}
+}
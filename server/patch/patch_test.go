@@ -0,0 +1,185 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func equalJSON(t *testing.T, want string, got []byte) {
+	t.Helper()
+
+	var w, g any
+
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(w, g) {
+		t.Errorf("want %s; got %s", want, got)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	for name, tc := range map[string]struct {
+		doc   string
+		patch string
+		want  string
+	}{
+		"replace": {
+			doc:   `{"a": "b", "c": 1}`,
+			patch: `{"a": "z"}`,
+			want:  `{"a": "z", "c": 1}`,
+		},
+		"remove": {
+			doc:   `{"a": "b", "c": 1}`,
+			patch: `{"a": null}`,
+			want:  `{"c": 1}`,
+		},
+		"nested": {
+			doc:   `{"a": {"b": 1, "c": 2}}`,
+			patch: `{"a": {"b": null, "d": 3}}`,
+			want:  `{"a": {"c": 2, "d": 3}}`,
+		},
+		"array is replaced": {
+			doc:   `{"a": [1, 2]}`,
+			patch: `{"a": [3]}`,
+			want:  `{"a": [3]}`,
+		},
+		"not an object": {
+			doc:   `{"a": 1}`,
+			patch: `["b"]`,
+			want:  `["b"]`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := Merge([]byte(tc.doc), []byte(tc.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			equalJSON(t, tc.want, got)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	for name, tc := range map[string]struct {
+		doc   string
+		patch string
+		want  string
+		err   error
+	}{
+		"add": {
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "add", "path": "/b", "value": 2}]`,
+			want:  `{"a": 1, "b": 2}`,
+		},
+		"add to array": {
+			doc:   `{"a": [1, 3]}`,
+			patch: `[{"op": "add", "path": "/a/1", "value": 2}, {"op": "add", "path": "/a/-", "value": 4}]`,
+			want:  `{"a": [1, 2, 3, 4]}`,
+		},
+		"add null": {
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "add", "path": "/a", "value": null}]`,
+			want:  `{"a": null}`,
+		},
+		"remove": {
+			doc:   `{"a": 1, "b": [1, 2]}`,
+			patch: `[{"op": "remove", "path": "/a"}, {"op": "remove", "path": "/b/0"}]`,
+			want:  `{"b": [2]}`,
+		},
+		"replace": {
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "replace", "path": "/a", "value": "x"}]`,
+			want:  `{"a": "x"}`,
+		},
+		"move": {
+			doc:   `{"a": {"b": 1}}`,
+			patch: `[{"op": "move", "from": "/a/b", "path": "/c"}]`,
+			want:  `{"a": {}, "c": 1}`,
+		},
+		"copy": {
+			doc:   `{"a": {"b": 1}}`,
+			patch: `[{"op": "copy", "from": "/a", "path": "/c"}, {"op": "add", "path": "/c/b", "value": 2}]`,
+			want:  `{"a": {"b": 1}, "c": {"b": 2}}`,
+		},
+		"test": {
+			doc:   `{"a": {"b": [1, "x"]}}`,
+			patch: `[{"op": "test", "path": "/a", "value": {"b": [1, "x"]}}]`,
+			want:  `{"a": {"b": [1, "x"]}}`,
+		},
+		"escaped pointer": {
+			doc:   `{"a/b": 1, "c~d": 2}`,
+			patch: `[{"op": "replace", "path": "/a~1b", "value": 3}, {"op": "remove", "path": "/c~0d"}]`,
+			want:  `{"a/b": 3}`,
+		},
+		"test failed": {
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "replace", "path": "/a", "value": 2}, {"op": "test", "path": "/a", "value": 1}]`,
+			err:   ErrTestFailed,
+		},
+		"replace missing": {
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "replace", "path": "/b", "value": 2}]`,
+			err:   ErrPathNone,
+		},
+		"remove missing": {
+			doc:   `{"a": [1]}`,
+			patch: `[{"op": "remove", "path": "/a/1"}]`,
+			err:   ErrPathNone,
+		},
+		"add to missing parent": {
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "add", "path": "/b/c", "value": 2}]`,
+			err:   ErrPathNone,
+		},
+		"leading zero": {
+			doc:   `{"a": [1, 2]}`,
+			patch: `[{"op": "remove", "path": "/a/01"}]`,
+			err:   ErrPathNone,
+		},
+		"unknown op": {
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "frobnicate", "path": "/a"}]`,
+			err:   ErrInvalid,
+		},
+		"missing value": {
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "add", "path": "/b"}]`,
+			err:   ErrInvalid,
+		},
+		"missing path": {
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "remove"}]`,
+			err:   ErrInvalid,
+		},
+		"move into itself": {
+			doc:   `{"a": {"b": 1}}`,
+			patch: `[{"op": "move", "from": "/a", "path": "/a/c"}]`,
+			err:   ErrInvalid,
+		},
+		"not an array": {
+			doc:   `{"a": 1}`,
+			patch: `{"op": "remove", "path": "/a"}`,
+			err:   ErrInvalid,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := Apply([]byte(tc.doc), []byte(tc.patch))
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("want %v; got %v", tc.err, err)
+			}
+
+			if tc.err == nil {
+				equalJSON(t, tc.want, got)
+			}
+		})
+	}
+}
@@ -1,14 +1,111 @@
 package roles
 
-type Role int
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Permission is what a route requires of the roles of an admin. Roles and
+// the permissions they grant are stored in the database.
+type Permission string
 
 const (
-	Bingo Role = 1 << iota
-	Bluey
-	Chilli
-	Bandit
+	UsersRead      Permission = "users:read"
+	UsersCreate    Permission = "users:create"
+	UsersWrite     Permission = "users:write"
+	UsersDelete    Permission = "users:delete"
+	TokensManage   Permission = "tokens:manage"
+	AdminsManage   Permission = "admins:manage"
+	AuditRead      Permission = "audit:read"
+	WebhooksManage Permission = "webhooks:manage"
 )
 
-func IsAuthorized(permissions Role, role Role) bool {
-	return permissions&role != 0
+// Source loads the permissions granted by each role.
+type Source interface {
+	GetPermissions(context.Context) (map[string][]string, error)
+}
+
+// Resolver resolves the permissions of roles. Permissions are cached and
+// reloaded from the source once they are older than the TTL, so changes in
+// the database take effect without a restart.
+type Resolver struct {
+	source Source
+	ttl    time.Duration
+
+	mu          sync.Mutex
+	permissions map[string]map[Permission]bool
+	loaded      time.Time
+}
+
+func NewResolver(source Source, ttl time.Duration) *Resolver {
+	return &Resolver{source: source, ttl: ttl}
+}
+
+// IsAuthorized reports whether any of roles grants permission.
+func (r *Resolver) IsAuthorized(ctx context.Context, roles []string, permission Permission) (bool, error) {
+	permissions, err := r.get(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, role := range roles {
+		if permissions[role][permission] {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Refresh reloads the permissions from the source.
+func (r *Resolver) Refresh(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.load(ctx)
+}
+
+func (r *Resolver) get(ctx context.Context) (map[string]map[Permission]bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.permissions == nil || time.Since(r.loaded) > r.ttl {
+		if err := r.load(ctx); err != nil {
+			// keep serving stale permissions rather than failing every request
+			if r.permissions == nil {
+				return nil, err
+			}
+
+			log.Printf("failed to refresh permissions: %v", err)
+
+			// retry once the ttl passes again
+			r.loaded = time.Now()
+		}
+	}
+
+	return r.permissions, nil
+}
+
+func (r *Resolver) load(ctx context.Context) error {
+	m, err := r.source.GetPermissions(ctx)
+	if err != nil {
+		return err
+	}
+
+	permissions := map[string]map[Permission]bool{}
+
+	for role, names := range m {
+		permissions[role] = map[Permission]bool{}
+
+		for _, name := range names {
+			permissions[role][Permission(name)] = true
+		}
+	}
+
+	r.permissions = permissions
+	r.loaded = time.Now()
+
+	return nil
 }
@@ -0,0 +1,109 @@
+package roles
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeSource struct {
+	permissions map[string][]string
+	err         error
+	loads       int
+}
+
+func (s *fakeSource) GetPermissions(context.Context) (map[string][]string, error) {
+	s.loads++
+
+	return s.permissions, s.err
+}
+
+func TestResolverIsAuthorized(t *testing.T) {
+	r := NewResolver(&fakeSource{permissions: map[string][]string{
+		"bingo":  {"users:read"},
+		"bandit": {"users:read", "users:delete"},
+	}}, time.Hour)
+
+	for name, tc := range map[string]struct {
+		roles      []string
+		permission Permission
+		want       bool
+	}{
+		"granted":             {[]string{"bingo"}, UsersRead, true},
+		"granted by any role": {[]string{"bingo", "bandit"}, UsersDelete, true},
+		"not granted":         {[]string{"bingo"}, UsersDelete, false},
+		"unknown role":        {[]string{"unknown"}, UsersRead, false},
+		"no roles":            {nil, UsersRead, false},
+		"unknown permissions": {[]string{"bandit"}, AdminsManage, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := r.IsAuthorized(context.Background(), tc.roles, tc.permission)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tc.want {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestResolverRefresh(t *testing.T) {
+	source := &fakeSource{permissions: map[string][]string{"bingo": {"users:read"}}}
+
+	r := NewResolver(source, time.Hour)
+
+	if ok, _ := r.IsAuthorized(context.Background(), []string{"bingo"}, UsersWrite); ok {
+		t.Error("want users:write not granted yet")
+	}
+
+	// changes are only picked up once the ttl passes or on refresh
+	source.permissions = map[string][]string{"bingo": {"users:read", "users:write"}}
+
+	if ok, _ := r.IsAuthorized(context.Background(), []string{"bingo"}, UsersWrite); ok {
+		t.Error("want cached permissions")
+	}
+
+	if err := r.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _ := r.IsAuthorized(context.Background(), []string{"bingo"}, UsersWrite); !ok {
+		t.Error("want users:write granted after refresh")
+	}
+
+	if source.loads != 2 {
+		t.Errorf("want %v loads; got %v", 2, source.loads)
+	}
+}
+
+func TestResolverTTL(t *testing.T) {
+	source := &fakeSource{permissions: map[string][]string{"bingo": {"users:read"}}}
+
+	r := NewResolver(source, 0)
+
+	if ok, _ := r.IsAuthorized(context.Background(), []string{"bingo"}, UsersRead); !ok {
+		t.Error("want users:read granted")
+	}
+
+	// stale permissions are kept when reloading fails
+	source.err = errors.New("database down")
+
+	if ok, err := r.IsAuthorized(context.Background(), []string{"bingo"}, UsersRead); err != nil || !ok {
+		t.Errorf("want stale permissions; got %v, %v", ok, err)
+	}
+
+	if source.loads != 2 {
+		t.Errorf("want %v loads; got %v", 2, source.loads)
+	}
+}
+
+func TestResolverError(t *testing.T) {
+	r := NewResolver(&fakeSource{err: errors.New("database down")}, time.Hour)
+
+	if _, err := r.IsAuthorized(context.Background(), []string{"bingo"}, UsersRead); err == nil {
+		t.Error("want error")
+	}
+}
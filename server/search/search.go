@@ -0,0 +1,422 @@
+package search
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	"atmail"
+)
+
+// Index is an in-memory trigram index of the username and email of users.
+// Terms of at least three letters only look at the users sharing all of
+// their trigrams, shorter terms look at every user.
+type Index struct {
+	mu    sync.RWMutex
+	users map[int64]atmail.User
+	grams map[string]map[int64]bool
+}
+
+func NewIndex() *Index {
+	return &Index{
+		users: map[int64]atmail.User{},
+		grams: map[string]map[int64]bool{},
+	}
+}
+
+// Add indexes user, replacing the user with the same id.
+func (i *Index) Add(user atmail.User) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(user.Id)
+
+	i.users[user.Id] = user
+
+	for _, gram := range trigrams(strings.ToLower(user.Username + " " + user.Email)) {
+		if i.grams[gram] == nil {
+			i.grams[gram] = map[int64]bool{}
+		}
+
+		i.grams[gram][user.Id] = true
+	}
+}
+
+func (i *Index) Remove(id int64) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(id)
+}
+
+func (i *Index) remove(id int64) {
+	user, ok := i.users[id]
+	if !ok {
+		return
+	}
+
+	delete(i.users, id)
+
+	for _, gram := range trigrams(strings.ToLower(user.Username + " " + user.Email)) {
+		delete(i.grams[gram], id)
+
+		if len(i.grams[gram]) == 0 {
+			delete(i.grams, gram)
+		}
+	}
+}
+
+// Search returns at most limit users containing any of the terms of query,
+// skipping the first offset. Users are ranked by how much of their fields the
+// terms cover, then by id.
+func (i *Index) Search(query string, limit int, offset int) []atmail.UserMatch {
+	terms := atmail.SearchTerms(query)
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	scores := map[int64]float64{}
+
+	for _, term := range terms {
+		for _, id := range i.candidates(term) {
+			user := i.users[id]
+
+			for _, value := range []string{user.Username, user.Email} {
+				if strings.Contains(strings.ToLower(value), term) {
+					scores[id] += float64(len(term)) / float64(len(value))
+				}
+			}
+		}
+	}
+
+	ids := []int64{}
+
+	for id, score := range scores {
+		if score > 0 {
+			ids = append(ids, id)
+		}
+	}
+
+	slices.SortFunc(ids, func(a int64, b int64) int {
+		if c := cmp.Compare(scores[b], scores[a]); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(a, b)
+	})
+
+	matches := []atmail.UserMatch{}
+
+	for _, id := range ids[min(offset, len(ids)):min(offset+limit, len(ids))] {
+		matches = append(matches, atmail.UserMatch{User: i.users[id], Fields: atmail.MatchedFields(i.users[id], terms)})
+	}
+
+	return matches
+}
+
+// candidates returns the ids of the users that may contain term.
+func (i *Index) candidates(term string) []int64 {
+	grams := trigrams(term)
+
+	if len(grams) == 0 {
+		ids := []int64{}
+
+		for id := range i.users {
+			ids = append(ids, id)
+		}
+
+		return ids
+	}
+
+	// start from the rarest trigram to keep the intersection small
+	slices.SortFunc(grams, func(a string, b string) int {
+		return cmp.Compare(len(i.grams[a]), len(i.grams[b]))
+	})
+
+	ids := []int64{}
+
+outer:
+	for id := range i.grams[grams[0]] {
+		for _, gram := range grams[1:] {
+			if !i.grams[gram][id] {
+				continue outer
+			}
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// trigrams returns the distinct runs of three runes in s.
+func trigrams(s string) []string {
+	rs := []rune(s)
+
+	grams := []string{}
+
+	for j := 0; j+3 <= len(rs); j++ {
+		gram := string(rs[j : j+3])
+
+		if !slices.Contains(grams, gram) {
+			grams = append(grams, gram)
+		}
+	}
+
+	return grams
+}
+
+// Store adds search to a store that is not an atmail.Searcher by indexing its
+// users in memory. Users are loaded on the first search and the index is kept
+// in sync as users are created, updated, deleted and restored through the
+// Store, so it is only accurate when no other process writes to the same
+// users.
+type Store struct {
+	atmail.Store
+
+	index *Index
+
+	// mu excludes writes while the index is loaded
+	mu     sync.RWMutex
+	loaded bool
+}
+
+func NewStore(store atmail.Store) *Store {
+	return &Store{Store: store, index: NewIndex()}
+}
+
+// WithTx indexes the users written by fn once the transaction commits.
+func (s *Store) WithTx(ctx context.Context, fn func(atmail.Store) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tx := &txStore{index: s.index}
+
+	if err := s.Store.WithTx(ctx, func(store atmail.Store) error {
+		tx.Store = store
+		return fn(tx)
+	}); err != nil {
+		return err
+	}
+
+	for _, write := range tx.writes {
+		write()
+	}
+
+	return nil
+}
+
+// txStore defers writes to the index until the transaction commits.
+type txStore struct {
+	atmail.Store
+
+	index  *Index
+	writes []func()
+}
+
+func (s *txStore) CreateUser(ctx context.Context, user atmail.User) (int64, error) {
+	id, err := s.Store.CreateUser(ctx, user)
+	if err != nil {
+		return 0, err
+	}
+
+	user.Id, user.Version = id, 1
+
+	s.writes = append(s.writes, func() { s.index.Add(user) })
+
+	return id, nil
+}
+
+func (s *txStore) CreateUsers(ctx context.Context, users []atmail.User) ([]int64, error) {
+	ids, err := s.Store.CreateUsers(ctx, users)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, user := range users {
+		user.Id, user.Version = ids[i], 1
+
+		s.writes = append(s.writes, func() { s.index.Add(user) })
+	}
+
+	return ids, nil
+}
+
+func (s *txStore) UpdateUser(ctx context.Context, user atmail.User) error {
+	if err := s.Store.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+
+	user.Version++
+
+	s.writes = append(s.writes, func() { s.index.Add(user) })
+
+	return nil
+}
+
+func (s *txStore) DeleteUser(ctx context.Context, id int64) error {
+	if err := s.Store.DeleteUser(ctx, id); err != nil {
+		return err
+	}
+
+	s.writes = append(s.writes, func() { s.index.Remove(id) })
+
+	return nil
+}
+
+func (s *txStore) RestoreUser(ctx context.Context, id int64) (atmail.User, error) {
+	user, err := s.Store.RestoreUser(ctx, id)
+	if err != nil {
+		return atmail.User{}, err
+	}
+
+	s.writes = append(s.writes, func() { s.index.Add(user) })
+
+	return user, nil
+}
+
+func (s *txStore) PurgeUser(ctx context.Context, id int64) error {
+	if err := s.Store.PurgeUser(ctx, id); err != nil {
+		return err
+	}
+
+	s.writes = append(s.writes, func() { s.index.Remove(id) })
+
+	return nil
+}
+
+// WithTx joins the transaction, as does the store it wraps.
+func (s *txStore) WithTx(ctx context.Context, fn func(atmail.Store) error) error {
+	return fn(s)
+}
+
+func (s *Store) CreateUser(ctx context.Context, user atmail.User) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, err := s.Store.CreateUser(ctx, user)
+	if err != nil {
+		return 0, err
+	}
+
+	user.Id, user.Version = id, 1
+
+	s.index.Add(user)
+
+	return id, nil
+}
+
+func (s *Store) CreateUsers(ctx context.Context, users []atmail.User) ([]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids, err := s.Store.CreateUsers(ctx, users)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, user := range users {
+		user.Id, user.Version = ids[i], 1
+
+		s.index.Add(user)
+	}
+
+	return ids, nil
+}
+
+func (s *Store) UpdateUser(ctx context.Context, user atmail.User) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.Store.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+
+	user.Version++
+
+	s.index.Add(user)
+
+	return nil
+}
+
+func (s *Store) DeleteUser(ctx context.Context, id int64) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.Store.DeleteUser(ctx, id); err != nil {
+		return err
+	}
+
+	s.index.Remove(id)
+
+	return nil
+}
+
+func (s *Store) RestoreUser(ctx context.Context, id int64) (atmail.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, err := s.Store.RestoreUser(ctx, id)
+	if err != nil {
+		return atmail.User{}, err
+	}
+
+	s.index.Add(user)
+
+	return user, nil
+}
+
+// PurgeUser removes the user from the index, as it may not have been deleted
+// first. Users purged by PurgeUsers were deleted, so are already removed.
+func (s *Store) PurgeUser(ctx context.Context, id int64) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.Store.PurgeUser(ctx, id); err != nil {
+		return err
+	}
+
+	s.index.Remove(id)
+
+	return nil
+}
+
+func (s *Store) SearchUsers(ctx context.Context, query string, limit int, offset int) ([]atmail.UserMatch, error) {
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.index.Search(query, limit, offset), nil
+}
+
+func (s *Store) load(ctx context.Context) error {
+	s.mu.RLock()
+	loaded := s.loaded
+	s.mu.RUnlock()
+
+	if loaded {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.loaded {
+		return nil
+	}
+
+	users, err := s.Store.ListUsers(ctx, atmail.ListUsersOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		s.index.Add(user)
+	}
+
+	s.loaded = true
+
+	return nil
+}
@@ -0,0 +1,225 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"atmail"
+)
+
+var testUsers = []atmail.User{
+	{Id: 1, Username: "bingo", Email: "bingo@heeler.com", Age: 6},
+	{Id: 2, Username: "bluey", Email: "bluey@heeler.com", Age: 7},
+	{Id: 3, Username: "chilli", Email: "chilli@heeler.com", Age: 38},
+	{Id: 4, Username: "bandit", Email: "bandit@heeler.com", Age: 40},
+	{Id: 5, Username: "muffin", Email: "muffin@cattle.com", Age: 3},
+	{Id: 6, Username: "socks", Email: "socks@cattle.com", Age: 7},
+}
+
+func TestIndexSearch(t *testing.T) {
+	index := NewIndex()
+
+	for _, user := range testUsers {
+		index.Add(user)
+	}
+
+	for name, tc := range map[string]struct {
+		query  string
+		limit  int
+		offset int
+		want   []int64
+		fields [][]string
+	}{
+		"username": {
+			query:  "chill",
+			limit:  10,
+			want:   []int64{3},
+			fields: [][]string{{"username", "email"}},
+		},
+		"email only": {
+			query:  "cattle",
+			limit:  10,
+			want:   []int64{6, 5},
+			fields: [][]string{{"email"}, {"email"}},
+		},
+		"short term": {
+			query:  "ck",
+			limit:  10,
+			want:   []int64{6},
+			fields: [][]string{{"username", "email"}},
+		},
+		"any term": {
+			query:  "socks bluey",
+			limit:  10,
+			want:   []int64{2, 6},
+			fields: [][]string{{"username", "email"}, {"username", "email"}},
+		},
+		"case insensitive": {
+			query:  "MUFFIN",
+			limit:  10,
+			want:   []int64{5},
+			fields: [][]string{{"username", "email"}},
+		},
+		"paginated": {
+			query:  "heeler",
+			limit:  2,
+			offset: 2,
+			want:   []int64{3, 4},
+			fields: [][]string{{"email"}, {"email"}},
+		},
+		"no match": {
+			query:  "nana",
+			limit:  10,
+			want:   []int64{},
+			fields: [][]string{},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ids := []int64{}
+			fields := [][]string{}
+
+			for _, match := range index.Search(tc.query, tc.limit, tc.offset) {
+				ids = append(ids, match.User.Id)
+				fields = append(fields, match.Fields)
+			}
+
+			if !reflect.DeepEqual(tc.want, ids) {
+				t.Errorf("want %v; got %v", tc.want, ids)
+			}
+
+			if !reflect.DeepEqual(tc.fields, fields) {
+				t.Errorf("want %v; got %v", tc.fields, fields)
+			}
+		})
+	}
+}
+
+func TestIndexRemove(t *testing.T) {
+	index := NewIndex()
+
+	index.Add(testUsers[0])
+
+	// updating a user replaces its trigrams
+	index.Add(atmail.User{Id: 1, Username: "coco", Email: "coco@poodle.com", Age: 6})
+
+	if got := index.Search("bingo", 10, 0); len(got) != 0 {
+		t.Errorf("want no matches; got %v", got)
+	}
+
+	index.Remove(1)
+
+	if got := index.Search("coco", 10, 0); len(got) != 0 {
+		t.Errorf("want no matches; got %v", got)
+	}
+
+	if want, got := 0, len(index.grams); want != got {
+		t.Errorf("want %v; got %v", want, got)
+	}
+}
+
+// fakeStore stores users in a map, the rest of atmail.Store is not used.
+type fakeStore struct {
+	atmail.Store
+
+	users   map[int64]atmail.User
+	deleted map[int64]atmail.User
+}
+
+func (s fakeStore) CreateUser(ctx context.Context, user atmail.User) (int64, error) {
+	user.Id = int64(len(s.users) + 1)
+	s.users[user.Id] = user
+
+	return user.Id, nil
+}
+
+func (s fakeStore) UpdateUser(ctx context.Context, user atmail.User) error {
+	s.users[user.Id] = user
+	return nil
+}
+
+func (s fakeStore) DeleteUser(ctx context.Context, id int64) error {
+	s.deleted[id] = s.users[id]
+	delete(s.users, id)
+
+	return nil
+}
+
+func (s fakeStore) RestoreUser(ctx context.Context, id int64) (atmail.User, error) {
+	user, ok := s.deleted[id]
+	if !ok {
+		return atmail.User{}, atmail.ErrUserNone
+	}
+
+	s.users[id] = user
+	delete(s.deleted, id)
+
+	return user, nil
+}
+
+func (s fakeStore) ListUsers(context.Context, atmail.ListUsersOptions) ([]atmail.User, error) {
+	users := []atmail.User{}
+
+	for _, user := range s.users {
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+func TestStoreSync(t *testing.T) {
+	store := NewStore(fakeStore{users: map[int64]atmail.User{1: testUsers[0]}, deleted: map[int64]atmail.User{}})
+
+	search := func(query string) []int64 {
+		matches, err := store.SearchUsers(context.Background(), query, 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids := []int64{}
+
+		for _, match := range matches {
+			ids = append(ids, match.User.Id)
+		}
+
+		return ids
+	}
+
+	// loaded from the store on the first search
+	if want, got := []int64{1}, search("bingo"); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v; got %v", want, got)
+	}
+
+	id, err := store.CreateUser(context.Background(), testUsers[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := []int64{id}, search("bluey"); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v; got %v", want, got)
+	}
+
+	if err := store.UpdateUser(context.Background(), atmail.User{Id: id, Username: "coco", Email: "coco@poodle.com", Age: 7}); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := []int64{}, search("bluey"); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v; got %v", want, got)
+	}
+
+	if err := store.DeleteUser(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := []int64{}, search("bingo"); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v; got %v", want, got)
+	}
+
+	if _, err := store.RestoreUser(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := []int64{1}, search("bingo"); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v; got %v", want, got)
+	}
+}
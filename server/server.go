@@ -2,34 +2,181 @@ package server
 
 import (
 	"net/http"
+	"time"
 
 	"atmail"
+	"atmail/server/audit"
+	"atmail/server/change"
+	"atmail/server/feed"
 	"atmail/server/roles"
+	"atmail/server/search"
+	"atmail/server/webhook"
 )
 
-func New(store atmail.Store) *http.ServeMux {
-	// convenience closure
-	toHandler := func(fn handlerFunc, roles roles.Role) http.Handler {
-		return handler{store, roles, fn}
+// Option configures the server returned by New.
+type Option func(*options)
+
+type options struct {
+	auth          Authenticator
+	realm         string
+	resolver      *roles.Resolver
+	timeout       time.Duration
+	routeTimeouts map[string]time.Duration
+}
+
+// WithAuthenticators authenticates requests with the first of as that finds
+// credentials in the request. By default, admins are authenticated with Basic
+// authentication and API tokens.
+func WithAuthenticators(as ...Authenticator) Option {
+	return func(o *options) {
+		o.auth = Chain(as)
+	}
+}
+
+// WithRealm sets the realm clients are challenged with when unauthorized.
+// Defaults to "atmail".
+func WithRealm(realm string) Option {
+	return func(o *options) {
+		o.realm = realm
+	}
+}
+
+// WithResolver resolves the permissions of roles with r. By default they are
+// loaded from the store and cached for 30 seconds.
+func WithResolver(r *roles.Resolver) Option {
+	return func(o *options) {
+		o.resolver = r
+	}
+}
+
+// WithTimeout sets the deadline of each request, after which the store gives
+// up and the request fails with 504 Gateway Timeout. Defaults to 10 seconds,
+// 0 means no deadline.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithRouteTimeout sets the deadline of the requests of a route, given by its
+// pattern, e.g. "GET /users/{id}", overriding WithTimeout. Imports and
+// exports and GET /events have no deadline by default.
+func WithRouteTimeout(pattern string, d time.Duration) Option {
+	return func(o *options) {
+		o.routeTimeouts[pattern] = d
+	}
+}
+
+// NewStore wraps store so that the users written through it are audited,
+// delivered to webhooks and appended to the feed. Stores that cannot search
+// users are wrapped in a search.Store first. Users written outside of
+// requests, e.g. by the purger, must be written through it too.
+func NewStore(store atmail.Store) atmail.Store {
+	if _, ok := store.(*change.Store); ok {
+		return store
 	}
 
+	if _, ok := store.(atmail.Searcher); !ok {
+		store = search.NewStore(store)
+	}
+
+	return change.NewStore(store, audit.Record, webhook.Enqueue, feed.Append)
+}
+
+// New returns the routes of the API, writing users through NewStore unless
+// store already is one.
+func New(store atmail.Store, opts ...Option) *http.ServeMux {
+	store = NewStore(store)
+
+	o := options{
+		auth:     Chain{NewBasicAuthenticator(store), NewTokenAuthenticator(store)},
+		realm:    "atmail",
+		resolver: roles.NewResolver(store, 30*time.Second),
+		timeout:  10 * time.Second,
+		routeTimeouts: map[string]time.Duration{
+			"POST /users:import": 0,
+			"GET /users:export":  0,
+			"GET /events":        0,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	challenges := challenges(o.auth, o.realm)
+
 	mux := http.NewServeMux()
 
-	mux.Handle("GET /users/{id}", toHandler(getUser,
-		roles.Bingo|roles.Bluey|roles.Chilli|roles.Bandit,
-	))
+	// convenience closure
+	handle := func(pattern string, fn handlerFunc, permission roles.Permission) {
+		timeout, ok := o.routeTimeouts[pattern]
+		if !ok {
+			timeout = o.timeout
+		}
+
+		mux.Handle(pattern, handler{store, permission, fn, o.auth, o.resolver, challenges, timeout})
+	}
+
+	handle("GET /users", listUsers, roles.UsersRead)
+
+	handle("POST /users:import", importUsers, roles.UsersCreate)
+
+	handle("GET /users:export", exportUsers, roles.UsersRead)
+
+	handle("GET /users/search", searchUsers, roles.UsersRead)
+
+	handle("GET /users/{id}", getUser, roles.UsersRead)
+
+	handle("POST /users", createUser, roles.UsersCreate)
+
+	handle("PUT /users/{id}", updateUser, roles.UsersWrite)
+
+	handle("PATCH /users/{id}", patchUser, roles.UsersWrite)
+
+	handle("DELETE /users/{id}", deleteUser, roles.UsersDelete)
+
+	handle("POST /users/{id}/restore", restoreUser, roles.UsersDelete)
+
+	handle("POST /tokens", createToken, roles.TokensManage)
+
+	handle("DELETE /tokens/{id}", deleteToken, roles.TokensManage)
+
+	handle("POST /admins", createAdmin, roles.AdminsManage)
+
+	handle("GET /admins", listAdmins, roles.AdminsManage)
+
+	handle("GET /admins/{user}", getAdmin, roles.AdminsManage)
+
+	handle("PUT /admins/{user}/roles", setAdminRoles, roles.AdminsManage)
+
+	handle("PUT /admins/{user}/password", setAdminPassword, roles.AdminsManage)
+
+	handle("POST /admins/{user}/disable", disableAdmin, roles.AdminsManage)
+
+	handle("POST /admins/{user}/enable", enableAdmin, roles.AdminsManage)
+
+	handle("DELETE /admins/{user}", deleteAdmin, roles.AdminsManage)
+
+	handle("GET /audit", listAuditEvents, roles.AuditRead)
+
+	handle("POST /webhooks", createWebhook, roles.WebhooksManage)
+
+	handle("GET /webhooks", listWebhooks, roles.WebhooksManage)
+
+	handle("GET /webhooks/{id}", getWebhook, roles.WebhooksManage)
+
+	handle("PUT /webhooks/{id}", updateWebhook, roles.WebhooksManage)
+
+	handle("DELETE /webhooks/{id}", deleteWebhook, roles.WebhooksManage)
+
+	handle("GET /webhooks/{id}/deliveries", listWebhookDeliveries, roles.WebhooksManage)
 
-	mux.Handle("POST /users", toHandler(createUser,
-		roles.Bluey|roles.Chilli|roles.Bandit,
-	))
+	handle("GET /webhooks/{id}/deliveries/{delivery}", getWebhookDelivery, roles.WebhooksManage)
 
-	mux.Handle("PUT /users/{id}", toHandler(updateUser,
-		roles.Chilli|roles.Bandit,
-	))
+	handle("POST /webhooks/{id}/deliveries/{delivery}/redeliver", redeliverWebhookDelivery, roles.WebhooksManage)
 
-	mux.Handle("DELETE /users/{id}", toHandler(deleteUser,
-		roles.Bandit,
-	))
+	handle("GET /events", listEvents, roles.UsersRead)
 
 	return mux
 }
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"atmail"
+	"atmail/memstore"
+	"atmail/server/roles"
+)
+
+var testResolver = roles.NewResolver(memstore.New(), time.Hour)
+
+// newTestStore returns a store seeded with seed, whose roles and permissions
+// are those of the migrations.
+func newTestStore(t *testing.T, seed memstore.Seed) *memstore.Store {
+	t.Helper()
+
+	store := memstore.New()
+
+	if err := store.Seed(seed); err != nil {
+		t.Fatal(err)
+	}
+
+	return store
+}
+
+// txCounts counts the transactions of a faultStore.
+type txCounts struct {
+	committed  int
+	rolledBack int
+}
+
+// faultStore fails like a store shared with concurrent requests would.
+type faultStore struct {
+	atmail.Store
+	txs *txCounts
+	// createUserErr fails every user created, e.g. as if another request
+	// created the same user first
+	createUserErr error
+	// concurrentUpdates are made to every user right after it is read
+	concurrentUpdates int
+	// concurrentUsers are created right before every batch of users
+	concurrentUsers []atmail.User
+}
+
+func (s faultStore) WithTx(ctx context.Context, fn func(atmail.Store) error) error {
+	err := s.Store.WithTx(ctx, func(tx atmail.Store) error {
+		s := s
+		s.Store = tx
+
+		return fn(s)
+	})
+
+	if s.txs != nil {
+		if err != nil {
+			s.txs.rolledBack++
+		} else {
+			s.txs.committed++
+		}
+	}
+
+	return err
+}
+
+func (s faultStore) CreateUser(ctx context.Context, user atmail.User) (int64, error) {
+	if s.createUserErr != nil {
+		return 0, s.createUserErr
+	}
+
+	return s.Store.CreateUser(ctx, user)
+}
+
+func (s faultStore) CreateUsers(ctx context.Context, users []atmail.User) ([]int64, error) {
+	for _, user := range s.concurrentUsers {
+		if _, err := s.Store.CreateUser(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.Store.CreateUsers(ctx, users)
+}
+
+func (s faultStore) GetUser(ctx context.Context, id int64) (atmail.User, error) {
+	user, err := s.Store.GetUser(ctx, id)
+	if err != nil {
+		return user, err
+	}
+
+	// the user returned is the one read, before the updates
+	updated := user
+
+	for range s.concurrentUpdates {
+		if err := s.Store.UpdateUser(ctx, updated); err != nil {
+			return atmail.User{}, err
+		}
+
+		updated.Version++
+	}
+
+	return user, nil
+}
+
+// bumpVersion updates the user with id n times without changing it, so that
+// its version is n higher.
+func bumpVersion(t *testing.T, store atmail.Store, id int64, n int) {
+	t.Helper()
+
+	for range n {
+		user, err := store.GetUser(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := store.UpdateUser(context.Background(), user); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"atmail"
+)
+
+const (
+	tokenPrefix     = "atm_"
+	tokenDefaultTTL = 24 * time.Hour
+	tokenMaxTTL     = 90 * 24 * time.Hour
+)
+
+// newToken returns a random opaque token along with its hash.
+func newToken() (string, string, error) {
+	bs := make([]byte, 32)
+
+	if _, err := rand.Read(bs); err != nil {
+		return "", "", err
+	}
+
+	token := tokenPrefix + base64.RawURLEncoding.EncodeToString(bs)
+
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+type createTokenInload struct {
+	ExpiresIn *int64   `json:"expires_in"`
+	Roles     []string `json:"roles"`
+}
+
+type createTokenOutload struct {
+	Id        int64     `json:"id"`
+	Token     string    `json:"token"`
+	Roles     []string  `json:"roles"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (o createTokenOutload) code() int {
+	return http.StatusCreated
+}
+
+func createToken(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	p, ok := PrincipalFromContext(r.Context())
+	if !ok {
+		return nil, errors.New("principal missing from context")
+	}
+
+	// tokens belong to admins, external principals have nothing to revoke them
+	if !p.IsAdmin() {
+		return badRequest("tokens can only be created by admins!"), nil
+	}
+
+	// a token that could create tokens would outlive its own expiry, by
+	// creating the next one before it expires
+	if p.Method != MethodBasic {
+		return badRequest("tokens can only be created with a password!"), nil
+	}
+
+	i := createTokenInload{}
+
+	if err := json.NewDecoder(r.Body).Decode(&i); err != nil {
+		return badRequest("invalid json!"), nil
+	}
+
+	ttl := tokenDefaultTTL
+
+	if i.ExpiresIn != nil {
+		ttl = time.Duration(*i.ExpiresIn) * time.Second
+
+		if ttl <= 0 || ttl > tokenMaxTTL {
+			return badRequest("expires_in is invalid!"), nil
+		}
+	}
+
+	// tokens may only carry a subset of the admin's own roles
+	if i.Roles != nil {
+		if len(i.Roles) == 0 {
+			return badRequest("roles are invalid!"), nil
+		}
+
+		for _, role := range i.Roles {
+			if !slices.Contains(p.Roles, role) {
+				return badRequest("roles are invalid!"), nil
+			}
+		}
+	}
+
+	token, hash, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	t := atmail.Token{
+		Admin:     p.Id,
+		Hash:      hash,
+		Roles:     i.Roles,
+		ExpiresAt: time.Now().Add(ttl).UTC().Truncate(time.Second),
+	}
+
+	id, err := s.CreateToken(r.Context(), t)
+	if err != nil {
+		return nil, err
+	}
+
+	o := createTokenOutload{
+		Id:        id,
+		Token:     token,
+		Roles:     p.Roles,
+		ExpiresAt: t.ExpiresAt,
+	}
+
+	if t.Roles != nil {
+		o.Roles = t.Roles
+	}
+
+	return o, nil
+}
+
+func deleteToken(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	p, ok := PrincipalFromContext(r.Context())
+	if !ok {
+		return nil, errors.New("principal missing from context")
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return badRequest("token does not exist!"), nil
+	}
+
+	// admins can only revoke their own tokens
+	if err := s.DeleteToken(r.Context(), id, p.Id); err != nil {
+		if !errors.Is(err, atmail.ErrTokenNone) {
+			return nil, err
+		}
+
+		return badRequest("token does not exist!"), nil
+	}
+
+	return messageOutload{fmt.Sprintf("successfully revoked token %d!", id)}, nil
+}
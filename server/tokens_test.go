@@ -0,0 +1,206 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"atmail"
+	"atmail/memstore"
+)
+
+func withPrincipal(r *http.Request, p Principal) *http.Request {
+	return r.WithContext(WithPrincipal(r.Context(), p))
+}
+
+func TestCreateTokenOk(t *testing.T) {
+	for name, tc := range map[string]struct {
+		inload string
+		roles  []string
+	}{
+		"defaults": {
+			inload: `{}`,
+			roles:  []string{"chilli", "bandit"},
+		},
+		"role subset": {
+			inload: `{ "expires_in": 60, "roles": ["chilli"] }`,
+			roles:  []string{"chilli"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/tokens", bytes.NewBufferString(tc.inload))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = withPrincipal(req, Principal{"foo", []string{"chilli", "bandit"}, MethodBasic})
+
+			rr := httptest.NewRecorder()
+
+			got, err := createToken(memstore.New(), rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			o, ok := got.(createTokenOutload)
+			if !ok {
+				t.Fatalf("want createTokenOutload; got %v", got)
+			}
+
+			if o.Id != 1 {
+				t.Errorf("want %v; got %v", 1, o.Id)
+			}
+
+			if !reflect.DeepEqual(tc.roles, o.Roles) {
+				t.Errorf("want %v; got %v", tc.roles, o.Roles)
+			}
+
+			if !strings.HasPrefix(o.Token, tokenPrefix) {
+				t.Errorf("want token prefixed with %s; got %s", tokenPrefix, o.Token)
+			}
+		})
+	}
+}
+
+func TestCreateTokenNotOk(t *testing.T) {
+	for name, tc := range map[string]struct {
+		inload string
+		want   outload
+	}{
+		"invalid json": {
+			inload: `this invalid json`,
+			want:   badRequest("invalid json!"),
+		},
+		"negative expires_in": {
+			inload: `{ "expires_in": -1 }`,
+			want:   badRequest("expires_in is invalid!"),
+		},
+		"expires_in too long": {
+			inload: `{ "expires_in": 99999999 }`,
+			want:   badRequest("expires_in is invalid!"),
+		},
+		"roles not a subset": {
+			inload: `{ "roles": ["bandit"] }`,
+			want:   badRequest("roles are invalid!"),
+		},
+		"empty roles": {
+			inload: `{ "roles": [] }`,
+			want:   badRequest("roles are invalid!"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/tokens", bytes.NewBufferString(tc.inload))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = withPrincipal(req, Principal{"foo", []string{"chilli"}, MethodBasic})
+
+			rr := httptest.NewRecorder()
+
+			got, err := createToken(memstore.New(), rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCreateTokenNotPassword(t *testing.T) {
+	for name, tc := range map[string]struct {
+		principal Principal
+		want      outload
+	}{
+		"not admin": {
+			principal: Principal{"jane", []string{"chilli"}, MethodJWT},
+			want:      badRequest("tokens can only be created by admins!"),
+		},
+		"token": {
+			principal: Principal{"foo", []string{"chilli"}, MethodToken},
+			want:      badRequest("tokens can only be created with a password!"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/tokens", bytes.NewBufferString(`{}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = withPrincipal(req, tc.principal)
+
+			rr := httptest.NewRecorder()
+
+			got, err := createToken(memstore.New(), rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDeleteToken(t *testing.T) {
+	for name, tc := range map[string]struct {
+		id    string
+		admin string
+		want  outload
+	}{
+		"ok": {
+			id:    "1",
+			admin: "foo",
+			want:  messageOutload{"successfully revoked token 1!"},
+		},
+		"token of another admin": {
+			id:    "1",
+			admin: "bar",
+			want:  badRequest("token does not exist!"),
+		},
+		"token does not exist": {
+			id:    "2",
+			admin: "foo",
+			want:  badRequest("token does not exist!"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("DELETE", "/tokens/"+tc.id, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.SetPathValue("id", tc.id)
+
+			req = withPrincipal(req, Principal{tc.admin, []string{"bingo"}, MethodBasic})
+
+			store := newTestStore(t, memstore.Seed{
+				Admins: []memstore.SeedAdmin{{User: "foo", Password: "password", Roles: []string{"bingo"}}},
+			})
+
+			if _, err := store.CreateToken(context.Background(), atmail.Token{Admin: "foo", Hash: hashToken("atm_sometoken"), Roles: []string{"bingo"}, ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			got, err := deleteToken(store, rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
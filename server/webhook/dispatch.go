@@ -0,0 +1,187 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"atmail"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is attempted before it fails.
+	MaxAttempts = 10
+
+	minBackoff = 30 * time.Second
+	maxBackoff = 6 * time.Hour
+
+	// batchSize is how many due deliveries are attempted at once.
+	batchSize = 100
+
+	// lease is how long a claimed delivery is left to its dispatcher before
+	// others may attempt it, longer than any post should take.
+	lease = 5 * time.Minute
+)
+
+// backoff returns how long to wait after the given number of failed attempts,
+// doubling from 30 seconds up to 6 hours.
+func backoff(attempts int) time.Duration {
+	d := minBackoff
+
+	for range attempts - 1 {
+		d *= 2
+
+		if d >= maxBackoff {
+			return maxBackoff
+		}
+	}
+
+	return d
+}
+
+// Dispatcher delivers the due deliveries of the outbox every interval,
+// retrying failed ones with exponential backoff. Deliveries are at least
+// once, receivers tell them apart by their X-Atmail-Delivery header.
+type Dispatcher struct {
+	store    atmail.Store
+	client   *http.Client
+	interval time.Duration
+}
+
+// NewDispatcher returns a dispatcher that delivers with client, which should
+// have a timeout.
+func NewDispatcher(store atmail.Store, client *http.Client, interval time.Duration) *Dispatcher {
+	return &Dispatcher{store: store, client: client, interval: interval}
+}
+
+// Run dispatches every interval until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		// failures are retried on the next tick
+		if _, err := d.Dispatch(ctx); err != nil {
+			log.Printf("failed to dispatch webhook deliveries: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch attempts the due deliveries once and returns how many there were.
+// Every delivery is claimed before it is posted, so that the dispatchers of
+// other instances sharing the store skip it. Deliveries that fail are logged
+// and retried on a later dispatch, without holding up the others.
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	now := time.Now()
+
+	deliveries, err := d.store.ListWebhookDeliveries(ctx, atmail.WebhookDeliveryOptions{Limit: batchSize, Due: now, Asc: true})
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+
+	for _, delivery := range deliveries {
+		if err := d.store.ClaimWebhookDelivery(ctx, delivery.Id, now, now.Add(lease)); err != nil {
+			// claimed by another dispatcher since it was listed
+			if !errors.Is(err, atmail.ErrWebhookDeliveryNone) {
+				log.Printf("failed to claim webhook delivery %d: %v", delivery.Id, err)
+			}
+
+			continue
+		}
+
+		count++
+
+		if err := d.Attempt(ctx, delivery); err != nil {
+			log.Printf("failed to attempt webhook delivery %d: %v", delivery.Id, err)
+		}
+	}
+
+	return count, nil
+}
+
+// Attempt delivers delivery once and records the attempt, scheduling the next
+// one if it failed.
+func (d *Dispatcher) Attempt(ctx context.Context, delivery atmail.WebhookDelivery) error {
+	attempt := atmail.WebhookAttempt{DeliveryId: delivery.Id, Time: time.Now()}
+
+	w, err := d.store.GetWebhook(ctx, delivery.WebhookId)
+	if errors.Is(err, atmail.ErrWebhookNone) {
+		// deleted along with its deliveries in between
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	attempt.StatusCode, err = d.post(ctx, w, delivery)
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+
+	attempt.Duration = time.Since(attempt.Time)
+
+	delivery.Attempts++
+
+	switch {
+	case err == nil:
+		delivery.Status = atmail.DeliverySucceeded
+	case delivery.Attempts >= MaxAttempts:
+		delivery.Status = atmail.DeliveryFailed
+	default:
+		delivery.NextAttemptAt = time.Now().Add(backoff(delivery.Attempts))
+	}
+
+	return d.store.WithTx(ctx, func(tx atmail.Store) error {
+		if _, err := tx.CreateWebhookAttempt(ctx, attempt); err != nil {
+			return err
+		}
+
+		return tx.UpdateWebhookDelivery(ctx, delivery)
+	})
+}
+
+// post posts the payload of delivery to the webhook, failing unless it
+// responds with a 2xx status.
+func (d *Dispatcher) post(ctx context.Context, w atmail.Webhook, delivery atmail.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "atmail-webhooks")
+	req.Header.Set("X-Atmail-Event", delivery.Event)
+	req.Header.Set("X-Atmail-Delivery", strconv.FormatInt(delivery.Id, 10))
+	req.Header.Set(SignatureHeader, Sign(w.Secret, time.Now(), delivery.Payload))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer res.Body.Close()
+
+	// drain some of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("webhook responded with %s", res.Status)
+	}
+
+	return res.StatusCode, nil
+}
@@ -0,0 +1,218 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"atmail"
+	"atmail/memstore"
+)
+
+func TestBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		9:  128 * time.Minute,
+		10: 256 * time.Minute,
+		11: 6 * time.Hour,
+		50: 6 * time.Hour,
+	} {
+		if got := backoff(attempts); got != want {
+			t.Errorf("%d: want %v; got %v", attempts, want, got)
+		}
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	// fails once, then succeeds
+	failures := 1
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		if err := Verify("billing-secret-123", r.Header.Get(SignatureHeader), body, time.Now(), time.Minute); err != nil {
+			t.Errorf("want nil; got %v", err)
+		}
+
+		if got := r.Header.Get("X-Atmail-Event"); got != atmail.ActionUserCreate {
+			t.Errorf("want %v; got %v", atmail.ActionUserCreate, got)
+		}
+
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+	}))
+	defer srv.Close()
+
+	fake := newFakeStore(atmail.Webhook{Id: 1, Url: srv.URL, Secret: "billing-secret-123"})
+
+	*fake.deliveries = []atmail.WebhookDelivery{
+		{Id: 1, WebhookId: 1, Event: atmail.ActionUserCreate, Payload: []byte(`{}`), Status: atmail.DeliveryPending, NextAttemptAt: time.Now()},
+		// not due yet
+		{Id: 2, WebhookId: 1, Event: atmail.ActionUserCreate, Payload: []byte(`{}`), Status: atmail.DeliveryPending, NextAttemptAt: time.Now().Add(time.Hour)},
+	}
+
+	d := NewDispatcher(fake, srv.Client(), time.Minute)
+
+	ctx := context.Background()
+
+	n, err := d.Dispatch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 1 {
+		t.Fatalf("want %v; got %v", 1, n)
+	}
+
+	got := (*fake.deliveries)[0]
+
+	if got.Status != atmail.DeliveryPending || got.Attempts != 1 || time.Until(got.NextAttemptAt) < 20*time.Second {
+		t.Errorf("want pending delivery retried in 30s; got %v", got)
+	}
+
+	// the retry is not due yet
+	if n, err := d.Dispatch(ctx); err != nil || n != 0 {
+		t.Fatalf("want 0, nil; got %v, %v", n, err)
+	}
+
+	if err := d.Attempt(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+
+	got = (*fake.deliveries)[0]
+
+	if got.Status != atmail.DeliverySucceeded || got.Attempts != 2 {
+		t.Errorf("want succeeded delivery after 2 attempts; got %v", got)
+	}
+
+	attempts := *fake.attempts
+
+	if len(attempts) != 2 || attempts[0].StatusCode != http.StatusServiceUnavailable || attempts[0].Error == "" || attempts[1].StatusCode != http.StatusOK || attempts[1].Error != "" {
+		t.Errorf("want a failed and a successful attempt; got %v", attempts)
+	}
+}
+
+func TestDispatcherFailed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	fake := newFakeStore(atmail.Webhook{Id: 1, Url: srv.URL, Secret: "billing-secret-123"})
+
+	*fake.deliveries = []atmail.WebhookDelivery{
+		{Id: 1, WebhookId: 1, Event: atmail.ActionUserCreate, Payload: []byte(`{}`), Status: atmail.DeliveryPending, Attempts: MaxAttempts - 1},
+	}
+
+	if _, err := NewDispatcher(fake, srv.Client(), time.Minute).Dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := (*fake.deliveries)[0]; got.Status != atmail.DeliveryFailed || got.Attempts != MaxAttempts {
+		t.Errorf("want failed delivery after %d attempts; got %v", MaxAttempts, got)
+	}
+}
+
+func TestDispatcherCarriesOn(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	fake := newFakeStore(atmail.Webhook{Id: 1, Url: srv.URL, Secret: "billing-secret-123"})
+	fake.broken = 2
+
+	*fake.deliveries = []atmail.WebhookDelivery{
+		{Id: 1, WebhookId: 2, Event: atmail.ActionUserCreate, Payload: []byte(`{}`), Status: atmail.DeliveryPending},
+		{Id: 2, WebhookId: 1, Event: atmail.ActionUserCreate, Payload: []byte(`{}`), Status: atmail.DeliveryPending},
+	}
+
+	// a delivery that cannot be attempted does not stop the others
+	if n, err := NewDispatcher(fake, srv.Client(), time.Minute).Dispatch(context.Background()); err != nil || n != 2 {
+		t.Fatalf("want 2, nil; got %v, %v", n, err)
+	}
+
+	if got := (*fake.deliveries)[1]; got.Status != atmail.DeliverySucceeded {
+		t.Errorf("want succeeded delivery; got %v", got)
+	}
+
+	// and is left to a later dispatch once its claim runs out
+	if got := (*fake.deliveries)[0]; got.Status != atmail.DeliveryPending || time.Until(got.NextAttemptAt) < lease-time.Minute {
+		t.Errorf("want pending delivery claimed for %v; got %v", lease, got)
+	}
+}
+
+func TestDispatcherConcurrent(t *testing.T) {
+	var mu sync.Mutex
+
+	posts := map[string]int{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		posts[r.Header.Get("X-Atmail-Delivery")]++
+		mu.Unlock()
+
+		// so that the dispatchers overlap
+		time.Sleep(time.Millisecond)
+	}))
+	defer srv.Close()
+
+	store := memstore.New()
+
+	ctx := context.Background()
+
+	hookId, err := store.CreateWebhook(ctx, atmail.Webhook{Url: srv.URL, Secret: "billing-secret-123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 50 {
+		if _, err := store.CreateWebhookDelivery(ctx, atmail.WebhookDelivery{WebhookId: hookId, Event: atmail.ActionUserCreate, Payload: []byte(`{}`), Status: atmail.DeliveryPending, NextAttemptAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// two instances dispatch from the same store at once
+	counts := make([]int, 2)
+
+	var wg sync.WaitGroup
+
+	for i := range counts {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			n, err := NewDispatcher(store, srv.Client(), time.Minute).Dispatch(ctx)
+			if err != nil {
+				t.Error(err)
+			}
+
+			counts[i] = n
+		}()
+	}
+
+	wg.Wait()
+
+	if counts[0]+counts[1] != 50 {
+		t.Errorf("want %v; got %v", 50, counts)
+	}
+
+	if len(posts) != 50 {
+		t.Errorf("want %v; got %v", 50, len(posts))
+	}
+
+	for id, n := range posts {
+		if n != 1 {
+			t.Errorf("%s: want %v; got %v", id, 1, n)
+		}
+	}
+}
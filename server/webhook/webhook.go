@@ -0,0 +1,132 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"atmail"
+	"atmail/server/change"
+)
+
+// User is the user of a payload. Only the id is known of users purged after
+// they were deleted.
+type User struct {
+	Id       int64  `json:"id"`
+	Username string `json:"username,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Age      uint   `json:"age,omitempty"`
+}
+
+// Payload is the json body delivered to webhooks.
+type Payload struct {
+	Event   string          `json:"event"`
+	Time    time.Time       `json:"time"`
+	User    User            `json:"user"`
+	Changes []atmail.Change `json:"changes"`
+}
+
+// Enqueue adds a delivery to the outbox for every webhook subscribed to the
+// changes, in the transaction that writes them.
+func Enqueue(ctx context.Context, tx atmail.Store, changes []change.Change) error {
+	webhooks, err := tx.ListWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range changes {
+		p := payload(c.Action, c.User, c.Changes)
+
+		bs, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+
+		for _, w := range webhooks {
+			if !w.Subscribes(p.Event) {
+				continue
+			}
+
+			if _, err := tx.CreateWebhookDelivery(ctx, atmail.WebhookDelivery{
+				WebhookId:     w.Id,
+				Event:         p.Event,
+				Payload:       bs,
+				Status:        atmail.DeliveryPending,
+				NextAttemptAt: p.Time,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func payload(event string, user atmail.User, changes []atmail.Change) Payload {
+	return Payload{
+		Event:   event,
+		Time:    time.Now().UTC(),
+		User:    User{user.Id, user.Username, user.Email, user.Age},
+		Changes: changes,
+	}
+}
+
+// SignatureHeader is the header of the signature of a payload, e.g.
+// "t=1700000000,v1=5257a869...". v1 is the hex HMAC-SHA256 of the timestamp,
+// a dot and the body, keyed with the secret of the webhook.
+const SignatureHeader = "X-Atmail-Signature"
+
+var ErrSignatureInvalid = errors.New("error signature invalid")
+
+func sign(secret string, t int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+
+	fmt.Fprintf(mac, "%d.", t)
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Sign returns the signature header of body at time t.
+func Sign(secret string, t time.Time, body []byte) string {
+	return fmt.Sprintf("t=%d,v1=%s", t.Unix(), sign(secret, t.Unix(), body))
+}
+
+// Verify checks the signature header of body, which must have been signed no
+// longer than tolerance before now so that deliveries cannot be replayed.
+func Verify(secret string, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var t int64
+	var signatures []string
+
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(part, "=")
+
+		switch k {
+		case "t":
+			t, _ = strconv.ParseInt(v, 10, 64)
+		case "v1":
+			signatures = append(signatures, v)
+		}
+	}
+
+	if t == 0 || now.Sub(time.Unix(t, 0)).Abs() > tolerance {
+		return ErrSignatureInvalid
+	}
+
+	want := sign(secret, t, body)
+
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(want)) {
+			return nil
+		}
+	}
+
+	return ErrSignatureInvalid
+}
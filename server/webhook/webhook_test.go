@@ -0,0 +1,259 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"atmail"
+	"atmail/memstore"
+	"atmail/server/change"
+)
+
+// fakeStore stores webhooks and deliveries in memory and keeps the deliveries
+// and attempts of transactions that commit, the rest of atmail.Store is not
+// used.
+type fakeStore struct {
+	atmail.Store
+
+	webhooks   map[int64]atmail.Webhook
+	deliveries *[]atmail.WebhookDelivery
+	attempts   *[]atmail.WebhookAttempt
+	// parent is the store of a transaction, whose deliveries are updated
+	// in place
+	parent *fakeStore
+	// broken is the id of a webhook that cannot be read
+	broken int64
+}
+
+func newFakeStore(webhooks ...atmail.Webhook) *fakeStore {
+	s := &fakeStore{
+		webhooks:   map[int64]atmail.Webhook{},
+		deliveries: &[]atmail.WebhookDelivery{},
+		attempts:   &[]atmail.WebhookAttempt{},
+	}
+
+	for _, w := range webhooks {
+		s.webhooks[w.Id] = w
+	}
+
+	return s
+}
+
+func (s *fakeStore) WithTx(ctx context.Context, fn func(atmail.Store) error) error {
+	if s.parent != nil {
+		return fn(s)
+	}
+
+	tx := &fakeStore{webhooks: s.webhooks, deliveries: &[]atmail.WebhookDelivery{}, attempts: &[]atmail.WebhookAttempt{}, parent: s}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	*s.deliveries = append(*s.deliveries, *tx.deliveries...)
+	*s.attempts = append(*s.attempts, *tx.attempts...)
+
+	return nil
+}
+
+func (s *fakeStore) GetWebhook(ctx context.Context, id int64) (atmail.Webhook, error) {
+	if id == s.broken {
+		return atmail.Webhook{}, errors.New("webhook is broken")
+	}
+
+	w, ok := s.webhooks[id]
+	if !ok {
+		return atmail.Webhook{}, atmail.ErrWebhookNone
+	}
+
+	return w, nil
+}
+
+func (s *fakeStore) ListWebhooks(ctx context.Context) ([]atmail.Webhook, error) {
+	webhooks := []atmail.Webhook{}
+
+	for id := range int64(len(s.webhooks)) {
+		webhooks = append(webhooks, s.webhooks[id+1])
+	}
+
+	return webhooks, nil
+}
+
+func (s *fakeStore) CreateWebhookDelivery(ctx context.Context, d atmail.WebhookDelivery) (int64, error) {
+	d.Id = int64(len(*s.deliveries) + 1)
+	*s.deliveries = append(*s.deliveries, d)
+
+	return d.Id, nil
+}
+
+func (s *fakeStore) ListWebhookDeliveries(ctx context.Context, o atmail.WebhookDeliveryOptions) ([]atmail.WebhookDelivery, error) {
+	deliveries := []atmail.WebhookDelivery{}
+
+	for _, d := range *s.deliveries {
+		if o.Match(d) {
+			deliveries = append(deliveries, d)
+		}
+	}
+
+	return deliveries, nil
+}
+
+func (s *fakeStore) UpdateWebhookDelivery(ctx context.Context, d atmail.WebhookDelivery) error {
+	if s.parent != nil {
+		return s.parent.UpdateWebhookDelivery(ctx, d)
+	}
+
+	for i := range *s.deliveries {
+		if (*s.deliveries)[i].Id == d.Id {
+			(*s.deliveries)[i] = d
+			return nil
+		}
+	}
+
+	return atmail.ErrWebhookDeliveryNone
+}
+
+func (s *fakeStore) ClaimWebhookDelivery(ctx context.Context, id int64, due time.Time, until time.Time) error {
+	for i, d := range *s.deliveries {
+		if d.Id == id && d.Status == atmail.DeliveryPending && !d.NextAttemptAt.After(due) {
+			(*s.deliveries)[i].NextAttemptAt = until
+			return nil
+		}
+	}
+
+	return atmail.ErrWebhookDeliveryNone
+}
+
+func (s *fakeStore) CreateWebhookAttempt(ctx context.Context, a atmail.WebhookAttempt) (int64, error) {
+	*s.attempts = append(*s.attempts, a)
+	return int64(len(*s.attempts)), nil
+}
+
+func TestEnqueue(t *testing.T) {
+	store := memstore.New()
+
+	ctx := context.Background()
+
+	for _, w := range []atmail.Webhook{
+		{Url: "https://billing.example.com", Secret: "billing-secret-123"},
+		{Url: "https://crm.example.com", Secret: "crm-secret-123456", Events: []string{atmail.ActionUserDelete}},
+	} {
+		if _, err := store.CreateWebhook(ctx, w); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bluey := atmail.User{Id: 1, Username: "bluey", Email: "bluey@heeler.com", Age: 7}
+
+	if err := Enqueue(ctx, store, []change.Change{
+		{Action: atmail.ActionUserCreate, User: bluey, Changes: atmail.DiffUsers(nil, &bluey)},
+		{Action: atmail.ActionUserDelete, User: bluey, Changes: atmail.DiffUsers(&bluey, nil)},
+		// only the id is known of users purged after they were deleted
+		{Action: atmail.ActionUserPurge, User: atmail.User{Id: 1}, Changes: []atmail.Change{}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries, err := store.ListWebhookDeliveries(ctx, atmail.WebhookDeliveryOptions{Asc: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type delivery struct {
+		WebhookId int64
+		Event     string
+		User      User
+		Changes   int
+	}
+
+	want := []delivery{
+		{1, atmail.ActionUserCreate, User{1, "bluey", "bluey@heeler.com", 7}, 3},
+		{1, atmail.ActionUserDelete, User{1, "bluey", "bluey@heeler.com", 7}, 3},
+		{2, atmail.ActionUserDelete, User{1, "bluey", "bluey@heeler.com", 7}, 3},
+		{1, atmail.ActionUserPurge, User{Id: 1}, 0},
+	}
+
+	got := []delivery{}
+
+	for _, d := range deliveries {
+		if d.Status != atmail.DeliveryPending {
+			t.Errorf("want %v; got %v", atmail.DeliveryPending, d.Status)
+		}
+
+		p := Payload{}
+
+		if err := json.Unmarshal(d.Payload, &p); err != nil {
+			t.Fatal(err)
+		}
+
+		if p.Event != d.Event {
+			t.Errorf("want %v; got %v", d.Event, p.Event)
+		}
+
+		got = append(got, delivery{d.WebhookId, d.Event, p.User, len(p.Changes)})
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v; got %v", want, got)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"event":"user.create"}`)
+	now := time.Unix(1700000000, 0)
+
+	header := Sign("secret", now, body)
+
+	for name, tc := range map[string]struct {
+		secret string
+		header string
+		body   []byte
+		now    time.Time
+		want   error
+	}{
+		"valid": {
+			secret: "secret",
+			header: header,
+			body:   body,
+			now:    now.Add(time.Minute),
+		},
+		"wrong secret": {
+			secret: "other",
+			header: header,
+			body:   body,
+			now:    now,
+			want:   ErrSignatureInvalid,
+		},
+		"tampered body": {
+			secret: "secret",
+			header: header,
+			body:   []byte(`{"event":"user.delete"}`),
+			now:    now,
+			want:   ErrSignatureInvalid,
+		},
+		"replayed": {
+			secret: "secret",
+			header: header,
+			body:   body,
+			now:    now.Add(time.Hour),
+			want:   ErrSignatureInvalid,
+		},
+		"no timestamp": {
+			secret: "secret",
+			header: "v1=abc",
+			body:   body,
+			now:    now,
+			want:   ErrSignatureInvalid,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := Verify(tc.secret, tc.header, tc.body, tc.now, 5*time.Minute); got != tc.want {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
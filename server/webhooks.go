@@ -0,0 +1,427 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"atmail"
+)
+
+const webhookSecretPrefix = "whsec_"
+
+// newWebhookSecret returns a random secret for webhooks created without one.
+func newWebhookSecret() (string, error) {
+	bs := make([]byte, 32)
+
+	if _, err := rand.Read(bs); err != nil {
+		return "", err
+	}
+
+	return webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(bs), nil
+}
+
+type webhookOutload struct {
+	Id     int64    `json:"id"`
+	Url    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret is only shown when the webhook is created.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func toWebhookOutload(w atmail.Webhook) webhookOutload {
+	o := webhookOutload{
+		Id:        w.Id,
+		Url:       w.Url,
+		Events:    w.Events,
+		CreatedAt: w.CreatedAt,
+	}
+
+	if o.Events == nil {
+		o.Events = []string{}
+	}
+
+	return o
+}
+
+type getWebhookOutload struct {
+	webhookOutload
+}
+
+func (o getWebhookOutload) code() int {
+	return http.StatusOK
+}
+
+type createWebhookOutload struct {
+	webhookOutload
+}
+
+func (o createWebhookOutload) code() int {
+	return http.StatusCreated
+}
+
+type webhookInload struct {
+	Url    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
+
+// webhookId parses the {id} of webhook routes.
+func webhookId(r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	return id, err == nil
+}
+
+// createWebhook creates a webhook, with a random secret unless one is given.
+// The secret is only ever shown in the response.
+func createWebhook(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	i := webhookInload{}
+
+	if err := json.NewDecoder(r.Body).Decode(&i); err != nil {
+		return badRequest("invalid json!"), nil
+	}
+
+	if i.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+
+		i.Secret = secret
+	}
+
+	webhook := atmail.Webhook{
+		Url:       i.Url,
+		Secret:    i.Secret,
+		Events:    i.Events,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	if s, ok := atmail.ValidateWebhook(webhook); !ok {
+		return badRequest(s), nil
+	}
+
+	id, err := s.CreateWebhook(r.Context(), webhook)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.Id = id
+
+	o := createWebhookOutload{toWebhookOutload(webhook)}
+	o.Secret = webhook.Secret
+
+	return o, nil
+}
+
+type listWebhooksOutload struct {
+	Webhooks []webhookOutload `json:"webhooks"`
+}
+
+func (o listWebhooksOutload) code() int {
+	return http.StatusOK
+}
+
+func listWebhooks(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	webhooks, err := s.ListWebhooks(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	o := listWebhooksOutload{Webhooks: []webhookOutload{}}
+
+	for _, webhook := range webhooks {
+		o.Webhooks = append(o.Webhooks, toWebhookOutload(webhook))
+	}
+
+	return o, nil
+}
+
+func getWebhook(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	id, ok := webhookId(r)
+	if !ok {
+		return badRequest("webhook does not exist!"), nil
+	}
+
+	webhook, err := s.GetWebhook(r.Context(), id)
+	if err != nil {
+		if !errors.Is(err, atmail.ErrWebhookNone) {
+			return nil, err
+		}
+
+		return badRequest("webhook does not exist!"), nil
+	}
+
+	return getWebhookOutload{toWebhookOutload(webhook)}, nil
+}
+
+// updateWebhook replaces the url and events of a webhook, and its secret if
+// one is given.
+func updateWebhook(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	id, ok := webhookId(r)
+	if !ok {
+		return badRequest("webhook does not exist!"), nil
+	}
+
+	i := webhookInload{}
+
+	if err := json.NewDecoder(r.Body).Decode(&i); err != nil {
+		return badRequest("invalid json!"), nil
+	}
+
+	webhook, err := s.GetWebhook(r.Context(), id)
+	if err != nil {
+		if !errors.Is(err, atmail.ErrWebhookNone) {
+			return nil, err
+		}
+
+		return badRequest("webhook does not exist!"), nil
+	}
+
+	webhook.Url, webhook.Events = i.Url, i.Events
+
+	if i.Secret != "" {
+		webhook.Secret = i.Secret
+	}
+
+	if s, ok := atmail.ValidateWebhook(webhook); !ok {
+		return badRequest(s), nil
+	}
+
+	if err := s.UpdateWebhook(r.Context(), webhook); err != nil {
+		if !errors.Is(err, atmail.ErrWebhookNone) {
+			return nil, err
+		}
+
+		return badRequest("webhook does not exist!"), nil
+	}
+
+	return getWebhookOutload{toWebhookOutload(webhook)}, nil
+}
+
+func deleteWebhook(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	id, ok := webhookId(r)
+	if !ok {
+		return badRequest("webhook does not exist!"), nil
+	}
+
+	if err := s.DeleteWebhook(r.Context(), id); err != nil {
+		if !errors.Is(err, atmail.ErrWebhookNone) {
+			return nil, err
+		}
+
+		return badRequest("webhook does not exist!"), nil
+	}
+
+	return messageOutload{fmt.Sprintf("successfully deleted webhook %d!", id)}, nil
+}
+
+type deliveryOutload struct {
+	Id        int64  `json:"id"`
+	WebhookId int64  `json:"webhook_id"`
+	Event     string `json:"event"`
+	Status    string `json:"status"`
+	Attempts  int    `json:"attempts"`
+	// NextAttemptAt is only shown while the delivery is pending.
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func toDeliveryOutload(d atmail.WebhookDelivery) deliveryOutload {
+	o := deliveryOutload{
+		Id:        d.Id,
+		WebhookId: d.WebhookId,
+		Event:     d.Event,
+		Status:    d.Status,
+		Attempts:  d.Attempts,
+		CreatedAt: d.CreatedAt,
+	}
+
+	if d.Status == atmail.DeliveryPending {
+		o.NextAttemptAt = &d.NextAttemptAt
+	}
+
+	return o
+}
+
+type listDeliveriesOutload struct {
+	Items      []deliveryOutload `json:"items"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+func (o listDeliveriesOutload) code() int {
+	return http.StatusOK
+}
+
+// listWebhookDeliveries lists the deliveries of a webhook newest first,
+// optionally only those of a status.
+func listWebhookDeliveries(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	id, ok := webhookId(r)
+	if !ok {
+		return badRequest("webhook does not exist!"), nil
+	}
+
+	q := r.URL.Query()
+
+	o := atmail.WebhookDeliveryOptions{
+		Limit:     listUsersDefaultLimit,
+		WebhookId: id,
+		Status:    q.Get("status"),
+	}
+
+	if o.Status != "" && !slices.Contains([]string{atmail.DeliveryPending, atmail.DeliverySucceeded, atmail.DeliveryFailed}, o.Status) {
+		return badRequest("status is invalid!"), nil
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > listUsersMaxLimit {
+			return badRequest("limit is invalid!"), nil
+		}
+
+		o.Limit = limit
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		c, err := decodeCursor(v)
+		if err != nil || c.Id < 1 {
+			return badRequest("cursor is invalid!"), nil
+		}
+
+		o.Before = c.Id
+	}
+
+	if _, err := s.GetWebhook(r.Context(), id); err != nil {
+		if !errors.Is(err, atmail.ErrWebhookNone) {
+			return nil, err
+		}
+
+		return badRequest("webhook does not exist!"), nil
+	}
+
+	// fetch one more delivery to know whether there is a next page
+	o.Limit++
+
+	deliveries, err := s.ListWebhookDeliveries(r.Context(), o)
+	if err != nil {
+		return nil, err
+	}
+
+	out := listDeliveriesOutload{Items: []deliveryOutload{}}
+
+	if len(deliveries) == o.Limit {
+		deliveries = deliveries[:len(deliveries)-1]
+
+		out.NextCursor = encodeCursor(cursor{Id: deliveries[len(deliveries)-1].Id})
+	}
+
+	for _, d := range deliveries {
+		out.Items = append(out.Items, toDeliveryOutload(d))
+	}
+
+	return out, nil
+}
+
+type attemptOutload struct {
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+type getDeliveryOutload struct {
+	deliveryOutload
+	Payload json.RawMessage  `json:"payload"`
+	History []attemptOutload `json:"history"`
+}
+
+func (o getDeliveryOutload) code() int {
+	return http.StatusOK
+}
+
+// findDelivery returns the delivery of {delivery}, which must be of the webhook
+// of {id}.
+func findDelivery(s atmail.Store, r *http.Request) (atmail.WebhookDelivery, outload, error) {
+	hookId, ok := webhookId(r)
+	if !ok {
+		return atmail.WebhookDelivery{}, badRequest("webhook delivery does not exist!"), nil
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("delivery"), 10, 64)
+	if err != nil {
+		return atmail.WebhookDelivery{}, badRequest("webhook delivery does not exist!"), nil
+	}
+
+	d, err := s.GetWebhookDelivery(r.Context(), id)
+	if err != nil && !errors.Is(err, atmail.ErrWebhookDeliveryNone) {
+		return atmail.WebhookDelivery{}, nil, err
+	}
+
+	if err != nil || d.WebhookId != hookId {
+		return atmail.WebhookDelivery{}, badRequest("webhook delivery does not exist!"), nil
+	}
+
+	return d, nil, nil
+}
+
+// getWebhookDelivery returns a delivery with its payload and the history of
+// its attempts.
+func getWebhookDelivery(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	d, o, err := findDelivery(s, r)
+	if o != nil || err != nil {
+		return o, err
+	}
+
+	attempts, err := s.ListWebhookAttempts(r.Context(), d.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	out := getDeliveryOutload{
+		deliveryOutload: toDeliveryOutload(d),
+		Payload:         d.Payload,
+		History:         []attemptOutload{},
+	}
+
+	for _, a := range attempts {
+		out.History = append(out.History, attemptOutload{a.Time, a.StatusCode, a.Error, a.Duration.Milliseconds()})
+	}
+
+	return out, nil
+}
+
+type redeliverOutload struct {
+	deliveryOutload
+}
+
+func (o redeliverOutload) code() int {
+	return http.StatusAccepted
+}
+
+// redeliverWebhookDelivery schedules a delivery to be attempted again right
+// away, with all of its retries, whatever its status.
+func redeliverWebhookDelivery(s atmail.Store, w http.ResponseWriter, r *http.Request) (outload, error) {
+	d, o, err := findDelivery(s, r)
+	if o != nil || err != nil {
+		return o, err
+	}
+
+	d.Status, d.Attempts, d.NextAttemptAt = atmail.DeliveryPending, 0, time.Now().UTC().Truncate(time.Second)
+
+	if err := s.UpdateWebhookDelivery(r.Context(), d); err != nil {
+		if !errors.Is(err, atmail.ErrWebhookDeliveryNone) {
+			return nil, err
+		}
+
+		return badRequest("webhook delivery does not exist!"), nil
+	}
+
+	return redeliverOutload{toDeliveryOutload(d)}, nil
+}
@@ -0,0 +1,385 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"atmail"
+	"atmail/memstore"
+)
+
+var testWebhook = atmail.Webhook{Id: 1, Url: "https://billing.example.com/hooks", Secret: "billing-secret-123", Events: []string{atmail.ActionUserCreate}, CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+// testDeliveries are newest first, an hour apart, the last of another
+// webhook.
+var testDeliveries = []atmail.WebhookDelivery{
+	{Id: 4, WebhookId: 1, Event: atmail.ActionUserCreate, Payload: []byte(`{"event":"user.create"}`), Status: atmail.DeliveryPending, Attempts: 2, CreatedAt: time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)},
+	{Id: 3, WebhookId: 1, Event: atmail.ActionUserCreate, Payload: []byte(`{"event":"user.create"}`), Status: atmail.DeliveryFailed, Attempts: 10, CreatedAt: time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)},
+	{Id: 2, WebhookId: 1, Event: atmail.ActionUserCreate, Payload: []byte(`{"event":"user.create"}`), Status: atmail.DeliverySucceeded, Attempts: 1, CreatedAt: time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)},
+	{Id: 1, WebhookId: 2, Event: atmail.ActionUserDelete, Payload: []byte(`{"event":"user.delete"}`), Status: atmail.DeliverySucceeded, Attempts: 1, CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+}
+
+// newWebhookStore returns a store with testWebhook, testDeliveries and
+// attempts.
+func newWebhookStore(t *testing.T, attempts ...atmail.WebhookAttempt) *memstore.Store {
+	t.Helper()
+
+	store := memstore.New()
+
+	if _, err := store.CreateWebhook(context.Background(), testWebhook); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, d := range slices.Backward(testDeliveries) {
+		if _, err := store.CreateWebhookDelivery(context.Background(), d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, a := range attempts {
+		if _, err := store.CreateWebhookAttempt(context.Background(), a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return store
+}
+
+func TestCreateWebhookOk(t *testing.T) {
+	for name, tc := range map[string]struct {
+		inload string
+		events []string
+		secret string
+	}{
+		"all events": {
+			inload: `{ "url": "https://crm.example.com/hooks" }`,
+			events: []string{},
+		},
+		"some events with secret": {
+			inload: `{ "url": "http://crm.example.com/hooks", "secret": "crm-secret-123456", "events": ["user.create", "user.delete"] }`,
+			events: []string{"user.create", "user.delete"},
+			secret: "crm-secret-123456",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/webhooks", bytes.NewBufferString(tc.inload))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			got, err := createWebhook(newWebhookStore(t), rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			o, ok := got.(createWebhookOutload)
+			if !ok {
+				t.Fatalf("want createWebhookOutload; got %v", got)
+			}
+
+			if o.Id != 2 {
+				t.Errorf("want %v; got %v", 2, o.Id)
+			}
+
+			if !reflect.DeepEqual(tc.events, o.Events) {
+				t.Errorf("want %v; got %v", tc.events, o.Events)
+			}
+
+			if tc.secret == "" && !strings.HasPrefix(o.Secret, webhookSecretPrefix) {
+				t.Errorf("want secret prefixed with %s; got %s", webhookSecretPrefix, o.Secret)
+			}
+
+			if tc.secret != "" && o.Secret != tc.secret {
+				t.Errorf("want %v; got %v", tc.secret, o.Secret)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookNotOk(t *testing.T) {
+	for name, tc := range map[string]struct {
+		inload string
+		want   outload
+	}{
+		"invalid json": {
+			inload: `this invalid json`,
+			want:   badRequest("invalid json!"),
+		},
+		"blank url": {
+			inload: `{}`,
+			want:   badRequest("url is invalid!"),
+		},
+		"invalid scheme": {
+			inload: `{ "url": "ftp://crm.example.com" }`,
+			want:   badRequest("url is invalid!"),
+		},
+		"short secret": {
+			inload: `{ "url": "https://crm.example.com", "secret": "short" }`,
+			want:   badRequest("secret must be at least 16 characters!"),
+		},
+		"invalid event": {
+			inload: `{ "url": "https://crm.example.com", "events": ["user.create", "authorization.denied"] }`,
+			want:   badRequest("event authorization.denied is invalid!"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/webhooks", bytes.NewBufferString(tc.inload))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			got, err := createWebhook(memstore.New(), rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestUpdateWebhook(t *testing.T) {
+	for name, tc := range map[string]struct {
+		id     string
+		inload string
+		want   outload
+	}{
+		"keeps secret": {
+			id:     "1",
+			inload: `{ "url": "https://billing.example.com/v2/hooks" }`,
+			want: getWebhookOutload{webhookOutload{
+				Id:        1,
+				Url:       "https://billing.example.com/v2/hooks",
+				Events:    []string{},
+				CreatedAt: testWebhook.CreatedAt,
+			}},
+		},
+		"invalid": {
+			id:     "1",
+			inload: `{ "url": "billing" }`,
+			want:   badRequest("url is invalid!"),
+		},
+		"nonexistent": {
+			id:     "2",
+			inload: `{ "url": "https://billing.example.com" }`,
+			want:   badRequest("webhook does not exist!"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("PUT", "/webhooks/"+tc.id, bytes.NewBufferString(tc.inload))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.SetPathValue("id", tc.id)
+
+			rr := httptest.NewRecorder()
+
+			got, err := updateWebhook(newWebhookStore(t), rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestListWebhookDeliveries(t *testing.T) {
+	store := newWebhookStore(t)
+
+	for name, tc := range map[string]struct {
+		id    string
+		query string
+		want  []int64
+		err   outload
+	}{
+		"all": {
+			id:   "1",
+			want: []int64{4, 3, 2},
+		},
+		"status": {
+			id:    "1",
+			query: "status=failed",
+			want:  []int64{3},
+		},
+		"invalid status": {
+			id:    "1",
+			query: "status=lost",
+			err:   badRequest("status is invalid!"),
+		},
+		"nonexistent webhook": {
+			id:  "3",
+			err: badRequest("webhook does not exist!"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got := []int64{}
+
+			// page through two deliveries at a time
+			next := ""
+
+			for range len(testDeliveries) {
+				req, err := http.NewRequest("GET", "/webhooks/"+tc.id+"/deliveries?limit=2&"+tc.query+"&cursor="+next, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.SetPathValue("id", tc.id)
+
+				rr := httptest.NewRecorder()
+
+				res, err := listWebhookDeliveries(store, rr, req)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if tc.err != nil {
+					if !reflect.DeepEqual(tc.err, res) {
+						t.Errorf("want %v; got %v", tc.err, res)
+					}
+
+					return
+				}
+
+				o, ok := res.(listDeliveriesOutload)
+				if !ok {
+					t.Fatalf("want listDeliveriesOutload; got %v", res)
+				}
+
+				for _, item := range o.Items {
+					got = append(got, item.Id)
+				}
+
+				if o.NextCursor == "" {
+					break
+				}
+
+				next = o.NextCursor
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestGetWebhookDelivery(t *testing.T) {
+	attempt := atmail.WebhookAttempt{Id: 1, DeliveryId: 4, Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), StatusCode: 503, Error: "webhook responded with 503 Service Unavailable", Duration: 120 * time.Millisecond}
+
+	store := newWebhookStore(t, attempt)
+
+	for name, tc := range map[string]struct {
+		id       string
+		delivery string
+		want     outload
+	}{
+		"ok": {
+			id:       "1",
+			delivery: "4",
+			want: getDeliveryOutload{
+				deliveryOutload: deliveryOutload{Id: 4, WebhookId: 1, Event: "user.create", Status: "pending", Attempts: 2, NextAttemptAt: &time.Time{}, CreatedAt: testDeliveries[0].CreatedAt},
+				Payload:         []byte(`{"event":"user.create"}`),
+				History:         []attemptOutload{{attempt.Time, 503, attempt.Error, 120}},
+			},
+		},
+		"of another webhook": {
+			id:       "1",
+			delivery: "1",
+			want:     badRequest("webhook delivery does not exist!"),
+		},
+		"nonexistent": {
+			id:       "1",
+			delivery: "5",
+			want:     badRequest("webhook delivery does not exist!"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/webhooks/"+tc.id+"/deliveries/"+tc.delivery, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.SetPathValue("id", tc.id)
+			req.SetPathValue("delivery", tc.delivery)
+
+			rr := httptest.NewRecorder()
+
+			got, err := getWebhookDelivery(store, rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRedeliverWebhookDelivery(t *testing.T) {
+	store := newWebhookStore(t)
+
+	for name, tc := range map[string]struct {
+		delivery string
+		want     outload
+	}{
+		"failed": {
+			delivery: "3",
+		},
+		"nonexistent": {
+			delivery: "5",
+			want:     badRequest("webhook delivery does not exist!"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/webhooks/1/deliveries/"+tc.delivery+"/redeliver", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.SetPathValue("id", "1")
+			req.SetPathValue("delivery", tc.delivery)
+
+			rr := httptest.NewRecorder()
+
+			got, err := redeliverWebhookDelivery(store, rr, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.want != nil {
+				if !reflect.DeepEqual(tc.want, got) {
+					t.Errorf("want %v; got %v", tc.want, got)
+				}
+
+				return
+			}
+
+			o, ok := got.(redeliverOutload)
+			if !ok {
+				t.Fatalf("want redeliverOutload; got %v", got)
+			}
+
+			if o.code() != http.StatusAccepted || o.Status != atmail.DeliveryPending || o.Attempts != 0 || o.NextAttemptAt == nil {
+				t.Errorf("want pending delivery without attempts; got %v", o)
+			}
+		})
+	}
+}
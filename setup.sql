@@ -1,23 +1,12 @@
-DROP TABLE IF EXISTS admins;
-CREATE TABLE admins (
-  user varchar(255) NOT NULL,
-  password varchar(255) NOT NULL,
-  role int NOT NULL,
-  UNIQUE KEY user (user)
-);
+-- example admins for development, one of each role, see the roles table in
+-- README.md. Run after the migrations, e.g. go run ./cmd/atmail migrate up
 
-INSERT INTO admins VALUES ('alice','pass1234',1);
-INSERT INTO admins VALUES ('bob','pass2345',2);
-INSERT INTO admins VALUES ('craig','pass3456',4);
-INSERT INTO admins VALUES ('dan','pass4567',8);
+INSERT IGNORE INTO admins VALUES ('alice','$argon2id$v=19$m=19456,t=2,p=1$918m77qPjyazuCcQBApDCQ$Fq/nh+imBblUympel+ioTHmbeOukbb8TbxUG3yy+qLo',false);
+INSERT IGNORE INTO admins VALUES ('bob','$argon2id$v=19$m=19456,t=2,p=1$T5YAxRp9jimXz89ovoFSkA$BA/3pEBeveOtARAKVvLEx4zilD21GOb6I1lYGgcjaW0',false);
+INSERT IGNORE INTO admins VALUES ('craig','$argon2id$v=19$m=19456,t=2,p=1$RSbZbjhrs/4TyIxtJhRd3Q$8wGui0orGFtAiWaGJGUy7gdoTluYham6wsZZ/OWux0Y',false);
+INSERT IGNORE INTO admins VALUES ('dan','$argon2id$v=19$m=19456,t=2,p=1$LQOgLuKT1W1xVBzGdrZO9A$nsABXzLrYWhijopqOSKFqV2dX7jeNtRLnGedHukdwQg',false);
 
-DROP TABLE IF EXISTS users;
-CREATE TABLE users (
-  id int NOT NULL AUTO_INCREMENT,
-  username varchar(255) NOT NULL,
-  email varchar(255) NOT NULL,
-  age int NOT NULL,
-  PRIMARY KEY (id),
-  UNIQUE KEY username (username),
-  UNIQUE KEY email (email)
-);
+INSERT IGNORE INTO admin_roles VALUES ('alice',1);
+INSERT IGNORE INTO admin_roles VALUES ('bob',2);
+INSERT IGNORE INTO admin_roles VALUES ('craig',3);
+INSERT IGNORE INTO admin_roles VALUES ('dan',4);
@@ -0,0 +1,22 @@
+package atmail
+
+import (
+	"database/sql"
+	"net/url"
+)
+
+// OpenSQLite opens the SQLite database file at path, creating it if there is
+// none. Transactions lock the database for writing as soon as they begin, so
+// that they run one at a time, and wait up to 5 seconds for the lock.
+func OpenSQLite(path string) (*sql.DB, error) {
+	q := url.Values{}
+
+	q.Add("_pragma", "busy_timeout(5000)")
+	q.Add("_pragma", "journal_mode(WAL)")
+	q.Add("_pragma", "foreign_keys(1)")
+	// times are written as text that sorts in time order when in UTC
+	q.Set("_time_format", "sqlite")
+	q.Set("_txlock", "immediate")
+
+	return sql.Open("sqlite", "file:"+path+"?"+q.Encode())
+}
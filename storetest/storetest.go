@@ -0,0 +1,861 @@
+// Package storetest defines how every atmail.Store must behave, as tests
+// that each implementation runs against itself:
+//
+//	func TestStore(t *testing.T) {
+//		storetest.Run(t, func() atmail.Store { return memstore.New() })
+//	}
+package storetest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"atmail"
+)
+
+// missingId is the id of a user that no test creates.
+const missingId = 999999
+
+// Run runs every test against its own store from newStore, which must have
+// the roles of the migrations but no users, admins or tokens.
+func Run(t *testing.T, newStore func() atmail.Store) {
+	for name, test := range map[string]func(*testing.T, atmail.Store){
+		"CreateUser":  testCreateUser,
+		"ListUsers":   testListUsers,
+		"Uniqueness":  testUniqueness,
+		"UpdateUser":  testUpdateUser,
+		"DeleteUser":  testDeleteUser,
+		"WithTx":      testWithTx,
+		"Concurrency": testConcurrency,
+		"Admins":      testAdmins,
+		"Roles":       testRoles,
+		"SearchUsers": testSearchUsers,
+		"Deliveries":  testDeliveries,
+	} {
+		t.Run(name, func(t *testing.T) {
+			test(t, newStore())
+		})
+	}
+}
+
+var (
+	bingo  = atmail.User{Username: "bingo", Email: "bingo@heeler.com", Age: 6}
+	bluey  = atmail.User{Username: "bluey", Email: "bluey@heeler.com", Age: 7}
+	chilli = atmail.User{Username: "chilli", Email: "chilli@heeler.com", Age: 38}
+)
+
+// createUsers creates users and returns them as they are stored.
+func createUsers(t *testing.T, s atmail.Store, users ...atmail.User) []atmail.User {
+	t.Helper()
+
+	created := []atmail.User{}
+
+	for _, user := range users {
+		id, err := s.CreateUser(context.Background(), user)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		user.Id, user.Version = id, 1
+		created = append(created, user)
+	}
+
+	return created
+}
+
+func testCreateUser(t *testing.T, s atmail.Store) {
+	ctx := context.Background()
+
+	user := createUsers(t, s, bingo)[0]
+
+	got, err := s.GetUser(ctx, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(user, got) {
+		t.Errorf("want %v; got %v", user, got)
+	}
+
+	ids, err := s.CreateUsers(ctx, []atmail.User{bluey, chilli})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ids go up in the order of the users
+	if len(ids) != 2 || ids[0] <= user.Id || ids[1] <= ids[0] {
+		t.Errorf("want %v ids after %v; got %v", 2, user.Id, ids)
+	}
+
+	for i, want := range []atmail.User{bluey, chilli} {
+		if i >= len(ids) {
+			break
+		}
+
+		want.Id, want.Version = ids[i], 1
+
+		got, err := s.GetUser(ctx, ids[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("want %v; got %v", want, got)
+		}
+	}
+
+	if _, err := s.GetUser(ctx, missingId); err != atmail.ErrUserNone {
+		t.Errorf("want %v; got %v", atmail.ErrUserNone, err)
+	}
+}
+
+func testListUsers(t *testing.T, s atmail.Store) {
+	ctx := context.Background()
+
+	users := createUsers(t, s, bingo, bluey, chilli)
+
+	for name, tc := range map[string]struct {
+		options atmail.ListUsersOptions
+		want    []atmail.User
+	}{
+		"all": {
+			options: atmail.ListUsersOptions{},
+			want:    users,
+		},
+		"limit": {
+			options: atmail.ListUsersOptions{Limit: 2},
+			want:    users[:2],
+		},
+		"after": {
+			options: atmail.ListUsersOptions{After: &users[0]},
+			want:    users[1:],
+		},
+		"desc": {
+			options: atmail.ListUsersOptions{Desc: true, Limit: 1},
+			want:    users[2:],
+		},
+		"username prefix": {
+			options: atmail.ListUsersOptions{UsernamePrefix: "bl"},
+			want:    users[1:2],
+		},
+		"username prefix case": {
+			options: atmail.ListUsersOptions{UsernamePrefix: "Bl"},
+			want:    users[1:2],
+		},
+		"email prefix case": {
+			options: atmail.ListUsersOptions{EmailPrefix: "CHILLI@"},
+			want:    users[2:],
+		},
+		"age": {
+			options: atmail.ListUsersOptions{MinAge: 7, MaxAge: 7},
+			want:    users[1:2],
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := s.ListUsers(ctx, tc.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+
+	// deleted users are not listed
+	if err := s.DeleteUser(ctx, users[0].Id); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.ListUsers(ctx, atmail.ListUsersOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(users[1:], got) {
+		t.Errorf("want %v; got %v", users[1:], got)
+	}
+
+	// the wildcards of LIKE in prefixes match only themselves
+	wildcards := createUsers(t, s,
+		atmail.User{Username: "john_doe", Email: "john%doe@doe.com", Age: 30},
+		atmail.User{Username: "johnxdoe", Email: "johnxdoe@doe.com", Age: 30},
+	)
+
+	for name, options := range map[string]atmail.ListUsersOptions{
+		"username underscore": {UsernamePrefix: "john_"},
+		"email percent":       {EmailPrefix: "john%"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := s.ListUsers(ctx, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(wildcards[:1], got) {
+				t.Errorf("want %v; got %v", wildcards[:1], got)
+			}
+		})
+	}
+}
+
+func testUniqueness(t *testing.T, s atmail.Store) {
+	ctx := context.Background()
+
+	// taken by a user whose username and email are not lower case
+	bob := atmail.User{Username: "Bob", Email: "Bob@Heeler.com", Age: 8}
+
+	users := createUsers(t, s, bingo, bluey, bob)
+
+	for name, tc := range map[string]struct {
+		user  atmail.User
+		field string
+	}{
+		"username": {
+			user:  atmail.User{Username: "bingo", Email: "other@heeler.com", Age: 6},
+			field: "username",
+		},
+		"username case": {
+			user:  atmail.User{Username: "BINGO", Email: "other@heeler.com", Age: 6},
+			field: "username",
+		},
+		"email": {
+			user:  atmail.User{Username: "other", Email: "bingo@heeler.com", Age: 6},
+			field: "email",
+		},
+		"email case": {
+			user:  atmail.User{Username: "other", Email: "Bingo@Heeler.com", Age: 6},
+			field: "email",
+		},
+		"username lower case": {
+			user:  atmail.User{Username: "bob", Email: "other@heeler.com", Age: 8},
+			field: "username",
+		},
+		"email lower case": {
+			user:  atmail.User{Username: "other", Email: "bob@heeler.com", Age: 8},
+			field: "email",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := s.CreateUser(ctx, tc.user)
+
+			var exists atmail.ErrUserExists
+
+			if !errors.As(err, &exists) || exists.Field != tc.field {
+				t.Errorf("want %v; got %v", atmail.ErrUserExists{Field: tc.field}, err)
+			}
+
+			// taking it by an update instead
+			user := tc.user
+			user.Id, user.Version = users[1].Id, users[1].Version
+
+			if err := s.UpdateUser(ctx, user); !errors.As(err, &exists) || exists.Field != tc.field {
+				t.Errorf("want %v; got %v", atmail.ErrUserExists{Field: tc.field}, err)
+			}
+		})
+	}
+
+	// a batch with a taken username creates none of its users
+	_, err := s.CreateUsers(ctx, []atmail.User{chilli, {Username: "Chilli", Email: "other@heeler.com", Age: 38}})
+
+	var exists atmail.ErrUserExists
+
+	if !errors.As(err, &exists) || exists.Field != "username" {
+		t.Errorf("want %v; got %v", atmail.ErrUserExists{Field: "username"}, err)
+	}
+
+	if exists, err := s.CheckUser(ctx, "chilli", ""); err != nil || exists {
+		t.Errorf("want %v; got %v", false, exists)
+	}
+
+	if exists, err := s.CheckUser(ctx, "bob", ""); err != nil || !exists {
+		t.Errorf("want %v; got %v", true, exists)
+	}
+
+	// deleted users keep their username reserved
+	if err := s.DeleteUser(ctx, users[0].Id); err != nil {
+		t.Fatal(err)
+	}
+
+	if exists, err := s.CheckUser(ctx, "Bingo", ""); err != nil || !exists {
+		t.Errorf("want %v; got %v", true, exists)
+	}
+
+	if _, err := s.CreateUser(ctx, bingo); !errors.As(err, &exists) {
+		t.Errorf("want %v; got %v", atmail.ErrUserExists{Field: "username"}, err)
+	}
+}
+
+func testUpdateUser(t *testing.T, s atmail.Store) {
+	ctx := context.Background()
+
+	user := createUsers(t, s, bingo)[0]
+	user.Username, user.Email, user.Age = "bingo2", "bingo2@heeler.com", 7
+
+	if err := s.UpdateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	user.Version++
+
+	got, err := s.GetUser(ctx, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(user, got) {
+		t.Errorf("want %v; got %v", user, got)
+	}
+
+	for name, tc := range map[string]struct {
+		user atmail.User
+		want error
+	}{
+		"stale version": {
+			user: atmail.User{Id: user.Id, Username: "bingo3", Email: "bingo3@heeler.com", Age: 8, Version: 1},
+			want: atmail.ErrVersionConflict{Id: user.Id, Version: 2},
+		},
+		"zero id": {
+			user: atmail.User{Username: "bingo3", Email: "bingo3@heeler.com", Age: 8, Version: 1},
+			want: atmail.ErrUserNone,
+		},
+		"missing id": {
+			user: atmail.User{Id: missingId, Username: "bingo3", Email: "bingo3@heeler.com", Age: 8, Version: 1},
+			want: atmail.ErrUserNone,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := s.UpdateUser(ctx, tc.user)
+
+			var conflict atmail.ErrVersionConflict
+
+			if errors.As(err, &conflict) {
+				err = conflict
+			}
+
+			if err != tc.want {
+				t.Errorf("want %v; got %v", tc.want, err)
+			}
+		})
+	}
+
+	// nothing was updated by the failures
+	got, err = s.GetUser(ctx, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(user, got) {
+		t.Errorf("want %v; got %v", user, got)
+	}
+
+	// deleted users cannot be updated
+	if err := s.DeleteUser(ctx, user.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.UpdateUser(ctx, user); err != atmail.ErrUserNone {
+		t.Errorf("want %v; got %v", atmail.ErrUserNone, err)
+	}
+}
+
+func testDeleteUser(t *testing.T, s atmail.Store) {
+	ctx := context.Background()
+
+	user := createUsers(t, s, bingo)[0]
+
+	if err := s.DeleteUser(ctx, user.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.GetUser(ctx, user.Id); err != atmail.ErrUserNone {
+		t.Errorf("want %v; got %v", atmail.ErrUserNone, err)
+	}
+
+	if err := s.DeleteUser(ctx, user.Id); err != atmail.ErrUserNone {
+		t.Errorf("want %v; got %v", atmail.ErrUserNone, err)
+	}
+
+	// restoring increments the version
+	user.Version++
+
+	got, err := s.RestoreUser(ctx, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(user, got) {
+		t.Errorf("want %v; got %v", user, got)
+	}
+
+	for name, id := range map[string]int64{
+		"not deleted": user.Id,
+		"missing id":  missingId,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := s.RestoreUser(ctx, id); err != atmail.ErrUserNone {
+				t.Errorf("want %v; got %v", atmail.ErrUserNone, err)
+			}
+		})
+	}
+
+	// purging works whether the user is deleted or not
+	if err := s.PurgeUser(ctx, user.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.PurgeUser(ctx, user.Id); err != atmail.ErrUserNone {
+		t.Errorf("want %v; got %v", atmail.ErrUserNone, err)
+	}
+
+	if _, err := s.RestoreUser(ctx, user.Id); err != atmail.ErrUserNone {
+		t.Errorf("want %v; got %v", atmail.ErrUserNone, err)
+	}
+
+	// and only deleted users are purged in bulk
+	users := createUsers(t, s, bluey, chilli)
+
+	if err := s.DeleteUser(ctx, users[0].Id); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids, err := s.ListDeletedUsers(ctx, time.Now().Add(time.Minute)); err != nil || !reflect.DeepEqual([]int64{users[0].Id}, ids) {
+		t.Errorf("want %v; got %v (%v)", []int64{users[0].Id}, ids, err)
+	}
+
+	if ids, err := s.ListDeletedUsers(ctx, time.Now().Add(-time.Minute)); err != nil || len(ids) != 0 {
+		t.Errorf("want %v; got %v (%v)", []int64{}, ids, err)
+	}
+
+	if n, err := s.PurgeUsers(ctx, time.Now().Add(time.Minute)); err != nil || n != 1 {
+		t.Errorf("want %v; got %v (%v)", 1, n, err)
+	}
+
+	if _, err := s.GetUser(ctx, users[1].Id); err != nil {
+		t.Error(err)
+	}
+}
+
+// testSearchUsers runs against the stores that search users themselves, the
+// others are searched by server/search.
+func testSearchUsers(t *testing.T, s atmail.Store) {
+	searcher, ok := s.(atmail.Searcher)
+	if !ok {
+		t.Skip("store cannot search users")
+	}
+
+	ctx := context.Background()
+
+	users := createUsers(t, s, bingo, bluey, chilli)
+
+	for name, tc := range map[string]struct {
+		query string
+		want  []int64
+	}{
+		"word":         {query: "bluey", want: []int64{users[1].Id}},
+		"word prefix":  {query: "heel", want: []int64{users[0].Id, users[1].Id, users[2].Id}},
+		"short term":   {query: "bi", want: []int64{users[0].Id}},
+		"single char":  {query: "b", want: []int64{users[0].Id, users[1].Id}},
+		"short first":  {query: "chi bingo", want: []int64{users[0].Id, users[2].Id}},
+		"no match":     {query: "ba", want: []int64{}},
+		"inner prefix": {query: "ngo", want: []int64{}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			matches, err := searcher.SearchUsers(ctx, tc.query, 10, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []int64{}
+
+			for _, match := range matches {
+				got = append(got, match.User.Id)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v; got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func testDeliveries(t *testing.T, s atmail.Store) {
+	ctx := context.Background()
+
+	hookId, err := s.CreateWebhook(ctx, atmail.Webhook{Url: "https://billing.example.com", Secret: "billing-secret-123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	due := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	id, err := s.CreateWebhookDelivery(ctx, atmail.WebhookDelivery{WebhookId: hookId, Event: atmail.ActionUserCreate, Payload: []byte(`{}`), Status: atmail.DeliveryPending, NextAttemptAt: due})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+
+	if err := s.ClaimWebhookDelivery(ctx, id, now, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// a claimed delivery is no longer due, so it is only claimed once
+	if err := s.ClaimWebhookDelivery(ctx, id, now, now.Add(time.Hour)); err != atmail.ErrWebhookDeliveryNone {
+		t.Errorf("want %v; got %v", atmail.ErrWebhookDeliveryNone, err)
+	}
+
+	if deliveries, err := s.ListWebhookDeliveries(ctx, atmail.WebhookDeliveryOptions{Due: now}); err != nil || len(deliveries) != 0 {
+		t.Errorf("want %v; got %v (%v)", []atmail.WebhookDelivery{}, deliveries, err)
+	}
+
+	// until its claim runs out
+	later := now.Add(2 * time.Hour)
+
+	if err := s.ClaimWebhookDelivery(ctx, id, later, later.Add(time.Hour)); err != nil {
+		t.Error(err)
+	}
+
+	if err := s.ClaimWebhookDelivery(ctx, missingId, later, later.Add(time.Hour)); err != atmail.ErrWebhookDeliveryNone {
+		t.Errorf("want %v; got %v", atmail.ErrWebhookDeliveryNone, err)
+	}
+}
+
+func testWithTx(t *testing.T, s atmail.Store) {
+	ctx := context.Background()
+
+	rollback := errors.New("rollback")
+
+	if err := s.WithTx(ctx, func(tx atmail.Store) error {
+		if _, err := tx.CreateUser(ctx, bingo); err != nil {
+			return err
+		}
+
+		// the transaction sees its own writes
+		if exists, err := tx.CheckUser(ctx, "bingo", ""); err != nil || !exists {
+			t.Errorf("want %v; got %v", true, exists)
+		}
+
+		return rollback
+	}); err != rollback {
+		t.Errorf("want %v; got %v", rollback, err)
+	}
+
+	if exists, err := s.CheckUser(ctx, "bingo", ""); err != nil || exists {
+		t.Errorf("want %v; got %v", false, exists)
+	}
+
+	var id int64
+
+	if err := s.WithTx(ctx, func(tx atmail.Store) error {
+		var err error
+
+		id, err = tx.CreateUser(ctx, bingo)
+
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.GetUser(ctx, id); err != nil {
+		t.Error(err)
+	}
+}
+
+// concurrently is how many goroutines race in testConcurrency.
+const concurrently = 10
+
+// race runs fn concurrently and returns the errors of each run.
+func race(fn func(i int) error) []error {
+	var wg sync.WaitGroup
+
+	errs := make([]error, concurrently)
+
+	for i := range concurrently {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			errs[i] = fn(i)
+		}()
+	}
+
+	wg.Wait()
+
+	return errs
+}
+
+func testConcurrency(t *testing.T, s atmail.Store) {
+	ctx := context.Background()
+
+	// only one of the users taking a username gets it
+	errs := race(func(i int) error {
+		_, err := s.CreateUser(ctx, atmail.User{Username: "bingo", Email: "bingo" + string(rune('a'+i)) + "@heeler.com", Age: 6})
+		return err
+	})
+
+	created := 0
+
+	for _, err := range errs {
+		var exists atmail.ErrUserExists
+
+		switch {
+		case err == nil:
+			created++
+		case !errors.As(err, &exists):
+			t.Errorf("want %v; got %v", atmail.ErrUserExists{Field: "username"}, err)
+		}
+	}
+
+	if created != 1 {
+		t.Errorf("want %v; got %v", 1, created)
+	}
+
+	// every user created at once gets their own id
+	ids := make([]int64, concurrently)
+
+	errs = race(func(i int) error {
+		var err error
+
+		ids[i], err = s.CreateUser(ctx, atmail.User{Username: "bluey" + string(rune('a'+i)), Email: "bluey" + string(rune('a'+i)) + "@heeler.com", Age: 7})
+
+		return err
+	})
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	slices.Sort(ids)
+
+	if len(slices.Compact(ids)) != concurrently {
+		t.Errorf("want %v ids; got %v", concurrently, ids)
+	}
+
+	// only one of the updates of a version wins
+	user := createUsers(t, s, chilli)[0]
+
+	errs = race(func(i int) error {
+		update := user
+		update.Age += uint(i)
+
+		return s.UpdateUser(ctx, update)
+	})
+
+	updated := 0
+
+	for _, err := range errs {
+		var conflict atmail.ErrVersionConflict
+
+		switch {
+		case err == nil:
+			updated++
+		case !errors.As(err, &conflict) || conflict.Version != 2:
+			t.Errorf("want %v; got %v", atmail.ErrVersionConflict{Id: user.Id, Version: 2}, err)
+		}
+	}
+
+	if updated != 1 {
+		t.Errorf("want %v; got %v", 1, updated)
+	}
+
+	// only one of the admins created at once with the same user, whatever
+	// its case, is created
+	errs = race(func(i int) error {
+		user := "dan"
+
+		if i%2 == 1 {
+			user = "Dan"
+		}
+
+		return s.CreateAdmin(ctx, atmail.Admin{User: user, Roles: []string{"bandit"}}, "pass4567")
+	})
+
+	created = 0
+
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case err != atmail.ErrAdminExists:
+			t.Errorf("want %v; got %v", atmail.ErrAdminExists, err)
+		}
+	}
+
+	if created != 1 {
+		t.Errorf("want %v; got %v", 1, created)
+	}
+}
+
+func testAdmins(t *testing.T, s atmail.Store) {
+	ctx := context.Background()
+
+	for name, fn := range map[string]func() error{
+		"GetAdmin":         func() error { _, err := s.GetAdmin(ctx, "dan"); return err },
+		"SetAdminRoles":    func() error { return s.SetAdminRoles(ctx, "dan", []string{"bingo"}) },
+		"SetAdminPassword": func() error { return s.SetAdminPassword(ctx, "dan", "pass8901") },
+		"SetAdminDisabled": func() error { return s.SetAdminDisabled(ctx, "dan", true) },
+		"DeleteAdmin":      func() error { return s.DeleteAdmin(ctx, "dan") },
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := fn(); err != atmail.ErrAdminNone {
+				t.Errorf("want %v; got %v", atmail.ErrAdminNone, err)
+			}
+		})
+	}
+
+	// admins with roles that do not exist are not created
+	if err := s.CreateAdmin(ctx, atmail.Admin{User: "dan", Roles: []string{"muffin"}}, "pass4567"); err != atmail.ErrRoleNone {
+		t.Errorf("want %v; got %v", atmail.ErrRoleNone, err)
+	}
+
+	admin := atmail.Admin{User: "dan", Roles: []string{"bandit", "bingo"}}
+
+	if err := s.CreateAdmin(ctx, admin, "pass4567"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.CreateAdmin(ctx, atmail.Admin{User: "Dan", Roles: []string{"bingo"}}, "pass4567"); err != atmail.ErrAdminExists {
+		t.Errorf("want %v; got %v", atmail.ErrAdminExists, err)
+	}
+
+	got, err := s.GetAdmin(ctx, "dan")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(admin, got) {
+		t.Errorf("want %v; got %v", admin, got)
+	}
+
+	admins, err := s.ListAdmins(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual([]atmail.Admin{admin}, admins) {
+		t.Errorf("want %v; got %v", []atmail.Admin{admin}, admins)
+	}
+
+	if err := s.SetAdminRoles(ctx, "dan", []string{"muffin"}); err != atmail.ErrRoleNone {
+		t.Errorf("want %v; got %v", atmail.ErrRoleNone, err)
+	}
+
+	if err := s.DeleteAdmin(ctx, "dan"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.GetAdmin(ctx, "dan"); err != atmail.ErrAdminNone {
+		t.Errorf("want %v; got %v", atmail.ErrAdminNone, err)
+	}
+}
+
+func testRoles(t *testing.T, s atmail.Store) {
+	ctx := context.Background()
+
+	if err := s.CreateAdmin(ctx, atmail.Admin{User: "dan", Roles: []string{"bingo", "bandit"}}, "pass4567"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.CreateAdmin(ctx, atmail.Admin{User: "erin", Roles: []string{"bluey"}}, "pass8901"); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tc := range map[string]struct {
+		user     string
+		password string
+		want     []string
+		err      error
+	}{
+		"roles": {
+			user:     "dan",
+			password: "pass4567",
+			want:     []string{"bandit", "bingo"},
+		},
+		"user case": {
+			user:     "DAN",
+			password: "pass4567",
+			want:     []string{"bandit", "bingo"},
+		},
+		"wrong password": {
+			user:     "dan",
+			password: "pass8901",
+			err:      atmail.ErrAdminNone,
+		},
+		"password of another admin": {
+			user:     "erin",
+			password: "pass4567",
+			err:      atmail.ErrAdminNone,
+		},
+		"missing admin": {
+			user:     "frank",
+			password: "pass4567",
+			err:      atmail.ErrAdminNone,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			roles, err := s.GetRoles(ctx, tc.user, tc.password)
+			if err != tc.err {
+				t.Fatalf("want %v; got %v", tc.err, err)
+			}
+
+			if tc.err == nil && !reflect.DeepEqual(tc.want, roles) {
+				t.Errorf("want %v; got %v", tc.want, roles)
+			}
+		})
+	}
+
+	// roles follow the changes of the admin
+	if err := s.SetAdminRoles(ctx, "dan", []string{"chilli"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if roles, err := s.GetRoles(ctx, "dan", "pass4567"); err != nil || !reflect.DeepEqual([]string{"chilli"}, roles) {
+		t.Errorf("want %v; got %v (%v)", []string{"chilli"}, roles, err)
+	}
+
+	if err := s.SetAdminPassword(ctx, "dan", "pass2345"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.GetRoles(ctx, "dan", "pass4567"); err != atmail.ErrAdminNone {
+		t.Errorf("want %v; got %v", atmail.ErrAdminNone, err)
+	}
+
+	if err := s.SetAdminDisabled(ctx, "dan", true); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.GetRoles(ctx, "dan", "pass2345"); err != atmail.ErrAdminNone {
+		t.Errorf("want %v; got %v", atmail.ErrAdminNone, err)
+	}
+
+	if err := s.SetAdminDisabled(ctx, "dan", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if roles, err := s.GetRoles(ctx, "dan", "pass2345"); err != nil || !reflect.DeepEqual([]string{"chilli"}, roles) {
+		t.Errorf("want %v; got %v (%v)", []string{"chilli"}, roles, err)
+	}
+
+	// and every role grants permissions
+	permissions, err := s.GetPermissions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, role := range []string{"bingo", "bluey", "chilli", "bandit"} {
+		if len(permissions[role]) == 0 {
+			t.Errorf("want permissions of %v; got %v", role, permissions)
+		}
+	}
+}
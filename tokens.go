@@ -0,0 +1,109 @@
+package atmail
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Token is an API token issued to an admin. Only the SHA-256 hash of the token
+// is stored, the token itself is shown once when it is created.
+type Token struct {
+	Id    int64
+	Admin string
+	Hash  string
+	// Roles restricts the token to a subset of the roles of its admin. A token
+	// created without roles has all the roles of its admin.
+	Roles     []string
+	ExpiresAt time.Time
+}
+
+func (t Token) Expired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+func (s store) CreateToken(ctx context.Context, token Token) (int64, error) {
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	id, err := s.insert(ctx, tx, "INSERT INTO admin_tokens (admin, hash, expires_at) VALUES (?, ?, ?)", token.Admin, token.Hash, token.ExpiresAt.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	for _, role := range token.Roles {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO token_roles (token_id, role_id) SELECT ?, id FROM roles WHERE name = ?", id, role); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// GetToken returns the token with the given hash. The roles of the token are
+// narrowed to the current roles of its admin so that tokens never outlive a
+// demotion, and tokens of disabled admins are not returned at all.
+func (s store) GetToken(ctx context.Context, hash string) (Token, error) {
+	token := Token{}
+
+	if err := s.conn().QueryRowContext(ctx, "SELECT t.id, t.admin, t.hash, t.expires_at FROM admin_tokens t JOIN admins a ON a.`user` = t.admin WHERE t.hash = ? AND NOT a.disabled", hash).Scan(&token.Id, &token.Admin, &token.Hash, &token.ExpiresAt); err != nil {
+		if err != sql.ErrNoRows {
+			return Token{}, err
+		}
+
+		return Token{}, ErrTokenNone
+	}
+
+	rows, err := s.conn().QueryContext(ctx, `SELECT r.name FROM admin_roles ar JOIN roles r ON r.id = ar.role_id
+		WHERE ar.admin = ? AND (
+			NOT EXISTS (SELECT 1 FROM token_roles tr WHERE tr.token_id = ?)
+			OR ar.role_id IN (SELECT tr.role_id FROM token_roles tr WHERE tr.token_id = ?)
+		) ORDER BY r.name`, token.Admin, token.Id, token.Id)
+	if err != nil {
+		return Token{}, err
+	}
+
+	token.Roles, err = scanStrings(rows)
+	if err != nil {
+		return Token{}, err
+	}
+
+	return token, nil
+}
+
+func (s store) DeleteToken(ctx context.Context, id int64, admin string) error {
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM admin_tokens WHERE id = ? AND admin = ?", id, admin)
+	if err != nil {
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count != 1 {
+		return ErrTokenNone
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM token_roles WHERE token_id = ?", id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
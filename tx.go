@@ -0,0 +1,169 @@
+package atmail
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ErrUserExists is returned when a user would take the username or email of
+// another user.
+type ErrUserExists struct {
+	// Field is "username" or "email".
+	Field string
+}
+
+func (e ErrUserExists) Error() string {
+	return fmt.Sprintf("error user exists: %s", e.Field)
+}
+
+// ErrVersionConflict is returned when a user is updated from a version that
+// is not its current version, i.e. someone else updated it in between.
+type ErrVersionConflict struct {
+	Id int64
+	// Version is the current version of the user.
+	Version int64
+}
+
+func (e ErrVersionConflict) Error() string {
+	return fmt.Sprintf("error version conflict: user %d is at version %d", e.Id, e.Version)
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}
+
+type txn interface {
+	querier
+	Commit() error
+	Rollback() error
+}
+
+// nestedTx is a transaction begun within WithTx, it is committed or rolled
+// back along with the outer transaction.
+type nestedTx struct {
+	*sql.Tx
+}
+
+func (tx nestedTx) Commit() error {
+	return nil
+}
+
+func (tx nestedTx) Rollback() error {
+	return nil
+}
+
+func (s store) conn() querier {
+	if s.tx != nil {
+		return s.dialect.querier(s.tx)
+	}
+
+	return s.dialect.querier(writer{s.db})
+}
+
+func (s store) begin(ctx context.Context) (txn, error) {
+	markWritten(ctx)
+
+	if s.tx != nil {
+		return s.dialect.txn(nestedTx{s.tx}), nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.dialect.txn(tx), nil
+}
+
+// insert runs query, which inserts one row into a table with an id column,
+// and returns the id of the row.
+func (s store) insert(ctx context.Context, q querier, query string, args ...any) (int64, error) {
+	markWritten(ctx)
+
+	if s.dialect.returning {
+		var id int64
+
+		err := q.QueryRowContext(ctx, query+" RETURNING id", args...).Scan(&id)
+
+		return id, err
+	}
+
+	result, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+type isolationKey struct{}
+
+// WithIsolation sets the isolation level of the transactions begun by WithTx
+// with ctx. By default it is the default of the database.
+func WithIsolation(ctx context.Context, level sql.IsolationLevel) context.Context {
+	return context.WithValue(ctx, isolationKey{}, level)
+}
+
+// IsolationFromContext returns the isolation level set by WithIsolation.
+func IsolationFromContext(ctx context.Context) sql.IsolationLevel {
+	level, _ := ctx.Value(isolationKey{}).(sql.IsolationLevel)
+	return level
+}
+
+// WithTx calls fn with a store whose methods all run in one transaction. The
+// transaction is committed if fn returns nil and rolled back otherwise. When
+// the store is already in a transaction, fn joins it.
+func (s store) WithTx(ctx context.Context, fn func(Store) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+
+	markWritten(ctx)
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: IsolationFromContext(ctx)})
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	s.tx = tx
+
+	if err := fn(s); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// userExists translates the duplicate key errors of the unique keys of users
+// into ErrUserExists.
+func (s store) userExists(err error) error {
+	key, ok := s.dialect.duplicateKey(err)
+	if !ok {
+		return err
+	}
+
+	key = strings.TrimPrefix(key, "users.")
+
+	if key != "username" && key != "email" {
+		return err
+	}
+
+	return ErrUserExists{key}
+}
+
+// adminExists translates the duplicate key errors of the unique key of admins
+// into ErrAdminExists.
+func (s store) adminExists(err error) error {
+	if key, ok := s.dialect.duplicateKey(err); ok && key == "admins.user" {
+		return ErrAdminExists
+	}
+
+	return err
+}
@@ -0,0 +1,400 @@
+package atmail
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrWebhookNone = errors.New("error webhook none")
+var ErrWebhookDeliveryNone = errors.New("error webhook delivery none")
+
+// WebhookEvents are the actions that webhooks can subscribe to.
+var WebhookEvents = []string{ActionUserCreate, ActionUserUpdate, ActionUserDelete, ActionUserRestore, ActionUserPurge}
+
+// Webhook is a url that the user events it subscribes to are delivered to.
+type Webhook struct {
+	Id  int64
+	Url string
+	// Secret signs the payloads delivered to the webhook.
+	Secret string
+	// Events are the actions delivered to the webhook, all of WebhookEvents
+	// if empty.
+	Events    []string
+	CreatedAt time.Time
+}
+
+// Subscribes reports whether event is delivered to the webhook.
+func (w Webhook) Subscribes(event string) bool {
+	return len(w.Events) == 0 || slices.Contains(w.Events, event)
+}
+
+func ValidateWebhook(w Webhook) (string, bool) {
+	u, err := url.Parse(w.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "url is invalid!", false
+	}
+
+	if len(w.Secret) < 16 {
+		return "secret must be at least 16 characters!", false
+	}
+
+	for _, event := range w.Events {
+		if !slices.Contains(WebhookEvents, event) {
+			return fmt.Sprintf("event %s is invalid!", event), false
+		}
+	}
+
+	return "", true
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is an event waiting to be delivered to a webhook, or that
+// was, in the outbox. Deliveries are added in the same transaction as the
+// change of the event, so that no change goes undelivered.
+type WebhookDelivery struct {
+	Id        int64
+	WebhookId int64
+	Event     string
+	// Payload is the json body delivered.
+	Payload []byte
+	// Status is one of the Delivery constants.
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+}
+
+// WebhookAttempt is one attempt at delivering a delivery. StatusCode is 0 if
+// there was no response, in which case Error says why.
+type WebhookAttempt struct {
+	Id         int64
+	DeliveryId int64
+	Time       time.Time
+	StatusCode int
+	Error      string
+	Duration   time.Duration
+}
+
+// WebhookDeliveryOptions filters the deliveries returned by
+// ListWebhookDeliveries, newest first. Zero fields do not filter.
+type WebhookDeliveryOptions struct {
+	Limit int
+	// Before only returns deliveries older than the delivery with this id.
+	Before    int64
+	WebhookId int64
+	Status    string
+	// Due only returns pending deliveries whose next attempt is not after it.
+	Due time.Time
+	// Asc returns the oldest deliveries first.
+	Asc bool
+}
+
+// Match reports whether delivery passes the filters of o.
+func (o WebhookDeliveryOptions) Match(d WebhookDelivery) bool {
+	return (o.Before == 0 || d.Id < o.Before) &&
+		(o.WebhookId == 0 || d.WebhookId == o.WebhookId) &&
+		(o.Status == "" || d.Status == o.Status) &&
+		(o.Due.IsZero() || (d.Status == DeliveryPending && !d.NextAttemptAt.After(o.Due)))
+}
+
+func (s store) CreateWebhook(ctx context.Context, w Webhook) (int64, error) {
+	if w.CreatedAt.IsZero() {
+		w.CreatedAt = time.Now()
+	}
+
+	return s.insert(ctx, s.conn(), "INSERT INTO webhooks (url, secret, events, created_at) VALUES (?, ?, ?, ?)", w.Url, w.Secret, strings.Join(w.Events, ","), w.CreatedAt.UTC())
+}
+
+func scanWebhook(row interface{ Scan(...any) error }) (Webhook, error) {
+	w := Webhook{}
+
+	var events string
+
+	if err := row.Scan(&w.Id, &w.Url, &w.Secret, &events, &w.CreatedAt); err != nil {
+		return Webhook{}, err
+	}
+
+	if events != "" {
+		w.Events = strings.Split(events, ",")
+	}
+
+	return w, nil
+}
+
+func (s store) GetWebhook(ctx context.Context, id int64) (Webhook, error) {
+	w, err := scanWebhook(s.conn().QueryRowContext(ctx, "SELECT id, url, secret, events, created_at FROM webhooks WHERE id = ?", id))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return Webhook{}, err
+		}
+
+		return Webhook{}, ErrWebhookNone
+	}
+
+	return w, nil
+}
+
+func (s store) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	rows, err := s.conn().QueryContext(ctx, "SELECT id, url, secret, events, created_at FROM webhooks ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	webhooks := []Webhook{}
+
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (s store) UpdateWebhook(ctx context.Context, w Webhook) error {
+	result, err := s.conn().ExecContext(ctx, "UPDATE webhooks SET url = ?, secret = ?, events = ? WHERE id = ?", w.Url, w.Secret, strings.Join(w.Events, ","), w.Id)
+	if err != nil {
+		return err
+	}
+
+	// unchanged webhooks are not affected either
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		if _, err := s.GetWebhook(ctx, w.Id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteWebhook deletes the webhook along with its deliveries.
+func (s store) DeleteWebhook(ctx context.Context, id int64) error {
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count != 1 {
+		return ErrWebhookNone
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM webhook_attempts WHERE delivery_id IN (SELECT id FROM webhook_deliveries WHERE webhook_id = ?)", id); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE webhook_id = ?", id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s store) CreateWebhookDelivery(ctx context.Context, d WebhookDelivery) (int64, error) {
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now()
+	}
+
+	return s.insert(ctx, s.conn(), "INSERT INTO webhook_deliveries (webhook_id, event, payload, status, attempts, next_attempt_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)", d.WebhookId, d.Event, d.Payload, d.Status, d.Attempts, d.NextAttemptAt.UTC(), d.CreatedAt.UTC())
+}
+
+const deliveryColumns = "id, webhook_id, event, payload, status, attempts, next_attempt_at, created_at"
+
+func scanDelivery(row interface{ Scan(...any) error }) (WebhookDelivery, error) {
+	d := WebhookDelivery{}
+
+	if err := row.Scan(&d.Id, &d.WebhookId, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.CreatedAt); err != nil {
+		return WebhookDelivery{}, err
+	}
+
+	return d, nil
+}
+
+func (s store) GetWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error) {
+	d, err := scanDelivery(s.conn().QueryRowContext(ctx, "SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE id = ?", id))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return WebhookDelivery{}, err
+		}
+
+		return WebhookDelivery{}, ErrWebhookDeliveryNone
+	}
+
+	return d, nil
+}
+
+func (s store) ListWebhookDeliveries(ctx context.Context, o WebhookDeliveryOptions) ([]WebhookDelivery, error) {
+	where := []string{"TRUE"}
+	args := []any{}
+
+	if o.Before != 0 {
+		where = append(where, "id < ?")
+		args = append(args, o.Before)
+	}
+
+	if o.WebhookId != 0 {
+		where = append(where, "webhook_id = ?")
+		args = append(args, o.WebhookId)
+	}
+
+	if o.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, o.Status)
+	}
+
+	if !o.Due.IsZero() {
+		where = append(where, "status = ? AND next_attempt_at <= ?")
+		args = append(args, DeliveryPending, o.Due.UTC())
+	}
+
+	query := "SELECT " + deliveryColumns + " FROM webhook_deliveries WHERE " + strings.Join(where, " AND ") + " ORDER BY id"
+
+	if !o.Asc {
+		query += " DESC"
+	}
+
+	if o.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, o.Limit)
+	}
+
+	rows, err := s.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	deliveries := []WebhookDelivery{}
+
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// UpdateWebhookDelivery updates the status, attempts and next attempt of the
+// delivery.
+func (s store) UpdateWebhookDelivery(ctx context.Context, d WebhookDelivery) error {
+	result, err := s.conn().ExecContext(ctx, "UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ? WHERE id = ?", d.Status, d.Attempts, d.NextAttemptAt.UTC(), d.Id)
+	if err != nil {
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		if _, err := s.GetWebhookDelivery(ctx, d.Id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ClaimWebhookDelivery leases the delivery until until, if it is still
+// pending and due at due, so that no other dispatcher attempts it in the
+// meantime. It fails with ErrWebhookDeliveryNone if it is not, e.g. because
+// another dispatcher claimed it first.
+func (s store) ClaimWebhookDelivery(ctx context.Context, id int64, due time.Time, until time.Time) error {
+	result, err := s.conn().ExecContext(ctx, "UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id = ? AND status = ? AND next_attempt_at <= ?", until.UTC(), id, DeliveryPending, due.UTC())
+	if err != nil {
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count != 1 {
+		return ErrWebhookDeliveryNone
+	}
+
+	return nil
+}
+
+func (s store) CreateWebhookAttempt(ctx context.Context, a WebhookAttempt) (int64, error) {
+	return s.insert(ctx, s.conn(), "INSERT INTO webhook_attempts (delivery_id, time, status_code, error, duration_ms) VALUES (?, ?, ?, ?, ?)", a.DeliveryId, a.Time.UTC(), a.StatusCode, a.Error, a.Duration.Milliseconds())
+}
+
+// ListWebhookAttempts lists the attempts of a delivery, oldest first.
+func (s store) ListWebhookAttempts(ctx context.Context, deliveryId int64) ([]WebhookAttempt, error) {
+	rows, err := s.conn().QueryContext(ctx, "SELECT id, delivery_id, time, status_code, error, duration_ms FROM webhook_attempts WHERE delivery_id = ? ORDER BY id", deliveryId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	attempts := []WebhookAttempt{}
+
+	for rows.Next() {
+		a := WebhookAttempt{}
+
+		var ms int64
+
+		if err := rows.Scan(&a.Id, &a.DeliveryId, &a.Time, &a.StatusCode, &a.Error, &ms); err != nil {
+			return nil, err
+		}
+
+		a.Duration = time.Duration(ms) * time.Millisecond
+
+		attempts = append(attempts, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attempts, nil
+}